@@ -0,0 +1,68 @@
+// --- File: cmd/keyservice/auth_test.go ---
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/keyservice/config"
+)
+
+func TestNewAuthMiddleware_AuthMode(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	const apiKey = "pre-shared-key"
+
+	// A minimal identity service: discovery metadata and an empty key set.
+	var identity *httptest.Server
+	identity = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/.well-known/oauth-authorization-server":
+			_, _ = io.WriteString(w, `{"issuer":"`+identity.URL+`","jwks_uri":"`+identity.URL+`/jwks.json","id_token_signing_alg_values_supported":["RS256"]}`)
+		case "/jwks.json":
+			_, _ = io.WriteString(w, `{"keys":[]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(identity.Close)
+
+	// serveWithAPIKey sends a request carrying only API key credentials.
+	serveWithAPIKey := func(t *testing.T, cfg *config.Config) int {
+		t.Helper()
+		authMiddleware, err := newAuthMiddleware(cfg, logger)
+		require.NoError(t, err)
+		handler := authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		req := httptest.NewRequest(http.MethodPost, "/keys/urn:sm:user:someone", nil)
+		req.Header.Set(api.APIKeyHeader, apiKey)
+		req.Header.Set(api.EntityIDHeader, "someone")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	t.Run("Success - apikey mode accepts the key", func(t *testing.T) {
+		// Act
+		code := serveWithAPIKey(t, &config.Config{AuthMode: config.AuthModeAPIKey, APIKey: apiKey})
+
+		// Assert
+		assert.Equal(t, http.StatusNoContent, code)
+	})
+
+	t.Run("Failure - jwt mode ignores the key", func(t *testing.T) {
+		// Act
+		code := serveWithAPIKey(t, &config.Config{AuthMode: config.AuthModeJWT, IdentityServiceURL: identity.URL, APIKey: apiKey})
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+}
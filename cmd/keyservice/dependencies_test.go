@@ -0,0 +1,126 @@
+// --- File: cmd/keyservice/dependencies_test.go ---
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+
+	"github.com/tinywideclouds/go-key-service/internal/storage/cdc"
+	fs "github.com/tinywideclouds/go-key-service/internal/storage/firestore"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/internal/storage/shadow"
+	"github.com/tinywideclouds/go-key-service/keyservice/config"
+)
+
+func TestNewDependencies_FirestoreFailure(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	failingFactory := func(context.Context, string) (*firestore.Client, error) {
+		return nil, errors.New("no credentials")
+	}
+
+	t.Run("Success - falls back to in-memory when enabled", func(t *testing.T) {
+		// Arrange
+		cfg := &config.Config{ProjectID: "test-project", FallbackToInMemory: true}
+
+		// Act
+		store, cleanup, err := newDependencies(context.Background(), cfg, logger, failingFactory)
+
+		// Assert
+		require.NoError(t, err)
+		t.Cleanup(cleanup)
+		assert.IsType(t, &inmemory.Store{}, store)
+	})
+
+	t.Run("Success - wraps the fallback store for change records", func(t *testing.T) {
+		// Arrange
+		cfg := &config.Config{ProjectID: "test-project", FallbackToInMemory: true,
+			CDCFilePath: filepath.Join(t.TempDir(), "cdc.jsonl")}
+
+		// Act
+		store, cleanup, err := newDependencies(context.Background(), cfg, logger, failingFactory)
+
+		// Assert
+		require.NoError(t, err)
+		t.Cleanup(cleanup)
+		assert.IsType(t, &cdc.Store{}, store)
+	})
+
+	t.Run("Failure - exits when fallback is disabled", func(t *testing.T) {
+		// Arrange
+		cfg := &config.Config{ProjectID: "test-project"}
+
+		// Act
+		store, cleanup, err := newDependencies(context.Background(), cfg, logger, failingFactory)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, store)
+		assert.Nil(t, cleanup)
+	})
+}
+
+func TestNewDependencies_ShadowCollection(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	// The client connects lazily, so no Firestore is needed to build the store.
+	offlineFactory := func(ctx context.Context, projectID string) (*firestore.Client, error) {
+		return firestore.NewClient(ctx, projectID, option.WithoutAuthentication(), option.WithEndpoint("localhost:1"))
+	}
+
+	// Arrange
+	cfg := &config.Config{ProjectID: "test-project", FirestoreCollection: "public-keys", ShadowFirestoreCollection: "public-keys-v2"}
+
+	// Act
+	store, cleanup, err := newDependencies(context.Background(), cfg, logger, offlineFactory)
+
+	// Assert
+	require.NoError(t, err)
+	t.Cleanup(cleanup)
+	assert.IsType(t, &shadow.Store{}, store)
+}
+
+func TestCheckFirestoreIndexes(t *testing.T) {
+	client, err := firestore.NewClient(context.Background(), "test-project",
+		option.WithoutAuthentication(), option.WithEndpoint("localhost:1"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	store := fs.NewFirestoreStore(client, "public-keys", slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	t.Run("Success - logs the commands for enabled admin queries", func(t *testing.T) {
+		// Arrange
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&logs, nil))
+		cfg := &config.Config{AdminUserIDs: []string{"admin"}}
+
+		// Act
+		err := checkFirestoreIndexes(context.Background(), cfg, store, logger)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Contains(t, logs.String(), "gcloud firestore indexes fields update sigKey --collection-group=public-keys")
+		assert.Contains(t, logs.String(), "gcloud firestore indexes fields update updatedAt --collection-group=public-keys")
+	})
+
+	t.Run("Success - no guidance without admin routes", func(t *testing.T) {
+		// Arrange
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&logs, nil))
+		cfg := &config.Config{VerifyFirestoreIndexes: true}
+
+		// Act
+		err := checkFirestoreIndexes(context.Background(), cfg, store, logger)
+
+		// Assert
+		require.NoError(t, err)
+		assert.NotContains(t, logs.String(), "Firestore index required")
+	})
+}
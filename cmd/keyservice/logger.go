@@ -0,0 +1,35 @@
+// --- File: cmd/keyservice/logger.go ---
+package main
+
+import (
+	"io"
+	"log/slog"
+
+	"github.com/tinywideclouds/go-key-service/keyservice/config"
+)
+
+// parseLogLevel maps a LOG_LEVEL value to a slog level, defaulting to info.
+func parseLogLevel(level string) slog.Level {
+	switch level {
+	case "debug", "DEBUG":
+		return slog.LevelDebug
+	case "info", "INFO":
+		return slog.LevelInfo
+	case "warn", "WARN":
+		return slog.LevelWarn
+	case "error", "ERROR":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// newLogHandler builds the slog handler for the given output format.
+// Anything other than config.LogFormatText produces JSON.
+func newLogHandler(w io.Writer, format string, level slog.Level) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level}
+	if format == config.LogFormatText {
+		return slog.NewTextHandler(w, opts)
+	}
+	return slog.NewJSONHandler(w, opts)
+}
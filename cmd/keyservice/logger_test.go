@@ -0,0 +1,45 @@
+// --- File: cmd/keyservice/logger_test.go ---
+package main
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tinywideclouds/go-key-service/keyservice/config"
+)
+
+func TestNewLogHandler(t *testing.T) {
+	testCases := []struct {
+		name   string
+		format string
+		isJSON bool
+	}{
+		{"json", config.LogFormatJSON, true},
+		{"text", config.LogFormatText, false},
+		{"empty falls back to json", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			handler := newLogHandler(io.Discard, tc.format, slog.LevelInfo)
+
+			// Assert
+			if tc.isJSON {
+				assert.IsType(t, &slog.JSONHandler{}, handler)
+			} else {
+				assert.IsType(t, &slog.TextHandler{}, handler)
+			}
+		})
+	}
+}
+
+func TestNewLogHandler_Level(t *testing.T) {
+	handler := newLogHandler(io.Discard, config.LogFormatText, parseLogLevel("warn"))
+
+	assert.False(t, handler.Enabled(t.Context(), slog.LevelInfo))
+	assert.True(t, handler.Enabled(t.Context(), slog.LevelWarn))
+}
@@ -15,7 +15,15 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/pubsub/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/cdc"
 	fs "github.com/tinywideclouds/go-key-service/internal/storage/firestore"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/internal/storage/keyevents"
+	"github.com/tinywideclouds/go-key-service/internal/storage/rediscache"
+	"github.com/tinywideclouds/go-key-service/internal/storage/shadow"
 	"github.com/tinywideclouds/go-key-service/keyservice"
 	"github.com/tinywideclouds/go-key-service/keyservice/config"
 	keyservicepkg "github.com/tinywideclouds/go-key-service/pkg/keystore"
@@ -27,36 +35,32 @@ import (
 var configFile []byte
 
 func main() {
+	// --- 0. Read the raw YAML first; the log format defaults by run_mode ---
+	var yamlCfg config.YamlConfig
+	if err := yaml.Unmarshal(configFile, &yamlCfg); err != nil {
+		slog.Error("Failed to unmarshal embedded yaml config", "err", err)
+		os.Exit(1)
+	}
+
 	// 1. Setup structured logging
-	var logLevel slog.Level
-	switch os.Getenv("LOG_LEVEL") {
-	case "debug", "DEBUG":
-		logLevel = slog.LevelDebug
-	case "info", "INFO":
-		logLevel = slog.LevelInfo
-	case "warn", "WARN":
-		logLevel = slog.LevelWarn
-	case "error", "ERROR":
-		logLevel = slog.LevelError
-	default:
-		logLevel = slog.LevelInfo
+	logLevel := parseLogLevel(os.Getenv("LOG_LEVEL"))
+	requestedFormat := os.Getenv("LOG_FORMAT")
+	if requestedFormat == "" {
+		requestedFormat = yamlCfg.LogFormat
+	}
+	logFormat, err := config.ResolveLogFormat(requestedFormat, yamlCfg.RunMode)
+	if err != nil {
+		slog.Error("Invalid log format", "err", err)
+		os.Exit(1)
 	}
 
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: logLevel,
-	}))
+	logger := slog.New(newLogHandler(os.Stdout, logFormat, logLevel))
 	slog.SetDefault(logger) // Set as the global default
 
-	logger.Info("Starting Key Service", "logLevel", logLevel)
+	logger.Info("Starting Key Service", "logLevel", logLevel, "logFormat", logFormat)
 
 	ctx := context.Background()
 	// --- 1. Load Configuration (Stage 1: From YAML) ---
-	var yamlCfg config.YamlConfig
-
-	if err := yaml.Unmarshal(configFile, &yamlCfg); err != nil {
-		logger.Error("Failed to unmarshal embedded yaml config", "err", err)
-		os.Exit(1)
-	}
 
 	baseCfg, err := config.NewConfigFromYaml(&yamlCfg, logger)
 	if err != nil {
@@ -71,12 +75,12 @@ func main() {
 		os.Exit(1)
 	}
 
-	logger.Info("Configuration loaded", "run_mode", cfg.RunMode)
+	config.LogEffectiveConfig(logger, cfg)
 
 	// --- 3. Dependency Injection ---
 
 	// 3a. Data Store
-	store, err := newDependencies(ctx, cfg, logger)
+	store, closeStore, err := newDependencies(ctx, cfg, logger, newFirestoreClient)
 	if err != nil {
 		logger.Error("Failed to initialize core dependencies", "err", err)
 		os.Exit(1)
@@ -111,33 +115,192 @@ func main() {
 		os.Exit(1)
 	case sig := <-quit:
 		logger.Info("OS signal received, initiating shutdown.", "signal", sig.String())
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		// Allow the drain period on top of the time to finish in-flight requests.
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second+cfg.ShutdownDrainPeriod)
 		defer cancel()
 		if shutdownErr := service.Shutdown(ctx); shutdownErr != nil {
 			logger.Error("Service shutdown failed", "err", shutdownErr)
 		} else {
 			logger.Info("Service shutdown complete")
 		}
+		// No request can reach the store any more, so its connections and
+		// background work can go.
+		closeStore()
 	}
 }
 
-// newDependencies builds the service's data layer dependencies (Firestore client and the Store).
-func newDependencies(ctx context.Context, cfg *config.Config, logger *slog.Logger) (keyservicepkg.Store, error) {
-	logger.Debug("Connecting to Firestore", "project_id", cfg.ProjectID)
-	fsClient, err := firestore.NewClient(ctx, cfg.ProjectID)
+// firestoreClientFactory creates the Firestore client for a project.
+type firestoreClientFactory func(ctx context.Context, projectID string) (*firestore.Client, error)
+
+// newFirestoreClient is the production firestoreClientFactory.
+func newFirestoreClient(ctx context.Context, projectID string) (*firestore.Client, error) {
+	return firestore.NewClient(ctx, projectID)
+}
+
+// newDependencies builds the service's data layer dependencies (Firestore
+// client and the Store). The returned cleanup releases the connections and
+// background work the store holds, in the reverse of the order they were
+// opened, and must be called once the store is no longer in use.
+func newDependencies(ctx context.Context, cfg *config.Config, logger *slog.Logger, newClient firestoreClientFactory) (keyservicepkg.Store, func(), error) {
+	var closers []func()
+	cleanup := func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
+	}
+
+	// Change records wrap the backend itself, beneath any shadow or cache,
+	// so key stores can read the before-image in their own transaction.
+	cdcSink, err := newCDCSink(ctx, cfg, logger)
 	if err != nil {
+		return nil, nil, err
+	}
+	withCDC := func(backend keyservicepkg.Store) keyservicepkg.Store {
+		if cdcSink == nil {
+			return backend
+		}
+		return cdc.New(backend, cdcSink, logger, cdc.WithActor(func(ctx context.Context) string {
+			userID, _ := middleware.GetUserIDFromContext(ctx)
+			return userID
+		}))
+	}
+
+	logger.Debug("Connecting to Firestore", "project_id", cfg.ProjectID)
+	var store keyservicepkg.Store
+	fsClient, err := newClient(ctx, cfg.ProjectID)
+	switch {
+	case err == nil:
+		// Use the collection name from the configuration
+		fsOpts := []fs.Option{fs.WithMaxConcurrency(cfg.FirestoreMaxConcurrency)}
+		if cfg.AccessCounting {
+			fsOpts = append(fsOpts, fs.WithAccessCounting())
+		}
+		fsStore := fs.NewFirestoreStore(fsClient, cfg.FirestoreCollection, logger, fsOpts...)
+		if err := checkFirestoreIndexes(ctx, cfg, fsStore, logger); err != nil {
+			return nil, nil, err
+		}
+		store = withCDC(fsStore)
+		logger.Info("Using Firestore key store",
+			"project_id", cfg.ProjectID,
+			"collection", cfg.FirestoreCollection,
+			"max_concurrency", cfg.FirestoreMaxConcurrency)
+		if cfg.ShadowFirestoreCollection != "" {
+			logger.Info("Shadowing reads to a candidate collection", "collection", cfg.ShadowFirestoreCollection)
+			candidate := fs.NewFirestoreStore(fsClient, cfg.ShadowFirestoreCollection, logger,
+				fs.WithMaxConcurrency(cfg.FirestoreMaxConcurrency))
+			store = shadow.New(store, candidate, 0, logger)
+		}
+	case cfg.FallbackToInMemory:
+		logger.Error("FIRESTORE UNAVAILABLE: falling back to a NON-DURABLE in-memory key store. Stored keys will be lost on restart.",
+			"project_id", cfg.ProjectID, "err", err)
+		memOpts := []inmemory.Option{inmemory.WithStatsLogging(logger, cfg.InMemoryStatsInterval)}
+		if cfg.AccessCounting {
+			memOpts = append(memOpts, inmemory.WithAccessCounting())
+		}
+		memStore := inmemory.New(memOpts...)
+		closers = append(closers, func() { _ = memStore.Close() })
+		store = withCDC(memStore)
+	default:
 		logger.Error("Failed to create Firestore client", "project_id", cfg.ProjectID, "err", err)
-		return nil, fmt.Errorf("failed to create Firestore client for project %s: %w", cfg.ProjectID, err)
+		return nil, nil, fmt.Errorf("failed to create Firestore client for project %s: %w", cfg.ProjectID, err)
 	}
 
-	// Use the collection name from the configuration
-	store := fs.NewFirestoreStore(fsClient, cfg.FirestoreCollection, logger)
-	logger.Info("Using Firestore key store", "project_id", cfg.ProjectID, "collection", cfg.FirestoreCollection)
-	return store, nil
+	if cfg.RedisAddr != "" {
+		client := redis.NewClient(&redis.Options{Addr: cfg.RedisAddr, Password: cfg.RedisPassword})
+		closers = append(closers, func() {
+			if err := client.Close(); err != nil {
+				logger.Warn("Failed to close the Redis client", "err", err)
+			}
+		})
+		cached, err := rediscache.New(ctx, store, client, cfg.RedisCacheTTL, logger)
+		if err != nil {
+			logger.Error("Failed to connect to the Redis cache", "addr", cfg.RedisAddr, "err", err)
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to connect to Redis at %s: %w", cfg.RedisAddr, err)
+		}
+		// The invalidation subscriber goes before the client it uses.
+		closers = append(closers, func() {
+			if err := cached.Close(); err != nil {
+				logger.Warn("Failed to stop the Redis invalidation subscriber", "err", err)
+			}
+		})
+		logger.Info("Caching key reads in Redis", "addr", cfg.RedisAddr, "ttl", cfg.RedisCacheTTL)
+		store = cached
+	}
+
+	if cfg.KeyEventsTopic == "" {
+		return store, cleanup, nil
+	}
+	psClient, err := pubsub.NewClient(ctx, cfg.ProjectID)
+	if err != nil {
+		logger.Error("Failed to create Pub/Sub client", "project_id", cfg.ProjectID, "err", err)
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to create Pub/Sub client for project %s: %w", cfg.ProjectID, err)
+	}
+	closers = append(closers, func() {
+		if err := psClient.Close(); err != nil {
+			logger.Warn("Failed to close the Pub/Sub client", "err", err)
+		}
+	})
+	logger.Info("Publishing key change events", "topic", cfg.KeyEventsTopic)
+	publisher := keyevents.NewPubSubPublisher(psClient, cfg.KeyEventsTopic)
+	// Pending events are flushed before the client goes.
+	closers = append(closers, publisher.Stop)
+	return keyevents.New(store, publisher, "", logger), cleanup, nil
 }
 
-// newAuthMiddleware creates the JWT-validating middleware.
+// checkFirestoreIndexes logs the indexes the enabled features' queries need
+// and, when verify_firestore_indexes is set, fails if any is missing.
+func checkFirestoreIndexes(ctx context.Context, cfg *config.Config, store *fs.Store, logger *slog.Logger) error {
+	// The queries are only issued from admin routes.
+	adminEnabled := len(cfg.AdminUserIDs) > 0
+	reqs := store.RequiredIndexes(fs.IndexFeatures{MissingSigKey: adminEnabled, DeleteOlderThan: adminEnabled})
+	for _, req := range reqs {
+		logger.Info("Firestore index required", "feature", req.Feature, "field", req.Field, "command", req.Command)
+	}
+	if !cfg.VerifyFirestoreIndexes || len(reqs) == 0 {
+		return nil
+	}
+	if err := store.VerifyIndexes(ctx, reqs); err != nil {
+		logger.Error("Firestore index check failed", "err", err)
+		return fmt.Errorf("firestore index check failed: %w", err)
+	}
+	logger.Info("Verified Firestore indexes", "count", len(reqs))
+	return nil
+}
+
+// newCDCSink returns the configured change-data-capture sink, or nil when
+// change records are disabled.
+func newCDCSink(ctx context.Context, cfg *config.Config, logger *slog.Logger) (cdc.Sink, error) {
+	switch {
+	case cfg.CDCFilePath != "":
+		sink, err := cdc.NewFileSink(cfg.CDCFilePath)
+		if err != nil {
+			logger.Error("Failed to open the CDC file", "path", cfg.CDCFilePath, "err", err)
+			return nil, err
+		}
+		logger.Info("Writing change records to a file", "path", cfg.CDCFilePath)
+		return sink, nil
+	case cfg.CDCPubSubTopic != "":
+		psClient, err := pubsub.NewClient(ctx, cfg.ProjectID)
+		if err != nil {
+			logger.Error("Failed to create Pub/Sub client", "project_id", cfg.ProjectID, "err", err)
+			return nil, fmt.Errorf("failed to create Pub/Sub client for project %s: %w", cfg.ProjectID, err)
+		}
+		logger.Info("Publishing change records", "topic", cfg.CDCPubSubTopic)
+		return cdc.NewPubSubSink(psClient, cfg.CDCPubSubTopic), nil
+	}
+	return nil, nil
+}
+
+// newAuthMiddleware creates the JWT-validating middleware, or the API key
+// middleware when auth_mode is apikey.
 func newAuthMiddleware(cfg *config.Config, logger *slog.Logger) (func(http.Handler) http.Handler, error) {
+	if cfg.AuthMode == config.AuthModeAPIKey {
+		logger.Warn("Authenticating with a pre-shared API key; any caller holding it can act for any entity")
+		return api.APIKeyAuth(cfg.APIKey, logger), nil
+	}
+
 	sanitizedIdentityURL := strings.Trim(cfg.IdentityServiceURL, "\"")
 	logger.Debug("Discovering JWT config", "identity_url", sanitizedIdentityURL)
 
@@ -0,0 +1,232 @@
+// --- File: internal/api/asyncwrites.go ---
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tinywideclouds/go-key-service/internal/storage/budget"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// asyncWriteTimeout bounds a single queued write against the store.
+const asyncWriteTimeout = 30 * time.Second
+
+// asyncWriteRetention is how long a finished write's outcome can be read.
+const asyncWriteRetention = 15 * time.Minute
+
+// Async write states reported by GET /writes/{writeID}.
+const (
+	AsyncWritePending   = "pending"
+	AsyncWriteSucceeded = "succeeded"
+	AsyncWriteFailed    = "failed"
+)
+
+// AsyncWrites queues key writes accepted with Prefer: respond-async and
+// persists them in order on one background worker. The outcome of each
+// write is kept for asyncWriteRetention after it finishes, for
+// GET /writes/{writeID}.
+type AsyncWrites struct {
+	store  keystore.Store
+	lock   *WriteLock
+	logger *slog.Logger
+	now    func() time.Time
+
+	queue chan *asyncWrite
+	done  chan struct{}
+
+	mu     sync.Mutex
+	writes map[string]*asyncWrite
+	closed bool
+}
+
+// asyncWrite is one queued write. The fields below the blank line are
+// guarded by AsyncWrites.mu.
+type asyncWrite struct {
+	id        string
+	owner     string
+	entityURN urn.URN
+	keys      keys.PublicKeys
+	meta      keystore.Metadata
+	ctx       context.Context
+	logger    *slog.Logger
+
+	status        string
+	err           error
+	code          string
+	correlationID string
+	writtenAfter  time.Time
+	finishedAt    time.Time
+}
+
+// NewAsyncWrites starts the worker for a queue of up to queueSize writes.
+// Writes are refused while lock is held at the time they reach the front
+// of the queue, as they would have been if sent synchronously.
+func NewAsyncWrites(store keystore.Store, lock *WriteLock, queueSize int, logger *slog.Logger) *AsyncWrites {
+	q := &AsyncWrites{
+		store:  store,
+		lock:   lock,
+		logger: logger.With("component", "async_writes"),
+		now:    time.Now,
+		queue:  make(chan *asyncWrite, queueSize),
+		done:   make(chan struct{}),
+		writes: make(map[string]*asyncWrite),
+	}
+	go q.run()
+	return q
+}
+
+// Enqueue queues a write of keys and meta to entityURN on behalf of owner
+// and returns its ID. It reports false, queueing nothing, when the queue
+// is full or closed; the caller should then write synchronously. The
+// write keeps ctx's values but not its cancellation.
+func (q *AsyncWrites) Enqueue(ctx context.Context, owner string, entityURN urn.URN, keys keys.PublicKeys, meta keystore.Metadata, logger *slog.Logger) (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return "", false
+	}
+	q.pruneLocked()
+
+	id := uuid.NewString()
+	write := &asyncWrite{
+		id:        id,
+		owner:     owner,
+		entityURN: entityURN,
+		keys:      keys,
+		meta:      meta,
+		ctx:       context.WithoutCancel(ctx),
+		logger:    logger.With("write_id", id),
+		status:    AsyncWritePending,
+	}
+	select {
+	case q.queue <- write:
+	default:
+		return "", false
+	}
+	q.writes[id] = write
+	return write.id, true
+}
+
+// asyncWriteStatus is a snapshot of one write's progress.
+type asyncWriteStatus struct {
+	ID            string
+	EntityURN     urn.URN
+	Status        string
+	Err           error
+	Code          string
+	CorrelationID string
+	WrittenAfter  time.Time
+}
+
+// status returns the write's progress if it exists and belongs to owner.
+func (q *AsyncWrites) status(id, owner string) (asyncWriteStatus, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	write, ok := q.writes[id]
+	if !ok || write.owner != owner {
+		return asyncWriteStatus{}, false
+	}
+	return asyncWriteStatus{
+		ID:            write.id,
+		EntityURN:     write.entityURN,
+		Status:        write.status,
+		Err:           write.err,
+		Code:          write.code,
+		CorrelationID: write.correlationID,
+		WrittenAfter:  write.writtenAfter,
+	}, true
+}
+
+// Close stops accepting writes and waits until every queued write has been
+// persisted or ctx is done, whichever is first.
+func (q *AsyncWrites) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.queue)
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		q.logger.Warn("Abandoned queued key writes at shutdown", "queued", len(q.queue))
+		return ctx.Err()
+	}
+}
+
+// run persists queued writes until the queue is closed and empty.
+func (q *AsyncWrites) run() {
+	defer close(q.done)
+	for write := range q.queue {
+		q.persist(write)
+	}
+}
+
+// persist writes one queued entry and records its outcome.
+func (q *AsyncWrites) persist(write *asyncWrite) {
+	var err error
+	var code, correlationID string
+	writtenAfter := q.now()
+	if q.lock != nil && q.lock.Locked() {
+		err, code = errors.New("writes are locked"), ErrCodeWritesLocked
+		write.logger.Info("StoreKeys: Async write refused while writes are locked")
+	} else {
+		ctx, cancel := context.WithTimeout(write.ctx, asyncWriteTimeout)
+		err = q.store.StorePublicKeysWithMetadata(ctx, write.entityURN, write.keys, write.meta)
+		cancel()
+		switch {
+		case err == nil:
+			write.logger.Info("StoreKeys: Async write stored public keys")
+		case errors.Is(err, budget.ErrExceeded):
+			code = ErrCodeStorageBudgetExceeded
+			write.logger.Warn("StoreKeys: Rejected async write over the storage budget")
+		default:
+			correlationID = uuid.NewString()
+			write.logger.Error("StoreKeys: Async write failed to store public keys", "err", err, "correlation_id", correlationID)
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	write.status = AsyncWriteSucceeded
+	if err != nil {
+		write.status = AsyncWriteFailed
+	}
+	write.err, write.code, write.correlationID = err, code, correlationID
+	write.writtenAfter = writtenAfter
+	write.finishedAt = q.now()
+	// The entry only serves status reads from here on.
+	write.keys, write.meta, write.ctx = keys.PublicKeys{}, keystore.Metadata{}, nil
+}
+
+// pruneLocked drops finished writes past their retention. q.mu must be held.
+func (q *AsyncWrites) pruneLocked() {
+	cutoff := q.now().Add(-asyncWriteRetention)
+	for id, write := range q.writes {
+		if write.status != AsyncWritePending && write.finishedAt.Before(cutoff) {
+			delete(q.writes, id)
+		}
+	}
+}
+
+// prefersAsync reports whether a Prefer header value asks for respond-async.
+func prefersAsync(prefer string) bool {
+	for _, preference := range strings.Split(prefer, ",") {
+		token, _, _ := strings.Cut(preference, ";")
+		token, _, _ = strings.Cut(token, "=")
+		if strings.EqualFold(strings.TrimSpace(token), "respond-async") {
+			return true
+		}
+	}
+	return false
+}
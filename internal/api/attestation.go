@@ -0,0 +1,98 @@
+// --- File: internal/api/attestation.go ---
+package api
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// Attestation is the server's signed statement that it served keys with
+// the given fingerprint for an entity at a point in time. A client can
+// keep it to prove later what the service returned.
+type Attestation struct {
+	URN            string    `json:"urn"`
+	KeyFingerprint string    `json:"keyFingerprint"`
+	ServedAt       time.Time `json:"servedAt"`
+	// Signature is the Ed25519 signature over Payload().
+	Signature []byte `json:"signature"`
+}
+
+// Payload returns the canonical bytes the signature covers: the compact
+// JSON object {"urn","keyFingerprint","servedAt"} with the fields in that
+// order and servedAt in RFC 3339 UTC.
+func (a Attestation) Payload() ([]byte, error) {
+	return json.Marshal(struct {
+		URN            string    `json:"urn"`
+		KeyFingerprint string    `json:"keyFingerprint"`
+		ServedAt       time.Time `json:"servedAt"`
+	}{a.URN, a.KeyFingerprint, a.ServedAt.UTC()})
+}
+
+// Verify reports whether the attestation was signed by pub.
+func (a Attestation) Verify(pub ed25519.PublicKey) bool {
+	payload, err := a.Payload()
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(pub, payload, a.Signature)
+}
+
+// AttestationFingerprint returns the digest identifying a key pair in an
+// attestation (see keystore.Fingerprint).
+func AttestationFingerprint(pk keys.PublicKeys) string {
+	return keystore.Fingerprint(pk)
+}
+
+// Attester signs attestations with the service's Ed25519 key.
+type Attester struct {
+	key ed25519.PrivateKey
+}
+
+// NewAttester creates an attester that signs with key.
+func NewAttester(key ed25519.PrivateKey) *Attester {
+	return &Attester{key: key}
+}
+
+// PublicKey returns the key clients verify attestations against.
+func (a *Attester) PublicKey() ed25519.PublicKey {
+	return a.key.Public().(ed25519.PublicKey)
+}
+
+// Attest signs a statement that pk was served for entityURN at servedAt.
+func (a *Attester) Attest(entityURN urn.URN, pk keys.PublicKeys, servedAt time.Time) (Attestation, error) {
+	att := Attestation{
+		URN:            entityURN.String(),
+		KeyFingerprint: AttestationFingerprint(pk),
+		ServedAt:       servedAt.UTC(),
+	}
+	payload, err := att.Payload()
+	if err != nil {
+		return Attestation{}, err
+	}
+	att.Signature = ed25519.Sign(a.key, payload)
+	return att, nil
+}
+
+// attestationKeyResponse is the body of GET /attestation/key.
+type attestationKeyResponse struct {
+	Alg       string `json:"alg"`
+	PublicKey []byte `json:"publicKey"`
+}
+
+// GetAttestationKeyHandler handles GET /attestation/key, returning the
+// public key that attestations are signed with.
+func (a *API) GetAttestationKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if a.Attester == nil {
+		response.WriteJSONError(w, http.StatusNotFound, "Attestation is not enabled")
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, attestationKeyResponse{Alg: "Ed25519", PublicKey: a.Attester.PublicKey()})
+}
@@ -0,0 +1,132 @@
+// --- File: internal/api/attestation_test.go ---
+package api_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func TestAttester_Attest(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+	attester := api.NewAttester(key)
+	userURN, err := urn.New(urn.SecureMessaging, "user", "alice")
+	require.NoError(t, err)
+	pk := keys.PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5, 6}}
+	servedAt := time.Date(2026, 10, 16, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	t.Run("Success - signature covers the canonical payload", func(t *testing.T) {
+		// Act
+		att, err := attester.Attest(userURN, pk, servedAt)
+		require.NoError(t, err)
+
+		// Assert: rebuild the payload by hand rather than through Payload().
+		digest := sha256.Sum256([]byte{0, 0, 0, 3, 1, 2, 3, 0, 0, 0, 3, 4, 5, 6})
+		fingerprint := hex.EncodeToString(digest[:])
+		canonical := `{"urn":"urn:sm:user:alice","keyFingerprint":"` + fingerprint + `","servedAt":"2026-10-16T10:00:00Z"}`
+		assert.Equal(t, fingerprint, att.KeyFingerprint)
+		assert.True(t, ed25519.Verify(key.Public().(ed25519.PublicKey), []byte(canonical), att.Signature))
+		assert.True(t, att.Verify(attester.PublicKey()))
+	})
+
+	t.Run("Failure - tampered attestation does not verify", func(t *testing.T) {
+		// Arrange
+		att, err := attester.Attest(userURN, pk, servedAt)
+		require.NoError(t, err)
+
+		// Act
+		att.ServedAt = att.ServedAt.Add(time.Hour)
+
+		// Assert
+		assert.False(t, att.Verify(attester.PublicKey()))
+	})
+}
+
+func TestGetKeysHandler_Attestation(t *testing.T) {
+	ctx := t.Context()
+	userURN, err := urn.New(urn.SecureMessaging, "user", "alice")
+	require.NoError(t, err)
+	pk := keys.PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5, 6}}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	attester := api.NewAttester(ed25519.NewKeyFromSeed(bytes.Repeat([]byte{2}, ed25519.SeedSize)))
+
+	get := func(t *testing.T, apiHandler *api.API, query string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/keys/"+userURN.String()+query, nil)
+		req.SetPathValue("entityURN", userURN.String())
+		rr := httptest.NewRecorder()
+		apiHandler.GetKeysHandler(rr, req)
+		return rr
+	}
+
+	store := inmemory.New()
+	require.NoError(t, store.StorePublicKeys(ctx, userURN, pk))
+
+	t.Run("Success - attestation verifies against the served keys", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: store, Logger: newTestLogger(), Attester: attester, Now: func() time.Time { return now }}
+
+		// Act
+		rr := get(t, apiHandler, "?attest=true")
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		var body struct {
+			Attestation api.Attestation `json:"attestation"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		att := body.Attestation
+		assert.Equal(t, userURN.String(), att.URN)
+		assert.Equal(t, api.AttestationFingerprint(pk), att.KeyFingerprint)
+		assert.True(t, now.Equal(att.ServedAt))
+		assert.True(t, att.Verify(attester.PublicKey()))
+	})
+
+	t.Run("Success - no attestation unless asked", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: store, Logger: newTestLogger(), Attester: attester}
+
+		// Act
+		rr := get(t, apiHandler, "")
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.NotContains(t, rr.Body.String(), "attestation")
+	})
+
+	t.Run("Failure - 400 when attestation is not enabled", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: store, Logger: newTestLogger()}
+
+		// Act
+		rr := get(t, apiHandler, "?attest=true")
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Failure - 400 invalid attest value", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: store, Logger: newTestLogger(), Attester: attester}
+
+		// Act
+		rr := get(t, apiHandler, "?attest=maybe")
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
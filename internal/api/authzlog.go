@@ -0,0 +1,43 @@
+// --- File: internal/api/authzlog.go ---
+package api
+
+import (
+	"context"
+	"log/slog"
+
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// AuthzDecisionMessage is the message of every authorization decision
+// record.
+const AuthzDecisionMessage = "Authorization decision"
+
+// Reasons recorded with an authorization decision.
+const (
+	// AuthzReasonOwnEntity allows a user to store or delete keys for itself.
+	AuthzReasonOwnEntity = "own_entity"
+	// AuthzReasonEntityMismatch denies a store or delete for another entity.
+	AuthzReasonEntityMismatch = "entity_mismatch"
+	// AuthzReasonSelfStoreDenied denies a self-store or delete for an
+	// entity type that only admins may provision.
+	AuthzReasonSelfStoreDenied = "self_store_denied_type"
+)
+
+// logAuthzDecision records the outcome of an authorization check when
+// LogAuthzDecisions is set: allows at INFO, denies at WARN. Every record
+// has the same message and attributes, so audits can filter on them.
+func (a *API) logAuthzDecision(ctx context.Context, logger *slog.Logger, handlerName string, allowed bool, authedUserID string, target urn.URN, reason string) {
+	if !a.LogAuthzDecisions {
+		return
+	}
+	level, decision := slog.LevelInfo, "allow"
+	if !allowed {
+		level, decision = slog.LevelWarn, "deny"
+	}
+	logger.LogAttrs(ctx, level, AuthzDecisionMessage,
+		slog.String("handler", handlerName),
+		slog.String("decision", decision),
+		slog.String("authed_user", authedUserID),
+		slog.String("target_entity", target.String()),
+		slog.String("reason", reason))
+}
@@ -0,0 +1,82 @@
+// --- File: internal/api/consistency.go ---
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// ConsistencyTokenHeader carries an opaque token naming a version of an
+// entity's keys. POST returns one for the version it wrote, and GET
+// returns one for the version it served. A GET that sends one back is
+// only answered with that version or a newer one.
+const ConsistencyTokenHeader = "X-Consistency-Token"
+
+// DefaultConsistencyWait is how long a GET waits for the store to catch
+// up with a consistency token when no wait is configured.
+const DefaultConsistencyWait = time.Second
+
+// consistencyPollInterval is how often a waiting GET re-reads the store.
+const consistencyPollInterval = 50 * time.Millisecond
+
+// errNotYetConsistent is returned by awaitConsistency when the store did
+// not catch up with the token in time.
+var errNotYetConsistent = errors.New("store has not caught up with the consistency token")
+
+// encodeConsistencyToken encodes a record's update time, the version the
+// stores keep, at the microsecond precision every backend preserves.
+func encodeConsistencyToken(updatedAt time.Time) string {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(updatedAt.UnixMicro()))
+	return base64.RawURLEncoding.EncodeToString(buf[:])
+}
+
+// parseConsistencyToken decodes a token made by encodeConsistencyToken.
+func parseConsistencyToken(token string) (time.Time, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != 8 {
+		return time.Time{}, errors.New("malformed consistency token")
+	}
+	return time.UnixMicro(int64(binary.BigEndian.Uint64(raw))).UTC(), nil
+}
+
+// caughtUp reports whether record is at least as new as the token's version.
+func caughtUp(record keystore.KeyRecord, minUpdatedAt time.Time) bool {
+	return !record.UpdatedAt.Truncate(time.Microsecond).Before(minUpdatedAt)
+}
+
+// awaitConsistency re-reads the entity's record until it is at least as
+// new as minUpdatedAt, or returns errNotYetConsistent once the configured
+// wait has passed. A missing record counts as not caught up, since the
+// write the token came from may not be visible yet.
+func (a *API) awaitConsistency(ctx context.Context, entityURN urn.URN, minUpdatedAt time.Time) (keystore.KeyRecord, error) {
+	wait := a.ConsistencyWait
+	if wait <= 0 {
+		wait = DefaultConsistencyWait
+	}
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	ticker := time.NewTicker(consistencyPollInterval)
+	defer ticker.Stop()
+	for {
+		record, err := a.Store.GetKeyRecord(ctx, entityURN)
+		if err == nil && caughtUp(record, minUpdatedAt) {
+			return record, nil
+		}
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return keystore.KeyRecord{}, errNotYetConsistent
+			}
+			return keystore.KeyRecord{}, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// --- File: internal/api/entityverifier.go ---
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultEntityVerificationCacheTTL is used when no cache TTL is configured.
+const DefaultEntityVerificationCacheTTL = time.Minute
+
+// entityVerificationTimeout bounds a single lookup against the identity service.
+const entityVerificationTimeout = 5 * time.Second
+
+// EntityVerifier asks the identity service whether an entity ID belongs to
+// a known user. It issues GET <endpoint>/<entityID> and treats 200 as known
+// and 404 as unknown; anything else is an error. Answers are cached briefly
+// so a client retrying a store does not hit the identity service each time.
+type EntityVerifier struct {
+	endpoint string
+	client   *http.Client
+	ttl      time.Duration
+	logger   *slog.Logger
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]entityVerification
+}
+
+type entityVerification struct {
+	exists    bool
+	checkedAt time.Time
+}
+
+// NewEntityVerifier creates a verifier for the given identity endpoint.
+// A non-positive ttl selects DefaultEntityVerificationCacheTTL.
+func NewEntityVerifier(endpoint string, ttl time.Duration, logger *slog.Logger) *EntityVerifier {
+	if ttl <= 0 {
+		ttl = DefaultEntityVerificationCacheTTL
+	}
+	return &EntityVerifier{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   &http.Client{Timeout: entityVerificationTimeout},
+		ttl:      ttl,
+		logger:   logger.With("component", "entity_verifier"),
+		now:      time.Now,
+		entries:  make(map[string]entityVerification),
+	}
+}
+
+// Exists reports whether the identity service knows entityID.
+func (v *EntityVerifier) Exists(ctx context.Context, entityID string) (bool, error) {
+	v.mu.Lock()
+	entry, ok := v.entries[entityID]
+	v.mu.Unlock()
+	if ok && v.now().Sub(entry.checkedAt) < v.ttl {
+		return entry.exists, nil
+	}
+
+	exists, err := v.lookup(ctx, entityID)
+	if err != nil {
+		return false, err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	// Drop expired answers while we hold the lock so the map stays bounded
+	// by the number of entities seen within one TTL.
+	for id, e := range v.entries {
+		if v.now().Sub(e.checkedAt) >= v.ttl {
+			delete(v.entries, id)
+		}
+	}
+	v.entries[entityID] = entityVerification{exists: exists, checkedAt: v.now()}
+	return exists, nil
+}
+
+func (v *EntityVerifier) lookup(ctx context.Context, entityID string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.endpoint+"/"+url.PathEscape(entityID), nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to build entity lookup: %w", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("entity lookup failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		v.logger.Debug("Identity service does not know entity", "entity_id", entityID)
+		return false, nil
+	default:
+		return false, fmt.Errorf("entity lookup returned unexpected status %d", resp.StatusCode)
+	}
+}
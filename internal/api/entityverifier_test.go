@@ -0,0 +1,147 @@
+// --- File: internal/api/entityverifier_test.go ---
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// newStubIdentityService answers 200 for the given users and 404 otherwise,
+// counting every lookup it serves.
+func newStubIdentityService(t *testing.T, known ...string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var lookups atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		id := strings.TrimPrefix(r.URL.Path, "/users/")
+		for _, k := range known {
+			if id == k {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &lookups
+}
+
+func TestEntityVerifier_Exists(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success - known and unknown entities, answers cached", func(t *testing.T) {
+		// Arrange
+		srv, lookups := newStubIdentityService(t, "alice")
+		verifier := api.NewEntityVerifier(srv.URL+"/users/", 0, newTestLogger())
+
+		// Act
+		aliceKnown, err := verifier.Exists(ctx, "alice")
+		require.NoError(t, err)
+		bobKnown, err := verifier.Exists(ctx, "bob")
+		require.NoError(t, err)
+		_, err = verifier.Exists(ctx, "alice")
+		require.NoError(t, err)
+		_, err = verifier.Exists(ctx, "bob")
+		require.NoError(t, err)
+
+		// Assert
+		assert.True(t, aliceKnown)
+		assert.False(t, bobKnown)
+		assert.EqualValues(t, 2, lookups.Load(), "repeat lookups should be served from the cache")
+	})
+
+	t.Run("Failure - unexpected status is an error", func(t *testing.T) {
+		// Arrange
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+		verifier := api.NewEntityVerifier(srv.URL, 0, newTestLogger())
+
+		// Act
+		_, err := verifier.Exists(ctx, "alice")
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestStoreKeysHandler_EntityVerification(t *testing.T) {
+	srv, _ := newStubIdentityService(t, "known-user")
+	verifier := api.NewEntityVerifier(srv.URL+"/users", 0, newTestLogger())
+
+	store := func(entityID string) *httptest.ResponseRecorder {
+		entityURN, err := urn.New(urn.SecureMessaging, "user", entityID)
+		require.NoError(t, err)
+		apiHandler := &api.API{Store: inmemory.New(), Logger: newTestLogger(), Entities: verifier}
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+entityURN.String(),
+			strings.NewReader(`{"encKey":"AQID","sigKey":"BAUG"}`))
+		req.SetPathValue("entityURN", entityURN.String())
+		rr := httptest.NewRecorder()
+		apiHandler.StoreKeysHandler(rr, req.WithContext(middleware.ContextWithUserID(context.Background(), entityID)))
+		return rr
+	}
+
+	t.Run("Success - 201 known entity", func(t *testing.T) {
+		// Act
+		rr := store("known-user")
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("Failure - 400 UNKNOWN_ENTITY", func(t *testing.T) {
+		// Act
+		rr := store("ghost-user")
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var errResp api.CodedAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, api.ErrCodeUnknownEntity, errResp.Code)
+	})
+
+	t.Run("Failure - 503 IDENTITY_SERVICE_UNAVAILABLE with Retry-After", func(t *testing.T) {
+		// Arrange
+		down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer down.Close()
+		entityURN, err := urn.New(urn.SecureMessaging, "user", "known-user")
+		require.NoError(t, err)
+		apiHandler := &api.API{
+			Store:      inmemory.New(),
+			Logger:     newTestLogger(),
+			Entities:   api.NewEntityVerifier(down.URL, 0, newTestLogger()),
+			RetryAfter: 1500 * time.Millisecond,
+		}
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+entityURN.String(),
+			strings.NewReader(`{"encKey":"AQID","sigKey":"BAUG"}`))
+		req.SetPathValue("entityURN", entityURN.String())
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.StoreKeysHandler(rr, req.WithContext(middleware.ContextWithUserID(context.Background(), "known-user")))
+
+		// Assert
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		var errResp api.CodedAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, api.ErrCodeIdentityServiceUnavailable, errResp.Code)
+		assert.Equal(t, "2", rr.Header().Get("Retry-After"), "partial seconds round up")
+	})
+}
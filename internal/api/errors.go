@@ -0,0 +1,107 @@
+// --- File: internal/api/errors.go ---
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+)
+
+// StatusClientClosedRequest is the non-standard status (popularised by nginx)
+// recorded when the client disconnects before the response is written.
+const StatusClientClosedRequest = 499
+
+// Machine-readable error codes returned alongside the error message.
+const (
+	// ErrCodeSelfStoreForbidden means the entity type's keys can only be
+	// provisioned through the admin route.
+	ErrCodeSelfStoreForbidden = "SELF_STORE_FORBIDDEN"
+	// ErrCodeUntrustedIssuer means the token's issuer is not in the allowlist.
+	ErrCodeUntrustedIssuer = "UNTRUSTED_ISSUER"
+	// ErrCodeWritesLocked means an admin has paused writes for maintenance.
+	ErrCodeWritesLocked = "WRITES_LOCKED"
+	// ErrCodeKeysIdentical means encKey and sigKey hold the same bytes.
+	ErrCodeKeysIdentical = "KEYS_IDENTICAL"
+	// ErrCodeRotationTooFrequent means the entity's keys changed too
+	// recently; Retry-After says when another change will be accepted.
+	ErrCodeRotationTooFrequent = "ROTATION_TOO_FREQUENT"
+	// ErrCodeInvalidKeyBinding means encKeySignature did not verify
+	// against sigKey.
+	ErrCodeInvalidKeyBinding = "INVALID_KEY_BINDING"
+	// ErrCodeUnknownEntity means the identity service has no such user.
+	ErrCodeUnknownEntity = "UNKNOWN_ENTITY"
+	// ErrCodeUntrustedProvisioning means the stored keys carry a
+	// provisioning signature that no trusted provisioner key verifies.
+	ErrCodeUntrustedProvisioning = "UNTRUSTED_PROVISIONING"
+	// ErrCodeEntityIDInvalid means the URN's entity ID does not match the
+	// configured entity ID pattern.
+	ErrCodeEntityIDInvalid = "ENTITY_ID_INVALID"
+	// ErrCodeBatchTooLarge means a batch request names more entities than
+	// the service accepts in one call.
+	ErrCodeBatchTooLarge = "BATCH_TOO_LARGE"
+	// ErrCodeNotYetConsistent means the store did not reach the version
+	// named by a consistency token in time.
+	ErrCodeNotYetConsistent = "NOT_YET_CONSISTENT"
+	// ErrCodeStorageBudgetExceeded means the store is at its configured
+	// size budget and the write would have grown it.
+	ErrCodeStorageBudgetExceeded = "STORAGE_BUDGET_EXCEEDED"
+	// ErrCodeBackendOverloaded means the key store is shedding load or
+	// briefly unreachable; Retry-After says when to try again.
+	ErrCodeBackendOverloaded = "BACKEND_OVERLOADED"
+	// ErrCodeIdentityServiceUnavailable means the identity service could
+	// not confirm the entity; Retry-After says when to try again.
+	ErrCodeIdentityServiceUnavailable = "IDENTITY_SERVICE_UNAVAILABLE"
+	// ErrCodeInvalidUsageRestrictions means the declared usage restrictions
+	// are malformed, already expired, or not accepted by this deployment.
+	ErrCodeInvalidUsageRestrictions = "INVALID_USAGE_RESTRICTIONS"
+	// ErrCodeInviteInvalid means the invite token's signature or claims
+	// did not verify.
+	ErrCodeInviteInvalid = "INVITE_INVALID"
+	// ErrCodeInviteExpired means the invite token is past its exp.
+	ErrCodeInviteExpired = "INVITE_EXPIRED"
+	// ErrCodeInviteAlreadyUsed means the invite token was redeemed before,
+	// or the invited entity already has keys.
+	ErrCodeInviteAlreadyUsed = "INVITE_ALREADY_USED"
+)
+
+// CodedAPIError extends response.APIError with a stable code clients can
+// branch on without matching the message text.
+type CodedAPIError struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// writeJSONErrorWithCode writes an error response carrying both a
+// human-readable message and a machine-readable code.
+func writeJSONErrorWithCode(w http.ResponseWriter, statusCode int, code, message string) {
+	response.WriteJSON(w, statusCode, CodedAPIError{Error: message, Code: code})
+}
+
+// InternalAPIError is the body of a 500 response. CorrelationID matches the
+// correlation_id of the server-side log record, which holds the full error.
+type InternalAPIError struct {
+	Error         string `json:"error"`
+	CorrelationID string `json:"correlationId"`
+}
+
+// writeInternalError logs err in full under a new correlation ID and
+// responds 500. The client sees only message and the ID unless
+// FullErrorDetail is set, in which case err's text is appended. If the
+// backend is overloaded it responds 503 BACKEND_OVERLOADED with
+// Retry-After instead, since the request can simply be retried.
+func (a *API) writeInternalError(w http.ResponseWriter, logger *slog.Logger, logMessage, message string, err error, args ...any) {
+	if isBackendOverloaded(err) {
+		logger.Warn(logMessage, append([]any{"err", err, "overloaded", true}, args...)...)
+		writeRetryableError(w, http.StatusServiceUnavailable, ErrCodeBackendOverloaded,
+			"The key store is temporarily overloaded; retry later", a.retryAfter())
+		return
+	}
+	correlationID := uuid.NewString()
+	logger.Error(logMessage, append([]any{"err", err, "correlation_id", correlationID}, args...)...)
+	if a.FullErrorDetail {
+		message += ": " + err.Error()
+	}
+	response.WriteJSON(w, http.StatusInternalServerError, InternalAPIError{Error: message, CorrelationID: correlationID})
+}
@@ -0,0 +1,29 @@
+// --- File: internal/api/etag.go ---
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// bodyETag is a strong ETag for a canonical response body. Canonical
+// bodies are equal byte for byte whenever their content is, so every
+// instance computes the same tag for the same response.
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header value names etag.
+// As RFC 9110 requires for If-None-Match, the comparison is weak, so a
+// W/ prefix is ignored.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
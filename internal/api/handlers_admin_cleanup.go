@@ -0,0 +1,74 @@
+// --- File: internal/api/handlers_admin_cleanup.go ---
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// deleteOlderThanResponse is the body of a POST /admin/keys:deleteOlderThan
+// response. Count is how many entities were deleted or, on a dry run,
+// how many would have been.
+type deleteOlderThanResponse struct {
+	Count  int  `json:"count"`
+	DryRun bool `json:"dryRun"`
+}
+
+// DeleteOlderThanHandler handles the POST /admin/keys:deleteOlderThan request.
+// It deletes every entity whose keys were last stored more than ?age ago
+// (a Go duration such as 2160h). With ?dryRun=true nothing is deleted and
+// the count is of the entities that would be, judged by this server's
+// clock rather than the store's.
+func (a *API) DeleteOlderThanHandler(w http.ResponseWriter, r *http.Request) {
+	adminUserID, ok := a.requireAdmin(w, r, "DeleteOlderThan")
+	if !ok {
+		return
+	}
+
+	age, err := time.ParseDuration(r.URL.Query().Get("age"))
+	if err != nil || age <= 0 {
+		response.WriteJSONError(w, http.StatusBadRequest, "age must be a positive duration, e.g. 2160h")
+		return
+	}
+	dryRun := false
+	if raw := r.URL.Query().Get("dryRun"); raw != "" {
+		dryRun, err = strconv.ParseBool(raw)
+		if err != nil {
+			response.WriteJSONError(w, http.StatusBadRequest, "dryRun must be a boolean")
+			return
+		}
+	}
+	logger := a.Logger.With("admin_user", adminUserID, "age", age, "dry_run", dryRun)
+
+	if dryRun {
+		cutoff := a.now().Add(-age)
+		count := 0
+		err := a.Store.IterateKeyRecords(r.Context(), func(_ urn.URN, record keystore.KeyRecord) error {
+			if record.UpdatedAt.Before(cutoff) {
+				count++
+			}
+			return nil
+		})
+		if err != nil {
+			a.writeInternalError(w, logger, "DeleteOlderThan: Failed to count old entities", "Failed to count old entities", err)
+			return
+		}
+		logger.Info("DeleteOlderThan: Dry run", "count", count)
+		response.WriteJSON(w, http.StatusOK, deleteOlderThanResponse{Count: count, DryRun: true})
+		return
+	}
+
+	deleted, err := a.Store.DeleteOlderThan(r.Context(), age)
+	if err != nil {
+		a.writeInternalError(w, logger, "DeleteOlderThan: Failed to delete old entities", "Failed to delete old entities", err,
+			"deleted", deleted)
+		return
+	}
+	logger.Info("DeleteOlderThan: Deleted old entities", "count", deleted)
+	response.WriteJSON(w, http.StatusOK, deleteOlderThanResponse{Count: deleted})
+}
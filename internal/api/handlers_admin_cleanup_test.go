@@ -0,0 +1,105 @@
+// --- File: internal/api/handlers_admin_cleanup_test.go ---
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func TestDeleteOlderThanHandler(t *testing.T) {
+	ctx := context.Background()
+	adminCtx := middleware.ContextWithUserID(ctx, "admin-user")
+
+	// newAgedStore stores one entity per age, each written that long
+	// before the returned clock's current time.
+	newAgedStore := func(t *testing.T) (*inmemory.Store, func() time.Time) {
+		t.Helper()
+		start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		now := start
+		clock := func() time.Time { return now }
+		store := inmemory.New(inmemory.WithClock(clock))
+		for id, age := range map[string]time.Duration{"fresh": time.Hour, "stale": 48 * time.Hour, "ancient": 400 * time.Hour} {
+			now = start.Add(-age)
+			entityURN, err := urn.New(urn.SecureMessaging, "user", id)
+			require.NoError(t, err)
+			require.NoError(t, store.StorePublicKeys(ctx, entityURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+		}
+		now = start
+		return store, clock
+	}
+	post := func(ctx context.Context, apiHandler *api.API, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/keys:deleteOlderThan?"+query, nil)
+		rr := httptest.NewRecorder()
+		apiHandler.DeleteOlderThanHandler(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	t.Run("Success - deletes entities older than the age", func(t *testing.T) {
+		// Arrange
+		store, clock := newAgedStore(t)
+		apiHandler := &api.API{Store: store, Logger: newTestLogger(), AdminUserIDs: []string{"admin-user"}, Now: clock}
+
+		// Act
+		rr := post(adminCtx, apiHandler, "age=24h")
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"count":2,"dryRun":false}`, rr.Body.String())
+		stats, err := store.Stats(ctx)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, stats.Entities)
+	})
+
+	t.Run("Success - dry run counts without deleting", func(t *testing.T) {
+		// Arrange
+		store, clock := newAgedStore(t)
+		apiHandler := &api.API{Store: store, Logger: newTestLogger(), AdminUserIDs: []string{"admin-user"}, Now: clock}
+
+		// Act
+		rr := post(adminCtx, apiHandler, "age=100h&dryRun=true")
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"count":1,"dryRun":true}`, rr.Body.String())
+		stats, err := store.Stats(ctx)
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, stats.Entities)
+	})
+
+	t.Run("Failure - 400 missing or non-positive age", func(t *testing.T) {
+		// Arrange
+		store, clock := newAgedStore(t)
+		apiHandler := &api.API{Store: store, Logger: newTestLogger(), AdminUserIDs: []string{"admin-user"}, Now: clock}
+
+		for _, query := range []string{"", "age=0s", "age=-1h", "age=soon", "age=1h&dryRun=maybe"} {
+			// Act
+			rr := post(adminCtx, apiHandler, query)
+
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+		}
+	})
+
+	t.Run("Failure - 403 Forbidden for non-admin", func(t *testing.T) {
+		// Arrange
+		store, clock := newAgedStore(t)
+		apiHandler := &api.API{Store: store, Logger: newTestLogger(), AdminUserIDs: []string{"admin-user"}, Now: clock}
+
+		// Act
+		rr := post(middleware.ContextWithUserID(ctx, "fresh"), apiHandler, "age=1h")
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
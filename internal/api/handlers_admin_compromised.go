@@ -0,0 +1,112 @@
+// --- File: internal/api/handlers_admin_compromised.go ---
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// CompromisedWarning is the Warning header value sent with keys that an
+// admin has flagged as compromised.
+const CompromisedWarning = `299 keyservice "keys are flagged as compromised"`
+
+// markCompromisedRequest is the body of a PUT /admin/keys/{entityURN}/compromised request.
+type markCompromisedRequest struct {
+	Reason string `json:"reason"`
+}
+
+// compromisedResponse reports the flag after an admin change.
+type compromisedResponse struct {
+	URN         string `json:"urn"`
+	Compromised bool   `json:"compromised"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// MarkCompromisedHandler handles the PUT /admin/keys/{entityURN}/compromised
+// request. It flags the entity's keys so every GET warns clients not to use
+// them, until the flag is cleared or new keys are stored. A reason is required.
+func (a *API) MarkCompromisedHandler(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := a.requireAdmin(w, r, "MarkCompromised")
+	if !ok {
+		return
+	}
+	entityURN, ok := a.adminEntityURN(w, r, "MarkCompromised")
+	if !ok {
+		return
+	}
+	logger := a.Logger.With("entity_urn", entityURN.String(), "admin_user", adminID)
+
+	var req markCompromisedRequest
+	if err := decodeJSONWithMaxDepth(r.Body, &req, a.MaxJSONDepth); err != nil {
+		logger.Warn("MarkCompromised: Failed to unmarshal JSON body", "err", err)
+		response.WriteJSONError(w, http.StatusBadRequest, "Invalid JSON body format")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		response.WriteJSONError(w, http.StatusBadRequest, "reason must not be empty")
+		return
+	}
+
+	if err := a.Store.MarkCompromised(r.Context(), entityURN, req.Reason); err != nil {
+		a.writeCompromisedStoreError(w, logger, "MarkCompromised", "Failed to flag keys", err)
+		return
+	}
+	logger.Warn("MarkCompromised: Keys flagged as compromised", "reason", req.Reason)
+	response.WriteJSON(w, http.StatusOK, compromisedResponse{URN: entityURN.String(), Compromised: true, Reason: req.Reason})
+}
+
+// ClearCompromisedHandler handles the DELETE /admin/keys/{entityURN}/compromised request.
+func (a *API) ClearCompromisedHandler(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := a.requireAdmin(w, r, "ClearCompromised")
+	if !ok {
+		return
+	}
+	entityURN, ok := a.adminEntityURN(w, r, "ClearCompromised")
+	if !ok {
+		return
+	}
+	logger := a.Logger.With("entity_urn", entityURN.String(), "admin_user", adminID)
+
+	if err := a.Store.ClearCompromised(r.Context(), entityURN); err != nil {
+		a.writeCompromisedStoreError(w, logger, "ClearCompromised", "Failed to clear flag", err)
+		return
+	}
+	logger.Warn("ClearCompromised: Compromised flag cleared")
+	response.WriteJSON(w, http.StatusOK, compromisedResponse{URN: entityURN.String(), Compromised: false})
+}
+
+// writeCompromisedStoreError answers a failed flag change: 404 only when
+// the entity has no keys, so an outage is never reported as a missing key.
+func (a *API) writeCompromisedStoreError(w http.ResponseWriter, logger *slog.Logger, handlerName, msg string, err error) {
+	switch {
+	case errors.Is(err, keystore.ErrKeyNotFound):
+		logger.Warn(handlerName+": Key not found", "err", err)
+		response.WriteJSONError(w, http.StatusNotFound, "Key not found")
+	case errors.Is(err, context.Canceled):
+		logger.Info(handlerName+": Client closed request", "err", err)
+		w.WriteHeader(StatusClientClosedRequest)
+	default:
+		a.writeInternalError(w, logger, handlerName+": "+msg, msg, err)
+	}
+}
+
+// adminEntityURN parses the {entityURN} path value. On failure it writes
+// the response and returns false.
+func (a *API) adminEntityURN(w http.ResponseWriter, r *http.Request, handlerName string) (urn.URN, bool) {
+	entityURNStr := r.PathValue("entityURN")
+	entityURN, err := urn.Parse(entityURNStr)
+	if err != nil {
+		a.Logger.Warn(handlerName+": Invalid URN format", "err", err, "raw_urn", entityURNStr)
+		response.WriteJSONError(w, http.StatusBadRequest, "Invalid URN format")
+		return urn.URN{}, false
+	}
+	return entityURN, true
+}
@@ -0,0 +1,124 @@
+// --- File: internal/api/handlers_admin_compromised_test.go ---
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func TestCompromisedHandlers(t *testing.T) {
+	ctx := context.Background()
+	userURN, err := urn.New(urn.SecureMessaging, "user", "alice")
+	require.NoError(t, err)
+
+	store := inmemory.New()
+	require.NoError(t, store.StorePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5, 6}}))
+	apiHandler := &api.API{Store: store, Logger: newTestLogger(), AdminUserIDs: []string{"admin-1"}}
+
+	admin := func(handler http.HandlerFunc, method, body, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/admin/keys/"+userURN.String()+"/compromised", strings.NewReader(body))
+		req.SetPathValue("entityURN", userURN.String())
+		rr := httptest.NewRecorder()
+		handler(rr, req.WithContext(middleware.ContextWithUserID(ctx, userID)))
+		return rr
+	}
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/keys/"+userURN.String(), nil)
+		req.SetPathValue("entityURN", userURN.String())
+		rr := httptest.NewRecorder()
+		apiHandler.GetKeysHandler(rr, req)
+		return rr
+	}
+
+	t.Run("Success - flag surfaces on GET", func(t *testing.T) {
+		// Act
+		rr := admin(apiHandler.MarkCompromisedHandler, http.MethodPut, `{"reason":"device stolen"}`, "admin-1")
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		getRR := get()
+		require.Equal(t, http.StatusOK, getRR.Code)
+		var body struct {
+			EncKey      []byte `json:"encKey"`
+			Compromised bool   `json:"compromised"`
+		}
+		require.NoError(t, json.Unmarshal(getRR.Body.Bytes(), &body))
+		assert.True(t, body.Compromised)
+		assert.Equal(t, []byte{1, 2, 3}, body.EncKey, "the keys are still served")
+		assert.NotContains(t, getRR.Body.String(), "device stolen", "the reason is for admins only")
+		assert.Equal(t, api.CompromisedWarning, getRR.Header().Get("Warning"))
+	})
+
+	t.Run("Success - flag can be cleared", func(t *testing.T) {
+		// Act
+		rr := admin(apiHandler.ClearCompromisedHandler, http.MethodDelete, "", "admin-1")
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		getRR := get()
+		assert.NotContains(t, getRR.Body.String(), "compromised")
+		assert.Empty(t, getRR.Header().Get("Warning"))
+	})
+
+	t.Run("Failure - 400 missing reason", func(t *testing.T) {
+		// Act
+		rr := admin(apiHandler.MarkCompromisedHandler, http.MethodPut, `{"reason":"  "}`, "admin-1")
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Failure - 403 not an admin", func(t *testing.T) {
+		// Act
+		rr := admin(apiHandler.MarkCompromisedHandler, http.MethodPut, `{"reason":"leaked"}`, "alice")
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.NotContains(t, get().Body.String(), "compromised")
+	})
+
+	t.Run("Failure - 404 entity has no keys", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: inmemory.New(), Logger: newTestLogger(), AdminUserIDs: []string{"admin-1"}}
+
+		// Act
+		markRR := admin(apiHandler.MarkCompromisedHandler, http.MethodPut, `{"reason":"leaked"}`, "admin-1")
+		clearRR := admin(apiHandler.ClearCompromisedHandler, http.MethodDelete, "", "admin-1")
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, markRR.Code)
+		assert.Equal(t, http.StatusNotFound, clearRR.Code)
+	})
+
+	t.Run("Failure - 500 store error is not reported as not found", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("MarkCompromised", mock.Anything, userURN, "leaked").Return(errors.New("backend down"))
+		mockStore.On("ClearCompromised", mock.Anything, userURN).Return(errors.New("backend down"))
+		apiHandler := &api.API{Store: mockStore, Logger: newTestLogger(), AdminUserIDs: []string{"admin-1"}}
+
+		// Act
+		markRR := admin(apiHandler.MarkCompromisedHandler, http.MethodPut, `{"reason":"leaked"}`, "admin-1")
+		clearRR := admin(apiHandler.ClearCompromisedHandler, http.MethodDelete, "", "admin-1")
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, markRR.Code)
+		assert.Equal(t, http.StatusInternalServerError, clearRR.Code)
+		mockStore.AssertExpectations(t)
+	})
+}
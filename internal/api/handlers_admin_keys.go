@@ -0,0 +1,117 @@
+// --- File: internal/api/handlers_admin_keys.go ---
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// entitySummary describes one stored entity for auditing. It carries
+// fingerprints of the keys, never the key bytes themselves.
+type entitySummary struct {
+	URN               string     `json:"urn"`
+	KeyID             string     `json:"kid"`
+	UpdatedAt         *time.Time `json:"updatedAt,omitempty"`
+	RotationHint      *time.Time `json:"rotationHint,omitempty"`
+	EncKeyFingerprint string     `json:"encKeyFingerprint"`
+	SigKeyFingerprint string     `json:"sigKeyFingerprint"`
+	AccessCount       int64      `json:"accessCount,omitempty"`
+	Compromised       bool       `json:"compromised,omitempty"`
+	CompromisedReason string     `json:"compromisedReason,omitempty"`
+}
+
+// keyFingerprint returns the hex SHA-256 digest of an encoded key.
+func keyFingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:])
+}
+
+// optionalTime returns nil for the zero time so it is omitted from JSON.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// ListEntitiesHandler handles the GET /admin/keys request.
+// By default it returns the sorted URNs of every stored entity. With
+// ?verbose=true each entry also carries its kid, update time, rotation hint
+// and key fingerprints, so operators can audit freshness at a glance, plus
+// its access count when the store counts reads and any compromised flag.
+func (a *API) ListEntitiesHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := a.requireAdmin(w, r, "ListEntities"); !ok {
+		return
+	}
+
+	verbose := false
+	if raw := r.URL.Query().Get("verbose"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			response.WriteJSONError(w, http.StatusBadRequest, "verbose must be a boolean")
+			return
+		}
+		verbose = v
+	}
+
+	summaries := []entitySummary{}
+	err := a.Store.IterateKeyRecords(r.Context(), func(entityURN urn.URN, record keystore.KeyRecord) error {
+		summary := entitySummary{URN: entityURN.String()}
+		if verbose {
+			summary.KeyID = keyIDForURN(entityURN)
+			summary.UpdatedAt = optionalTime(record.UpdatedAt)
+			summary.RotationHint = optionalTime(record.Metadata.RotationHint)
+			summary.EncKeyFingerprint = keyFingerprint(record.Keys.EncKey)
+			summary.SigKeyFingerprint = keyFingerprint(record.Keys.SigKey)
+			summary.AccessCount = record.AccessCount
+			summary.Compromised = record.Compromised
+			summary.CompromisedReason = record.CompromisedReason
+		}
+		summaries = append(summaries, summary)
+		return nil
+	})
+	if err != nil {
+		a.writeInternalError(w, a.Logger, "ListEntities: Failed to iterate key records", "Failed to list entities", err)
+		return
+	}
+	slices.SortFunc(summaries, func(x, y entitySummary) int { return strings.Compare(x.URN, y.URN) })
+
+	if verbose {
+		response.WriteJSON(w, http.StatusOK, map[string]any{"entities": summaries})
+		return
+	}
+	urns := make([]string, 0, len(summaries))
+	for _, summary := range summaries {
+		urns = append(urns, summary.URN)
+	}
+	response.WriteJSON(w, http.StatusOK, map[string]any{"entities": urns})
+}
+
+// ListMissingSigKeyHandler handles the GET /admin/keys:missingSig request.
+// It returns the sorted URNs of entities that have stored keys but no
+// signing key, so admins can chase them before signing becomes required.
+func (a *API) ListMissingSigKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := a.requireAdmin(w, r, "ListMissingSigKey"); !ok {
+		return
+	}
+
+	entityURNs, err := a.Store.ListEntitiesMissingSigKey(r.Context())
+	if err != nil {
+		a.writeInternalError(w, a.Logger, "ListMissingSigKey: Failed to list entities", "Failed to list entities", err)
+		return
+	}
+	urns := make([]string, 0, len(entityURNs))
+	for _, entityURN := range entityURNs {
+		urns = append(urns, entityURN.String())
+	}
+	response.WriteJSON(w, http.StatusOK, map[string]any{"entities": urns})
+}
@@ -0,0 +1,142 @@
+// --- File: internal/api/handlers_admin_keys_test.go ---
+package api_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func TestListEntitiesHandler(t *testing.T) {
+	ctx := context.Background()
+	store := inmemory.New()
+
+	aliceURN, err := urn.New(urn.SecureMessaging, "user", "alice")
+	require.NoError(t, err)
+	bobURN, err := urn.New(urn.SecureMessaging, "user", "bob")
+	require.NoError(t, err)
+	aliceKeys := keys.PublicKeys{EncKey: []byte("alice-enc"), SigKey: []byte("alice-sig")}
+	hint := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, store.StorePublicKeysWithMetadata(ctx, aliceURN, aliceKeys, keystore.Metadata{RotationHint: hint}))
+	require.NoError(t, store.StorePublicKeys(ctx, bobURN, keys.PublicKeys{EncKey: []byte("bob-enc"), SigKey: []byte("bob-sig")}))
+
+	apiHandler := &api.API{Store: store, Logger: newTestLogger(), AdminUserIDs: []string{"admin-user"}}
+	adminCtx := middleware.ContextWithUserID(ctx, "admin-user")
+
+	t.Run("Success - plain list of URNs by default", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/admin/keys", nil)
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.ListEntitiesHandler(rr, req.WithContext(adminCtx))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"entities":["urn:sm:user:alice","urn:sm:user:bob"]}`, rr.Body.String())
+	})
+
+	t.Run("Success - verbose list with metadata and fingerprints", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/admin/keys?verbose=true", nil)
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.ListEntitiesHandler(rr, req.WithContext(adminCtx))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var body struct {
+			Entities []struct {
+				URN               string     `json:"urn"`
+				KeyID             string     `json:"kid"`
+				UpdatedAt         *time.Time `json:"updatedAt"`
+				RotationHint      *time.Time `json:"rotationHint"`
+				EncKeyFingerprint string     `json:"encKeyFingerprint"`
+				SigKeyFingerprint string     `json:"sigKeyFingerprint"`
+			} `json:"entities"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		require.Len(t, body.Entities, 2)
+
+		alice := body.Entities[0]
+		assert.Equal(t, aliceURN.String(), alice.URN)
+		assert.Equal(t, aliceURN.String(), alice.KeyID)
+		require.NotNil(t, alice.UpdatedAt)
+		require.NotNil(t, alice.RotationHint)
+		assert.True(t, hint.Equal(*alice.RotationHint))
+		encSum := sha256.Sum256(aliceKeys.EncKey)
+		assert.Equal(t, hex.EncodeToString(encSum[:]), alice.EncKeyFingerprint)
+		assert.Nil(t, body.Entities[1].RotationHint)
+
+		// No raw key material is ever returned
+		assert.NotContains(t, rr.Body.String(), "YWxpY2UtZW5j") // base64("alice-enc")
+	})
+
+	t.Run("Failure - 403 Forbidden for non-admin", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/admin/keys", nil)
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.ListEntitiesHandler(rr, req.WithContext(middleware.ContextWithUserID(ctx, "alice")))
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+func TestListMissingSigKeyHandler(t *testing.T) {
+	ctx := context.Background()
+	store := inmemory.New()
+	for id, pk := range map[string]keys.PublicKeys{
+		"alice": {EncKey: []byte("alice-enc"), SigKey: []byte("alice-sig")},
+		"bob":   {EncKey: []byte("bob-enc")},
+		"carol": {EncKey: []byte("carol-enc"), SigKey: []byte("carol-sig")},
+		"dave":  {EncKey: []byte("dave-enc")},
+	} {
+		entityURN, err := urn.New(urn.SecureMessaging, "user", id)
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, pk))
+	}
+	apiHandler := &api.API{Store: store, Logger: newTestLogger(), AdminUserIDs: []string{"admin-user"}}
+
+	t.Run("Success - lists only enc-only entities", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/admin/keys:missingSig", nil)
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.ListMissingSigKeyHandler(rr, req.WithContext(middleware.ContextWithUserID(ctx, "admin-user")))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"entities":["urn:sm:user:bob","urn:sm:user:dave"]}`, rr.Body.String())
+	})
+
+	t.Run("Failure - 403 Forbidden for non-admin", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/admin/keys:missingSig", nil)
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.ListMissingSigKeyHandler(rr, req.WithContext(middleware.ContextWithUserID(ctx, "bob")))
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
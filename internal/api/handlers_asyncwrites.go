@@ -0,0 +1,60 @@
+// --- File: internal/api/handlers_asyncwrites.go ---
+package api
+
+import (
+	"net/http"
+
+	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+)
+
+// asyncWriteResponse is the body of a 202 from a queued store and of
+// GET /writes/{writeID}. Error, Code and CorrelationID describe a failed
+// write the same way a synchronous store would have.
+type asyncWriteResponse struct {
+	ID            string `json:"id"`
+	URN           string `json:"urn"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+	Code          string `json:"code,omitempty"`
+	CorrelationID string `json:"correlationId,omitempty"`
+}
+
+// GetAsyncWriteHandler handles the GET /writes/{writeID} request. It
+// reports whether a write queued with Prefer: respond-async is pending,
+// succeeded or failed. Only the user who sent the write can see it; any
+// other ID, or one whose outcome has expired, is 404. A succeeded write
+// carries the consistency token its synchronous response would have.
+func (a *API) GetAsyncWriteHandler(w http.ResponseWriter, r *http.Request) {
+	authedUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		a.Logger.Debug("GetAsyncWrite: Failed. No user ID in token context.")
+		response.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized: No user ID in token")
+		return
+	}
+	if a.AsyncWrites == nil {
+		response.WriteJSONError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	write, ok := a.AsyncWrites.status(r.PathValue("writeID"), authedUserID)
+	if !ok {
+		response.WriteJSONError(w, http.StatusNotFound, "Write not found")
+		return
+	}
+
+	body := asyncWriteResponse{ID: write.ID, URN: write.EntityURN.String(), Status: write.Status}
+	switch {
+	case write.Status == AsyncWriteSucceeded:
+		w.Header().Set(ConsistencyTokenHeader, encodeConsistencyToken(write.WrittenAfter))
+	case write.Code == ErrCodeWritesLocked:
+		body.Error, body.Code = "Writes are temporarily paused for maintenance", write.Code
+	case write.Code == ErrCodeStorageBudgetExceeded:
+		body.Error, body.Code = "The key store is full", write.Code
+	case write.Status == AsyncWriteFailed:
+		body.Error, body.CorrelationID = "Failed to store public keys", write.CorrelationID
+		if a.FullErrorDetail {
+			body.Error += ": " + write.Err.Error()
+		}
+	}
+	response.WriteJSON(w, http.StatusOK, body)
+}
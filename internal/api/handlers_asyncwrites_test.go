@@ -0,0 +1,200 @@
+// --- File: internal/api/handlers_asyncwrites_test.go ---
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/budget"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// asyncWriteBody mirrors the JSON of a queued store and its status.
+type asyncWriteBody struct {
+	ID            string `json:"id"`
+	URN           string `json:"urn"`
+	Status        string `json:"status"`
+	Error         string `json:"error"`
+	Code          string `json:"code"`
+	CorrelationID string `json:"correlationId"`
+}
+
+func TestStoreKeysHandler_AsyncWrites(t *testing.T) {
+	authedUserID := "async-user"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
+	require.NoError(t, err)
+	mockBodyJSON := `{"encKey":"AQID","sigKey":"BAUG"}`
+
+	newAPI := func(t *testing.T, store keystore.Store) *api.API {
+		t.Helper()
+		lock := &api.WriteLock{}
+		asyncWrites := api.NewAsyncWrites(store, lock, 8, newTestLogger())
+		t.Cleanup(func() { _ = asyncWrites.Close(context.Background()) })
+		return &api.API{Store: store, Logger: newTestLogger(), WriteLock: lock, AsyncWrites: asyncWrites, BasePath: "/keyservice"}
+	}
+	store := func(apiHandler *api.API, prefer string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(mockBodyJSON))
+		req.SetPathValue("entityURN", userURN.String())
+		if prefer != "" {
+			req.Header.Set("Prefer", prefer)
+		}
+		rr := httptest.NewRecorder()
+		apiHandler.StoreKeysHandler(rr, req.WithContext(middleware.ContextWithUserID(req.Context(), authedUserID)))
+		return rr
+	}
+	getStatus := func(t *testing.T, apiHandler *api.API, writeID, userID string) (*httptest.ResponseRecorder, asyncWriteBody) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/writes/"+writeID, nil)
+		req.SetPathValue("writeID", writeID)
+		rr := httptest.NewRecorder()
+		apiHandler.GetAsyncWriteHandler(rr, req.WithContext(middleware.ContextWithUserID(req.Context(), userID)))
+		var body asyncWriteBody
+		if rr.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		}
+		return rr, body
+	}
+	// awaitFinished polls the status until the write is no longer pending.
+	awaitFinished := func(t *testing.T, apiHandler *api.API, writeID string) (*httptest.ResponseRecorder, asyncWriteBody) {
+		t.Helper()
+		var rr *httptest.ResponseRecorder
+		var body asyncWriteBody
+		require.Eventually(t, func() bool {
+			rr, body = getStatus(t, apiHandler, writeID, authedUserID)
+			return body.Status != api.AsyncWritePending
+		}, 5*time.Second, 5*time.Millisecond)
+		return rr, body
+	}
+
+	t.Run("Success - 202 and the write eventually lands", func(t *testing.T) {
+		// Arrange
+		backend := inmemory.New()
+		apiHandler := newAPI(t, backend)
+
+		// Act
+		rr := store(apiHandler, "respond-async, wait=5")
+
+		// Assert
+		require.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, "respond-async", rr.Header().Get("Preference-Applied"))
+		var accepted asyncWriteBody
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &accepted))
+		assert.Equal(t, api.AsyncWritePending, accepted.Status)
+		assert.Equal(t, userURN.String(), accepted.URN)
+		assert.Equal(t, "/keyservice/writes/"+accepted.ID, rr.Header().Get("Location"))
+
+		statusRR, status := awaitFinished(t, apiHandler, accepted.ID)
+		assert.Equal(t, api.AsyncWriteSucceeded, status.Status)
+		assert.NotEmpty(t, statusRR.Header().Get(api.ConsistencyTokenHeader))
+		stored, err := backend.GetPublicKeys(context.Background(), userURN)
+		require.NoError(t, err)
+		assert.Equal(t, keys.PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5, 6}}, stored)
+	})
+
+	t.Run("Success - without the preference the store is synchronous", func(t *testing.T) {
+		// Arrange
+		apiHandler := newAPI(t, inmemory.New())
+
+		// Act
+		rr := store(apiHandler, "")
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Empty(t, rr.Header().Get("Preference-Applied"))
+	})
+
+	t.Run("Failure - failed write reports a correlation ID", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{}, keystore.ErrKeyNotFound)
+		mockStore.On("StorePublicKeysWithMetadata", mock.Anything, userURN, mock.Anything, keystore.Metadata{}).
+			Return(&keystore.StoreError{Op: keystore.OpStorePublicKeysWithMetadata, URN: userURN, Err: errors.New("backend down")})
+		apiHandler := newAPI(t, mockStore)
+
+		// Act
+		rr := store(apiHandler, "respond-async")
+
+		// Assert
+		require.Equal(t, http.StatusAccepted, rr.Code)
+		var accepted asyncWriteBody
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &accepted))
+		_, status := awaitFinished(t, apiHandler, accepted.ID)
+		assert.Equal(t, api.AsyncWriteFailed, status.Status)
+		assert.Equal(t, "Failed to store public keys", status.Error)
+		assert.NotEmpty(t, status.CorrelationID)
+	})
+
+	t.Run("Failure - budget and write lock failures carry their codes", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{}, keystore.ErrKeyNotFound)
+		mockStore.On("StorePublicKeysWithMetadata", mock.Anything, userURN, mock.Anything, keystore.Metadata{}).
+			Return(&keystore.StoreError{Op: keystore.OpStorePublicKeysWithMetadata, URN: userURN, Err: budget.ErrExceeded}).Once()
+		apiHandler := newAPI(t, mockStore)
+
+		// Act
+		overBudget := store(apiHandler, "respond-async")
+		var overBudgetBody asyncWriteBody
+		require.NoError(t, json.Unmarshal(overBudget.Body.Bytes(), &overBudgetBody))
+		_, overBudgetStatus := awaitFinished(t, apiHandler, overBudgetBody.ID)
+
+		apiHandler.WriteLock.Lock()
+		locked := store(apiHandler, "respond-async")
+		var lockedBody asyncWriteBody
+		require.NoError(t, json.Unmarshal(locked.Body.Bytes(), &lockedBody))
+		_, lockedStatus := awaitFinished(t, apiHandler, lockedBody.ID)
+
+		// Assert
+		assert.Equal(t, api.AsyncWriteFailed, overBudgetStatus.Status)
+		assert.Equal(t, api.ErrCodeStorageBudgetExceeded, overBudgetStatus.Code)
+		assert.Equal(t, api.AsyncWriteFailed, lockedStatus.Status)
+		assert.Equal(t, api.ErrCodeWritesLocked, lockedStatus.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("Failure - 404 for another user's write", func(t *testing.T) {
+		// Arrange
+		apiHandler := newAPI(t, inmemory.New())
+		rr := store(apiHandler, "respond-async")
+		var accepted asyncWriteBody
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &accepted))
+
+		// Act
+		statusRR, _ := getStatus(t, apiHandler, accepted.ID, "someone-else")
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, statusRR.Code)
+	})
+
+	t.Run("Success - Close persists writes still queued", func(t *testing.T) {
+		// Arrange
+		backend := inmemory.New()
+		asyncWrites := api.NewAsyncWrites(backend, nil, 8, newTestLogger())
+		apiHandler := &api.API{Store: backend, Logger: newTestLogger(), AsyncWrites: asyncWrites}
+		rr := store(apiHandler, "respond-async")
+		require.Equal(t, http.StatusAccepted, rr.Code)
+
+		// Act
+		require.NoError(t, asyncWrites.Close(context.Background()))
+
+		// Assert
+		_, err := backend.GetPublicKeys(context.Background(), userURN)
+		assert.NoError(t, err)
+		_, queued := asyncWrites.Enqueue(context.Background(), authedUserID, userURN, keys.PublicKeys{EncKey: []byte("enc")}, keystore.Metadata{}, newTestLogger())
+		assert.False(t, queued, "a closed queue accepts no writes")
+	})
+}
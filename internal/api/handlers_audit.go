@@ -0,0 +1,39 @@
+// --- File: internal/api/handlers_audit.go ---
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/tinywideclouds/go-key-service/internal/storage/auditchain"
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+)
+
+// auditVerifyResponse is the body of a GET /admin/audit/verify response.
+type auditVerifyResponse struct {
+	Intact bool   `json:"intact"`
+	Error  string `json:"error,omitempty"`
+}
+
+// VerifyAuditChainHandler handles the GET /admin/audit/verify request.
+// It reports whether the hash chain of key writes is intact and, if not,
+// the first entry that fails.
+func (a *API) VerifyAuditChainHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := a.requireAdmin(w, r, "VerifyAuditChain"); !ok {
+		return
+	}
+	if a.AuditChain == nil {
+		response.WriteJSONError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	err := a.AuditChain.VerifyChain(r.Context())
+	switch {
+	case err == nil:
+		response.WriteJSON(w, http.StatusOK, auditVerifyResponse{Intact: true})
+	case errors.Is(err, auditchain.ErrChainBroken):
+		a.Logger.Warn("VerifyAuditChain: Audit chain is broken", "err", err)
+		response.WriteJSON(w, http.StatusOK, auditVerifyResponse{Error: err.Error()})
+	default:
+		a.writeInternalError(w, a.Logger, "VerifyAuditChain: Failed to read audit chain", "Failed to read audit chain", err)
+	}
+}
@@ -0,0 +1,66 @@
+// --- File: internal/api/handlers_audit_test.go ---
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/auditchain"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func TestVerifyAuditChainHandler(t *testing.T) {
+	auditStore, err := auditchain.New(context.Background(), inmemory.New(), &auditchain.MemoryLog{}, newTestLogger())
+	require.NoError(t, err)
+	entityURN, err := urn.Parse("urn:sm:user:audited")
+	require.NoError(t, err)
+	require.NoError(t, auditStore.StorePublicKeys(context.Background(), entityURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+	apiHandler := &api.API{
+		Store:        auditStore,
+		Logger:       newTestLogger(),
+		AdminUserIDs: []string{"admin-user"},
+		AuditChain:   auditStore,
+	}
+
+	t.Run("Success - 200 OK reports an intact chain", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/admin/audit/verify", nil)
+		ctx := middleware.ContextWithUserID(context.Background(), "admin-user")
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.VerifyAuditChainHandler(rr, req.WithContext(ctx))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var body struct {
+			Intact bool   `json:"intact"`
+			Error  string `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.True(t, body.Intact)
+		assert.Empty(t, body.Error)
+	})
+
+	t.Run("Failure - 403 Forbidden for non-admin", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/admin/audit/verify", nil)
+		ctx := middleware.ContextWithUserID(context.Background(), "someone-else")
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.VerifyAuditChainHandler(rr, req.WithContext(ctx))
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
@@ -0,0 +1,110 @@
+// --- File: internal/api/handlers_batchget.go ---
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// MaxKeysBatch is the most URNs one POST /keys:batchGet accepts.
+const MaxKeysBatch = 100
+
+// batchGetRequest is the body of a POST /keys:batchGet request.
+type batchGetRequest struct {
+	URNs []string `json:"urns"`
+}
+
+// batchGetResponse maps each requested URN that has keys, in canonical
+// form, to its keys. Missing lists the rest in request order. Compromised
+// lists entities whose keys are served but flagged, which clients should
+// refuse to use exactly as when GET reports compromised.
+type batchGetResponse struct {
+	Keys        map[string]keys.PublicKeys `json:"keys"`
+	Missing     []string                   `json:"missing"`
+	Compromised []string                   `json:"compromised,omitempty"`
+}
+
+// BatchGetKeysHandler handles the POST /keys:batchGet request. It fetches
+// the keys of up to MaxKeysBatch entities in one round trip, for example
+// every participant in a group conversation. Like GET /keys/{entityURN},
+// it needs no authentication and withholds keys that strict provisioning
+// would withhold there.
+func (a *API) BatchGetKeysHandler(w http.ResponseWriter, r *http.Request) {
+	// 1. Body: Decode and bound the list of URNs.
+	var req batchGetRequest
+	if err := decodeJSONWithMaxDepth(r.Body, &req, a.MaxJSONDepth); err != nil {
+		if errors.Is(err, errJSONTooDeep) {
+			a.Logger.Warn("BatchGetKeys: Rejected over-nested JSON body", "err", err)
+			response.WriteJSONError(w, http.StatusBadRequest, "JSON body is nested too deeply")
+			return
+		}
+		a.Logger.Warn("BatchGetKeys: Failed to unmarshal JSON body", "err", err)
+		response.WriteJSONError(w, http.StatusBadRequest, "Invalid JSON body format")
+		return
+	}
+	if len(req.URNs) > MaxKeysBatch {
+		a.Logger.Warn("BatchGetKeys: Rejected oversized batch", "count", len(req.URNs))
+		writeJSONErrorWithCode(w, http.StatusBadRequest, ErrCodeBatchTooLarge,
+			fmt.Sprintf("At most %d URNs may be requested at once", MaxKeysBatch))
+		return
+	}
+
+	// 2. URNs: Every entry must parse, so a typo is not mistaken for absence.
+	entityURNs := make([]urn.URN, 0, len(req.URNs))
+	for _, raw := range req.URNs {
+		entityURN, err := urn.Parse(raw)
+		if err != nil {
+			a.Logger.Warn("BatchGetKeys: Invalid URN format", "err", err, "raw_urn", raw)
+			response.WriteJSONError(w, http.StatusBadRequest, "Invalid URN format: "+raw)
+			return
+		}
+		entityURNs = append(entityURNs, entityURN)
+	}
+
+	// 3. Store: Read every entity in one call.
+	records, err := a.Store.GetPublicKeysBatch(r.Context(), entityURNs)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			a.Logger.Info("BatchGetKeys: Client closed request during lookup", "err", err)
+			w.WriteHeader(StatusClientClosedRequest)
+			return
+		}
+		a.writeInternalError(w, a.Logger, "BatchGetKeys: Failed to get public keys", "Failed to get public keys", err,
+			"count", len(entityURNs))
+		return
+	}
+
+	// 4. Trust: Sort each entity into the response, applying the same
+	// provisioning rule as GET. Withheld keys are reported as missing.
+	resp := batchGetResponse{Keys: make(map[string]keys.PublicKeys, len(records)), Missing: []string{}}
+	seen := make(map[string]bool, len(entityURNs))
+	for _, entityURN := range entityURNs {
+		entityKey := entityURN.String()
+		if seen[entityKey] {
+			continue
+		}
+		seen[entityKey] = true
+
+		record, ok := records[entityKey]
+		if ok && a.RequireProvisioningSignature &&
+			!verifyProvisioningSignature(a.ProvisionerKeys, entityURN, record.Keys, record.Metadata.ProvisioningSignature) {
+			a.Logger.Warn("BatchGetKeys: Withholding keys without a trusted provisioning signature", "entity_urn", entityKey)
+			ok = false
+		}
+		if !ok {
+			resp.Missing = append(resp.Missing, entityKey)
+			continue
+		}
+		resp.Keys[entityKey] = record.Keys
+		if record.Compromised {
+			resp.Compromised = append(resp.Compromised, entityKey)
+		}
+	}
+	response.WriteJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,164 @@
+// --- File: internal/api/handlers_batchget_test.go ---
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func TestBatchGetKeysHandler(t *testing.T) {
+	logger := newTestLogger()
+	ctx := context.Background()
+	aliceKeys := keys.PublicKeys{EncKey: []byte("alice-enc"), SigKey: []byte("alice-sig")}
+	carolKeys := keys.PublicKeys{EncKey: []byte("carol-enc"), SigKey: []byte("carol-sig")}
+	aliceURN, err := urn.New(urn.SecureMessaging, "user", "alice")
+	require.NoError(t, err)
+	carolURN, err := urn.New(urn.SecureMessaging, "user", "carol")
+	require.NoError(t, err)
+
+	store := inmemory.New()
+	require.NoError(t, store.StorePublicKeys(ctx, aliceURN, aliceKeys))
+	require.NoError(t, store.StorePublicKeys(ctx, carolURN, carolKeys))
+	apiHandler := &api.API{Store: store, Logger: logger}
+
+	post := func(apiHandler *api.API, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/keys:batchGet", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		apiHandler.BatchGetKeysHandler(rr, req)
+		return rr
+	}
+
+	type batchBody struct {
+		Keys        map[string]keys.PublicKeys `json:"keys"`
+		Missing     []string                   `json:"missing"`
+		Compromised []string                   `json:"compromised"`
+	}
+
+	t.Run("Success - 200 found keys and missing entities", func(t *testing.T) {
+		// Act
+		rr := post(apiHandler, `{"urns":["urn:sm:user:alice","urn:sm:user:bob","urn:sm:user:carol","urn:sm:user:dave"]}`)
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		var body batchBody
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, map[string]keys.PublicKeys{
+			aliceURN.String(): aliceKeys,
+			carolURN.String(): carolKeys,
+		}, body.Keys)
+		assert.Equal(t, []string{"urn:sm:user:bob", "urn:sm:user:dave"}, body.Missing)
+		assert.Empty(t, body.Compromised)
+	})
+
+	t.Run("Success - 200 empty batch", func(t *testing.T) {
+		// Act
+		rr := post(apiHandler, `{"urns":[]}`)
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"keys":{},"missing":[]}`, rr.Body.String())
+	})
+
+	t.Run("Success - 200 compromised keys are flagged", func(t *testing.T) {
+		// Arrange
+		flagged := inmemory.New()
+		require.NoError(t, flagged.StorePublicKeys(ctx, aliceURN, aliceKeys))
+		require.NoError(t, flagged.MarkCompromised(ctx, aliceURN, "leaked"))
+		apiHandler := &api.API{Store: flagged, Logger: logger}
+
+		// Act
+		rr := post(apiHandler, `{"urns":["urn:sm:user:alice"]}`)
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		var body batchBody
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Contains(t, body.Keys, aliceURN.String())
+		assert.Equal(t, []string{aliceURN.String()}, body.Compromised)
+	})
+
+	t.Run("Success - 200 strict mode withholds unsigned keys", func(t *testing.T) {
+		// Arrange
+		trusted := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{5}, ed25519.SeedSize))
+		payload, err := api.ProvisioningPayload(aliceURN, aliceKeys)
+		require.NoError(t, err)
+		signed := inmemory.New()
+		require.NoError(t, signed.StorePublicKeysWithMetadata(ctx, aliceURN, aliceKeys,
+			keystore.Metadata{ProvisioningSignature: ed25519.Sign(trusted, payload)}))
+		require.NoError(t, signed.StorePublicKeys(ctx, carolURN, carolKeys))
+		strict := &api.API{
+			Store:                        signed,
+			Logger:                       logger,
+			ProvisionerKeys:              []ed25519.PublicKey{trusted.Public().(ed25519.PublicKey)},
+			RequireProvisioningSignature: true,
+		}
+
+		// Act
+		rr := post(strict, `{"urns":["urn:sm:user:alice","urn:sm:user:carol"]}`)
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		var body batchBody
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, map[string]keys.PublicKeys{aliceURN.String(): aliceKeys}, body.Keys)
+		assert.Equal(t, []string{carolURN.String()}, body.Missing)
+	})
+
+	t.Run("Failure - 400 BATCH_TOO_LARGE", func(t *testing.T) {
+		// Arrange
+		urns := make([]string, api.MaxKeysBatch+1)
+		for i := range urns {
+			urns[i] = fmt.Sprintf("urn:sm:user:user-%d", i)
+		}
+		body, err := json.Marshal(map[string][]string{"urns": urns})
+		require.NoError(t, err)
+
+		// Act
+		rr := post(apiHandler, string(body))
+
+		// Assert
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		var errResp api.CodedAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, api.ErrCodeBatchTooLarge, errResp.Code)
+	})
+
+	t.Run("Failure - 400 invalid URN", func(t *testing.T) {
+		// Act
+		rr := post(apiHandler, `{"urns":["urn:sm:user:alice","urn:bad"]}`)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Failure - 500 store error", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("GetPublicKeysBatch", mock.Anything, []urn.URN{aliceURN}).Return(nil, errors.New("backend down"))
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+
+		// Act
+		rr := post(apiHandler, `{"urns":["urn:sm:user:alice"]}`)
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,62 @@
+// --- File: internal/api/handlers_capabilities.go ---
+package api
+
+import (
+	"net/http"
+
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+)
+
+// Capabilities is the body of a GET /capabilities response. It tells
+// clients which optional features this deployment has enabled and the
+// limits it enforces, so they can adapt without out-of-band config.
+type Capabilities struct {
+	Features CapabilityFeatures `json:"features"`
+	Limits   CapabilityLimits   `json:"limits"`
+}
+
+// CapabilityFeatures reports each optional feature as on or off. Features
+// the service does not implement at all are reported as off, so clients
+// can ask about them without guessing from the version.
+type CapabilityFeatures struct {
+	Versioning                   bool `json:"versioning"`
+	Devices                      bool `json:"devices"`
+	Protobuf                     bool `json:"protobuf"`
+	BatchFingerprints            bool `json:"batchFingerprints"`
+	BatchGet                     bool `json:"batchGet"`
+	KeyDiff                      bool `json:"keyDiff"`
+	JWKS                         bool `json:"jwks"`
+	Attestation                  bool `json:"attestation"`
+	ConsistencyTokens            bool `json:"consistencyTokens"`
+	GzipRequests                 bool `json:"gzipRequests"`
+	MultipartUploads             bool `json:"multipartUploads"`
+	RequireUTF8Bodies            bool `json:"requireUtf8Bodies"`
+	RequireProvisioningSignature bool `json:"requireProvisioningSignature"`
+	AsyncWrites                  bool `json:"asyncWrites"`
+	InviteTokens                 bool `json:"inviteTokens"`
+	UsageRestrictions            bool `json:"usageRestrictions"`
+	CanonicalResponses           bool `json:"canonicalResponses"`
+}
+
+// CapabilityLimits reports the limits a client's requests must fit.
+// Durations are in whole seconds or milliseconds, as named, and zero
+// means no limit unless noted.
+type CapabilityLimits struct {
+	KeyValidationMode       string `json:"keyValidationMode"`
+	MaxFingerprintBatch     int    `json:"maxFingerprintBatch"`
+	MaxKeysBatch            int    `json:"maxKeysBatch"`
+	MaxJSONDepth            int    `json:"maxJsonDepth"`
+	MaxBodyBytes            int64  `json:"maxBodyBytes,omitempty"`
+	RotationCooldownSeconds int64  `json:"rotationCooldownSeconds"`
+	ConsistencyWaitMillis   int64  `json:"consistencyWaitMillis"`
+}
+
+// GetCapabilitiesHandler handles the GET /capabilities request.
+// It serves the document built from the effective config at startup.
+func (a *API) GetCapabilitiesHandler(w http.ResponseWriter, r *http.Request) {
+	if a.Capabilities == nil {
+		response.WriteJSONError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, a.Capabilities)
+}
@@ -0,0 +1,78 @@
+// --- File: internal/api/handlers_delete.go ---
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// DeleteKeysHandler handles the DELETE /keys/{entityURN} request. It
+// removes the entity's published keys, for example when a user
+// deregisters, under the same authorization rules as StoreKeysHandler.
+func (a *API) DeleteKeysHandler(w http.ResponseWriter, r *http.Request) {
+	// 1. Auth: Get the authenticated user's ID from the JWT context.
+	authedUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		a.Logger.Debug("DeleteKeys: Failed. No user ID in token context.")
+		response.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized: No user ID in token")
+		return
+	}
+
+	// 2. Path: Get the URN from the path.
+	entityURNStr := r.PathValue("entityURN")
+	entityURN, err := urn.Parse(entityURNStr)
+	if err != nil {
+		a.Logger.Warn("DeleteKeys: Invalid URN format", "err", err, "raw_urn", entityURNStr)
+		response.WriteJSONError(w, http.StatusBadRequest, "Invalid URN format")
+		return
+	}
+
+	logger := withTokenClaims(r.Context(), a.Logger).With("entity_urn", entityURN.String())
+
+	// 3. Authz: User can only delete their own key.
+	if entityURN.EntityID() != authedUserID {
+		a.logAuthzDecision(r.Context(), logger, "DeleteKeys", false, authedUserID, entityURN, AuthzReasonEntityMismatch)
+		logger.Warn("DeleteKeys: Forbidden. User tried to delete key for another entity",
+			"authed_user", authedUserID,
+			"target_entity_id", entityURN.EntityID())
+		response.WriteJSONError(w, http.StatusForbidden, "Forbidden: You can only delete your own key")
+		return
+	}
+
+	// 3b. Authz: Managed entity types are left to the admins who provision them.
+	if slices.Contains(a.SelfStoreDeniedTypes, entityURN.EntityType()) {
+		a.logAuthzDecision(r.Context(), logger, "DeleteKeys", false, authedUserID, entityURN, AuthzReasonSelfStoreDenied)
+		logger.Warn("DeleteKeys: Forbidden. Entity type cannot self-manage keys",
+			"entity_type", entityURN.EntityType())
+		writeJSONErrorWithCode(w, http.StatusForbidden, ErrCodeSelfStoreForbidden,
+			"Forbidden: Keys for this entity type are managed by an admin")
+		return
+	}
+	a.logAuthzDecision(r.Context(), logger, "DeleteKeys", true, authedUserID, entityURN, AuthzReasonOwnEntity)
+
+	// 4. Delete: The store reports whether there was anything to delete.
+	deleted, err := a.Store.DeletePublicKeys(r.Context(), entityURN)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			logger.Info("DeleteKeys: Client closed request during delete", "err", err)
+			w.WriteHeader(StatusClientClosedRequest)
+			return
+		}
+		a.writeInternalError(w, logger, "DeleteKeys: Failed to delete public keys", "Failed to delete public keys", err)
+		return
+	}
+	if !deleted {
+		logger.Debug("DeleteKeys: No keys to delete")
+		response.WriteJSONError(w, http.StatusNotFound, "Key not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	logger.Info("DeleteKeys: Successfully deleted public keys")
+}
@@ -0,0 +1,125 @@
+// --- File: internal/api/handlers_delete_test.go ---
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func TestDeleteKeysHandler(t *testing.T) {
+	logger := newTestLogger()
+	authedUserID := "leaving-user"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
+	require.NoError(t, err)
+	testKeys := keys.PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5, 6}}
+
+	// deleteKeys sends a DELETE for entityURN as userID (empty for anonymous).
+	deleteKeys := func(apiHandler *api.API, entityURN urn.URN, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/keys/"+entityURN.String(), nil)
+		req.SetPathValue("entityURN", entityURN.String())
+		if userID != "" {
+			req = req.WithContext(middleware.ContextWithUserID(context.Background(), userID))
+		}
+		rr := httptest.NewRecorder()
+		apiHandler.DeleteKeysHandler(rr, req)
+		return rr
+	}
+
+	t.Run("Success - 204 keys deleted", func(t *testing.T) {
+		// Arrange
+		store := inmemory.New()
+		require.NoError(t, store.StorePublicKeys(context.Background(), userURN, testKeys))
+		apiHandler := &api.API{Store: store, Logger: logger}
+
+		// Act
+		rr := deleteKeys(apiHandler, userURN, authedUserID)
+
+		// Assert
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Empty(t, rr.Body.String())
+		_, err := store.GetPublicKeys(context.Background(), userURN)
+		assert.Error(t, err, "keys should be gone")
+	})
+
+	t.Run("Failure - 404 no keys to delete", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: inmemory.New(), Logger: logger}
+
+		// Act
+		rr := deleteKeys(apiHandler, userURN, authedUserID)
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Failure - 401 unauthenticated", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore) // No calls expected
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+
+		// Act
+		rr := deleteKeys(apiHandler, userURN, "")
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockStore.AssertNotCalled(t, "DeletePublicKeys")
+	})
+
+	t.Run("Failure - 403 another user's keys", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore) // No calls expected
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+
+		// Act
+		rr := deleteKeys(apiHandler, userURN, "someone-else")
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		mockStore.AssertNotCalled(t, "DeletePublicKeys")
+	})
+
+	t.Run("Failure - 403 entity type denied self-store", func(t *testing.T) {
+		// Arrange
+		deviceURN, err := urn.New(urn.SecureMessaging, "device", authedUserID)
+		require.NoError(t, err)
+		mockStore := new(MockStore) // No calls expected
+		apiHandler := &api.API{Store: mockStore, Logger: logger, SelfStoreDeniedTypes: []string{"device"}}
+
+		// Act
+		rr := deleteKeys(apiHandler, deviceURN, authedUserID)
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		var errResp api.CodedAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, api.ErrCodeSelfStoreForbidden, errResp.Code)
+		mockStore.AssertNotCalled(t, "DeletePublicKeys")
+	})
+
+	t.Run("Failure - 500 store error", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("DeletePublicKeys", mock.Anything, userURN).Return(false, errors.New("backend down"))
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+
+		// Act
+		rr := deleteKeys(apiHandler, userURN, authedUserID)
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+}
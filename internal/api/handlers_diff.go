@@ -0,0 +1,104 @@
+// --- File: internal/api/handlers_diff.go ---
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// keyDiffResponse is the body of a GET /keys:diff response. It says which
+// keys the two entities share and never carries the keys themselves.
+type keyDiffResponse struct {
+	A           string `json:"a"`
+	B           string `json:"b"`
+	EncKeyEqual bool   `json:"encKeyEqual"`
+	SigKeyEqual bool   `json:"sigKeyEqual"`
+	Identical   bool   `json:"identical"`
+}
+
+// DiffKeysHandler handles the GET /keys:diff?a=<urn>&b=<urn> request.
+// It tells a verifier whether two entities publish the same keys, for
+// example to catch one key pair reused across accounts. Like
+// GET /keys/{entityURN}, it needs no authentication, and it treats keys
+// that GET would withhold as absent.
+func (a *API) DiffKeysHandler(w http.ResponseWriter, r *http.Request) {
+	// 1. Query: Both URNs are required and must parse.
+	query := r.URL.Query()
+	entityURNs := make([]urn.URN, 0, 2)
+	for _, param := range []string{"a", "b"} {
+		raw := query.Get(param)
+		if raw == "" {
+			a.Logger.Warn("DiffKeys: Missing URN parameter", "param", param)
+			response.WriteJSONError(w, http.StatusBadRequest, "Query parameters a and b are required")
+			return
+		}
+		entityURN, err := urn.Parse(raw)
+		if err != nil {
+			a.Logger.Warn("DiffKeys: Invalid URN format", "err", err, "param", param, "raw_urn", raw)
+			response.WriteJSONError(w, http.StatusBadRequest, "Invalid URN format: "+raw)
+			return
+		}
+		entityURNs = append(entityURNs, entityURN)
+	}
+
+	logger := a.Logger.With("a_urn", entityURNs[0].String(), "b_urn", entityURNs[1].String())
+
+	// 2. Provisioning: In strict mode, keys without a trusted provisioning
+	// signature are withheld as in a batch GET, so whether they are shared
+	// must not leak either.
+	if a.RequireProvisioningSignature {
+		records, err := a.Store.GetPublicKeysBatch(r.Context(), entityURNs)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				logger.Info("DiffKeys: Client closed request during lookup", "err", err)
+				w.WriteHeader(StatusClientClosedRequest)
+				return
+			}
+			a.writeInternalError(w, logger, "DiffKeys: Failed to read public keys", "Failed to compare public keys", err)
+			return
+		}
+		for _, entityURN := range entityURNs {
+			record, ok := records[entityURN.String()]
+			if ok && !verifyProvisioningSignature(a.ProvisionerKeys, entityURN, record.Keys, record.Metadata.ProvisioningSignature) {
+				logger.Warn("DiffKeys: Withholding keys without a trusted provisioning signature", "entity_urn", entityURN.String())
+				response.WriteJSONError(w, http.StatusNotFound, "Key not found: "+entityURN.String())
+				return
+			}
+		}
+	}
+
+	// 3. Store: The store compares fingerprints, so no key bytes leave it.
+	diff, err := a.Store.DiffPublicKeys(r.Context(), entityURNs[0], entityURNs[1])
+	if err != nil {
+		if errors.Is(err, keystore.ErrKeyNotFound) {
+			missing := "one of the entities"
+			var storeErr *keystore.StoreError
+			if errors.As(err, &storeErr) && !storeErr.URN.IsZero() {
+				missing = storeErr.URN.String()
+			}
+			logger.Debug("DiffKeys: Key not found", "err", err)
+			response.WriteJSONError(w, http.StatusNotFound, "Key not found: "+missing)
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			logger.Info("DiffKeys: Client closed request during lookup", "err", err)
+			w.WriteHeader(StatusClientClosedRequest)
+			return
+		}
+		a.writeInternalError(w, logger, "DiffKeys: Failed to compare public keys", "Failed to compare public keys", err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, keyDiffResponse{
+		A:           entityURNs[0].String(),
+		B:           entityURNs[1].String(),
+		EncKeyEqual: diff.EncKeyEqual,
+		SigKeyEqual: diff.SigKeyEqual,
+		Identical:   diff.Identical(),
+	})
+}
@@ -0,0 +1,160 @@
+// --- File: internal/api/handlers_diff_test.go ---
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func TestDiffKeysHandler(t *testing.T) {
+	logger := newTestLogger()
+	ctx := context.Background()
+	sharedKeys := keys.PublicKeys{EncKey: []byte("shared-enc"), SigKey: []byte("shared-sig")}
+	aliceURN, err := urn.New(urn.SecureMessaging, "user", "alice")
+	require.NoError(t, err)
+	bobURN, err := urn.New(urn.SecureMessaging, "user", "bob")
+	require.NoError(t, err)
+	carolURN, err := urn.New(urn.SecureMessaging, "user", "carol")
+	require.NoError(t, err)
+	daveURN, err := urn.New(urn.SecureMessaging, "user", "dave")
+	require.NoError(t, err)
+
+	store := inmemory.New()
+	require.NoError(t, store.StorePublicKeys(ctx, aliceURN, sharedKeys))
+	require.NoError(t, store.StorePublicKeys(ctx, bobURN, sharedKeys))
+	require.NoError(t, store.StorePublicKeys(ctx, carolURN, keys.PublicKeys{EncKey: []byte("carol-enc"), SigKey: sharedKeys.SigKey}))
+	apiHandler := &api.API{Store: store, Logger: logger}
+
+	// diff sends a GET /keys:diff with the given raw a and b parameters.
+	diff := func(apiHandler *api.API, a, b string) *httptest.ResponseRecorder {
+		query := url.Values{}
+		if a != "" {
+			query.Set("a", a)
+		}
+		if b != "" {
+			query.Set("b", b)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/keys:diff?"+query.Encode(), nil)
+		rr := httptest.NewRecorder()
+		apiHandler.DiffKeysHandler(rr, req)
+		return rr
+	}
+
+	type diffBody struct {
+		A           string `json:"a"`
+		B           string `json:"b"`
+		EncKeyEqual bool   `json:"encKeyEqual"`
+		SigKeyEqual bool   `json:"sigKeyEqual"`
+		Identical   bool   `json:"identical"`
+	}
+
+	t.Run("Success - 200 identical keys reported equal", func(t *testing.T) {
+		// Act
+		rr := diff(apiHandler, aliceURN.String(), bobURN.String())
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		var body diffBody
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, diffBody{A: aliceURN.String(), B: bobURN.String(), EncKeyEqual: true, SigKeyEqual: true, Identical: true}, body)
+		assert.NotContains(t, rr.Body.String(), "shared-enc")
+	})
+
+	t.Run("Success - 200 differing keys reported different", func(t *testing.T) {
+		// Act
+		rr := diff(apiHandler, aliceURN.String(), carolURN.String())
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		var body diffBody
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.False(t, body.EncKeyEqual)
+		assert.True(t, body.SigKeyEqual)
+		assert.False(t, body.Identical)
+	})
+
+	t.Run("Failure - 404 either entity missing", func(t *testing.T) {
+		for _, pair := range [][2]urn.URN{{daveURN, aliceURN}, {aliceURN, daveURN}} {
+			// Act
+			rr := diff(apiHandler, pair[0].String(), pair[1].String())
+
+			// Assert
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+			var errResp response.APIError
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+			assert.Equal(t, "Key not found: "+daveURN.String(), errResp.Error)
+		}
+	})
+
+	t.Run("Failure - 404 strict mode withholds unsigned keys", func(t *testing.T) {
+		// Arrange
+		trusted := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{5}, ed25519.SeedSize))
+		payload, err := api.ProvisioningPayload(aliceURN, sharedKeys)
+		require.NoError(t, err)
+		signed := inmemory.New()
+		require.NoError(t, signed.StorePublicKeysWithMetadata(ctx, aliceURN, sharedKeys,
+			keystore.Metadata{ProvisioningSignature: ed25519.Sign(trusted, payload)}))
+		require.NoError(t, signed.StorePublicKeys(ctx, bobURN, sharedKeys))
+		strict := &api.API{
+			Store:                        signed,
+			Logger:                       logger,
+			ProvisionerKeys:              []ed25519.PublicKey{trusted.Public().(ed25519.PublicKey)},
+			RequireProvisioningSignature: true,
+		}
+
+		// Act
+		rr := diff(strict, aliceURN.String(), bobURN.String())
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		var errResp response.APIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, "Key not found: "+bobURN.String(), errResp.Error)
+		assert.NotContains(t, rr.Body.String(), "identical")
+	})
+
+	t.Run("Failure - 400 missing or invalid URN", func(t *testing.T) {
+		for _, tc := range []struct{ a, b string }{
+			{aliceURN.String(), ""},
+			{"", bobURN.String()},
+			{aliceURN.String(), "urn:bad"},
+		} {
+			// Act
+			rr := diff(apiHandler, tc.a, tc.b)
+
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, rr.Code, tc)
+		}
+	})
+
+	t.Run("Failure - 500 store error", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("DiffPublicKeys", mock.Anything, aliceURN, bobURN).Return(keystore.KeyDiff{}, errors.New("backend down"))
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+
+		// Act
+		rr := diff(apiHandler, aliceURN.String(), bobURN.String())
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,104 @@
+// --- File: internal/api/handlers_fingerprints.go ---
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// MaxFingerprintBatch is the most URNs one POST /keys:fingerprints accepts.
+const MaxFingerprintBatch = 100
+
+// fingerprintsRequest is the body of a POST /keys:fingerprints request.
+type fingerprintsRequest struct {
+	URNs []string `json:"urns"`
+}
+
+// fingerprintsResponse maps each requested URN that has keys, in
+// canonical form, to the keystore.Fingerprint of its keys.
+type fingerprintsResponse struct {
+	Fingerprints map[string]string `json:"fingerprints"`
+}
+
+// GetFingerprintsHandler handles the POST /keys:fingerprints request.
+// It tells a client which of up to MaxFingerprintBatch entities have keys,
+// and which keys, in one round trip and without sending the key bytes.
+// Like GET /keys/{entityURN}, it needs no authentication, and it leaves
+// out entities whose keys GET would withhold.
+func (a *API) GetFingerprintsHandler(w http.ResponseWriter, r *http.Request) {
+	// 1. Body: Decode and bound the list of URNs.
+	var req fingerprintsRequest
+	if err := decodeJSONWithMaxDepth(r.Body, &req, a.MaxJSONDepth); err != nil {
+		if errors.Is(err, errJSONTooDeep) {
+			a.Logger.Warn("GetFingerprints: Rejected over-nested JSON body", "err", err)
+			response.WriteJSONError(w, http.StatusBadRequest, "JSON body is nested too deeply")
+			return
+		}
+		a.Logger.Warn("GetFingerprints: Failed to unmarshal JSON body", "err", err)
+		response.WriteJSONError(w, http.StatusBadRequest, "Invalid JSON body format")
+		return
+	}
+	if len(req.URNs) > MaxFingerprintBatch {
+		a.Logger.Warn("GetFingerprints: Rejected oversized batch", "count", len(req.URNs))
+		writeJSONErrorWithCode(w, http.StatusBadRequest, ErrCodeBatchTooLarge,
+			fmt.Sprintf("At most %d URNs may be requested at once", MaxFingerprintBatch))
+		return
+	}
+
+	// 2. URNs: Every entry must parse, so a typo is not mistaken for absence.
+	entityURNs := make([]urn.URN, 0, len(req.URNs))
+	for _, raw := range req.URNs {
+		entityURN, err := urn.Parse(raw)
+		if err != nil {
+			a.Logger.Warn("GetFingerprints: Invalid URN format", "err", err, "raw_urn", raw)
+			response.WriteJSONError(w, http.StatusBadRequest, "Invalid URN format: "+raw)
+			return
+		}
+		entityURNs = append(entityURNs, entityURN)
+	}
+
+	// 3. Store: Read every fingerprint in one call.
+	var fingerprints map[string]string
+	var err error
+	if a.RequireProvisioningSignature {
+		fingerprints, err = a.trustedFingerprints(r, entityURNs)
+	} else {
+		fingerprints, err = a.Store.GetFingerprintsBatch(r.Context(), entityURNs)
+	}
+	if err != nil {
+		a.writeInternalError(w, a.Logger, "GetFingerprints: Failed to read fingerprints", "Failed to read fingerprints", err,
+			"count", len(entityURNs))
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, fingerprintsResponse{Fingerprints: fingerprints})
+}
+
+// trustedFingerprints is GetFingerprintsBatch for strict mode. Checking
+// the provisioning signature needs the metadata, so it reads the full
+// records and leaves out, as in a batch GET, any without a trusted
+// signature.
+func (a *API) trustedFingerprints(r *http.Request, entityURNs []urn.URN) (map[string]string, error) {
+	records, err := a.Store.GetPublicKeysBatch(r.Context(), entityURNs)
+	if err != nil {
+		return nil, err
+	}
+	fingerprints := make(map[string]string, len(records))
+	for _, entityURN := range entityURNs {
+		entityKey := entityURN.String()
+		record, ok := records[entityKey]
+		if !ok {
+			continue
+		}
+		if !verifyProvisioningSignature(a.ProvisionerKeys, entityURN, record.Keys, record.Metadata.ProvisioningSignature) {
+			a.Logger.Warn("GetFingerprints: Withholding keys without a trusted provisioning signature", "entity_urn", entityKey)
+			continue
+		}
+		fingerprints[entityKey] = keystore.Fingerprint(record.Keys)
+	}
+	return fingerprints, nil
+}
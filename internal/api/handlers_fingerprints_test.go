@@ -0,0 +1,126 @@
+// --- File: internal/api/handlers_fingerprints_test.go ---
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func TestGetFingerprintsHandler(t *testing.T) {
+	logger := newTestLogger()
+	store := inmemory.New()
+	testKeys := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+	aliceURN, err := urn.New(urn.SecureMessaging, "user", "alice")
+	require.NoError(t, err)
+	require.NoError(t, store.StorePublicKeys(context.Background(), aliceURN, testKeys))
+	apiHandler := &api.API{Store: store, Logger: logger}
+
+	post := func(apiHandler *api.API, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/keys:fingerprints", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		apiHandler.GetFingerprintsHandler(rr, req)
+		return rr
+	}
+
+	t.Run("Success - 200 present entities only", func(t *testing.T) {
+		// Act
+		rr := post(apiHandler, `{"urns":["urn:sm:user:alice","urn:sm:user:bob"]}`)
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		var body struct {
+			Fingerprints map[string]string `json:"fingerprints"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, map[string]string{"urn:sm:user:alice": keystore.Fingerprint(testKeys)}, body.Fingerprints)
+		assert.NotContains(t, rr.Body.String(), `"encKey"`)
+	})
+
+	t.Run("Success - 200 strict mode withholds unsigned keys", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		bobURN, err := urn.New(urn.SecureMessaging, "user", "bob")
+		require.NoError(t, err)
+		trusted := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{5}, ed25519.SeedSize))
+		payload, err := api.ProvisioningPayload(aliceURN, testKeys)
+		require.NoError(t, err)
+		signed := inmemory.New()
+		require.NoError(t, signed.StorePublicKeysWithMetadata(ctx, aliceURN, testKeys,
+			keystore.Metadata{ProvisioningSignature: ed25519.Sign(trusted, payload)}))
+		require.NoError(t, signed.StorePublicKeys(ctx, bobURN, testKeys))
+		strict := &api.API{
+			Store:                        signed,
+			Logger:                       logger,
+			ProvisionerKeys:              []ed25519.PublicKey{trusted.Public().(ed25519.PublicKey)},
+			RequireProvisioningSignature: true,
+		}
+
+		// Act
+		rr := post(strict, `{"urns":["urn:sm:user:alice","urn:sm:user:bob"]}`)
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		var body struct {
+			Fingerprints map[string]string `json:"fingerprints"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, map[string]string{aliceURN.String(): keystore.Fingerprint(testKeys)}, body.Fingerprints)
+	})
+
+	t.Run("Failure - 400 BATCH_TOO_LARGE", func(t *testing.T) {
+		// Arrange
+		urns := make([]string, api.MaxFingerprintBatch+1)
+		for i := range urns {
+			urns[i] = fmt.Sprintf("urn:sm:user:user-%d", i)
+		}
+		body, err := json.Marshal(map[string][]string{"urns": urns})
+		require.NoError(t, err)
+
+		// Act
+		rr := post(apiHandler, string(body))
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var errResp api.CodedAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, api.ErrCodeBatchTooLarge, errResp.Code)
+	})
+
+	t.Run("Failure - 400 invalid URN", func(t *testing.T) {
+		// Act
+		rr := post(apiHandler, `{"urns":["urn:sm:user:alice","urn:sm:user"]}`)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Failure - 500 store error", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("GetFingerprintsBatch", mock.Anything, mock.Anything).Return(map[string]string(nil), errors.New("db down"))
+
+		// Act
+		rr := post(&api.API{Store: mockStore, Logger: logger}, `{"urns":["urn:sm:user:alice"]}`)
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,115 @@
+// --- File: internal/api/handlers_invite.go ---
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/tinywideclouds/go-key-service/internal/storage/budget"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+)
+
+// redeemInviteResponse is the body of a successful POST /keys:redeemInvite
+// response, naming the entity the keys were bound to.
+type redeemInviteResponse struct {
+	URN string `json:"urn"`
+}
+
+// RedeemInviteHandler handles the POST /keys:redeemInvite request. It stores
+// the keys in the body for the entity named by the one-time invite token in
+// X-Invite-Token, in place of an authenticated user. The token is consumed:
+// it responds 201 the first time and 409 once the token has been redeemed
+// or the entity already has keys. Expired or invalid tokens get 403.
+func (a *API) RedeemInviteHandler(w http.ResponseWriter, r *http.Request) {
+	// 1. Auth: The invite token stands in for the JWT.
+	token := r.Header.Get(InviteTokenHeader)
+	if token == "" {
+		a.Logger.Debug("RedeemInvite: Failed. No invite token.")
+		response.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized: Missing "+InviteTokenHeader+" header")
+		return
+	}
+	inv, err := a.Invites.verify(token)
+	if errors.Is(err, errInviteExpired) {
+		a.Logger.Info("RedeemInvite: Rejected expired invite token")
+		writeJSONErrorWithCode(w, http.StatusForbidden, ErrCodeInviteExpired, "Invite token has expired")
+		return
+	}
+	if err != nil {
+		a.Logger.Warn("RedeemInvite: Rejected invalid invite token", "err", err, "remote_addr", r.RemoteAddr)
+		writeJSONErrorWithCode(w, http.StatusForbidden, ErrCodeInviteInvalid, "Invite token is invalid")
+		return
+	}
+	logger := a.Logger.With("entity_urn", inv.entityURN.String(), "invite_id", inv.id)
+
+	// 2. Body: Decode and validate as for any store, before the token is
+	// spent, so a rejected body can be corrected and resent.
+	keysToStore, meta, ok := a.decodeStoreRequest(w, r, inv.entityURN, logger)
+	if !ok {
+		return
+	}
+
+	// 3. Consume: Each token binds keys once.
+	if !a.Invites.consume(inv) {
+		logger.Warn("RedeemInvite: Rejected reused invite token")
+		writeJSONErrorWithCode(w, http.StatusConflict, ErrCodeInviteAlreadyUsed, "Invite token has already been used")
+		return
+	}
+
+	// 4. Store: Create-if-absent, so the invite only ever registers the
+	// entity's first keys, whichever replica redeems it.
+	writtenAfter := time.Now()
+	_, created, err := a.Store.GetOrCreatePublicKeys(r.Context(), inv.entityURN, keysToStore)
+	if err != nil {
+		a.Invites.release(inv)
+		if errors.Is(err, context.Canceled) {
+			logger.Info("RedeemInvite: Client closed request during store", "err", err)
+			w.WriteHeader(StatusClientClosedRequest)
+			return
+		}
+		if errors.Is(err, budget.ErrExceeded) {
+			logger.Warn("RedeemInvite: Rejected write over the storage budget")
+			writeJSONErrorWithCode(w, http.StatusInsufficientStorage, ErrCodeStorageBudgetExceeded,
+				"The key store is full")
+			return
+		}
+		a.writeInternalError(w, logger, "RedeemInvite: Failed to store public keys", "Failed to store public keys", err)
+		return
+	}
+	if !created {
+		logger.Warn("RedeemInvite: Rejected invite for an entity that already has keys")
+		writeJSONErrorWithCode(w, http.StatusConflict, ErrCodeInviteAlreadyUsed, "Invite token has already been used")
+		return
+	}
+	// 4b. Metadata: Stores cannot create keys with metadata in one
+	// conditional write, so it follows the create. If it fails, the create
+	// is undone and the token released, so the user can retry with the
+	// same token instead of being left with unsigned, unrestricted keys.
+	if !metadataEqual(meta, keystore.Metadata{}) {
+		if err := a.Store.StorePublicKeysWithMetadata(r.Context(), inv.entityURN, keysToStore, meta); err != nil {
+			if _, delErr := a.Store.DeletePublicKeys(context.WithoutCancel(r.Context()), inv.entityURN); delErr != nil {
+				logger.Error("RedeemInvite: Failed to remove keys stored without their metadata", "err", delErr)
+			}
+			a.Invites.release(inv)
+			if errors.Is(err, context.Canceled) {
+				logger.Info("RedeemInvite: Client closed request during metadata store", "err", err)
+				w.WriteHeader(StatusClientClosedRequest)
+				return
+			}
+			if errors.Is(err, budget.ErrExceeded) {
+				logger.Warn("RedeemInvite: Rejected metadata write over the storage budget")
+				writeJSONErrorWithCode(w, http.StatusInsufficientStorage, ErrCodeStorageBudgetExceeded,
+					"The key store is full")
+				return
+			}
+			a.writeInternalError(w, logger, "RedeemInvite: Failed to store key metadata", "Failed to store key metadata", err)
+			return
+		}
+	}
+
+	w.Header().Set(ConsistencyTokenHeader, encodeConsistencyToken(writtenAfter))
+	response.WriteJSON(w, http.StatusCreated, redeemInviteResponse{URN: inv.entityURN.String()})
+	logger.Info("RedeemInvite: Stored public keys for invited entity")
+}
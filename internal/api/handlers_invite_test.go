@@ -0,0 +1,221 @@
+// --- File: internal/api/handlers_invite_test.go ---
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/budget"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// failingMetadataStore fails StorePublicKeysWithMetadata with err, if set.
+type failingMetadataStore struct {
+	keystore.Store
+	err error
+}
+
+func (s *failingMetadataStore) StorePublicKeysWithMetadata(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys, meta keystore.Metadata) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.Store.StorePublicKeysWithMetadata(ctx, entityURN, pk, meta)
+}
+
+func TestRedeemInviteHandler(t *testing.T) {
+	ctx := context.Background()
+	secret := "invite-test-secret"
+	invitedURN, err := urn.New(urn.SecureMessaging, "user", "invited-user")
+	require.NoError(t, err)
+	mockBodyJSON := `{"encKey":"AQID","sigKey":"BAUG"}`
+
+	// signInvite issues an invite token the way the identity service does.
+	signInvite := func(t *testing.T, jti, purpose string, expiresAt time.Time) string {
+		t.Helper()
+		token, err := jwt.NewBuilder().
+			Subject(invitedURN.String()).
+			JwtID(jti).
+			Expiration(expiresAt).
+			Claim("purpose", purpose).
+			Build()
+		require.NoError(t, err)
+		signed, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte(secret)))
+		require.NoError(t, err)
+		return string(signed)
+	}
+	redeem := func(apiHandler *api.API, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/keys:redeemInvite", strings.NewReader(mockBodyJSON))
+		if token != "" {
+			req.Header.Set(api.InviteTokenHeader, token)
+		}
+		rr := httptest.NewRecorder()
+		apiHandler.RedeemInviteHandler(rr, req)
+		return rr
+	}
+	newAPI := func() (*api.API, *inmemory.Store) {
+		store := inmemory.New()
+		return &api.API{Store: store, Logger: newTestLogger(), Invites: api.NewInviteVerifier(secret)}, store
+	}
+
+	t.Run("Success - fresh token binds keys to the invited entity", func(t *testing.T) {
+		// Arrange
+		apiHandler, store := newAPI()
+		token := signInvite(t, "invite-1", api.InvitePurpose, time.Now().Add(time.Hour))
+
+		// Act
+		rr := redeem(apiHandler, token)
+
+		// Assert
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		assert.JSONEq(t, `{"urn":"`+invitedURN.String()+`"}`, rr.Body.String())
+		assert.NotEmpty(t, rr.Header().Get(api.ConsistencyTokenHeader))
+		stored, err := store.GetPublicKeys(ctx, invitedURN)
+		require.NoError(t, err)
+		assert.Equal(t, keys.PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5, 6}}, stored)
+	})
+
+	t.Run("Failure - 409 Conflict for a reused token", func(t *testing.T) {
+		// Arrange
+		apiHandler, _ := newAPI()
+		token := signInvite(t, "invite-2", api.InvitePurpose, time.Now().Add(time.Hour))
+		require.Equal(t, http.StatusCreated, redeem(apiHandler, token).Code)
+
+		// Act
+		rr := redeem(apiHandler, token)
+
+		// Assert
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		var body api.CodedAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, api.ErrCodeInviteAlreadyUsed, body.Code)
+	})
+
+	t.Run("Failure - 409 Conflict when the entity already has keys", func(t *testing.T) {
+		// Arrange
+		apiHandler, store := newAPI()
+		require.NoError(t, store.StorePublicKeys(ctx, invitedURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+		token := signInvite(t, "invite-3", api.InvitePurpose, time.Now().Add(time.Hour))
+
+		// Act
+		rr := redeem(apiHandler, token)
+
+		// Assert
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		stored, err := store.GetPublicKeys(ctx, invitedURN)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("enc"), stored.EncKey, "existing keys must not be replaced")
+	})
+
+	t.Run("Failure - 403 Forbidden for an expired token", func(t *testing.T) {
+		// Arrange
+		apiHandler, store := newAPI()
+		token := signInvite(t, "invite-4", api.InvitePurpose, time.Now().Add(-time.Hour))
+
+		// Act
+		rr := redeem(apiHandler, token)
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		var body api.CodedAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, api.ErrCodeInviteExpired, body.Code)
+		_, err := store.GetPublicKeys(ctx, invitedURN)
+		assert.Error(t, err)
+	})
+
+	t.Run("Failure - 403 Forbidden for a token with the wrong purpose or signature", func(t *testing.T) {
+		// Arrange
+		apiHandler, _ := newAPI()
+		wrongPurpose := signInvite(t, "invite-5", "session", time.Now().Add(time.Hour))
+		forged := signInvite(t, "invite-6", api.InvitePurpose, time.Now().Add(time.Hour))
+		forged = forged[:len(forged)-2] + "AA"
+
+		for _, token := range []string{wrongPurpose, forged} {
+			// Act
+			rr := redeem(apiHandler, token)
+
+			// Assert
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("Failure - 500 metadata write fails and the token stays usable", func(t *testing.T) {
+		// Arrange
+		store := &failingMetadataStore{Store: inmemory.New(), err: errors.New("backend down")}
+		apiHandler := &api.API{Store: store, Logger: newTestLogger(), Invites: api.NewInviteVerifier(secret)}
+		token := signInvite(t, "invite-4", api.InvitePurpose, time.Now().Add(time.Hour))
+		redeemWithHint := func() *httptest.ResponseRecorder {
+			body := `{"encKey":"AQID","sigKey":"BAUG","rotationHint":"2030-01-02T03:04:05Z"}`
+			req := httptest.NewRequest(http.MethodPost, "/keys:redeemInvite", strings.NewReader(body))
+			req.Header.Set(api.InviteTokenHeader, token)
+			rr := httptest.NewRecorder()
+			apiHandler.RedeemInviteHandler(rr, req)
+			return rr
+		}
+
+		// Act
+		rr := redeemWithHint()
+
+		// Assert: nothing is left half-bound.
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		_, err := store.GetPublicKeys(ctx, invitedURN)
+		assert.ErrorIs(t, err, keystore.ErrKeyNotFound)
+
+		// Act: the backend recovers and the user retries the same token.
+		store.err = nil
+		rr = redeemWithHint()
+
+		// Assert
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		record, err := store.GetKeyRecord(ctx, invitedURN)
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC), record.Metadata.RotationHint.UTC())
+	})
+
+	t.Run("Failure - 507 metadata write over the storage budget", func(t *testing.T) {
+		// Arrange
+		store := &failingMetadataStore{Store: inmemory.New(), err: budget.ErrExceeded}
+		apiHandler := &api.API{Store: store, Logger: newTestLogger(), Invites: api.NewInviteVerifier(secret)}
+		token := signInvite(t, "invite-5", api.InvitePurpose, time.Now().Add(time.Hour))
+		body := `{"encKey":"AQID","sigKey":"BAUG","rotationHint":"2030-01-02T03:04:05Z"}`
+		req := httptest.NewRequest(http.MethodPost, "/keys:redeemInvite", strings.NewReader(body))
+		req.Header.Set(api.InviteTokenHeader, token)
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.RedeemInviteHandler(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusInsufficientStorage, rr.Code)
+		var errResp api.CodedAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, api.ErrCodeStorageBudgetExceeded, errResp.Code)
+		_, err := store.GetPublicKeys(ctx, invitedURN)
+		assert.ErrorIs(t, err, keystore.ErrKeyNotFound)
+	})
+
+	t.Run("Failure - 401 Unauthorized without a token", func(t *testing.T) {
+		// Arrange
+		apiHandler, _ := newAPI()
+
+		// Act
+		rr := redeem(apiHandler, "")
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
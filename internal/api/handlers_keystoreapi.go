@@ -2,10 +2,21 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
 
+	"github.com/tinywideclouds/go-key-service/internal/storage/auditchain"
+	"github.com/tinywideclouds/go-key-service/internal/storage/budget"
+	"github.com/tinywideclouds/go-key-service/internal/storage/slo"
 	"github.com/tinywideclouds/go-key-service/pkg/keystore"
 	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
 	"github.com/tinywideclouds/go-microservice-base/pkg/response"
@@ -20,11 +31,123 @@ type API struct {
 	Store     keystore.Store
 	Logger    *slog.Logger
 	JWTSecret string
+	// KeyValidationMode controls how declared key algorithms are checked.
+	// The zero value behaves as keystore.ValidationLenient.
+	KeyValidationMode keystore.ValidationMode
+	// EntityTypePolicies overrides the default key policy (both keys
+	// required, KeyValidationMode) for the listed entity types.
+	EntityTypePolicies map[string]keystore.KeyPolicy
+	// JWKS serves the aggregated signing-key set. Nil disables the endpoint.
+	JWKS *JWKSCache
+	// SelfStoreDeniedTypes lists entity types whose keys can only be set
+	// through the admin route, never by the entity itself.
+	SelfStoreDeniedTypes []string
+	// AdminUserIDs lists the user IDs allowed to use the admin routes.
+	AdminUserIDs []string
+	// SLO reports the store's error-budget burn rates. Nil disables the endpoint.
+	SLO *slo.Store
+	// AuditChain verifies the hash chain of key writes. Nil disables the endpoint.
+	AuditChain *auditchain.Store
+	// MaxJSONDepth bounds nesting in request bodies (0 = DefaultMaxJSONDepth).
+	MaxJSONDepth int
+	// WriteLock is toggled by the admin lock/unlock routes. It must be
+	// non-nil when those routes are registered.
+	WriteLock *WriteLock
+	// AllowIdenticalKeys disables the check that encKey and sigKey differ.
+	AllowIdenticalKeys bool
+	// RotationCooldown is the minimum time between key changes for one
+	// entity (0 = no limit). Re-storing unchanged keys is always allowed.
+	RotationCooldown time.Duration
+	// Now returns the current time. Nil uses time.Now.
+	Now func() time.Time
+	// MaxClockSkew is how far a client-supplied timestamp may lag the
+	// server clock before it is rejected (0 = DefaultMaxClockSkew).
+	MaxClockSkew time.Duration
+	// Entities confirms the entity is a known user before a store. Nil
+	// disables the check.
+	Entities *EntityVerifier
+	// Attester signs freshness attestations for GET ?attest=true. Nil
+	// disables attestation.
+	Attester *Attester
+	// ProvisionerKeys are the trusted upstream provisioners' Ed25519 keys.
+	ProvisionerKeys []ed25519.PublicKey
+	// RequireProvisioningSignature makes GET serve only keys whose
+	// provisioning signature verifies against ProvisionerKeys.
+	RequireProvisioningSignature bool
+	// EntityIDPattern, when set, must match the whole entity ID of every
+	// URN keys are stored for. Nil accepts any ID.
+	EntityIDPattern *regexp.Regexp
+	// AcceptMultipart lets key stores arrive as multipart/form-data with
+	// encKey and sigKey file parts, alongside the JSON body.
+	AcceptMultipart bool
+	// MaxPartBytes caps each multipart part (0 = DefaultMaxDecompressedBodyBytes).
+	MaxPartBytes int64
+	// ConsistencyWait is how long a GET with a consistency token waits for
+	// the store to catch up (0 = DefaultConsistencyWait).
+	ConsistencyWait time.Duration
+	// Capabilities is served at GET /capabilities. Nil disables the endpoint.
+	Capabilities *Capabilities
+	// LogAuthzDecisions logs every allow and deny made by StoreKeysHandler
+	// in one structured form, for security audits.
+	LogAuthzDecisions bool
+	// FullErrorDetail includes internal error text in 500 responses.
+	// Without it clients get a generic message and a correlation ID.
+	FullErrorDetail bool
+	// AsyncWrites, when set, lets a store sent with Prefer: respond-async
+	// be queued and answered 202. Nil keeps every store synchronous.
+	AsyncWrites *AsyncWrites
+	// AcceptUsageRestrictions lets stores declare usageRestrictions, which
+	// are stored and served on GET. Without it they are rejected.
+	AcceptUsageRestrictions bool
+	// CanonicalResponses encodes GET bodies in canonical form, with encKey
+	// and sigKey first and the metadata after in a fixed order, and tags
+	// them with an ETag computed from those bytes.
+	CanonicalResponses bool
+	// RetryAfter is the back-off suggested in Retry-After when writes are
+	// locked or a dependency is unavailable (0 = DefaultRetryAfter).
+	RetryAfter time.Duration
+	// BasePath prefixes the status URLs returned for async writes.
+	BasePath string
+	// Invites verifies the tokens redeemed at POST /keys:redeemInvite.
+	// Nil disables the endpoint.
+	Invites *InviteVerifier
 }
 
+// DefaultMaxClockSkew is used when no clock-skew tolerance is configured.
+const DefaultMaxClockSkew = 5 * time.Minute
+
+// keyPolicy returns the key policy for an entity type, with an unset
+// validation mode resolved to the service-wide mode.
+func (a *API) keyPolicy(entityType string) keystore.KeyPolicy {
+	policy := a.EntityTypePolicies[entityType]
+	if policy.ValidationMode == "" {
+		policy.ValidationMode = a.KeyValidationMode
+	}
+	return policy
+}
+
+func (a *API) maxPartBytes() int64 {
+	if a.MaxPartBytes > 0 {
+		return a.MaxPartBytes
+	}
+	return DefaultMaxDecompressedBodyBytes
+}
+
+func (a *API) now() time.Time {
+	if a.Now != nil {
+		return a.Now()
+	}
+	return time.Now()
+}
+
+// RotationHintHeader carries an entity's advisory next-rotation time on GET.
+const RotationHintHeader = "X-Key-Rotation-Hint"
+
 // StoreKeysHandler handles the POST /keys/{entityURN} request.
 // It validates the authenticated user, parses the request body,
-// and persists the public keys to the store.
+// and persists the public keys to the store. It responds 201 when the
+// entity had no keys, and 200 when existing keys were replaced or the
+// submitted keys were identical to those already stored.
 func (a *API) StoreKeysHandler(w http.ResponseWriter, r *http.Request) {
 	// 1. Auth: Get the authenticated user's ID from the JWT context.
 	authedUserID, ok := middleware.GetUserIDFromContext(r.Context())
@@ -43,11 +166,12 @@ func (a *API) StoreKeysHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logger := a.Logger.With("entity_urn", entityURN.String())
+	logger := withTokenClaims(r.Context(), a.Logger).With("entity_urn", entityURN.String())
 
 	// 3. Authz: User can only store their own key.
 	// --- FIX: Compare the authenticated ID with the URN's ID, not the full URN string. ---
 	if entityURN.EntityID() != authedUserID {
+		a.logAuthzDecision(r.Context(), logger, "StoreKeys", false, authedUserID, entityURN, AuthzReasonEntityMismatch)
 		logger.Warn("StoreKeys: Forbidden. User tried to store key for another entity",
 			"authed_user", authedUserID,
 			"target_entity_id", entityURN.EntityID())
@@ -55,34 +179,302 @@ func (a *API) StoreKeysHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 4. Body: Decode directly into our native struct.
-	var keysToStore keys.PublicKeys
-	if err := json.NewDecoder(r.Body).Decode(&keysToStore); err != nil {
-		logger.Warn("StoreKeys: Failed to unmarshal JSON body", "err", err)
-		response.WriteJSONError(w, http.StatusBadRequest, "Invalid JSON body format")
+	// 3b. Authz: Managed entity types must be provisioned by an admin.
+	if slices.Contains(a.SelfStoreDeniedTypes, entityURN.EntityType()) {
+		a.logAuthzDecision(r.Context(), logger, "StoreKeys", false, authedUserID, entityURN, AuthzReasonSelfStoreDenied)
+		logger.Warn("StoreKeys: Forbidden. Entity type cannot self-store keys",
+			"entity_type", entityURN.EntityType())
+		writeJSONErrorWithCode(w, http.StatusForbidden, ErrCodeSelfStoreForbidden,
+			"Forbidden: Keys for this entity type must be provisioned by an admin")
+		return
+	}
+	a.logAuthzDecision(r.Context(), logger, "StoreKeys", true, authedUserID, entityURN, AuthzReasonOwnEntity)
+
+	a.storeKeys(w, r, entityURN, logger)
+}
+
+// AdminStoreKeysHandler handles the POST /admin/keys/{entityURN} request.
+// It lets a configured admin store keys for any entity, including entity
+// types that are denied self-store.
+func (a *API) AdminStoreKeysHandler(w http.ResponseWriter, r *http.Request) {
+	// 1-2. Auth and Authz: Only configured admins may provision keys.
+	authedUserID, ok := a.requireAdmin(w, r, "AdminStoreKeys")
+	if !ok {
+		return
+	}
+
+	// 3. Path: Get the URN from the path.
+	entityURNStr := r.PathValue("entityURN")
+	entityURN, err := urn.Parse(entityURNStr)
+	if err != nil {
+		a.Logger.Warn("AdminStoreKeys: Invalid URN format", "err", err, "raw_urn", entityURNStr)
+		response.WriteJSONError(w, http.StatusBadRequest, "Invalid URN format")
 		return
 	}
 
-	// 5. Validate that we actually have keys
-	if len(keysToStore.EncKey) == 0 || len(keysToStore.SigKey) == 0 {
-		logger.Warn("StoreKeys: Store request missing encKey or sigKey")
-		response.WriteJSONError(w, http.StatusBadRequest, "encKey and sigKey must not be empty")
+	logger := withTokenClaims(r.Context(), a.Logger).With(
+		"entity_urn", entityURN.String(),
+		"admin_user", authedUserID)
+	logger.Info("AdminStoreKeys: Provisioning keys")
+
+	a.storeKeys(w, r, entityURN, logger)
+}
+
+// requireAdmin checks that the request is authenticated as one of the
+// configured admins. On failure it writes the response and returns false.
+func (a *API) requireAdmin(w http.ResponseWriter, r *http.Request, handlerName string) (string, bool) {
+	authedUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		a.Logger.Debug(handlerName + ": Failed. No user ID in token context.")
+		response.WriteJSONError(w, http.StatusUnauthorized, "Unauthorized: No user ID in token")
+		return "", false
+	}
+	if !slices.Contains(a.AdminUserIDs, authedUserID) {
+		a.Logger.Warn(handlerName+": Forbidden. User is not an admin", "authed_user", authedUserID)
+		response.WriteJSONError(w, http.StatusForbidden, "Forbidden: Admin access required")
+		return "", false
+	}
+	return authedUserID, true
+}
+
+// storeKeys decodes, validates and persists the keys in the request body.
+// Callers are responsible for authorizing the write to entityURN first.
+func (a *API) storeKeys(w http.ResponseWriter, r *http.Request, entityURN urn.URN, logger *slog.Logger) {
+	keysToStore, meta, ok := a.decodeStoreRequest(w, r, entityURN, logger)
+	if !ok {
 		return
 	}
 
-	// 6. Store: Use the store method
-	if err := a.Store.StorePublicKeys(r.Context(), entityURN, keysToStore); err != nil {
-		logger.Error("StoreKeys: Failed to store public keys", "err", err)
-		response.WriteJSONError(w, http.StatusInternalServerError, "Failed to store public keys")
+	// 7. Compare: Read the current keys so the status reflects what happened
+	// and the rotation cooldown can be enforced. Only a not-found lookup
+	// means there are no existing keys; any other failure stops the write.
+	existing, err := a.Store.GetKeyRecord(r.Context(), entityURN)
+	if err != nil && !errors.Is(err, keystore.ErrKeyNotFound) {
+		if errors.Is(err, context.Canceled) {
+			logger.Info("StoreKeys: Client closed request before keys were stored")
+			w.WriteHeader(StatusClientClosedRequest)
+			return
+		}
+		a.writeInternalError(w, logger, "StoreKeys: Failed to read existing keys", "Failed to read existing keys", err)
 		return
 	}
+	exists := err == nil
+	if exists && publicKeysEqual(existing.Keys, keysToStore) && metadataEqual(existing.Metadata, meta) {
+		// Nothing to write; leaving the record alone keeps its updatedAt honest.
+		if !existing.UpdatedAt.IsZero() {
+			w.Header().Set(ConsistencyTokenHeader, encodeConsistencyToken(existing.UpdatedAt))
+		}
+		w.WriteHeader(http.StatusOK)
+		logger.Info("StoreKeys: Public keys unchanged")
+		return
+	}
+	if exists && a.RotationCooldown > 0 && !existing.UpdatedAt.IsZero() {
+		if wait := existing.UpdatedAt.Add(a.RotationCooldown).Sub(a.now()); wait > 0 {
+			logger.Warn("StoreKeys: Rejected key change within rotation cooldown",
+				"updated_at", existing.UpdatedAt, "retry_after", wait)
+			writeRetryableError(w, http.StatusTooManyRequests, ErrCodeRotationTooFrequent,
+				"Keys were changed too recently; retry later", wait)
+			return
+		}
+	}
+
+	// 7b. Optionally queue the write and answer before it lands. A full
+	// queue falls back to writing now, as Prefer allows.
+	if a.AsyncWrites != nil && prefersAsync(r.Header.Get("Prefer")) {
+		owner, _ := middleware.GetUserIDFromContext(r.Context())
+		if writeID, ok := a.AsyncWrites.Enqueue(r.Context(), owner, entityURN, keysToStore, meta, logger); ok {
+			w.Header().Set("Preference-Applied", "respond-async")
+			w.Header().Set("Location", a.BasePath+"/writes/"+writeID)
+			response.WriteJSON(w, http.StatusAccepted, asyncWriteResponse{
+				ID:     writeID,
+				URN:    entityURN.String(),
+				Status: AsyncWritePending,
+			})
+			logger.Info("StoreKeys: Queued public keys for an async write", "write_id", writeID)
+			return
+		}
+		logger.Warn("StoreKeys: Async write queue full; storing synchronously")
+	}
+
+	// 8. Store: Use the store method. The stores stamp the record with
+	// their own clock, so it is no earlier than this.
+	writtenAfter := time.Now()
+	if err := a.Store.StorePublicKeysWithMetadata(r.Context(), entityURN, keysToStore, meta); err != nil {
+		if errors.Is(err, context.Canceled) {
+			// Not a server fault: the client disconnected mid-write.
+			logger.Info("StoreKeys: Client closed request during store", "err", err)
+			w.WriteHeader(StatusClientClosedRequest)
+			return
+		}
+		if errors.Is(err, budget.ErrExceeded) {
+			logger.Warn("StoreKeys: Rejected write over the storage budget")
+			writeJSONErrorWithCode(w, http.StatusInsufficientStorage, ErrCodeStorageBudgetExceeded,
+				"The key store is full")
+			return
+		}
+		a.writeInternalError(w, logger, "StoreKeys: Failed to store public keys", "Failed to store public keys", err)
+		return
+	}
+	w.Header().Set(ConsistencyTokenHeader, encodeConsistencyToken(writtenAfter))
 
+	if exists {
+		w.WriteHeader(http.StatusOK)
+		logger.Info("StoreKeys: Successfully replaced public keys")
+		return
+	}
 	w.WriteHeader(http.StatusCreated)
 	logger.Info("StoreKeys: Successfully stored public keys")
 }
 
+// decodeStoreRequest decodes and validates the keys in the request body
+// for a write to entityURN. On failure it writes the response and returns
+// false.
+func (a *API) decodeStoreRequest(w http.ResponseWriter, r *http.Request, entityURN urn.URN, logger *slog.Logger) (keys.PublicKeys, keystore.Metadata, bool) {
+	// 3c. Policy: Optionally hold entity IDs to a fixed shape.
+	if a.EntityIDPattern != nil && !a.EntityIDPattern.MatchString(entityURN.EntityID()) {
+		logger.Warn("StoreKeys: Rejected entity ID not matching the configured pattern",
+			"entity_id", entityURN.EntityID(), "pattern", a.EntityIDPattern.String())
+		writeJSONErrorWithCode(w, http.StatusBadRequest, ErrCodeEntityIDInvalid,
+			"Entity ID does not match the required pattern")
+		return keys.PublicKeys{}, keystore.Metadata{}, false
+	}
+
+	// 4. Body: Decode the keys along with any declared algorithms, or
+	// optionally take them as key files from a multipart form.
+	var req storeKeysRequest
+	if a.AcceptMultipart && isMultipartForm(r) {
+		var err error
+		if req, err = decodeMultipartKeys(r, a.maxPartBytes()); err != nil {
+			if errors.Is(err, errPartTooLarge) {
+				logger.Warn("StoreKeys: Rejected oversized multipart part", "err", err)
+				response.WriteJSONError(w, http.StatusRequestEntityTooLarge, err.Error())
+				return keys.PublicKeys{}, keystore.Metadata{}, false
+			}
+			logger.Warn("StoreKeys: Failed to read multipart body", "err", err)
+			response.WriteJSONError(w, http.StatusBadRequest, "Invalid multipart body: "+err.Error())
+			return keys.PublicKeys{}, keystore.Metadata{}, false
+		}
+	} else if err := decodeJSONWithMaxDepth(r.Body, &req, a.MaxJSONDepth); err != nil {
+		if errors.Is(err, errJSONTooDeep) {
+			logger.Warn("StoreKeys: Rejected over-nested JSON body", "err", err)
+			response.WriteJSONError(w, http.StatusBadRequest, "JSON body is nested too deeply")
+			return keys.PublicKeys{}, keystore.Metadata{}, false
+		}
+		logger.Warn("StoreKeys: Failed to unmarshal JSON body", "err", err)
+		response.WriteJSONError(w, http.StatusBadRequest, "Invalid JSON body format")
+		return keys.PublicKeys{}, keystore.Metadata{}, false
+	}
+	keysToStore := req.Keys
+
+	// 5. Validate that we have the keys this entity type's policy requires.
+	policy := a.keyPolicy(entityURN.EntityType())
+	if err := policy.CheckRequiredKeys(keysToStore); err != nil {
+		logger.Warn("StoreKeys: Store request missing a required key", "err", err,
+			"entity_type", entityURN.EntityType())
+		response.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return keys.PublicKeys{}, keystore.Metadata{}, false
+	}
+
+	// 5b. The same bytes for both keys is almost certainly a client bug.
+	if !a.AllowIdenticalKeys && len(keysToStore.EncKey) > 0 && bytes.Equal(keysToStore.EncKey, keysToStore.SigKey) {
+		logger.Warn("StoreKeys: Rejected identical encKey and sigKey")
+		writeJSONErrorWithCode(w, http.StatusBadRequest, ErrCodeKeysIdentical, "encKey and sigKey must differ")
+		return keys.PublicKeys{}, keystore.Metadata{}, false
+	}
+
+	// 6. Validate the keys against their declared algorithms.
+	if err := keystore.ValidateKeyAlgorithms(policy.ValidationMode, req.EncAlg, req.SigAlg, keysToStore); err != nil {
+		logger.Warn("StoreKeys: Key algorithm validation failed", "err", err, "mode", policy.ValidationMode)
+		response.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return keys.PublicKeys{}, keystore.Metadata{}, false
+	}
+	// 6b. A rotation hint names a future time; allow for client clock drift.
+	if !req.RotationHint.IsZero() {
+		skew := a.MaxClockSkew
+		if skew <= 0 {
+			skew = DefaultMaxClockSkew
+		}
+		if earliest := a.now().Add(-skew); req.RotationHint.Before(earliest) {
+			logger.Warn("StoreKeys: Rejected rotation hint in the past",
+				"rotation_hint", req.RotationHint, "max_clock_skew", skew)
+			response.WriteJSONError(w, http.StatusBadRequest, "rotationHint must not be in the past")
+			return keys.PublicKeys{}, keystore.Metadata{}, false
+		}
+	}
+	// 6c. If the owner bound encKey to sigKey, the binding must hold.
+	if len(req.EncKeySignature) > 0 {
+		if err := verifyEncKeySignature(keysToStore, req.EncKeySignature); err != nil {
+			logger.Warn("StoreKeys: Rejected invalid key binding", "err", err)
+			writeJSONErrorWithCode(w, http.StatusBadRequest, ErrCodeInvalidKeyBinding, err.Error())
+			return keys.PublicKeys{}, keystore.Metadata{}, false
+		}
+	}
+	if req.EncAlg == "" || req.SigAlg == "" {
+		logger.Debug("StoreKeys: Inferred undeclared key algorithms",
+			"enc_algs", keystore.InferAlgorithms(len(keysToStore.EncKey)),
+			"sig_algs", keystore.InferAlgorithms(len(keysToStore.SigKey)))
+	}
+
+	// 6d. Usage restrictions, when accepted, must leave the keys usable.
+	var usage keystore.UsageRestrictions
+	if req.UsageRestrictions != nil {
+		if !a.AcceptUsageRestrictions {
+			logger.Warn("StoreKeys: Rejected usage restrictions while they are disabled")
+			writeJSONErrorWithCode(w, http.StatusBadRequest, ErrCodeInvalidUsageRestrictions,
+				"usageRestrictions are not accepted by this deployment")
+			return keys.PublicKeys{}, keystore.Metadata{}, false
+		}
+		usage = *req.UsageRestrictions
+		if err := usage.Validate(keysToStore, a.now()); err != nil {
+			logger.Warn("StoreKeys: Rejected invalid usage restrictions", "err", err)
+			writeJSONErrorWithCode(w, http.StatusBadRequest, ErrCodeInvalidUsageRestrictions, err.Error())
+			return keys.PublicKeys{}, keystore.Metadata{}, false
+		}
+	}
+
+	// 6e. Optionally refuse keys for entities the identity service doesn't know.
+	if a.Entities != nil {
+		known, err := a.Entities.Exists(r.Context(), entityURN.EntityID())
+		if err != nil {
+			logger.Error("StoreKeys: Failed to verify entity with identity service", "err", err)
+			writeRetryableError(w, http.StatusServiceUnavailable, ErrCodeIdentityServiceUnavailable,
+				"Unable to verify entity", a.retryAfter())
+			return keys.PublicKeys{}, keystore.Metadata{}, false
+		}
+		if !known {
+			logger.Warn("StoreKeys: Rejected keys for unknown entity")
+			writeJSONErrorWithCode(w, http.StatusBadRequest, ErrCodeUnknownEntity, "Entity is not known to the identity service")
+			return keys.PublicKeys{}, keystore.Metadata{}, false
+		}
+	}
+
+	return keysToStore, keystore.Metadata{
+		RotationHint:          req.RotationHint,
+		EncKeySignature:       req.EncKeySignature,
+		ProvisioningSignature: req.ProvisioningSignature,
+		UsageRestrictions:     usage,
+	}, true
+}
+
+// publicKeysEqual reports whether two key sets hold the same bytes.
+func publicKeysEqual(a, b keys.PublicKeys) bool {
+	return bytes.Equal(a.EncKey, b.EncKey) && bytes.Equal(a.SigKey, b.SigKey)
+}
+
+// metadataEqual reports whether two metadata values would store the same record.
+func metadataEqual(a, b keystore.Metadata) bool {
+	return a.RotationHint.Equal(b.RotationHint) &&
+		bytes.Equal(a.EncKeySignature, b.EncKeySignature) &&
+		bytes.Equal(a.ProvisioningSignature, b.ProvisioningSignature) &&
+		a.UsageRestrictions.Equal(b.UsageRestrictions)
+}
+
 // GetKeysHandler handles the GET /keys/{entityURN} request.
-// It retrieves the public keys for a given entity and returns them as JSON.
+// It retrieves the public keys for a given entity and returns them as JSON,
+// along with the canonical form of the entity URN. A ?fields= list limits
+// the body to the named fields, and ?attest=true adds a signed attestation
+// of what was served and when. ?format=qr serves a PNG QR code of the
+// keys' fingerprint instead of JSON.
 func (a *API) GetKeysHandler(w http.ResponseWriter, r *http.Request) {
 	// 1. Path: Get the URN from the path.
 	entityURNStr := r.PathValue("entityURN")
@@ -95,19 +487,165 @@ func (a *API) GetKeysHandler(w http.ResponseWriter, r *http.Request) {
 
 	logger := a.Logger.With("entity_urn", entityURN.String())
 
-	// 2. Store: Use the store method to retrieve the Go struct
-	retrievedKeys, err := a.Store.GetPublicKeys(r.Context(), entityURN)
+	// 1b. Query: Optionally limit the body to a subset of its fields.
+	fields, err := parseFields(r.URL.Query().Get("fields"))
 	if err != nil {
-		logger.Warn("GetKeys: Key not found", "err", err)
-		response.WriteJSONError(w, http.StatusNotFound, "Key not found")
+		logger.Warn("GetKeys: Invalid fields parameter", "err", err)
+		response.WriteJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	attest := false
+	if raw := r.URL.Query().Get("attest"); raw != "" {
+		if attest, err = strconv.ParseBool(raw); err != nil {
+			logger.Warn("GetKeys: Invalid attest parameter", "raw_attest", raw)
+			response.WriteJSONError(w, http.StatusBadRequest, "attest must be true or false")
+			return
+		}
+	}
+	if attest && a.Attester == nil {
+		logger.Warn("GetKeys: Attestation requested but not enabled")
+		response.WriteJSONError(w, http.StatusBadRequest, "Attestation is not enabled")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", FormatJSON:
+	case FormatQR:
+		if fields != nil || attest {
+			logger.Warn("GetKeys: QR code requested with JSON-only options")
+			response.WriteJSONError(w, http.StatusBadRequest, "fields and attest cannot be combined with format=qr")
+			return
+		}
+	default:
+		logger.Warn("GetKeys: Invalid format parameter", "raw_format", format)
+		response.WriteJSONError(w, http.StatusBadRequest, "format must be json or qr")
+		return
+	}
+
+	// 1c. Header: A consistency token from an earlier write asks for that
+	// version of the keys or a newer one.
+	var minUpdatedAt time.Time
+	if token := r.Header.Get(ConsistencyTokenHeader); token != "" {
+		if minUpdatedAt, err = parseConsistencyToken(token); err != nil {
+			logger.Warn("GetKeys: Invalid consistency token", "err", err)
+			response.WriteJSONError(w, http.StatusBadRequest, "Invalid "+ConsistencyTokenHeader+" header")
+			return
+		}
+	}
+
+	// 2. Store: Use the store method to retrieve the keys and their metadata,
+	// waiting briefly for a lagging store to reach the requested version.
+	record, err := a.Store.GetKeyRecord(r.Context(), entityURN)
+	if !minUpdatedAt.IsZero() && (err != nil || !caughtUp(record, minUpdatedAt)) {
+		record, err = a.awaitConsistency(r.Context(), entityURN, minUpdatedAt)
+		if errors.Is(err, errNotYetConsistent) {
+			logger.Warn("GetKeys: Store did not reach the requested version in time", "min_updated_at", minUpdatedAt)
+			writeJSONErrorWithCode(w, http.StatusConflict, ErrCodeNotYetConsistent,
+				"Keys at least as new as the consistency token are not available yet")
+			return
+		}
+	}
+	if err != nil {
+		if errors.Is(err, keystore.ErrKeyNotFound) {
+			logger.Warn("GetKeys: Key not found", "err", err)
+			response.WriteJSONError(w, http.StatusNotFound, "Key not found")
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			logger.Info("GetKeys: Client closed request during lookup", "err", err)
+			w.WriteHeader(StatusClientClosedRequest)
+			return
+		}
+		a.writeInternalError(w, logger, "GetKeys: Failed to get public keys", "Failed to get public keys", err)
+		return
+	}
+
+	// 2a. Trust: In strict mode, only serve keys a trusted provisioner
+	// vouched for. Unsigned keys are withheld as if absent; keys whose
+	// signature does not verify are a conflict worth surfacing.
+	if a.RequireProvisioningSignature {
+		if len(record.Metadata.ProvisioningSignature) == 0 {
+			logger.Warn("GetKeys: Withholding keys without a provisioning signature")
+			response.WriteJSONError(w, http.StatusNotFound, "Key not found")
+			return
+		}
+		if !verifyProvisioningSignature(a.ProvisionerKeys, entityURN, record.Keys, record.Metadata.ProvisioningSignature) {
+			logger.Warn("GetKeys: Withholding keys with an untrusted provisioning signature")
+			writeJSONErrorWithCode(w, http.StatusConflict, ErrCodeUntrustedProvisioning,
+				"Keys are not signed by a trusted provisioner")
+			return
+		}
+	}
 
-	// 3. Respond: Encode the native struct directly using standard json.
+	// 2b. Attest: Optionally sign a statement of exactly what is served.
+	var attestation *Attestation
+	if attest {
+		att, err := a.Attester.Attest(entityURN, record.Keys, a.now())
+		if err != nil {
+			a.writeInternalError(w, logger, "GetKeys: Failed to sign attestation", "Failed to sign attestation", err)
+			return
+		}
+		attestation = &att
+	}
+
+	// 3. Respond: Encode the keys alongside the canonical URN.
+	if !record.Metadata.RotationHint.IsZero() {
+		w.Header().Set(RotationHintHeader, record.Metadata.RotationHint.UTC().Format(time.RFC3339))
+	}
+	if !record.UpdatedAt.IsZero() {
+		w.Header().Set(ConsistencyTokenHeader, encodeConsistencyToken(record.UpdatedAt))
+	}
+	if record.Compromised {
+		logger.Warn("GetKeys: Serving keys flagged as compromised")
+		w.Header().Set("Warning", CompromisedWarning)
+	}
+	if format == FormatQR {
+		png, err := keysQRCode(entityURN, record.Keys)
+		if err != nil {
+			a.writeInternalError(w, logger, "GetKeys: Failed to render QR code", "Failed to render QR code", err)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		if _, err := w.Write(png); err != nil {
+			logger.Warn("GetKeys: Failed to write response", "err", err)
+			return
+		}
+		logger.Info("GetKeys: Successfully rendered public keys as a QR code")
+		return
+	}
+	body, err := json.Marshal(getKeysResponse{
+		Keys:                  record.Keys,
+		URN:                   entityURN,
+		EncKeySignature:       record.Metadata.EncKeySignature,
+		ProvisioningSignature: record.Metadata.ProvisioningSignature,
+		UsageRestrictions:     optionalUsageRestrictions(record.Metadata.UsageRestrictions),
+		Epoch:                 record.Epoch,
+		UpdatedAt:             record.UpdatedAt,
+		Compromised:           record.Compromised,
+		Attestation:           attestation,
+		Fields:                fields,
+		Canonical:             a.CanonicalResponses,
+	})
+	if err != nil {
+		a.writeInternalError(w, logger, "GetKeys: Failed to marshal keys to JSON", "Failed to serialize response", err)
+		return
+	}
+
+	// 3a. Canonical bodies are tagged, so clients and caches can revalidate.
+	if a.CanonicalResponses {
+		etag := bodyETag(body)
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			logger.Info("GetKeys: Public keys not modified")
+			return
+		}
+	} else {
+		body = append(body, '\n')
+	}
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(retrievedKeys); err != nil {
-		logger.Error("GetKeys: Failed to marshal keys to JSON", "err", err)
-		http.Error(w, "Failed to serialize response", http.StatusInternalServerError)
+	if _, err := w.Write(body); err != nil {
+		logger.Warn("GetKeys: Failed to write response", "err", err)
 		return
 	}
 
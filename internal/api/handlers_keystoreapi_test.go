@@ -2,25 +2,41 @@
 package api_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/budget"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
 	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
 	"github.com/tinywideclouds/go-microservice-base/pkg/response"
 
 	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
 	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // MockStore is a mock implementation of the keyservice.Store interface.
@@ -44,6 +60,114 @@ func (m *MockStore) GetPublicKeys(ctx context.Context, entityURN urn.URN) (keys.
 	return args.Get(0).(keys.PublicKeys), args.Error(1)
 }
 
+// StorePublicKeysWithMetadata is the mock implementation for storing keys with metadata.
+func (m *MockStore) StorePublicKeysWithMetadata(ctx context.Context, entityURN urn.URN, keys keys.PublicKeys, meta keystore.Metadata) error {
+	args := m.Called(ctx, entityURN, keys, meta)
+	return args.Error(0)
+}
+
+// GetOrCreatePublicKeys is the mock implementation for a create-if-absent store.
+func (m *MockStore) GetOrCreatePublicKeys(ctx context.Context, entityURN urn.URN, candidate keys.PublicKeys) (keys.PublicKeys, bool, error) {
+	args := m.Called(ctx, entityURN, candidate)
+	return args.Get(0).(keys.PublicKeys), args.Bool(1), args.Error(2)
+}
+
+// SwapPublicKeys is the mock implementation for an atomic key swap.
+func (m *MockStore) SwapPublicKeys(ctx context.Context, entityURN urn.URN, newKeys keys.PublicKeys) (keys.PublicKeys, error) {
+	args := m.Called(ctx, entityURN, newKeys)
+	return args.Get(0).(keys.PublicKeys), args.Error(1)
+}
+
+// GetKeyRecord is the mock implementation for retrieving keys with their metadata.
+func (m *MockStore) GetKeyRecord(ctx context.Context, entityURN urn.URN) (keystore.KeyRecord, error) {
+	args := m.Called(ctx, entityURN)
+	return args.Get(0).(keystore.KeyRecord), args.Error(1)
+}
+
+// GetPublicKeysIfModifiedSince is the mock implementation for a conditional get.
+func (m *MockStore) GetPublicKeysIfModifiedSince(ctx context.Context, entityURN urn.URN, since time.Time) (keys.PublicKeys, bool, error) {
+	args := m.Called(ctx, entityURN, since)
+	return args.Get(0).(keys.PublicKeys), args.Bool(1), args.Error(2)
+}
+
+// DeleteOlderThan is the mock implementation for an age-based cleanup.
+func (m *MockStore) DeleteOlderThan(ctx context.Context, age time.Duration) (int, error) {
+	args := m.Called(ctx, age)
+	return args.Int(0), args.Error(1)
+}
+
+// ListEntitiesMissingSigKey is the mock implementation for the missing-sigKey scan.
+func (m *MockStore) ListEntitiesMissingSigKey(ctx context.Context) ([]urn.URN, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]urn.URN), args.Error(1)
+}
+
+// GetFingerprintsBatch is the mock implementation for a batch fingerprint read.
+func (m *MockStore) GetFingerprintsBatch(ctx context.Context, entityURNs []urn.URN) (map[string]string, error) {
+	args := m.Called(ctx, entityURNs)
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
+// GetPublicKeysBatch is the mock implementation for a batch key read.
+func (m *MockStore) GetPublicKeysBatch(ctx context.Context, entityURNs []urn.URN) (map[string]keystore.KeyRecord, error) {
+	args := m.Called(ctx, entityURNs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]keystore.KeyRecord), args.Error(1)
+}
+
+// DiffPublicKeys is the mock implementation for comparing two entities' keys.
+func (m *MockStore) DiffPublicKeys(ctx context.Context, a, b urn.URN) (keystore.KeyDiff, error) {
+	args := m.Called(ctx, a, b)
+	return args.Get(0).(keystore.KeyDiff), args.Error(1)
+}
+
+// BumpEpoch is the mock implementation for advancing an entity's epoch.
+func (m *MockStore) BumpEpoch(ctx context.Context, entityURN urn.URN) (uint64, error) {
+	args := m.Called(ctx, entityURN)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+// MarkCompromised is the mock implementation for flagging keys as compromised.
+func (m *MockStore) MarkCompromised(ctx context.Context, entityURN urn.URN, reason string) error {
+	args := m.Called(ctx, entityURN, reason)
+	return args.Error(0)
+}
+
+// ClearCompromised is the mock implementation for clearing the compromised flag.
+func (m *MockStore) ClearCompromised(ctx context.Context, entityURN urn.URN) error {
+	args := m.Called(ctx, entityURN)
+	return args.Error(0)
+}
+
+// DeletePublicKeys is the mock implementation for deleting an entity's keys.
+func (m *MockStore) DeletePublicKeys(ctx context.Context, entityURN urn.URN) (bool, error) {
+	args := m.Called(ctx, entityURN)
+	return args.Bool(0), args.Error(1)
+}
+
+// Stats is the mock implementation for summarising the store.
+func (m *MockStore) Stats(ctx context.Context) (keystore.StoreStats, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(keystore.StoreStats), args.Error(1)
+}
+
+// IterateKeyRecords is the mock implementation for iterating every stored record.
+func (m *MockStore) IterateKeyRecords(ctx context.Context, fn func(entityURN urn.URN, record keystore.KeyRecord) error) error {
+	args := m.Called(ctx, fn)
+	return args.Error(0)
+}
+
+// IterateAll is the mock implementation for iterating every stored entity.
+func (m *MockStore) IterateAll(ctx context.Context, fn func(entityURN urn.URN, keys keys.PublicKeys) error) error {
+	args := m.Called(ctx, fn)
+	return args.Error(0)
+}
+
 // newTestLogger creates a discard logger for tests.
 func newTestLogger() *slog.Logger {
 	return slog.New(slog.NewTextHandler(io.Discard, nil))
@@ -67,8 +191,10 @@ func TestStoreKeysHandler(t *testing.T) {
 	t.Run("Success - 201 Created", func(t *testing.T) {
 		// Arrange
 		mockStore := new(MockStore)
+		// No keys exist yet, so this is a genuine create
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{}, keystore.ErrKeyNotFound)
 		// We assert that the store is called with the *native* Go struct
-		mockStore.On("StorePublicKeys", mock.Anything, userURN, mockKeys).Return(nil)
+		mockStore.On("StorePublicKeysWithMetadata", mock.Anything, userURN, mockKeys, keystore.Metadata{}).Return(nil)
 
 		apiHandler := &api.API{Store: mockStore, Logger: logger}
 		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(mockBodyJSON))
@@ -85,6 +211,96 @@ func TestStoreKeysHandler(t *testing.T) {
 		mockStore.AssertExpectations(t)
 	})
 
+	t.Run("Success - 200 OK (Unchanged Re-store)", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{Keys: mockKeys}, nil)
+
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(mockBodyJSON))
+		req.SetPathValue("entityURN", userURN.String())
+		ctx := middleware.ContextWithUserID(context.Background(), authedUserID)
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.StoreKeysHandler(rr, req.WithContext(ctx))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+		// Identical keys must not be rewritten
+		mockStore.AssertNotCalled(t, "StorePublicKeysWithMetadata")
+	})
+
+	t.Run("Success - 200 OK (Changed Keys)", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		oldKeys := keys.PublicKeys{EncKey: []byte{9, 9, 9}, SigKey: []byte{4, 5, 6}}
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{Keys: oldKeys}, nil)
+		mockStore.On("StorePublicKeysWithMetadata", mock.Anything, userURN, mockKeys, keystore.Metadata{}).Return(nil)
+
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(mockBodyJSON))
+		req.SetPathValue("entityURN", userURN.String())
+		ctx := middleware.ContextWithUserID(context.Background(), authedUserID)
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.StoreKeysHandler(rr, req.WithContext(ctx))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("Success - 200 OK (Rotation Hint Changed)", func(t *testing.T) {
+		// Arrange
+		hint := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+		mockStore := new(MockStore)
+		// Same keys, but the hint is new, so the record must be rewritten
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{Keys: mockKeys}, nil)
+		mockStore.On("StorePublicKeysWithMetadata", mock.Anything, userURN, mockKeys,
+			mock.MatchedBy(func(meta keystore.Metadata) bool { return meta.RotationHint.Equal(hint) })).Return(nil)
+
+		body := `{"encKey":"AQID","sigKey":"BAUG","rotationHint":"2030-01-02T03:04:05Z"}`
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(body))
+		req.SetPathValue("entityURN", userURN.String())
+		ctx := middleware.ContextWithUserID(context.Background(), authedUserID)
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.StoreKeysHandler(rr, req.WithContext(ctx))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("Success - 200 OK (Provisioning Signature Stored)", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{Keys: mockKeys}, nil)
+		mockStore.On("StorePublicKeysWithMetadata", mock.Anything, userURN, mockKeys,
+			mock.MatchedBy(func(meta keystore.Metadata) bool {
+				return bytes.Equal(meta.ProvisioningSignature, []byte{7, 8, 9})
+			})).Return(nil)
+
+		body := `{"encKey":"AQID","sigKey":"BAUG","provisioningSignature":"BwgJ"}`
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(body))
+		req.SetPathValue("entityURN", userURN.String())
+		ctx := middleware.ContextWithUserID(context.Background(), authedUserID)
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.StoreKeysHandler(rr, req.WithContext(ctx))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
 	t.Run("Failure - 401 Unauthorized (No Context)", func(t *testing.T) {
 		// Arrange
 		mockStore := new(MockStore) // No calls expected
@@ -98,7 +314,7 @@ func TestStoreKeysHandler(t *testing.T) {
 
 		// Assert
 		assert.Equal(t, http.StatusUnauthorized, rr.Code)
-		mockStore.AssertNotCalled(t, "StorePublicKeys")
+		mockStore.AssertNotCalled(t, "StorePublicKeysWithMetadata")
 	})
 
 	t.Run("Failure - 403 Forbidden (Mismatch User)", func(t *testing.T) {
@@ -116,7 +332,7 @@ func TestStoreKeysHandler(t *testing.T) {
 
 		// Assert
 		assert.Equal(t, http.StatusForbidden, rr.Code)
-		mockStore.AssertNotCalled(t, "StorePublicKeys")
+		mockStore.AssertNotCalled(t, "StorePublicKeysWithMetadata")
 	})
 
 	t.Run("Failure - 400 Bad JSON", func(t *testing.T) {
@@ -132,7 +348,7 @@ func TestStoreKeysHandler(t *testing.T) {
 
 		// Assert
 		assert.Equal(t, http.StatusBadRequest, rr.Code)
-		mockStore.AssertNotCalled(t, "StorePublicKeys")
+		mockStore.AssertNotCalled(t, "StorePublicKeysWithMetadata")
 	})
 
 	t.Run("Failure - 400 Missing Keys", func(t *testing.T) {
@@ -151,64 +367,1328 @@ func TestStoreKeysHandler(t *testing.T) {
 		// Assert
 		assert.Equal(t, http.StatusBadRequest, rr.Code)
 		assert.Contains(t, rr.Body.String(), "encKey and sigKey must not be empty")
-		mockStore.AssertNotCalled(t, "StorePublicKeys")
+		mockStore.AssertNotCalled(t, "StorePublicKeysWithMetadata")
+	})
+
+	t.Run("Failure - 503 BACKEND_OVERLOADED with Retry-After", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{}, keystore.ErrKeyNotFound)
+		mockStore.On("StorePublicKeysWithMetadata", mock.Anything, userURN, mockKeys, keystore.Metadata{}).
+			Return(status.Error(codes.ResourceExhausted, "quota exceeded"))
+		apiHandler := &api.API{Store: mockStore, Logger: logger, RetryAfter: 90 * time.Second}
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(mockBodyJSON))
+		req.SetPathValue("entityURN", userURN.String())
+		ctx := middleware.ContextWithUserID(context.Background(), authedUserID)
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.StoreKeysHandler(rr, req.WithContext(ctx))
+
+		// Assert
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		var errResp api.CodedAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, api.ErrCodeBackendOverloaded, errResp.Code)
+		assert.Equal(t, "90", rr.Header().Get("Retry-After"))
+	})
+
+	t.Run("Failure - 500 existing keys cannot be read", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{}, errors.New("backend down"))
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(mockBodyJSON))
+		req.SetPathValue("entityURN", userURN.String())
+		ctx := middleware.ContextWithUserID(context.Background(), authedUserID)
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.StoreKeysHandler(rr, req.WithContext(ctx))
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockStore.AssertNotCalled(t, "StorePublicKeysWithMetadata")
 	})
 }
 
-func TestGetKeysHandler(t *testing.T) {
+func TestStoreKeysHandler_KeyValidationMode(t *testing.T) {
 	logger := newTestLogger()
-	userURN, err := urn.New(urn.SecureMessaging, "user", "test-user-v2")
+	authedUserID := "authorized-user"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
 	require.NoError(t, err)
 
-	// This is the native Go struct we'll return from the mock store
-	mockKeys := keys.PublicKeys{
+	// 32-byte keys match X25519 (encryption) and Ed25519 (signing).
+	validKeys := keys.PublicKeys{
+		EncKey: bytes.Repeat([]byte{1}, 32),
+		SigKey: bytes.Repeat([]byte{2}, 32),
+	}
+	shortKeys := keys.PublicKeys{
 		EncKey: []byte{1, 2, 3},
 		SigKey: []byte{4, 5, 6},
 	}
 
-	// This is the JSON we expect our handler to produce
-	expectedJSON := `{"encKey":"AQID","sigKey":"BAUG"}`
+	testCases := []struct {
+		name           string
+		mode           keystore.ValidationMode
+		keys           keys.PublicKeys
+		encAlg         string
+		sigAlg         string
+		expectedStatus int
+	}{
+		{"Strict - matching declaration", keystore.ValidationStrict, validKeys, "X25519", "Ed25519", http.StatusCreated},
+		{"Strict - mismatching declaration", keystore.ValidationStrict, validKeys, "P-256", "Ed25519", http.StatusBadRequest},
+		{"Strict - missing declaration", keystore.ValidationStrict, validKeys, "", "", http.StatusBadRequest},
+		{"Lenient - matching declaration", keystore.ValidationLenient, validKeys, "X25519", "Ed25519", http.StatusCreated},
+		{"Lenient - mismatching declaration", keystore.ValidationLenient, validKeys, "X25519", "RSA-2048", http.StatusBadRequest},
+		{"Lenient - unknown algorithm", keystore.ValidationLenient, validKeys, "ROT13", "Ed25519", http.StatusBadRequest},
+		{"Lenient - no declaration", keystore.ValidationLenient, shortKeys, "", "", http.StatusCreated},
+		{"Default mode - no declaration", "", shortKeys, "", "", http.StatusCreated},
+		{"Off - mismatching declaration", keystore.ValidationOff, shortKeys, "P-256", "RSA-4096", http.StatusCreated},
+	}
 
-	t.Run("Success - 200 OK", func(t *testing.T) {
-		// Arrange
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockStore := new(MockStore)
+			mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{}, keystore.ErrKeyNotFound).Maybe()
+			mockStore.On("StorePublicKeysWithMetadata", mock.Anything, userURN, tc.keys, keystore.Metadata{}).Return(nil).Maybe()
+
+			body, err := json.Marshal(map[string]any{
+				"encKey": tc.keys.EncKey,
+				"sigKey": tc.keys.SigKey,
+				"encAlg": tc.encAlg,
+				"sigAlg": tc.sigAlg,
+			})
+			require.NoError(t, err)
+
+			apiHandler := &api.API{Store: mockStore, Logger: logger, KeyValidationMode: tc.mode}
+			req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), bytes.NewReader(body))
+			req.SetPathValue("entityURN", userURN.String())
+			ctx := middleware.ContextWithUserID(context.Background(), authedUserID)
+			rr := httptest.NewRecorder()
+
+			// Act
+			apiHandler.StoreKeysHandler(rr, req.WithContext(ctx))
+
+			// Assert
+			assert.Equal(t, tc.expectedStatus, rr.Code, rr.Body.String())
+			if tc.expectedStatus == http.StatusCreated {
+				mockStore.AssertExpectations(t)
+			} else {
+				mockStore.AssertNotCalled(t, "StorePublicKeysWithMetadata")
+			}
+		})
+	}
+}
+
+func TestStoreKeysHandler_EntityTypePolicies(t *testing.T) {
+	logger := newTestLogger()
+	authedUserID := "authorized-entity"
+	encKey := bytes.Repeat([]byte{1}, 32)
+	sigKey := bytes.Repeat([]byte{2}, 32)
+	policies := map[string]keystore.KeyPolicy{
+		"device":  {},
+		"service": {EncKeyOptional: true, ValidationMode: keystore.ValidationStrict},
+	}
+
+	testCases := []struct {
+		name           string
+		entityType     string
+		body           map[string]any
+		expectedStatus int
+	}{
+		{"Device - both keys", "device", map[string]any{"encKey": encKey, "sigKey": sigKey}, http.StatusCreated},
+		{"Device - missing encKey", "device", map[string]any{"sigKey": sigKey}, http.StatusBadRequest},
+		{"Service - signing key only", "service", map[string]any{"sigKey": sigKey, "sigAlg": "Ed25519"}, http.StatusCreated},
+		{"Service - strict mode needs a declaration", "service", map[string]any{"sigKey": sigKey}, http.StatusBadRequest},
+		{"Service - no keys at all", "service", map[string]any{}, http.StatusBadRequest},
+		{"Unknown type - default policy needs both keys", "user", map[string]any{"sigKey": sigKey}, http.StatusBadRequest},
+		{"Unknown type - default policy uses service-wide mode", "user", map[string]any{"encKey": encKey, "sigKey": sigKey}, http.StatusCreated},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			entityURN, err := urn.New(urn.SecureMessaging, tc.entityType, authedUserID)
+			require.NoError(t, err)
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			apiHandler := &api.API{
+				Store:              inmemory.New(),
+				Logger:             logger,
+				KeyValidationMode:  keystore.ValidationLenient,
+				EntityTypePolicies: policies,
+			}
+			req := httptest.NewRequest(http.MethodPost, "/keys/"+entityURN.String(), bytes.NewReader(body))
+			req.SetPathValue("entityURN", entityURN.String())
+			rr := httptest.NewRecorder()
+
+			// Act
+			apiHandler.StoreKeysHandler(rr, req.WithContext(middleware.ContextWithUserID(context.Background(), authedUserID)))
+
+			// Assert
+			assert.Equal(t, tc.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestStoreKeysHandler_MaxJSONDepth(t *testing.T) {
+	logger := newTestLogger()
+	authedUserID := "authorized-user"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"Normal payload accepted", `{"encKey":"AQID","sigKey":"BAUG"}`, http.StatusCreated},
+		{"Nested to the limit accepted", `{"encKey":"AQID","sigKey":"BAUG","x":[[[1]]]}`, http.StatusCreated},
+		{"Deeply nested payload rejected", `{"encKey":"AQID","sigKey":"BAUG","x":` + strings.Repeat("[", 1000) + strings.Repeat("]", 1000) + `}`, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockStore := new(MockStore)
+			mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{}, keystore.ErrKeyNotFound).Maybe()
+			mockStore.On("StorePublicKeysWithMetadata", mock.Anything, userURN, mock.Anything, keystore.Metadata{}).Return(nil).Maybe()
+
+			apiHandler := &api.API{Store: mockStore, Logger: logger}
+			req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(tc.body))
+			req.SetPathValue("entityURN", userURN.String())
+			ctx := middleware.ContextWithUserID(context.Background(), authedUserID)
+			rr := httptest.NewRecorder()
+
+			// Act
+			apiHandler.StoreKeysHandler(rr, req.WithContext(ctx))
+
+			// Assert
+			assert.Equal(t, tc.expectedStatus, rr.Code, rr.Body.String())
+			if tc.expectedStatus != http.StatusCreated {
+				mockStore.AssertNotCalled(t, "StorePublicKeysWithMetadata")
+			}
+		})
+	}
+}
+
+func TestStoreKeysHandler_ClientCancelled(t *testing.T) {
+	authedUserID := "authorized-user"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
+	require.NoError(t, err)
+	mockBodyJSON := `{"encKey":"AQID","sigKey":"BAUG"}`
+
+	// Arrange
+	var logBuf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	ctx, cancel := context.WithCancel(middleware.ContextWithUserID(context.Background(), authedUserID))
+	defer cancel()
+
+	mockStore := new(MockStore)
+	mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{}, keystore.ErrKeyNotFound)
+	// The client disconnects while the write is in flight.
+	mockStore.On("StorePublicKeysWithMetadata", mock.Anything, userURN, mock.Anything, keystore.Metadata{}).
+		Run(func(args mock.Arguments) { cancel() }).
+		Return(&keystore.StoreError{Op: keystore.OpStorePublicKeysWithMetadata, URN: userURN, Err: context.Canceled})
+
+	apiHandler := &api.API{Store: mockStore, Logger: logger}
+	req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(mockBodyJSON))
+	req.SetPathValue("entityURN", userURN.String())
+	rr := httptest.NewRecorder()
+
+	// Act
+	apiHandler.StoreKeysHandler(rr, req.WithContext(ctx))
+
+	// Assert
+	assert.Equal(t, api.StatusClientClosedRequest, rr.Code)
+	assert.NotContains(t, logBuf.String(), `"level":"ERROR"`)
+	mockStore.AssertExpectations(t)
+}
+
+func TestStoreKeysHandler_StorageBudgetExceeded(t *testing.T) {
+	authedUserID := "authorized-user"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
+	require.NoError(t, err)
+	mockBodyJSON := `{"encKey":"AQID","sigKey":"BAUG"}`
+
+	// Arrange
+	mockStore := new(MockStore)
+	mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{}, keystore.ErrKeyNotFound)
+	mockStore.On("StorePublicKeysWithMetadata", mock.Anything, userURN, mock.Anything, keystore.Metadata{}).
+		Return(&keystore.StoreError{Op: keystore.OpStorePublicKeysWithMetadata, URN: userURN, Err: budget.ErrExceeded})
+
+	apiHandler := &api.API{Store: mockStore, Logger: newTestLogger()}
+	req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(mockBodyJSON))
+	req.SetPathValue("entityURN", userURN.String())
+	ctx := middleware.ContextWithUserID(req.Context(), authedUserID)
+	rr := httptest.NewRecorder()
+
+	// Act
+	apiHandler.StoreKeysHandler(rr, req.WithContext(ctx))
+
+	// Assert
+	assert.Equal(t, http.StatusInsufficientStorage, rr.Code)
+	assert.Contains(t, rr.Body.String(), api.ErrCodeStorageBudgetExceeded)
+	assert.Empty(t, rr.Header().Get(api.ConsistencyTokenHeader))
+	mockStore.AssertExpectations(t)
+}
+
+func TestStoreKeysHandler_ErrorDetailVerbosity(t *testing.T) {
+	authedUserID := "authorized-user"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
+	require.NoError(t, err)
+	mockBodyJSON := `{"encKey":"AQID","sigKey":"BAUG"}`
+	const detail = "rpc error: code = Unavailable desc = firestore backend down"
+
+	// storeFailing runs a store that fails with detail and returns the
+	// response body and the one ERROR log record.
+	storeFailing := func(t *testing.T, fullErrorDetail bool) (api.InternalAPIError, map[string]any) {
+		t.Helper()
 		mockStore := new(MockStore)
-		mockStore.On("GetPublicKeys", mock.Anything, userURN).Return(mockKeys, nil)
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{}, keystore.ErrKeyNotFound)
+		mockStore.On("StorePublicKeysWithMetadata", mock.Anything, userURN, mock.Anything, keystore.Metadata{}).
+			Return(&keystore.StoreError{Op: keystore.OpStorePublicKeysWithMetadata, URN: userURN, Err: errors.New(detail)})
 
-		apiHandler := &api.API{Store: mockStore, Logger: logger}
-		req := httptest.NewRequest(http.MethodGet, "/keys/"+userURN.String(), nil)
+		var logBuf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&logBuf, nil))
+		apiHandler := &api.API{Store: mockStore, Logger: logger, FullErrorDetail: fullErrorDetail}
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(mockBodyJSON))
 		req.SetPathValue("entityURN", userURN.String())
+		ctx := middleware.ContextWithUserID(req.Context(), authedUserID)
 		rr := httptest.NewRecorder()
 
+		apiHandler.StoreKeysHandler(rr, req.WithContext(ctx))
+
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		var body api.InternalAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		var errorRecords []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(logBuf.String()), "\n") {
+			var record map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &record))
+			if record["level"] == "ERROR" {
+				errorRecords = append(errorRecords, record)
+			}
+		}
+		require.Len(t, errorRecords, 1)
+		return body, errorRecords[0]
+	}
+
+	t.Run("Success - minimal hides the detail behind a correlation ID", func(t *testing.T) {
 		// Act
-		apiHandler.GetKeysHandler(rr, req)
+		body, record := storeFailing(t, false)
 
 		// Assert
-		assert.Equal(t, http.StatusOK, rr.Code)
-		assert.JSONEq(t, expectedJSON, rr.Body.String())
-		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
-		mockStore.AssertExpectations(t)
+		assert.Equal(t, "Failed to store public keys", body.Error)
+		assert.NotEmpty(t, body.CorrelationID)
+		assert.Equal(t, body.CorrelationID, record["correlation_id"])
+		assert.Contains(t, record["err"], detail)
 	})
 
-	t.Run("Failure - 404 Not Found", func(t *testing.T) {
+	t.Run("Success - full returns the detail", func(t *testing.T) {
+		// Act
+		body, record := storeFailing(t, true)
+
+		// Assert
+		assert.Contains(t, body.Error, "Failed to store public keys")
+		assert.Contains(t, body.Error, detail)
+		assert.NotEmpty(t, body.CorrelationID)
+		assert.Equal(t, body.CorrelationID, record["correlation_id"])
+		assert.Contains(t, record["err"], detail)
+	})
+}
+
+// authzDecisions returns the authorization decision records in a JSON log.
+func authzDecisions(t *testing.T, logs *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		if record["msg"] == api.AuthzDecisionMessage {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func TestStoreKeysHandler_LogAuthzDecisions(t *testing.T) {
+	authedUserID := "authorized-user"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
+	require.NoError(t, err)
+	mockBodyJSON := `{"encKey":"AQID","sigKey":"BAUG"}`
+
+	t.Run("Success - allowed store logs an allow decision", func(t *testing.T) {
 		// Arrange
-		mockStore := new(MockStore)
-		// We must return the zero-value for keys.PublicKeys
-		mockStore.On("GetPublicKeys", mock.Anything, userURN).Return(keys.PublicKeys{}, errors.New("not found"))
+		var logBuf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&logBuf, nil))
+		apiHandler := &api.API{Store: inmemory.New(), Logger: logger, LogAuthzDecisions: true}
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(mockBodyJSON))
+		req.SetPathValue("entityURN", userURN.String())
+		ctx := middleware.ContextWithUserID(req.Context(), authedUserID)
+		rr := httptest.NewRecorder()
 
-		apiHandler := &api.API{Store: mockStore, Logger: logger}
-		req := httptest.NewRequest(http.MethodGet, "/keys/"+userURN.String(), nil)
+		// Act
+		apiHandler.StoreKeysHandler(rr, req.WithContext(ctx))
+
+		// Assert
+		require.Equal(t, http.StatusCreated, rr.Code)
+		decisions := authzDecisions(t, &logBuf)
+		require.Len(t, decisions, 1)
+		assert.Equal(t, "INFO", decisions[0]["level"])
+		assert.Equal(t, "allow", decisions[0]["decision"])
+		assert.Equal(t, authedUserID, decisions[0]["authed_user"])
+		assert.Equal(t, userURN.String(), decisions[0]["target_entity"])
+		assert.Equal(t, api.AuthzReasonOwnEntity, decisions[0]["reason"])
+	})
+
+	t.Run("Success - mismatched user logs a deny decision with the reason", func(t *testing.T) {
+		// Arrange
+		var logBuf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&logBuf, nil))
+		apiHandler := &api.API{Store: inmemory.New(), Logger: logger, LogAuthzDecisions: true}
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(mockBodyJSON))
 		req.SetPathValue("entityURN", userURN.String())
+		ctx := middleware.ContextWithUserID(req.Context(), "someone-else")
 		rr := httptest.NewRecorder()
 
 		// Act
-		apiHandler.GetKeysHandler(rr, req)
+		apiHandler.StoreKeysHandler(rr, req.WithContext(ctx))
 
 		// Assert
-		assert.Equal(t, http.StatusNotFound, rr.Code)
-		var errResp response.APIError
-		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
+		require.Equal(t, http.StatusForbidden, rr.Code)
+		decisions := authzDecisions(t, &logBuf)
+		require.Len(t, decisions, 1)
+		assert.Equal(t, "WARN", decisions[0]["level"])
+		assert.Equal(t, "deny", decisions[0]["decision"])
+		assert.Equal(t, "someone-else", decisions[0]["authed_user"])
+		assert.Equal(t, userURN.String(), decisions[0]["target_entity"])
+		assert.Equal(t, api.AuthzReasonEntityMismatch, decisions[0]["reason"])
+	})
+
+	t.Run("Success - nothing is logged when disabled", func(t *testing.T) {
+		// Arrange
+		var logBuf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&logBuf, nil))
+		apiHandler := &api.API{Store: inmemory.New(), Logger: logger}
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(mockBodyJSON))
+		req.SetPathValue("entityURN", userURN.String())
+		ctx := middleware.ContextWithUserID(req.Context(), authedUserID)
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.StoreKeysHandler(rr, req.WithContext(ctx))
+
+		// Assert
+		require.Equal(t, http.StatusCreated, rr.Code)
+		assert.Empty(t, authzDecisions(t, &logBuf))
+	})
+}
+
+func TestStoreKeysHandler_IdenticalKeys(t *testing.T) {
+	logger := newTestLogger()
+	authedUserID := "authorized-user"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
+	require.NoError(t, err)
+	identicalKeys := keys.PublicKeys{
+		EncKey: []byte{1, 2, 3},
+		SigKey: []byte{1, 2, 3},
+	}
+	identicalBodyJSON := `{"encKey":"AQID","sigKey":"AQID"}`
+
+	newRequest := func(body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(body))
+		req.SetPathValue("entityURN", userURN.String())
+		return req.WithContext(middleware.ContextWithUserID(context.Background(), authedUserID))
+	}
+
+	t.Run("Failure - 400 KEYS_IDENTICAL", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.StoreKeysHandler(rr, newRequest(identicalBodyJSON))
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var errResp api.CodedAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, api.ErrCodeKeysIdentical, errResp.Code)
+		mockStore.AssertNotCalled(t, "StorePublicKeysWithMetadata")
+	})
+
+	t.Run("Success - 201 distinct keys", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{}, keystore.ErrKeyNotFound)
+		mockStore.On("StorePublicKeysWithMetadata", mock.Anything, userURN, mock.Anything, keystore.Metadata{}).Return(nil)
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.StoreKeysHandler(rr, newRequest(`{"encKey":"AQID","sigKey":"BAUG"}`))
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("Success - 201 identical keys when allowed", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{}, keystore.ErrKeyNotFound)
+		mockStore.On("StorePublicKeysWithMetadata", mock.Anything, userURN, identicalKeys, keystore.Metadata{}).Return(nil)
+		apiHandler := &api.API{Store: mockStore, Logger: logger, AllowIdenticalKeys: true}
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.StoreKeysHandler(rr, newRequest(identicalBodyJSON))
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+}
+
+func TestStoreKeysHandler_RotationCooldown(t *testing.T) {
+	logger := newTestLogger()
+	authedUserID := "authorized-user"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
+	require.NoError(t, err)
+	const cooldown = time.Hour
+
+	store := func(apiHandler *api.API, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(body))
+		req.SetPathValue("entityURN", userURN.String())
+		rr := httptest.NewRecorder()
+		apiHandler.StoreKeysHandler(rr, req.WithContext(middleware.ContextWithUserID(context.Background(), authedUserID)))
+		return rr
+	}
+
+	t.Run("Failure - 429 second store within cooldown", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: inmemory.New(), Logger: logger, RotationCooldown: cooldown}
+		require.Equal(t, http.StatusCreated, store(apiHandler, `{"encKey":"AQID","sigKey":"BAUG"}`).Code)
+		apiHandler.Now = func() time.Time { return time.Now().Add(10 * time.Minute) }
+
+		// Act
+		rr := store(apiHandler, `{"encKey":"BwgJ","sigKey":"CgsM"}`)
+
+		// Assert
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		var errResp api.CodedAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, api.ErrCodeRotationTooFrequent, errResp.Code)
+		retryAfter, err := strconv.Atoi(rr.Header().Get("Retry-After"))
+		require.NoError(t, err)
+		assert.InDelta(t, (50 * time.Minute).Seconds(), retryAfter, 5)
+	})
+
+	t.Run("Success - 200 second store after cooldown", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: inmemory.New(), Logger: logger, RotationCooldown: cooldown}
+		require.Equal(t, http.StatusCreated, store(apiHandler, `{"encKey":"AQID","sigKey":"BAUG"}`).Code)
+		apiHandler.Now = func() time.Time { return time.Now().Add(cooldown + time.Minute) }
+
+		// Act
+		rr := store(apiHandler, `{"encKey":"BwgJ","sigKey":"CgsM"}`)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Success - 200 unchanged re-store within cooldown", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: inmemory.New(), Logger: logger, RotationCooldown: cooldown}
+		require.Equal(t, http.StatusCreated, store(apiHandler, `{"encKey":"AQID","sigKey":"BAUG"}`).Code)
+
+		// Act
+		rr := store(apiHandler, `{"encKey":"AQID","sigKey":"BAUG"}`)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
+
+func TestStoreKeysHandler_EntityIDPattern(t *testing.T) {
+	logger := newTestLogger()
+	pattern := regexp.MustCompile(`^(?:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+
+	store := func(apiHandler *api.API, entityID string) *httptest.ResponseRecorder {
+		entityURN, err := urn.New(urn.SecureMessaging, "user", entityID)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+entityURN.String(), strings.NewReader(`{"encKey":"AQID","sigKey":"BAUG"}`))
+		req.SetPathValue("entityURN", entityURN.String())
+		rr := httptest.NewRecorder()
+		apiHandler.StoreKeysHandler(rr, req.WithContext(middleware.ContextWithUserID(context.Background(), entityID)))
+		return rr
+	}
+
+	t.Run("Success - 201 conforming entity ID", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: inmemory.New(), Logger: logger, EntityIDPattern: pattern}
+
+		// Act
+		rr := store(apiHandler, "3f2b8c1e-4d5a-4e6f-9a7b-0c1d2e3f4a5b")
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("Failure - 400 non-conforming entity ID", func(t *testing.T) {
+		// Arrange
+		backend := inmemory.New()
+		apiHandler := &api.API{Store: backend, Logger: logger, EntityIDPattern: pattern}
+
+		// Act
+		rr := store(apiHandler, "3F2B8C1E-4D5A-4E6F-9A7B-0C1D2E3F4A5B")
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var errResp api.CodedAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, api.ErrCodeEntityIDInvalid, errResp.Code)
+		count := 0
+		require.NoError(t, backend.IterateKeyRecords(context.Background(), func(urn.URN, keystore.KeyRecord) error {
+			count++
+			return nil
+		}))
+		assert.Zero(t, count, "nothing may be stored")
+	})
+}
+
+func TestStoreKeysHandler_RotationHintClockSkew(t *testing.T) {
+	logger := newTestLogger()
+	authedUserID := "authorized-user"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
+	require.NoError(t, err)
+	now := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	const skew = time.Minute
+
+	testCases := []struct {
+		name     string
+		hint     time.Time
+		wantCode int
+	}{
+		{"Success - 201 hint in the future", now.Add(time.Hour), http.StatusCreated},
+		{"Success - 201 hint exactly at the skew boundary", now.Add(-skew), http.StatusCreated},
+		{"Failure - 400 hint just beyond the skew", now.Add(-skew - time.Second), http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockStore := new(MockStore)
+			mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{}, keystore.ErrKeyNotFound).Maybe()
+			mockStore.On("StorePublicKeysWithMetadata", mock.Anything, userURN, mock.Anything, mock.Anything).Return(nil).Maybe()
+			apiHandler := &api.API{
+				Store:        mockStore,
+				Logger:       logger,
+				MaxClockSkew: skew,
+				Now:          func() time.Time { return now },
+			}
+			body := `{"encKey":"AQID","sigKey":"BAUG","rotationHint":"` + tc.hint.Format(time.RFC3339) + `"}`
+			req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(body))
+			req.SetPathValue("entityURN", userURN.String())
+			rr := httptest.NewRecorder()
+
+			// Act
+			apiHandler.StoreKeysHandler(rr, req.WithContext(middleware.ContextWithUserID(context.Background(), authedUserID)))
+
+			// Assert
+			assert.Equal(t, tc.wantCode, rr.Code)
+			if tc.wantCode == http.StatusBadRequest {
+				mockStore.AssertNotCalled(t, "StorePublicKeysWithMetadata")
+			}
+		})
+	}
+}
+
+func TestStoreKeysHandler_UsageRestrictions(t *testing.T) {
+	authedUserID := "authorized-user"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
+	require.NoError(t, err)
+	now := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	store := func(apiHandler *api.API, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(body))
+		req.SetPathValue("entityURN", userURN.String())
+		rr := httptest.NewRecorder()
+		apiHandler.StoreKeysHandler(rr, req.WithContext(middleware.ContextWithUserID(context.Background(), authedUserID)))
+		return rr
+	}
+	newAPI := func(accept bool) *api.API {
+		return &api.API{
+			Store:                   inmemory.New(),
+			Logger:                  newTestLogger(),
+			AcceptUsageRestrictions: accept,
+			Now:                     func() time.Time { return now },
+		}
+	}
+
+	t.Run("Success - restrictions round-trip through GET", func(t *testing.T) {
+		// Arrange
+		apiHandler := newAPI(true)
+		restrictions := `{"usage":"encrypt-only","notBefore":"2030-01-01T00:00:00Z","notAfter":"2031-01-01T00:00:00Z"}`
+
+		// Act
+		rr := store(apiHandler, `{"encKey":"AQID","sigKey":"BAUG","usageRestrictions":`+restrictions+`}`)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		req := httptest.NewRequest(http.MethodGet, "/keys/"+userURN.String(), nil)
+		req.SetPathValue("entityURN", userURN.String())
+		getRR := httptest.NewRecorder()
+		apiHandler.GetKeysHandler(getRR, req)
+
+		// Assert
+		require.Equal(t, http.StatusOK, getRR.Code)
+		var body struct {
+			UsageRestrictions json.RawMessage `json:"usageRestrictions"`
+		}
+		require.NoError(t, json.Unmarshal(getRR.Body.Bytes(), &body))
+		assert.JSONEq(t, restrictions, string(body.UsageRestrictions))
+	})
+
+	t.Run("Success - changing only the restrictions is a write", func(t *testing.T) {
+		// Arrange
+		apiHandler := newAPI(true)
+		require.Equal(t, http.StatusCreated, store(apiHandler, `{"encKey":"AQID","sigKey":"BAUG"}`).Code)
+
+		// Act
+		rr := store(apiHandler, `{"encKey":"AQID","sigKey":"BAUG","usageRestrictions":{"usage":"sign-only"}}`)
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		record, err := apiHandler.Store.GetKeyRecord(context.Background(), userURN)
+		require.NoError(t, err)
+		assert.Equal(t, keystore.KeyUsageSignOnly, record.Metadata.UsageRestrictions.Usage)
+	})
+
+	testCases := []struct {
+		name         string
+		accept       bool
+		restrictions string
+	}{
+		{"Failure - 400 notAfter in the past", true, `{"notAfter":"2030-01-01T00:00:00Z"}`},
+		{"Failure - 400 notBefore after notAfter", true, `{"notBefore":"2031-06-01T00:00:00Z","notAfter":"2031-01-01T00:00:00Z"}`},
+		{"Failure - 400 unknown usage", true, `{"usage":"wrap-only"}`},
+		{"Failure - 400 restrictions not accepted", false, `{"usage":"encrypt-only"}`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			apiHandler := newAPI(tc.accept)
+
+			// Act
+			rr := store(apiHandler, `{"encKey":"AQID","sigKey":"BAUG","usageRestrictions":`+tc.restrictions+`}`)
+
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			var body api.CodedAPIError
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, api.ErrCodeInvalidUsageRestrictions, body.Code)
+			_, err := apiHandler.Store.GetKeyRecord(context.Background(), userURN)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestStoreKeysHandler_EncKeySignature(t *testing.T) {
+	logger := newTestLogger()
+	authedUserID := "authorized-user"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
+	require.NoError(t, err)
+
+	sigPub, sigPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	encKey := make([]byte, 32)
+	_, err = rand.Read(encKey)
+	require.NoError(t, err)
+	validSig := ed25519.Sign(sigPriv, encKey)
+
+	ecdsaPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecdsaPub, err := ecdsaPriv.PublicKey.ECDH()
+	require.NoError(t, err)
+	encDigest := sha256.Sum256(encKey)
+	ecdsaSig, err := ecdsa.SignASN1(rand.Reader, ecdsaPriv, encDigest[:])
+	require.NoError(t, err)
+
+	postKeys := func(apiHandler *api.API, sigKey, sig []byte) *httptest.ResponseRecorder {
+		body, err := json.Marshal(map[string][]byte{"encKey": encKey, "sigKey": sigKey, "encKeySignature": sig})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), bytes.NewReader(body))
+		req.SetPathValue("entityURN", userURN.String())
+		rr := httptest.NewRecorder()
+		apiHandler.StoreKeysHandler(rr, req.WithContext(middleware.ContextWithUserID(context.Background(), authedUserID)))
+		return rr
+	}
+
+	t.Run("Success - 201 valid Ed25519 binding is stored and returned", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: inmemory.New(), Logger: logger}
+
+		// Act
+		rr := postKeys(apiHandler, sigPub, validSig)
+
+		// Assert
+		require.Equal(t, http.StatusCreated, rr.Code)
+		req := httptest.NewRequest(http.MethodGet, "/keys/"+userURN.String(), nil)
+		req.SetPathValue("entityURN", userURN.String())
+		getRR := httptest.NewRecorder()
+		apiHandler.GetKeysHandler(getRR, req)
+		require.Equal(t, http.StatusOK, getRR.Code)
+		var body struct {
+			EncKeySignature []byte `json:"encKeySignature"`
+		}
+		require.NoError(t, json.Unmarshal(getRR.Body.Bytes(), &body))
+		assert.Equal(t, validSig, body.EncKeySignature)
+	})
+
+	t.Run("Success - 201 valid P-256 binding", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: inmemory.New(), Logger: logger}
+
+		// Act
+		rr := postKeys(apiHandler, ecdsaPub.Bytes(), ecdsaSig)
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("Failure - 400 INVALID_KEY_BINDING for a bad signature", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+		tampered := bytes.Clone(validSig)
+		tampered[0] ^= 0xff
+
+		// Act
+		rr := postKeys(apiHandler, sigPub, tampered)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var errResp api.CodedAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, api.ErrCodeInvalidKeyBinding, errResp.Code)
+		mockStore.AssertNotCalled(t, "StorePublicKeysWithMetadata")
+	})
+}
+
+func TestStoreKeysHandler_Base64Variants(t *testing.T) {
+	logger := newTestLogger()
+	authedUserID := "authorized-user"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
+	require.NoError(t, err)
+
+	// These bytes encode with '+', '/' and padding in standard base64.
+	encKey := []byte{0xfb, 0xff, 0xbf, 0x01}
+	sigKey := []byte{0xfe, 0xef, 0xfb, 0x02, 0x03}
+	signature := []byte{0xff, 0xfe, 0x7f}
+
+	testCases := []struct {
+		name     string
+		encoding *base64.Encoding
+	}{
+		{"Success - 201 standard padded", base64.StdEncoding},
+		{"Success - 201 standard unpadded", base64.RawStdEncoding},
+		{"Success - 201 URL-safe padded", base64.URLEncoding},
+		{"Success - 201 URL-safe unpadded", base64.RawURLEncoding},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			store := inmemory.New()
+			apiHandler := &api.API{Store: store, Logger: logger}
+			body, err := json.Marshal(map[string]string{
+				"encKey":                tc.encoding.EncodeToString(encKey),
+				"sigKey":                tc.encoding.EncodeToString(sigKey),
+				"provisioningSignature": tc.encoding.EncodeToString(signature),
+			})
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), bytes.NewReader(body))
+			req.SetPathValue("entityURN", userURN.String())
+			rr := httptest.NewRecorder()
+
+			// Act
+			apiHandler.StoreKeysHandler(rr, req.WithContext(middleware.ContextWithUserID(context.Background(), authedUserID)))
+
+			// Assert
+			require.Equal(t, http.StatusCreated, rr.Code)
+			record, err := store.GetKeyRecord(context.Background(), userURN)
+			require.NoError(t, err)
+			assert.Equal(t, encKey, record.Keys.EncKey)
+			assert.Equal(t, sigKey, record.Keys.SigKey)
+			assert.Equal(t, signature, record.Metadata.ProvisioningSignature)
+		})
+	}
+
+	t.Run("Failure - 400 invalid base64 signature", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: inmemory.New(), Logger: logger}
+		body := `{"encKey":"AQID","sigKey":"BAUG","provisioningSignature":"not*base64"}`
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(body))
+		req.SetPathValue("entityURN", userURN.String())
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.StoreKeysHandler(rr, req.WithContext(middleware.ContextWithUserID(context.Background(), authedUserID)))
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestStoreKeysHandler_SelfStoreDenied(t *testing.T) {
+	logger := newTestLogger()
+	orgURN, err := urn.New(urn.SecureMessaging, "org", "acme")
+	require.NoError(t, err)
+	mockKeys := keys.PublicKeys{
+		EncKey: []byte{1, 2, 3},
+		SigKey: []byte{4, 5, 6},
+	}
+	mockBodyJSON := `{"encKey":"AQID","sigKey":"BAUG"}`
+
+	newAPI := func(store *MockStore) *api.API {
+		return &api.API{
+			Store:                store,
+			Logger:               logger,
+			SelfStoreDeniedTypes: []string{"org"},
+			AdminUserIDs:         []string{"admin-user"},
+		}
+	}
+
+	t.Run("Failure - 403 SELF_STORE_FORBIDDEN via user route", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+orgURN.String(), strings.NewReader(mockBodyJSON))
+		req.SetPathValue("entityURN", orgURN.String())
+		// The entity ID matches, so only the type restriction applies
+		ctx := middleware.ContextWithUserID(context.Background(), "acme")
+		rr := httptest.NewRecorder()
+
+		// Act
+		newAPI(mockStore).StoreKeysHandler(rr, req.WithContext(ctx))
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		var errResp api.CodedAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, api.ErrCodeSelfStoreForbidden, errResp.Code)
+		mockStore.AssertNotCalled(t, "StorePublicKeysWithMetadata")
+	})
+
+	t.Run("Success - 201 admin provisioning", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("GetKeyRecord", mock.Anything, orgURN).Return(keystore.KeyRecord{}, keystore.ErrKeyNotFound)
+		mockStore.On("StorePublicKeysWithMetadata", mock.Anything, orgURN, mockKeys, keystore.Metadata{}).Return(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/admin/keys/"+orgURN.String(), strings.NewReader(mockBodyJSON))
+		req.SetPathValue("entityURN", orgURN.String())
+		ctx := middleware.ContextWithUserID(context.Background(), "admin-user")
+		rr := httptest.NewRecorder()
+
+		// Act
+		newAPI(mockStore).AdminStoreKeysHandler(rr, req.WithContext(ctx))
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("Failure - 403 admin route for non-admin", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		req := httptest.NewRequest(http.MethodPost, "/admin/keys/"+orgURN.String(), strings.NewReader(mockBodyJSON))
+		req.SetPathValue("entityURN", orgURN.String())
+		ctx := middleware.ContextWithUserID(context.Background(), "acme")
+		rr := httptest.NewRecorder()
+
+		// Act
+		newAPI(mockStore).AdminStoreKeysHandler(rr, req.WithContext(ctx))
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		mockStore.AssertNotCalled(t, "StorePublicKeysWithMetadata")
+	})
+}
+
+func TestGetKeysHandler(t *testing.T) {
+	logger := newTestLogger()
+	userURN, err := urn.New(urn.SecureMessaging, "user", "test-user-v2")
+	require.NoError(t, err)
+
+	// This is the native Go struct we'll return from the mock store
+	mockKeys := keys.PublicKeys{
+		EncKey: []byte{1, 2, 3},
+		SigKey: []byte{4, 5, 6},
+	}
+
+	// This is the JSON we expect our handler to produce
+	expectedJSON := `{"urn":"urn:sm:user:test-user-v2","encKey":"AQID","sigKey":"BAUG"}`
+
+	t.Run("Success - 200 OK", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{Keys: mockKeys}, nil)
+
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+		req := httptest.NewRequest(http.MethodGet, "/keys/"+userURN.String(), nil)
+		req.SetPathValue("entityURN", userURN.String())
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.GetKeysHandler(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, expectedJSON, rr.Body.String())
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		assert.Empty(t, rr.Header().Get(api.RotationHintHeader))
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("Success - 200 OK (Epoch)", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{Keys: mockKeys, Epoch: 7}, nil)
+
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+		req := httptest.NewRequest(http.MethodGet, "/keys/"+userURN.String(), nil)
+		req.SetPathValue("entityURN", userURN.String())
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.GetKeysHandler(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"urn":"urn:sm:user:test-user-v2","encKey":"AQID","sigKey":"BAUG","epoch":7}`, rr.Body.String())
+	})
+
+	t.Run("Success - 200 OK (Sparse Fieldsets)", func(t *testing.T) {
+		updatedAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+		testCases := []struct {
+			name         string
+			fields       string
+			expectedJSON string
+		}{
+			{"Subset", "encKey,updatedAt", `{"encKey":"AQID","updatedAt":"2030-01-02T03:04:05Z"}`},
+			{"Full set", "urn,encKey,sigKey,encKeySignature,epoch,updatedAt",
+				`{"urn":"urn:sm:user:test-user-v2","encKey":"AQID","sigKey":"BAUG","epoch":7,"updatedAt":"2030-01-02T03:04:05Z"}`},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				// Arrange
+				mockStore := new(MockStore)
+				mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{
+					Keys: mockKeys, Epoch: 7, UpdatedAt: updatedAt,
+				}, nil)
+
+				apiHandler := &api.API{Store: mockStore, Logger: logger}
+				req := httptest.NewRequest(http.MethodGet, "/keys/"+userURN.String()+"?fields="+tc.fields, nil)
+				req.SetPathValue("entityURN", userURN.String())
+				rr := httptest.NewRecorder()
+
+				// Act
+				apiHandler.GetKeysHandler(rr, req)
+
+				// Assert
+				assert.Equal(t, http.StatusOK, rr.Code)
+				assert.JSONEq(t, tc.expectedJSON, rr.Body.String())
+			})
+		}
+	})
+
+	t.Run("Failure - 400 Unknown Field", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+		req := httptest.NewRequest(http.MethodGet, "/keys/"+userURN.String()+"?fields=encKey,privateKey", nil)
+		req.SetPathValue("entityURN", userURN.String())
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.GetKeysHandler(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "privateKey")
+		mockStore.AssertNotCalled(t, "GetKeyRecord")
+	})
+
+	t.Run("Success - 200 OK (Rotation Hint Header)", func(t *testing.T) {
+		// Arrange
+		hint := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+		mockStore := new(MockStore)
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{
+			Keys:     mockKeys,
+			Metadata: keystore.Metadata{RotationHint: hint},
+		}, nil)
+
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+		req := httptest.NewRequest(http.MethodGet, "/keys/"+userURN.String(), nil)
+		req.SetPathValue("entityURN", userURN.String())
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.GetKeysHandler(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "2030-01-02T03:04:05Z", rr.Header().Get(api.RotationHintHeader))
+		assert.JSONEq(t, expectedJSON, rr.Body.String())
+	})
+
+	t.Run("Success - 200 OK (Canonical URN for legacy ID)", func(t *testing.T) {
+		// Arrange
+		legacyID := "test-user-v2"
+		canonicalURN, err := urn.Parse(legacyID)
+		require.NoError(t, err)
+
+		mockStore := new(MockStore)
+		mockStore.On("GetKeyRecord", mock.Anything, canonicalURN).Return(keystore.KeyRecord{Keys: mockKeys}, nil)
+
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+		req := httptest.NewRequest(http.MethodGet, "/keys/"+legacyID, nil)
+		req.SetPathValue("entityURN", legacyID)
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.GetKeysHandler(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var body struct {
+			URN string `json:"urn"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, canonicalURN.String(), body.URN)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("Failure - 404 Not Found", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		// Stores wrap the sentinel, so the handler must match it with errors.Is.
+		notFound := fmt.Errorf("document %s: %w", userURN, keystore.ErrKeyNotFound)
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{}, notFound)
+
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+		req := httptest.NewRequest(http.MethodGet, "/keys/"+userURN.String(), nil)
+		req.SetPathValue("entityURN", userURN.String())
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.GetKeysHandler(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		var errResp response.APIError
+		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
 		require.NoError(t, err)
 		assert.Equal(t, "Key not found", errResp.Error)
 		mockStore.AssertExpectations(t)
 	})
+
+	t.Run("Failure - 500 store error", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{}, errors.New("backend down"))
+
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+		req := httptest.NewRequest(http.MethodGet, "/keys/"+userURN.String(), nil)
+		req.SetPathValue("entityURN", userURN.String())
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.GetKeysHandler(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		var errResp response.APIError
+		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
+		require.NoError(t, err)
+		assert.Equal(t, "Failed to get public keys", errResp.Error)
+		mockStore.AssertExpectations(t)
+	})
+}
+
+func TestGetKeysHandler_CanonicalResponses(t *testing.T) {
+	logger := newTestLogger()
+	userURN, err := urn.New(urn.SecureMessaging, "user", "canonical-user")
+	require.NoError(t, err)
+	record := keystore.KeyRecord{
+		Keys: keys.PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5, 6}},
+		Metadata: keystore.Metadata{
+			EncKeySignature:   []byte{7, 8, 9},
+			UsageRestrictions: keystore.UsageRestrictions{Usage: keystore.KeyUsageEncryptOnly},
+		},
+		Epoch:       3,
+		UpdatedAt:   time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+		Compromised: true,
+	}
+	mockStore := new(MockStore)
+	mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(record, nil)
+
+	get := func(canonical bool, query, ifNoneMatch string) *httptest.ResponseRecorder {
+		apiHandler := &api.API{Store: mockStore, Logger: logger, CanonicalResponses: canonical}
+		req := httptest.NewRequest(http.MethodGet, "/keys/"+userURN.String()+query, nil)
+		req.SetPathValue("entityURN", userURN.String())
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		apiHandler.GetKeysHandler(rr, req)
+		return rr
+	}
+
+	t.Run("Success - keys come first and metadata follows in a fixed order", func(t *testing.T) {
+		// Act
+		rr := get(true, "", "")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `{"encKey":"AQID","sigKey":"BAUG","urn":"urn:sm:user:canonical-user",`+
+			`"encKeySignature":"BwgJ","usageRestrictions":{"usage":"encrypt-only"},"epoch":3,`+
+			`"updatedAt":"2030-01-02T03:04:05Z","compromised":true}`, rr.Body.String())
+	})
+
+	t.Run("Success - repeated responses are identical byte for byte", func(t *testing.T) {
+		// Arrange
+		first := get(true, "", "")
+		require.Equal(t, http.StatusOK, first.Code)
+
+		for range 50 {
+			// Act
+			rr := get(true, "", "")
+
+			// Assert
+			require.Equal(t, first.Body.Bytes(), rr.Body.Bytes())
+			require.Equal(t, first.Header().Get("ETag"), rr.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("Success - field selection keeps the canonical order", func(t *testing.T) {
+		// Act
+		rr := get(true, "?fields=epoch,urn,sigKey", "")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `{"sigKey":"BAUG","urn":"urn:sm:user:canonical-user","epoch":3}`, rr.Body.String())
+	})
+
+	t.Run("Success - 304 when If-None-Match names the ETag", func(t *testing.T) {
+		// Arrange
+		etag := get(true, "", "").Header().Get("ETag")
+		require.NotEmpty(t, etag)
+
+		// Act
+		rr := get(true, "", `"stale", W/`+etag)
+
+		// Assert
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Empty(t, rr.Body.String())
+		assert.Equal(t, etag, rr.Header().Get("ETag"))
+	})
+
+	t.Run("Success - 200 when If-None-Match names another ETag", func(t *testing.T) {
+		// Act
+		rr := get(true, "", `"stale"`)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Success - disabled by default", func(t *testing.T) {
+		// Act
+		rr := get(false, "", "")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("ETag"))
+		assert.True(t, strings.HasPrefix(rr.Body.String(), `{"compromised":true,`), "fields stay in alphabetical order")
+	})
+}
+
+func TestGetKeysHandler_ConsistencyToken(t *testing.T) {
+	logger := newTestLogger()
+	authedUserID := "consistent-user"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
+	require.NoError(t, err)
+	apiHandler := &api.API{Store: inmemory.New(), Logger: logger, ConsistencyWait: 100 * time.Millisecond}
+
+	get := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/keys/"+userURN.String(), nil)
+		req.SetPathValue("entityURN", userURN.String())
+		req.Header.Set(api.ConsistencyTokenHeader, token)
+		rr := httptest.NewRecorder()
+		apiHandler.GetKeysHandler(rr, req)
+		return rr
+	}
+
+	// Arrange: a write returns the token for the version it stored.
+	req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(`{"encKey":"AQID","sigKey":"BAUG"}`))
+	req.SetPathValue("entityURN", userURN.String())
+	rr := httptest.NewRecorder()
+	apiHandler.StoreKeysHandler(rr, req.WithContext(middleware.ContextWithUserID(context.Background(), authedUserID)))
+	require.Equal(t, http.StatusCreated, rr.Code)
+	writeToken := rr.Header().Get(api.ConsistencyTokenHeader)
+	require.NotEmpty(t, writeToken)
+
+	t.Run("Success - 200 token from the write is satisfied", func(t *testing.T) {
+		// Act
+		rr := get(writeToken)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NotEmpty(t, rr.Header().Get(api.ConsistencyTokenHeader))
+	})
+
+	t.Run("Success - 200 token from a read is satisfied", func(t *testing.T) {
+		// Arrange
+		readToken := get(writeToken).Header().Get(api.ConsistencyTokenHeader)
+
+		// Act
+		rr := get(readToken)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Failure - 409 NOT_YET_CONSISTENT for a version the store never reaches", func(t *testing.T) {
+		// Arrange: a token from a later write on a store that has not seen it
+		laterAPI := &api.API{Store: inmemory.New(), Logger: logger}
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(`{"encKey":"AQID","sigKey":"BAUG"}`))
+		req.SetPathValue("entityURN", userURN.String())
+		rr := httptest.NewRecorder()
+		time.Sleep(time.Millisecond)
+		laterAPI.StoreKeysHandler(rr, req.WithContext(middleware.ContextWithUserID(context.Background(), authedUserID)))
+		laterToken := rr.Header().Get(api.ConsistencyTokenHeader)
+		start := time.Now()
+
+		// Act
+		rr = get(laterToken)
+
+		// Assert
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		var errResp api.CodedAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, api.ErrCodeNotYetConsistent, errResp.Code)
+		assert.Less(t, time.Since(start), time.Second, "must give up after ConsistencyWait")
+	})
+
+	t.Run("Failure - 400 malformed token", func(t *testing.T) {
+		// Act
+		rr := get("not a token")
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
 }
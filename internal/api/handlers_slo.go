@@ -0,0 +1,21 @@
+// --- File: internal/api/handlers_slo.go ---
+package api
+
+import (
+	"net/http"
+
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+)
+
+// GetSLOHandler handles the GET /admin/slo request.
+// It reports the rolling error-budget burn rate of each store operation class.
+func (a *API) GetSLOHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := a.requireAdmin(w, r, "GetSLO"); !ok {
+		return
+	}
+	if a.SLO == nil {
+		response.WriteJSONError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, map[string]any{"operations": a.SLO.Status()})
+}
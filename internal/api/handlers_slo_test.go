@@ -0,0 +1,60 @@
+// --- File: internal/api/handlers_slo_test.go ---
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/internal/storage/slo"
+	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+)
+
+func TestGetSLOHandler(t *testing.T) {
+	sloStore := slo.New(inmemory.New(), 0.99, 100)
+	apiHandler := &api.API{
+		Store:        sloStore,
+		Logger:       newTestLogger(),
+		AdminUserIDs: []string{"admin-user"},
+		SLO:          sloStore,
+	}
+
+	t.Run("Success - 200 OK for admin", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/admin/slo", nil)
+		ctx := middleware.ContextWithUserID(context.Background(), "admin-user")
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.GetSLOHandler(rr, req.WithContext(ctx))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var body struct {
+			Operations []slo.OperationStatus `json:"operations"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		require.Len(t, body.Operations, 2)
+		assert.Equal(t, slo.OpGet, body.Operations[0].Operation)
+		assert.Equal(t, 0.99, body.Operations[0].Objective)
+	})
+
+	t.Run("Failure - 403 Forbidden for non-admin", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/admin/slo", nil)
+		ctx := middleware.ContextWithUserID(context.Background(), "someone-else")
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.GetSLOHandler(rr, req.WithContext(ctx))
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
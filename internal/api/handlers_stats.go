@@ -0,0 +1,29 @@
+// --- File: internal/api/handlers_stats.go ---
+package api
+
+import (
+	"net/http"
+
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+)
+
+// storeStatsResponse is the body of a GET /admin/stats response.
+type storeStatsResponse struct {
+	Entities    int64 `json:"entities"`
+	ApproxBytes int64 `json:"approxBytes"`
+}
+
+// GetStatsHandler handles the GET /admin/stats request.
+// It reports how many entities are stored and roughly how much space
+// they take, for capacity planning.
+func (a *API) GetStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := a.requireAdmin(w, r, "GetStats"); !ok {
+		return
+	}
+	stats, err := a.Store.Stats(r.Context())
+	if err != nil {
+		a.writeInternalError(w, a.Logger, "GetStats: Failed to compute store stats", "Failed to compute store stats", err)
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, storeStatsResponse{Entities: stats.Entities, ApproxBytes: stats.ApproxBytes})
+}
@@ -0,0 +1,80 @@
+// --- File: internal/api/handlers_stats_test.go ---
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func TestGetStatsHandler(t *testing.T) {
+	get := func(apiHandler *api.API, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+		rr := httptest.NewRecorder()
+		apiHandler.GetStatsHandler(rr, req.WithContext(middleware.ContextWithUserID(context.Background(), userID)))
+		return rr
+	}
+
+	t.Run("Success - entity count matches the number stored", func(t *testing.T) {
+		// Arrange
+		store := inmemory.New()
+		for i := range 5 {
+			entityURN, err := urn.New(urn.SecureMessaging, "user", fmt.Sprintf("user-%d", i))
+			require.NoError(t, err)
+			require.NoError(t, store.StorePublicKeys(context.Background(), entityURN, keys.PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5, 6}}))
+		}
+		apiHandler := &api.API{Store: store, Logger: newTestLogger(), AdminUserIDs: []string{"admin-user"}}
+
+		// Act
+		rr := get(apiHandler, "admin-user")
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		var body struct {
+			Entities    int64 `json:"entities"`
+			ApproxBytes int64 `json:"approxBytes"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.EqualValues(t, 5, body.Entities)
+		assert.Positive(t, body.ApproxBytes)
+	})
+
+	t.Run("Failure - 500 store error", func(t *testing.T) {
+		// Arrange
+		mockStore := new(MockStore)
+		mockStore.On("Stats", mock.Anything).Return(keystore.StoreStats{}, errors.New("backend down"))
+		apiHandler := &api.API{Store: mockStore, Logger: newTestLogger(), AdminUserIDs: []string{"admin-user"}}
+
+		// Act
+		rr := get(apiHandler, "admin-user")
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+
+	t.Run("Failure - 403 Forbidden for non-admin", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: inmemory.New(), Logger: newTestLogger(), AdminUserIDs: []string{"admin-user"}}
+
+		// Act
+		rr := get(apiHandler, "someone-else")
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
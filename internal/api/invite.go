@@ -0,0 +1,103 @@
+// --- File: internal/api/invite.go ---
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// InviteTokenHeader carries the one-time invite token on POST /keys:redeemInvite.
+const InviteTokenHeader = "X-Invite-Token"
+
+// InvitePurpose is the purpose claim every invite token must carry, so
+// that no other token signed with the same secret can be redeemed.
+const InvitePurpose = "key_invite"
+
+var errInviteExpired = errors.New("invite token has expired")
+
+// invite is a verified invite token.
+type invite struct {
+	id        string
+	entityURN urn.URN
+	expiresAt time.Time
+}
+
+// InviteVerifier checks the one-time invite tokens the identity service
+// issues to newly provisioned users. A token is an HS256 JWT signed with
+// the shared JWT secret whose sub is the entity URN the keys are bound to,
+// with a jti, an exp and a purpose of InvitePurpose.
+//
+// Redeemed token IDs are remembered until the token expires, so a token
+// cannot be used twice against this replica. Across replicas the store's
+// create-if-absent write refuses a second registration for the entity.
+type InviteVerifier struct {
+	secret []byte
+	now    func() time.Time
+
+	mu       sync.Mutex
+	consumed map[string]time.Time
+}
+
+// NewInviteVerifier creates a verifier for tokens signed with secret.
+func NewInviteVerifier(secret string) *InviteVerifier {
+	return &InviteVerifier{
+		secret:   []byte(secret),
+		now:      time.Now,
+		consumed: make(map[string]time.Time),
+	}
+}
+
+// verify checks token's signature, expiry and claims. It returns
+// errInviteExpired for an otherwise valid token past its exp.
+func (v *InviteVerifier) verify(token string) (invite, error) {
+	parsed, err := jwt.Parse([]byte(token),
+		jwt.WithKey(jwa.HS256, v.secret),
+		jwt.WithValidate(true),
+		jwt.WithClock(jwt.ClockFunc(v.now)),
+		jwt.WithRequiredClaim(jwt.JwtIDKey),
+		jwt.WithRequiredClaim(jwt.ExpirationKey),
+		jwt.WithClaimValue("purpose", InvitePurpose),
+	)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired()) {
+			return invite{}, errInviteExpired
+		}
+		return invite{}, err
+	}
+	entityURN, err := urn.Parse(parsed.Subject())
+	if err != nil {
+		return invite{}, fmt.Errorf("invite subject is not an entity URN: %w", err)
+	}
+	return invite{id: parsed.JwtID(), entityURN: entityURN, expiresAt: parsed.Expiration()}, nil
+}
+
+// consume marks inv as redeemed. It reports false if it already was.
+func (v *InviteVerifier) consume(inv invite) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	now := v.now()
+	for id, expiresAt := range v.consumed {
+		if expiresAt.Before(now) {
+			delete(v.consumed, id)
+		}
+	}
+	if _, used := v.consumed[inv.id]; used {
+		return false
+	}
+	v.consumed[inv.id] = inv.expiresAt
+	return true
+}
+
+// release forgets that inv was redeemed, after its keys failed to store,
+// so the user can retry with the same token.
+func (v *InviteVerifier) release(inv invite) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.consumed, inv.id)
+}
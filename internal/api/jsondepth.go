@@ -0,0 +1,56 @@
+// --- File: internal/api/jsondepth.go ---
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxJSONDepth bounds nesting in request bodies when no limit is
+// configured. Key bodies are a single flat object, so this is deliberately low.
+const DefaultMaxJSONDepth = 4
+
+// errJSONTooDeep is returned by decodeJSONWithMaxDepth for over-nested input.
+var errJSONTooDeep = errors.New("JSON nesting exceeds maximum depth")
+
+// decodeJSONWithMaxDepth reads a JSON document from r and decodes it into v,
+// rejecting it before decoding if objects or arrays nest deeper than maxDepth.
+// A non-positive maxDepth selects DefaultMaxJSONDepth.
+func decodeJSONWithMaxDepth(r io.Reader, v any, maxDepth int) error {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxJSONDepth
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := checkJSONDepth(data, maxDepth); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// checkJSONDepth walks the token stream without building any values.
+// Syntax errors are left for the real decode to report.
+func checkJSONDepth(data []byte, maxDepth int) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if depth > maxDepth {
+				return fmt.Errorf("%w (%d)", errJSONTooDeep, maxDepth)
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
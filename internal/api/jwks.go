@@ -0,0 +1,185 @@
+// --- File: internal/api/jwks.go ---
+package api
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// DefaultJWKSCacheTTL is used when no cache TTL is configured.
+const DefaultJWKSCacheTTL = 5 * time.Minute
+
+// JWKSCache aggregates every stored signing key into a single JWK Set.
+// Building the set walks the whole store, so the result is cached and
+// only rebuilt once it is older than the TTL.
+type JWKSCache struct {
+	store  keystore.Store
+	ttl    time.Duration
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	set     jwk.Set
+	builtAt time.Time
+}
+
+// NewJWKSCache creates a cache over the given store. A non-positive ttl
+// selects DefaultJWKSCacheTTL.
+func NewJWKSCache(store keystore.Store, ttl time.Duration, logger *slog.Logger) *JWKSCache {
+	if ttl <= 0 {
+		ttl = DefaultJWKSCacheTTL
+	}
+	return &JWKSCache{
+		store:  store,
+		ttl:    ttl,
+		logger: logger.With("component", "jwks_cache"),
+		now:    time.Now,
+	}
+}
+
+// TTL returns how long a built set is served before it is rebuilt.
+func (c *JWKSCache) TTL() time.Duration {
+	return c.ttl
+}
+
+// Get returns the cached JWK Set, rebuilding it first if it has expired.
+// Concurrent callers wait for a single rebuild rather than each walking the store.
+func (c *JWKSCache) Get(ctx context.Context) (jwk.Set, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.set != nil && c.now().Sub(c.builtAt) < c.ttl {
+		return c.set, nil
+	}
+
+	set, err := c.build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.set = set
+	c.builtAt = c.now()
+	return set, nil
+}
+
+// build walks the store and converts each signing key into a JWK.
+// Keys in a format we cannot represent are skipped.
+func (c *JWKSCache) build(ctx context.Context) (jwk.Set, error) {
+	c.logger.Debug("Rebuilding aggregated JWKS")
+	set := jwk.NewSet()
+	err := c.store.IterateAll(ctx, func(entityURN urn.URN, pk keys.PublicKeys) error {
+		key, err := signingKeyToJWK(entityURN, pk.SigKey)
+		if err != nil {
+			c.logger.Debug("Skipping signing key that cannot be expressed as a JWK",
+				"entity_urn", entityURN.String(), "err", err)
+			return nil
+		}
+		return set.AddKey(key)
+	})
+	if err != nil {
+		c.logger.Error("Failed to build aggregated JWKS", "err", err)
+		return nil, err
+	}
+	c.logger.Debug("Aggregated JWKS rebuilt", "keys", set.Len())
+	return set, nil
+}
+
+// keyIDForURN derives the JWK "kid" for an entity's signing key.
+// The canonical URN is already unique, so it is used as-is.
+func keyIDForURN(entityURN urn.URN) string {
+	return entityURN.String()
+}
+
+// signingKeyToJWK converts an encoded signing key into a JWK. It accepts a
+// raw Ed25519 key, an uncompressed P-256 point, or any SPKI (DER) public key.
+func signingKeyToJWK(entityURN urn.URN, sigKey []byte) (jwk.Key, error) {
+	raw, err := parseSigningKey(sigKey)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := jwk.FromRaw(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWK: %w", err)
+	}
+	if err := key.Set(jwk.KeyIDKey, keyIDForURN(entityURN)); err != nil {
+		return nil, err
+	}
+	if err := key.Set(jwk.KeyUsageKey, jwk.ForSignature); err != nil {
+		return nil, err
+	}
+	switch pub := raw.(type) {
+	case ed25519.PublicKey:
+		err = key.Set(jwk.AlgorithmKey, jwa.EdDSA)
+	case *ecdsa.PublicKey:
+		if pub.Curve == elliptic.P256() {
+			err = key.Set(jwk.AlgorithmKey, jwa.ES256)
+		}
+	}
+	return key, err
+}
+
+func parseSigningKey(sigKey []byte) (any, error) {
+	switch {
+	case len(sigKey) == ed25519.PublicKeySize:
+		return ed25519.PublicKey(sigKey), nil
+	case len(sigKey) == 65 && sigKey[0] == 0x04:
+		// Validate the point before trusting the coordinates.
+		if _, err := ecdh.P256().NewPublicKey(sigKey); err != nil {
+			return nil, fmt.Errorf("invalid P-256 point: %w", err)
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(sigKey[1:33]),
+			Y:     new(big.Int).SetBytes(sigKey[33:]),
+		}, nil
+	default:
+		pub, err := x509.ParsePKIXPublicKey(sigKey)
+		if err != nil {
+			return nil, fmt.Errorf("unrecognized signing key encoding: %w", err)
+		}
+		return pub, nil
+	}
+}
+
+// GetJWKSHandler handles the GET /.well-known/jwks.json request.
+// It serves every stored signing key as a single JWK Set, keyed by URN.
+func (a *API) GetJWKSHandler(w http.ResponseWriter, r *http.Request) {
+	if a.JWKS == nil {
+		response.WriteJSONError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	set, err := a.JWKS.Get(r.Context())
+	if err != nil {
+		a.writeInternalError(w, a.Logger, "GetJWKS: Failed to build key set", "Failed to build key set", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(a.JWKS.TTL().Seconds())))
+	if err := json.NewEncoder(w).Encode(set); err != nil {
+		a.Logger.Error("GetJWKS: Failed to marshal key set", "err", err)
+		return
+	}
+	a.Logger.Debug("GetJWKS: Served aggregated key set", "keys", set.Len())
+}
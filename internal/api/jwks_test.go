@@ -0,0 +1,137 @@
+// --- File: internal/api/jwks_test.go ---
+package api_test
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// newSigningKeys returns one encoded signing key per supported encoding.
+func newSigningKeys(t *testing.T) map[string][]byte {
+	t.Helper()
+
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	p256Raw, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecSPKI, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+
+	return map[string][]byte{
+		"ed25519-user": edPub,
+		"p256-user":    p256Raw.PublicKey().Bytes(),
+		"spki-user":    ecSPKI,
+	}
+}
+
+func TestGetJWKSHandler(t *testing.T) {
+	logger := newTestLogger()
+	ctx := context.Background()
+
+	store := inmemory.New()
+	signingKeys := newSigningKeys(t)
+	for entityID, sigKey := range signingKeys {
+		entityURN, err := urn.New(urn.SecureMessaging, "user", entityID)
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, keys.PublicKeys{EncKey: []byte{1}, SigKey: sigKey}))
+	}
+
+	t.Run("Success - one entry per stored entity", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: store, Logger: logger, JWKS: api.NewJWKSCache(store, time.Minute, logger)}
+		req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+		rr := httptest.NewRecorder()
+
+		// Act
+		apiHandler.GetJWKSHandler(rr, req)
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		set, err := jwk.Parse(rr.Body.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, len(signingKeys), set.Len())
+
+		for entityID := range signingKeys {
+			entityURN, _ := urn.New(urn.SecureMessaging, "user", entityID)
+			key, found := set.LookupKeyID(entityURN.String())
+			if assert.True(t, found, "missing key for %s", entityURN) {
+				assert.Equal(t, jwk.ForSignature.String(), key.KeyUsage())
+			}
+		}
+	})
+
+	t.Run("Success - unparseable signing keys are skipped", func(t *testing.T) {
+		// Arrange
+		mixedStore := inmemory.New()
+		goodURN, _ := urn.New(urn.SecureMessaging, "user", "good")
+		badURN, _ := urn.New(urn.SecureMessaging, "user", "bad")
+		require.NoError(t, mixedStore.StorePublicKeys(ctx, goodURN, keys.PublicKeys{EncKey: []byte{1}, SigKey: signingKeys["ed25519-user"]}))
+		require.NoError(t, mixedStore.StorePublicKeys(ctx, badURN, keys.PublicKeys{EncKey: []byte{1}, SigKey: []byte{1, 2, 3}}))
+		cache := api.NewJWKSCache(mixedStore, time.Minute, logger)
+
+		// Act
+		set, err := cache.Get(ctx)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 1, set.Len())
+		_, found := set.LookupKeyID(goodURN.String())
+		assert.True(t, found)
+	})
+
+	t.Run("Success - set is cached until the TTL expires", func(t *testing.T) {
+		// Arrange
+		cachedStore := inmemory.New()
+		firstURN, _ := urn.New(urn.SecureMessaging, "user", "first")
+		secondURN, _ := urn.New(urn.SecureMessaging, "user", "second")
+		require.NoError(t, cachedStore.StorePublicKeys(ctx, firstURN, keys.PublicKeys{EncKey: []byte{1}, SigKey: signingKeys["ed25519-user"]}))
+		cache := api.NewJWKSCache(cachedStore, 50*time.Millisecond, logger)
+
+		set, err := cache.Get(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, set.Len())
+
+		// Act: A new key is not visible while the cached set is fresh...
+		require.NoError(t, cachedStore.StorePublicKeys(ctx, secondURN, keys.PublicKeys{EncKey: []byte{1}, SigKey: signingKeys["p256-user"]}))
+		set, err = cache.Get(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, set.Len())
+
+		// ...but appears once the TTL has passed.
+		time.Sleep(60 * time.Millisecond)
+		set, err = cache.Get(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, set.Len())
+	})
+
+	t.Run("Failure - 404 when disabled", func(t *testing.T) {
+		apiHandler := &api.API{Store: store, Logger: logger}
+		rr := httptest.NewRecorder()
+
+		apiHandler.GetJWKSHandler(rr, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,40 @@
+// --- File: internal/api/keybinding.go ---
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+)
+
+// errInvalidKeyBinding is returned when an encKeySignature does not verify.
+var errInvalidKeyBinding = errors.New("encKeySignature does not verify against sigKey")
+
+// verifyEncKeySignature checks that sig is the entity's signing key's
+// signature over the raw encKey bytes, binding the two keys together.
+// Ed25519 signs the bytes directly; ECDSA signs their SHA-256 digest and
+// uses the ASN.1 signature encoding.
+func verifyEncKeySignature(pk keys.PublicKeys, sig []byte) error {
+	pub, err := parseSigningKey(pk.SigKey)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errInvalidKeyBinding, err)
+	}
+	switch pub := pub.(type) {
+	case ed25519.PublicKey:
+		if ed25519.Verify(pub, pk.EncKey, sig) {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(pk.EncKey)
+		if ecdsa.VerifyASN1(pub, digest[:], sig) {
+			return nil
+		}
+	default:
+		return fmt.Errorf("%w: unsupported signing key type %T", errInvalidKeyBinding, pub)
+	}
+	return errInvalidKeyBinding
+}
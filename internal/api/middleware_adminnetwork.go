@@ -0,0 +1,83 @@
+// --- File: internal/api/middleware_adminnetwork.go ---
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+)
+
+// DefaultAdminAllowedPrefixes limits the admin routes to loopback clients
+// when no allow list is configured.
+var DefaultAdminAllowedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("::1/128"),
+}
+
+// RestrictAdminNetwork creates middleware that rejects admin requests with
+// 403 unless the client IP is inside one of allowed and outside all of
+// denied; denied wins where the two overlap. The client IP is taken from
+// X-Forwarded-For only when the peer is a trusted proxy (see ClientIP).
+func RestrictAdminNetwork(allowed, denied, trustedProxies []netip.Prefix, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, ok := ClientIP(r, trustedProxies)
+			if ok && containsAddr(allowed, client) && !containsAddr(denied, client) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			logger.Warn("RestrictAdminNetwork: Rejected admin request from outside the allowed networks",
+				"client_ip", client, "remote_addr", r.RemoteAddr, "path", r.URL.Path)
+			response.WriteJSONError(w, http.StatusForbidden, "Forbidden")
+		})
+	}
+}
+
+// ClientIP returns the address of the client that made the request. It is
+// the connection peer, unless the peer is a trusted proxy: then the
+// X-Forwarded-For chain is walked from the right, skipping further trusted
+// proxies, and the first other address is the client. Addresses left of
+// it could have been written by the client, so they are never used.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) (netip.Addr, bool) {
+	peer, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	client := peer.Addr().Unmap()
+	if !containsAddr(trustedProxies, client) {
+		return client, true
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		addr, err := netip.ParseAddr(hop)
+		if err != nil {
+			// A malformed hop means the chain cannot be trusted beyond
+			// this point; fall back to the last proxy we can vouch for.
+			return client, true
+		}
+		client = addr.Unmap()
+		if !containsAddr(trustedProxies, client) {
+			return client, true
+		}
+	}
+	return client, true
+}
+
+// containsAddr reports whether addr is inside any of prefixes.
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+// --- File: internal/api/middleware_adminnetwork_test.go ---
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+)
+
+func TestRestrictAdminNetwork(t *testing.T) {
+	allowed := []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")}
+	denied := []netip.Prefix{netip.MustParsePrefix("192.168.66.0/24")}
+	trustedProxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := api.RestrictAdminNetwork(allowed, denied, trustedProxies, newTestLogger())(okHandler)
+
+	testCases := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		expectedCode int
+	}{
+		{"Success - allowed peer", "192.168.1.5:4000", "", http.StatusOK},
+		{"Success - allowed client behind trusted proxy", "10.0.0.2:4000", "192.168.1.5, 10.0.0.3", http.StatusOK},
+		{"Failure - 403 peer outside allow list", "203.0.113.9:4000", "", http.StatusForbidden},
+		{"Failure - 403 peer in deny list", "192.168.66.7:4000", "", http.StatusForbidden},
+		{"Failure - 403 forged header from untrusted peer", "203.0.113.9:4000", "192.168.1.5", http.StatusForbidden},
+		{"Failure - 403 spoofed left-most hop is ignored", "10.0.0.2:4000", "192.168.1.5, 203.0.113.9", http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			req := httptest.NewRequest(http.MethodGet, "/admin/keys", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			}
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(rr, req)
+
+			// Assert
+			assert.Equal(t, tc.expectedCode, rr.Code)
+		})
+	}
+
+	t.Run("Success - loopback allowed by default", func(t *testing.T) {
+		// Arrange
+		defaultHandler := api.RestrictAdminNetwork(api.DefaultAdminAllowedPrefixes, nil, nil, newTestLogger())(okHandler)
+		req := httptest.NewRequest(http.MethodGet, "/admin/keys", nil)
+		req.RemoteAddr = "[::1]:4000"
+		rr := httptest.NewRecorder()
+
+		// Act
+		defaultHandler.ServeHTTP(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
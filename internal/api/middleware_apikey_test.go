@@ -0,0 +1,76 @@
+// --- File: internal/api/middleware_apikey_test.go ---
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	const apiKey = "pre-shared-key"
+	userURN, err := urn.New(urn.SecureMessaging, "user", "api-key-user")
+	require.NoError(t, err)
+
+	// storeKeys posts keys for userURN through APIKeyAuth with the given headers.
+	storeKeys := func(t *testing.T, headers map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		apiHandler := &api.API{Store: inmemory.New(), Logger: newTestLogger()}
+		handler := api.APIKeyAuth(apiKey, newTestLogger())(http.HandlerFunc(apiHandler.StoreKeysHandler))
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(`{"encKey":"AQID","sigKey":"BAUG"}`))
+		req.SetPathValue("entityURN", userURN.String())
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Success - valid key stores for the named entity", func(t *testing.T) {
+		// Act
+		rr := storeKeys(t, map[string]string{api.APIKeyHeader: apiKey, api.EntityIDHeader: "api-key-user"})
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("Failure - 403 for a different entity", func(t *testing.T) {
+		// Act
+		rr := storeKeys(t, map[string]string{api.APIKeyHeader: apiKey, api.EntityIDHeader: "someone-else"})
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("Failure - 401 for an invalid key", func(t *testing.T) {
+		// Act
+		rr := storeKeys(t, map[string]string{api.APIKeyHeader: "wrong-key", api.EntityIDHeader: "api-key-user"})
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Failure - 401 without a key", func(t *testing.T) {
+		// Act
+		rr := storeKeys(t, map[string]string{api.EntityIDHeader: "api-key-user"})
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Failure - 401 without an entity ID", func(t *testing.T) {
+		// Act
+		rr := storeKeys(t, map[string]string{api.APIKeyHeader: apiKey})
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
@@ -0,0 +1,31 @@
+// --- File: internal/api/middleware_charset.go ---
+package api
+
+import (
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+)
+
+// RequireUTF8 creates middleware that rejects request bodies declared in
+// any charset other than UTF-8 with 415. JSON is UTF-8 by definition
+// (RFC 8259), so a body labelled otherwise was probably encoded wrongly
+// by the client, and decoding it anyway could store mangled keys. A
+// Content-Type without a charset parameter, or one that cannot be parsed,
+// is left for the handler to judge.
+func RequireUTF8(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if charset, ok := params["charset"]; err == nil && ok && !strings.EqualFold(charset, "utf-8") {
+				logger.Warn("RequireUTF8: Rejected non-UTF-8 request body", "charset", charset)
+				response.WriteJSONError(w, http.StatusUnsupportedMediaType, "Request bodies must be UTF-8")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
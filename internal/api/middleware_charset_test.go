@@ -0,0 +1,48 @@
+// --- File: internal/api/middleware_charset_test.go ---
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+)
+
+func TestRequireUTF8(t *testing.T) {
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := api.RequireUTF8(newTestLogger())(okHandler)
+
+	testCases := []struct {
+		name         string
+		contentType  string
+		expectedCode int
+	}{
+		{"Success - explicit utf-8", "application/json; charset=utf-8", http.StatusOK},
+		{"Success - charset is case-insensitive", "application/json; charset=UTF-8", http.StatusOK},
+		{"Success - no charset", "application/json", http.StatusOK},
+		{"Success - no Content-Type", "", http.StatusOK},
+		{"Failure - 415 iso-8859-1", "application/json; charset=iso-8859-1", http.StatusUnsupportedMediaType},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			req := httptest.NewRequest(http.MethodPost, "/keys/urn:sm:user:test", strings.NewReader(`{}`))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(rr, req)
+
+			// Assert
+			assert.Equal(t, tc.expectedCode, rr.Code)
+		})
+	}
+}
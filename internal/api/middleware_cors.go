@@ -0,0 +1,22 @@
+// --- File: internal/api/middleware_cors.go ---
+package api
+
+import (
+	"net/http"
+)
+
+// VaryCORS creates middleware that declares which request headers a CORS
+// response depends on, so a shared cache never serves one origin's
+// Access-Control-Allow-Origin to another. Every response varies on Origin;
+// preflights also vary on the requested method and headers. It must wrap
+// the CORS middleware, which answers preflights without calling through.
+func VaryCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		if r.Method == http.MethodOptions {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,65 @@
+// --- File: internal/api/middleware_cors_test.go ---
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+)
+
+func TestVaryCORS(t *testing.T) {
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	cors := middleware.NewCorsMiddleware(middleware.CorsConfig{
+		AllowedOrigins: []string{"https://a.example.com", "https://b.example.com"},
+	}, newTestLogger())
+	handler := api.VaryCORS(cors(okHandler))
+
+	send := func(method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/keys/urn:sm:user:test", nil)
+		req.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			req.Header.Set("Access-Control-Request-Headers", "Authorization")
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Success - preflights from two origins each get their own origin", func(t *testing.T) {
+		for _, origin := range []string{"https://a.example.com", "https://b.example.com"} {
+			// Act
+			rr := send(http.MethodOptions, origin)
+
+			// Assert
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, origin, rr.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+				rr.Header().Values("Vary"))
+		}
+	})
+
+	t.Run("Success - disallowed origin still varies", func(t *testing.T) {
+		// Act
+		rr := send(http.MethodOptions, "https://evil.example.com")
+
+		// Assert
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Contains(t, rr.Header().Values("Vary"), "Origin")
+	})
+
+	t.Run("Success - simple request varies on Origin only", func(t *testing.T) {
+		// Act
+		rr := send(http.MethodGet, "https://a.example.com")
+
+		// Assert
+		assert.Equal(t, "https://a.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, []string{"Origin"}, rr.Header().Values("Vary"))
+	})
+}
@@ -0,0 +1,113 @@
+// --- File: internal/api/middleware_gzip_test.go ---
+package api_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+)
+
+// gzipped compresses data as a client would before sending it.
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestDecompressRequest(t *testing.T) {
+	const body = `{"encKey":"AQID","sigKey":"BAUG"}`
+
+	// echo writes back the body the handler received.
+	var received string
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		received = string(data)
+		assert.Empty(t, r.Header.Get("Content-Encoding"))
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := api.DecompressRequest(1024, newTestLogger())(echo)
+
+	post := func(payload []byte, encoding string) *httptest.ResponseRecorder {
+		received = ""
+		req := httptest.NewRequest(http.MethodPost, "/keys/urn:sm:user:test", bytes.NewReader(payload))
+		if encoding != "" {
+			req.Header.Set("Content-Encoding", encoding)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Success - gzip body is decompressed", func(t *testing.T) {
+		// Act
+		rr := post(gzipped(t, []byte(body)), "gzip")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, body, received)
+	})
+
+	t.Run("Success - uncompressed body passes through", func(t *testing.T) {
+		// Act
+		rr := post([]byte(body), "")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, body, received)
+	})
+
+	t.Run("Failure - 400 malformed gzip", func(t *testing.T) {
+		// Arrange: a valid header followed by a truncated stream.
+		truncated := gzipped(t, []byte(body))
+		truncated = truncated[:len(truncated)-6]
+
+		// Act
+		rr := post(truncated, "gzip")
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Empty(t, received, "the handler must not run")
+	})
+
+	t.Run("Failure - 400 not gzip at all", func(t *testing.T) {
+		// Act
+		rr := post([]byte(body), "gzip")
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Failure - 413 body inflates past the limit", func(t *testing.T) {
+		// Arrange: a megabyte of zeros compresses to about a kilobyte.
+		bomb := gzipped(t, []byte(strings.Repeat("0", 1<<20)))
+		require.Less(t, len(bomb), 4096)
+
+		// Act
+		rr := post(bomb, "gzip")
+
+		// Assert
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Empty(t, received, "the handler must not run")
+	})
+
+	t.Run("Failure - 415 unsupported encoding", func(t *testing.T) {
+		// Act
+		rr := post([]byte(body), "br")
+
+		// Assert
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+}
@@ -0,0 +1,26 @@
+// --- File: internal/api/middleware_session.go ---
+package api
+
+import (
+	"net/http"
+
+	"github.com/tinywideclouds/go-key-service/internal/storage/sessioncache"
+)
+
+// SessionIDHeader carries the client-chosen session ID used for
+// read-your-writes consistency.
+const SessionIDHeader = "X-Session-ID"
+
+// SessionID creates middleware that copies the SessionIDHeader value into
+// the request context, where a sessioncache.Store can find it.
+// Requests without the header are passed through unchanged.
+func SessionID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessionID := r.Header.Get(SessionIDHeader)
+		if sessionID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(sessioncache.ContextWithSessionID(r.Context(), sessionID)))
+	})
+}
@@ -0,0 +1,51 @@
+// --- File: internal/api/middleware_tls.go ---
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/tinywideclouds/go-microservice-base/pkg/response"
+)
+
+// RequireTLS creates middleware that rejects requests not made over TLS.
+// A request counts as secure if it arrived on a TLS connection, or if it
+// carries "X-Forwarded-Proto: https" and came directly from one of the
+// trusted proxy ranges. The header is ignored from any other peer, since
+// a client could otherwise simply claim to be secure.
+func RequireTLS(trustedProxies []netip.Prefix, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS != nil || isForwardedHTTPS(r, trustedProxies) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			logger.Warn("RequireTLS: Rejected cleartext request", "remote_addr", r.RemoteAddr, "path", r.URL.Path)
+			w.Header().Set("Upgrade", "TLS/1.2, HTTP/1.1")
+			w.Header().Set("Connection", "Upgrade")
+			response.WriteJSONError(w, http.StatusUpgradeRequired, "TLS is required")
+		})
+	}
+}
+
+// isForwardedHTTPS reports whether a trusted proxy forwarded the request
+// from an HTTPS connection.
+func isForwardedHTTPS(r *http.Request, trustedProxies []netip.Prefix) bool {
+	if !strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		return false
+	}
+	peer, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	addr := peer.Addr().Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+// --- File: internal/api/middleware_tls_test.go ---
+package api_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+)
+
+func TestRequireTLS(t *testing.T) {
+	logger := newTestLogger()
+	trustedProxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := api.RequireTLS(trustedProxies, logger)(okHandler)
+
+	t.Run("Success - TLS connection", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/keys/urn:sm:user:test", nil)
+		req.TLS = &tls.ConnectionState{}
+		rr := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Success - HTTPS forwarded by trusted proxy", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/keys/urn:sm:user:test", nil)
+		req.RemoteAddr = "10.1.2.3:51234"
+		req.Header.Set("X-Forwarded-Proto", "https")
+		rr := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Failure - 426 cleartext request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/keys/urn:sm:user:test", nil)
+		rr := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusUpgradeRequired, rr.Code)
+		assert.Contains(t, rr.Body.String(), "TLS is required")
+	})
+
+	t.Run("Failure - 426 forwarded header from untrusted peer", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/keys/urn:sm:user:test", nil)
+		req.RemoteAddr = "203.0.113.7:51234"
+		req.Header.Set("X-Forwarded-Proto", "https")
+		rr := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusUpgradeRequired, rr.Code)
+	})
+}
@@ -0,0 +1,104 @@
+// --- File: internal/api/middleware_tokenclaims.go ---
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TokenClaims holds the JWT claims used to correlate a request with the
+// identity service's audit logs. It never carries the token itself.
+type TokenClaims struct {
+	Subject string
+	JWTID   string
+	Issuer  string
+}
+
+type tokenClaimsKey struct{}
+
+// ContextWithTokenClaims returns a copy of ctx carrying the given claims.
+func ContextWithTokenClaims(ctx context.Context, claims TokenClaims) context.Context {
+	return context.WithValue(ctx, tokenClaimsKey{}, claims)
+}
+
+// TokenClaimsFromContext returns the claims stored by CorrelateTokenClaims, if any.
+func TokenClaimsFromContext(ctx context.Context) (TokenClaims, bool) {
+	claims, ok := ctx.Value(tokenClaimsKey{}).(TokenClaims)
+	return claims, ok
+}
+
+// CorrelateTokenClaims creates middleware that records the bearer token's
+// "sub" and "jti" claims in the request context and on the current span.
+// It must run *after* the auth middleware: the token is re-read without
+// verifying its signature, which is only safe once it has been validated.
+// A token that cannot be read is passed through untouched.
+func CorrelateTokenClaims(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := tokenClaimsFromRequest(r)
+			if !ok {
+				logger.Debug("CorrelateTokenClaims: No readable bearer token")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			trace.SpanFromContext(r.Context()).SetAttributes(
+				attribute.String("enduser.id", claims.Subject),
+				attribute.String("jwt.jti", claims.JWTID),
+			)
+			next.ServeHTTP(w, r.WithContext(ContextWithTokenClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// tokenClaimsFromRequest reads sub and jti from the Authorization header.
+func tokenClaimsFromRequest(r *http.Request) (TokenClaims, bool) {
+	rawToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || rawToken == "" {
+		return TokenClaims{}, false
+	}
+	token, err := jwt.ParseInsecure([]byte(rawToken))
+	if err != nil {
+		return TokenClaims{}, false
+	}
+	return TokenClaims{Subject: token.Subject(), JWTID: token.JwtID(), Issuer: token.Issuer()}, true
+}
+
+// RequireTrustedIssuer creates middleware that rejects tokens whose "iss"
+// claim is not in the allowlist, with 403 UNTRUSTED_ISSUER. Like
+// CorrelateTokenClaims it must run after the auth middleware; it reuses
+// claims already in the context and otherwise reads them from the token.
+func RequireTrustedIssuer(allowedIssuers []string, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := TokenClaimsFromContext(r.Context())
+			if !ok {
+				claims, ok = tokenClaimsFromRequest(r)
+			}
+			if !ok || !slices.Contains(allowedIssuers, claims.Issuer) {
+				logger.Warn("RequireTrustedIssuer: Rejected token from untrusted issuer",
+					"issuer", claims.Issuer, "jwt_sub", claims.Subject)
+				writeJSONErrorWithCode(w, http.StatusForbidden, ErrCodeUntrustedIssuer,
+					"Forbidden: Token issuer is not trusted")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// withTokenClaims adds any correlated token claims to the logger.
+func withTokenClaims(ctx context.Context, logger *slog.Logger) *slog.Logger {
+	claims, ok := TokenClaimsFromContext(ctx)
+	if !ok {
+		return logger
+	}
+	return logger.With("jwt_sub", claims.Subject, "jwt_jti", claims.JWTID)
+}
@@ -0,0 +1,157 @@
+// --- File: internal/api/middleware_tokenclaims_test.go ---
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// newSignedTestToken builds a compact JWT; the signature is irrelevant
+// because CorrelateTokenClaims runs after validation.
+func newSignedTestToken(t *testing.T, subject, jwtID, issuer string) string {
+	t.Helper()
+	token, err := jwt.NewBuilder().Subject(subject).JwtID(jwtID).Issuer(issuer).Build()
+	require.NoError(t, err)
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte("test-secret")))
+	require.NoError(t, err)
+	return string(signed)
+}
+
+func TestCorrelateTokenClaims(t *testing.T) {
+	authedUserID := "authorized-user"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
+	require.NoError(t, err)
+
+	t.Run("Success - sub and jti logged, raw token never logged", func(t *testing.T) {
+		// Arrange
+		var logBuf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		rawToken := newSignedTestToken(t, authedUserID, "token-id-123", "https://identity.example.com")
+
+		mockStore := new(MockStore)
+		mockStore.On("GetKeyRecord", mock.Anything, userURN).Return(keystore.KeyRecord{}, keystore.ErrKeyNotFound)
+		mockStore.On("StorePublicKeysWithMetadata", mock.Anything, userURN, mock.Anything, keystore.Metadata{}).Return(nil)
+
+		apiHandler := &api.API{Store: mockStore, Logger: logger}
+		handler := api.CorrelateTokenClaims(logger)(http.HandlerFunc(apiHandler.StoreKeysHandler))
+
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), strings.NewReader(`{"encKey":"AQID","sigKey":"BAUG"}`))
+		req.SetPathValue("entityURN", userURN.String())
+		req.Header.Set("Authorization", "Bearer "+rawToken)
+		ctx := middleware.ContextWithUserID(context.Background(), authedUserID)
+		rr := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(rr, req.WithContext(ctx))
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		logs := logBuf.String()
+		assert.Contains(t, logs, `"jwt_sub":"authorized-user"`)
+		assert.Contains(t, logs, `"jwt_jti":"token-id-123"`)
+		assert.NotContains(t, logs, rawToken)
+	})
+
+	t.Run("Success - claims available from context", func(t *testing.T) {
+		// Arrange
+		rawToken := newSignedTestToken(t, "subject-1", "jti-1", "https://identity.example.com")
+		var got api.TokenClaims
+		var found bool
+		handler := api.CorrelateTokenClaims(newTestLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, found = api.TokenClaimsFromContext(r.Context())
+		}))
+		req := httptest.NewRequest(http.MethodPost, "/keys/x", nil)
+		req.Header.Set("Authorization", "Bearer "+rawToken)
+
+		// Act
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		// Assert
+		require.True(t, found)
+		assert.Equal(t, api.TokenClaims{Subject: "subject-1", JWTID: "jti-1", Issuer: "https://identity.example.com"}, got)
+	})
+
+	t.Run("Success - unreadable token passes through without claims", func(t *testing.T) {
+		// Arrange
+		var found bool
+		handler := api.CorrelateTokenClaims(newTestLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, found = api.TokenClaimsFromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		}))
+		req := httptest.NewRequest(http.MethodPost, "/keys/x", nil)
+		req.Header.Set("Authorization", "Bearer not-a-jwt")
+		rr := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.False(t, found)
+	})
+}
+
+func TestRequireTrustedIssuer(t *testing.T) {
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := api.RequireTrustedIssuer([]string{"https://identity.example.com"}, newTestLogger())(okHandler)
+
+	t.Run("Success - allowed issuer", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/keys/x", nil)
+		req.Header.Set("Authorization", "Bearer "+newSignedTestToken(t, "user-1", "jti-1", "https://identity.example.com"))
+		rr := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Failure - 403 disallowed issuer", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/keys/x", nil)
+		req.Header.Set("Authorization", "Bearer "+newSignedTestToken(t, "user-1", "jti-1", "https://rogue.example.com"))
+		rr := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		var errResp api.CodedAPIError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, api.ErrCodeUntrustedIssuer, errResp.Code)
+	})
+
+	t.Run("Success - uses claims already in context", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/keys/x", nil)
+		ctx := api.ContextWithTokenClaims(req.Context(), api.TokenClaims{Issuer: "https://identity.example.com"})
+		rr := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(rr, req.WithContext(ctx))
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
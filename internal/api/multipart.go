@@ -0,0 +1,63 @@
+// --- File: internal/api/multipart.go ---
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// errPartTooLarge is returned by decodeMultipartKeys for a part over the limit.
+var errPartTooLarge = errors.New("multipart part exceeds the size limit")
+
+// isMultipartForm reports whether the request body is multipart/form-data.
+func isMultipartForm(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// decodeMultipartKeys reads a multipart/form-data body whose "encKey" and
+// "sigKey" parts hold the raw key bytes, as uploaded from key files. Each
+// part is read up to maxPartBytes; a longer part returns errPartTooLarge
+// without reading the rest. Unknown or repeated parts are rejected, so
+// nothing a client sends is silently dropped.
+func decodeMultipartKeys(r *http.Request, maxPartBytes int64) (storeKeysRequest, error) {
+	var req storeKeysRequest
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return req, err
+	}
+	seen := map[string]bool{}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return req, nil
+		}
+		if err != nil {
+			return req, err
+		}
+		name := part.FormName()
+		if name != "encKey" && name != "sigKey" {
+			return req, fmt.Errorf("unexpected part %q", name)
+		}
+		if seen[name] {
+			return req, fmt.Errorf("repeated part %q", name)
+		}
+		seen[name] = true
+
+		data, err := io.ReadAll(io.LimitReader(part, maxPartBytes+1))
+		if err != nil {
+			return req, err
+		}
+		if int64(len(data)) > maxPartBytes {
+			return req, fmt.Errorf("%w: %s", errPartTooLarge, name)
+		}
+		if name == "encKey" {
+			req.Keys.EncKey = data
+		} else {
+			req.Keys.SigKey = data
+		}
+	}
+}
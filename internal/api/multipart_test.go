@@ -0,0 +1,108 @@
+// --- File: internal/api/multipart_test.go ---
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// multipartBody builds a form with one file part per entry, in order.
+func multipartBody(t *testing.T, parts [][2]string) (*bytes.Buffer, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, part := range parts {
+		fw, err := mw.CreateFormFile(part[0], part[0]+".bin")
+		require.NoError(t, err)
+		_, err = fw.Write([]byte(part[1]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+	return &buf, mw.FormDataContentType()
+}
+
+func TestStoreKeysHandler_Multipart(t *testing.T) {
+	logger := newTestLogger()
+	const authedUserID = "uploader"
+	userURN, err := urn.New(urn.SecureMessaging, "user", authedUserID)
+	require.NoError(t, err)
+
+	post := func(apiHandler *api.API, body *bytes.Buffer, contentType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/keys/"+userURN.String(), body)
+		req.Header.Set("Content-Type", contentType)
+		req.SetPathValue("entityURN", userURN.String())
+		rr := httptest.NewRecorder()
+		apiHandler.StoreKeysHandler(rr, req.WithContext(middleware.ContextWithUserID(context.Background(), authedUserID)))
+		return rr
+	}
+
+	t.Run("Success - multipart keys are stored as the JSON path stores them", func(t *testing.T) {
+		// Arrange
+		jsonStore := inmemory.New()
+		formStore := inmemory.New()
+		body, contentType := multipartBody(t, [][2]string{{"encKey", "\x01\x02\x03"}, {"sigKey", "\x04\x05\x06"}})
+
+		// Act
+		jsonRR := post(&api.API{Store: jsonStore, Logger: logger},
+			bytes.NewBufferString(`{"encKey":"AQID","sigKey":"BAUG"}`), "application/json")
+		formRR := post(&api.API{Store: formStore, Logger: logger, AcceptMultipart: true}, body, contentType)
+
+		// Assert
+		require.Equal(t, http.StatusCreated, jsonRR.Code)
+		require.Equal(t, http.StatusCreated, formRR.Code)
+		fromJSON, err := jsonStore.GetPublicKeys(context.Background(), userURN)
+		require.NoError(t, err)
+		fromForm, err := formStore.GetPublicKeys(context.Background(), userURN)
+		require.NoError(t, err)
+		assert.Equal(t, fromJSON, fromForm)
+	})
+
+	t.Run("Failure - 413 part over the limit", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: inmemory.New(), Logger: logger, AcceptMultipart: true, MaxPartBytes: 8}
+		body, contentType := multipartBody(t, [][2]string{{"encKey", strings.Repeat("x", 9)}, {"sigKey", "\x04\x05\x06"}})
+
+		// Act
+		rr := post(apiHandler, body, contentType)
+
+		// Assert
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+
+	t.Run("Failure - 400 unexpected part", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: inmemory.New(), Logger: logger, AcceptMultipart: true}
+		body, contentType := multipartBody(t, [][2]string{{"encKey", "\x01\x02\x03"}, {"rotationHint", "soon"}})
+
+		// Act
+		rr := post(apiHandler, body, contentType)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Failure - 400 multipart when not enabled", func(t *testing.T) {
+		// Arrange
+		apiHandler := &api.API{Store: inmemory.New(), Logger: logger}
+		body, contentType := multipartBody(t, [][2]string{{"encKey", "\x01\x02\x03"}, {"sigKey", "\x04\x05\x06"}})
+
+		// Act
+		rr := post(apiHandler, body, contentType)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -0,0 +1,40 @@
+// --- File: internal/api/provisioning.go ---
+package api
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// ProvisioningPayload returns the canonical bytes an upstream provisioner
+// signs to vouch for an entity's keys: the compact JSON object
+// {"urn","keyFingerprint"} with the fields in that order, where
+// keyFingerprint is AttestationFingerprint of the keys. Binding the URN
+// stops a signature for one entity being replayed for another.
+func ProvisioningPayload(entityURN urn.URN, pk keys.PublicKeys) ([]byte, error) {
+	return json.Marshal(struct {
+		URN            string `json:"urn"`
+		KeyFingerprint string `json:"keyFingerprint"`
+	}{entityURN.String(), AttestationFingerprint(pk)})
+}
+
+// verifyProvisioningSignature reports whether sig is a signature over the
+// entity's provisioning payload by any of the trusted keys.
+func verifyProvisioningSignature(trusted []ed25519.PublicKey, entityURN urn.URN, pk keys.PublicKeys, sig []byte) bool {
+	if len(sig) == 0 {
+		return false
+	}
+	payload, err := ProvisioningPayload(entityURN, pk)
+	if err != nil {
+		return false
+	}
+	for _, pub := range trusted {
+		if ed25519.Verify(pub, payload, sig) {
+			return true
+		}
+	}
+	return false
+}
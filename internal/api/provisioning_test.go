@@ -0,0 +1,117 @@
+// --- File: internal/api/provisioning_test.go ---
+package api_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func TestGetKeysHandler_RequireProvisioningSignature(t *testing.T) {
+	ctx := t.Context()
+	pk := keys.PublicKeys{EncKey: []byte{1, 2, 3}, SigKey: []byte{4, 5, 6}}
+	trusted := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{3}, ed25519.SeedSize))
+	untrusted := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{4}, ed25519.SeedSize))
+
+	sign := func(t *testing.T, key ed25519.PrivateKey, entityURN urn.URN) []byte {
+		t.Helper()
+		payload, err := api.ProvisioningPayload(entityURN, pk)
+		require.NoError(t, err)
+		return ed25519.Sign(key, payload)
+	}
+	entity := func(t *testing.T, id string) urn.URN {
+		t.Helper()
+		entityURN, err := urn.New(urn.SecureMessaging, "user", id)
+		require.NoError(t, err)
+		return entityURN
+	}
+	get := func(apiHandler *api.API, entityURN urn.URN) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/keys/"+entityURN.String(), nil)
+		req.SetPathValue("entityURN", entityURN.String())
+		rr := httptest.NewRecorder()
+		apiHandler.GetKeysHandler(rr, req)
+		return rr
+	}
+
+	signedURN := entity(t, "signed")
+	unsignedURN := entity(t, "unsigned")
+	untrustedURN := entity(t, "untrusted")
+	replayedURN := entity(t, "replayed")
+
+	store := inmemory.New()
+	require.NoError(t, store.StorePublicKeysWithMetadata(ctx, signedURN, pk,
+		keystore.Metadata{ProvisioningSignature: sign(t, trusted, signedURN)}))
+	require.NoError(t, store.StorePublicKeys(ctx, unsignedURN, pk))
+	require.NoError(t, store.StorePublicKeysWithMetadata(ctx, untrustedURN, pk,
+		keystore.Metadata{ProvisioningSignature: sign(t, untrusted, untrustedURN)}))
+	// A genuine signature, but for a different entity.
+	require.NoError(t, store.StorePublicKeysWithMetadata(ctx, replayedURN, pk,
+		keystore.Metadata{ProvisioningSignature: sign(t, trusted, signedURN)}))
+
+	strict := &api.API{
+		Store:                        store,
+		Logger:                       newTestLogger(),
+		ProvisionerKeys:              []ed25519.PublicKey{trusted.Public().(ed25519.PublicKey)},
+		RequireProvisioningSignature: true,
+	}
+
+	t.Run("Success - keys signed by a trusted provisioner are served", func(t *testing.T) {
+		// Act
+		rr := get(strict, signedURN)
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		var body struct {
+			EncKey                []byte `json:"encKey"`
+			ProvisioningSignature []byte `json:"provisioningSignature"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, pk.EncKey, body.EncKey)
+		assert.Equal(t, sign(t, trusted, signedURN), body.ProvisioningSignature)
+	})
+
+	t.Run("Success - unsigned keys are served outside strict mode", func(t *testing.T) {
+		// Arrange
+		lenient := &api.API{Store: store, Logger: newTestLogger()}
+
+		// Act
+		rr := get(lenient, unsignedURN)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Failure - 404 unsigned keys are withheld", func(t *testing.T) {
+		// Act
+		rr := get(strict, unsignedURN)
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.NotContains(t, rr.Body.String(), "encKey")
+	})
+
+	t.Run("Failure - 409 keys signed by an untrusted provisioner", func(t *testing.T) {
+		for _, entityURN := range []urn.URN{untrustedURN, replayedURN} {
+			// Act
+			rr := get(strict, entityURN)
+
+			// Assert
+			assert.Equal(t, http.StatusConflict, rr.Code, entityURN.String())
+			var body api.CodedAPIError
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, api.ErrCodeUntrustedProvisioning, body.Code)
+		}
+	})
+}
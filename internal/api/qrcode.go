@@ -0,0 +1,31 @@
+// --- File: internal/api/qrcode.go ---
+package api
+
+import (
+	"github.com/skip2/go-qrcode"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// Response formats accepted by GET /keys/{entityURN}?format=.
+const (
+	FormatJSON = "json"
+	FormatQR   = "qr"
+)
+
+// QRCodeSize is the width and height, in pixels, of a key QR code.
+const QRCodeSize = 256
+
+// keysQRCode renders the entity's keys as a PNG QR code for out-of-band
+// verification between devices. It encodes the same compact
+// {"urn","keyFingerprint"} object a provisioner signs, which is short
+// enough to scan reliably and can be compared with what another device
+// computes from the keys it was served.
+func keysQRCode(entityURN urn.URN, pk keys.PublicKeys) ([]byte, error) {
+	payload, err := ProvisioningPayload(entityURN, pk)
+	if err != nil {
+		return nil, err
+	}
+	return qrcode.Encode(string(payload), qrcode.Medium, QRCodeSize)
+}
@@ -0,0 +1,79 @@
+// --- File: internal/api/qrcode_test.go ---
+package api_test
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/makiuchi-d/gozxing"
+	zxingqr "github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func TestGetKeysHandler_QRCode(t *testing.T) {
+	pk := keys.PublicKeys{EncKey: []byte("qr-enc"), SigKey: []byte("qr-sig")}
+	entityURN, err := urn.New(urn.SecureMessaging, "user", "qr-user")
+	require.NoError(t, err)
+	store := inmemory.New()
+	require.NoError(t, store.StorePublicKeys(t.Context(), entityURN, pk))
+	apiHandler := &api.API{Store: store, Logger: newTestLogger()}
+
+	get := func(entityURN urn.URN, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/keys/"+entityURN.String()+"?"+query, nil)
+		req.SetPathValue("entityURN", entityURN.String())
+		rr := httptest.NewRecorder()
+		apiHandler.GetKeysHandler(rr, req)
+		return rr
+	}
+
+	t.Run("Success - 200 PNG decodes to the keys' fingerprint", func(t *testing.T) {
+		// Act
+		rr := get(entityURN, "format=qr")
+
+		// Assert
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+		img, err := png.Decode(bytes.NewReader(rr.Body.Bytes()))
+		require.NoError(t, err)
+		assert.Equal(t, api.QRCodeSize, img.Bounds().Dx())
+		bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+		require.NoError(t, err)
+		result, err := zxingqr.NewQRCodeReader().Decode(bitmap, nil)
+		require.NoError(t, err)
+		want, err := api.ProvisioningPayload(entityURN, pk)
+		require.NoError(t, err)
+		assert.Equal(t, string(want), result.GetText())
+	})
+
+	t.Run("Failure - 404 entity has no keys", func(t *testing.T) {
+		// Arrange
+		absentURN, err := urn.New(urn.SecureMessaging, "user", "absent")
+		require.NoError(t, err)
+
+		// Act
+		rr := get(absentURN, "format=qr")
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.NotEqual(t, "image/png", rr.Header().Get("Content-Type"))
+	})
+
+	t.Run("Failure - 400 unknown format or JSON-only options", func(t *testing.T) {
+		for _, query := range []string{"format=svg", "format=qr&fields=encKey", "format=qr&attest=true"} {
+			// Act
+			rr := get(entityURN, query)
+
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+// --- File: internal/api/requests.go ---
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+)
+
+// storeKeysRequest is the body of a POST /keys/{entityURN} request.
+// The key material is decoded by keys.PublicKeys itself, which accepts
+// standard or URL-safe base64 with or without padding; the remaining
+// fields are optional extras that sit alongside encKey and sigKey and
+// accept the same variants.
+type storeKeysRequest struct {
+	Keys   keys.PublicKeys    `json:"-"`
+	EncAlg keystore.Algorithm `json:"encAlg,omitempty"`
+	SigAlg keystore.Algorithm `json:"sigAlg,omitempty"`
+	// RotationHint is an optional RFC 3339 time when the owner plans to rotate.
+	RotationHint time.Time `json:"rotationHint,omitzero"`
+	// EncKeySignature is an optional base64 signature over encKey by sigKey.
+	EncKeySignature base64Bytes `json:"encKeySignature,omitempty"`
+	// ProvisioningSignature is an optional base64 Ed25519 signature over
+	// ProvisioningPayload by a trusted upstream provisioner.
+	ProvisioningSignature base64Bytes `json:"provisioningSignature,omitempty"`
+	// UsageRestrictions optionally limits what the keys may be used for.
+	UsageRestrictions *keystore.UsageRestrictions `json:"usageRestrictions,omitempty"`
+}
+
+// UnmarshalJSON decodes the keys and the extra fields from the same body.
+func (r *storeKeysRequest) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &r.Keys); err != nil {
+		return err
+	}
+	// The alias drops this method so the extras decode with the default rules.
+	type extras storeKeysRequest
+	return json.Unmarshal(data, (*extras)(r))
+}
+
+// base64Bytes is a []byte that decodes from standard or URL-safe base64,
+// with or without padding, matching what keys.PublicKeys accepts for the
+// keys. It encodes as standard base64, like any []byte.
+type base64Bytes []byte
+
+// UnmarshalJSON decodes any common base64 variant.
+func (b *base64Bytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if strings.ContainsAny(s, "-_") {
+		s = strings.NewReplacer("-", "+", "_", "/").Replace(s)
+	}
+	decoded, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return errors.New("invalid base64 value")
+	}
+	*b = decoded
+	return nil
+}
@@ -0,0 +1,145 @@
+// --- File: internal/api/responses.go ---
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// getKeysResponse is the body of a GET /keys/{entityURN} response.
+// The key material is encoded by keys.PublicKeys itself; the remaining
+// fields are additive extras that sit alongside encKey and sigKey.
+type getKeysResponse struct {
+	Keys keys.PublicKeys `json:"-"`
+	// URN is the canonical form of the requested entity URN.
+	URN urn.URN `json:"urn"`
+	// EncKeySignature binds encKey to sigKey, if the owner stored one.
+	EncKeySignature []byte `json:"encKeySignature,omitempty"`
+	// ProvisioningSignature vouches for the keys, if a provisioner signed them.
+	ProvisioningSignature []byte `json:"provisioningSignature,omitempty"`
+	// UsageRestrictions are the owner's declared usage constraints, which
+	// recipients should honor. Omitted when there are none.
+	UsageRestrictions *keystore.UsageRestrictions `json:"usageRestrictions,omitempty"`
+	// Epoch is the entity's rotation epoch; omitted until first bumped.
+	Epoch uint64 `json:"epoch,omitempty"`
+	// UpdatedAt is when the keys were last stored, if the store knows.
+	UpdatedAt time.Time `json:"updatedAt,omitzero"`
+	// Compromised is set when an admin has flagged the keys; clients
+	// should refuse to use them.
+	Compromised bool `json:"compromised,omitempty"`
+	// Attestation is the signed record of this response, when requested.
+	Attestation *Attestation `json:"attestation,omitempty"`
+	// Fields, when non-empty, limits the body to the named fields.
+	Fields []string `json:"-"`
+	// Canonical encodes the body in canonical form; see canonicalJSON.
+	Canonical bool `json:"-"`
+}
+
+// optionalUsageRestrictions returns nil for no restrictions, so they are
+// omitted from the response.
+func optionalUsageRestrictions(u keystore.UsageRestrictions) *keystore.UsageRestrictions {
+	if u.IsZero() {
+		return nil
+	}
+	return &u
+}
+
+// getKeysResponseFields lists every field a GET body can carry, in the
+// order canonical bodies list them: the keys, then the metadata. These are
+// the names accepted by the ?fields= query parameter.
+var getKeysResponseFields = []string{"encKey", "sigKey", "urn", "encKeySignature", "provisioningSignature", "usageRestrictions", "epoch", "updatedAt", "compromised", "attestation"}
+
+// parseFields parses a comma-separated ?fields= value. An empty value
+// selects every field and returns nil.
+func parseFields(raw string) ([]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var fields []string
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(getKeysResponseFields, field) {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// MarshalJSON encodes the keys and the extra fields into a single object.
+func (r getKeysResponse) MarshalJSON() ([]byte, error) {
+	if r.Canonical {
+		return r.canonicalJSON()
+	}
+	keysJSON, err := json.Marshal(r.Keys)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(keysJSON, &fields); err != nil {
+		return nil, err
+	}
+	if err := r.extraFields(fields); err != nil {
+		return nil, err
+	}
+	if len(r.Fields) > 0 {
+		for name := range fields {
+			if !slices.Contains(r.Fields, name) {
+				delete(fields, name)
+			}
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// canonicalJSON encodes the body compactly with its fields in
+// getKeysResponseFields order, so equal responses are equal byte for
+// byte. The keys are encoded here as padded standard base64 rather than
+// by keys.PublicKeys, whose protojson output is deliberately unstable.
+func (r getKeysResponse) canonicalJSON() ([]byte, error) {
+	fields := make(map[string]json.RawMessage)
+	for name, key := range map[string][]byte{"encKey": r.Keys.EncKey, "sigKey": r.Keys.SigKey} {
+		if len(key) > 0 {
+			fields[name] = json.RawMessage(`"` + base64.StdEncoding.EncodeToString(key) + `"`)
+		}
+	}
+	if err := r.extraFields(fields); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, name := range getKeysResponseFields {
+		value, ok := fields[name]
+		if !ok || (len(r.Fields) > 0 && !slices.Contains(r.Fields, name)) {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`"` + name + `":`)
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// extraFields adds the encoded fields other than the keys to fields.
+func (r getKeysResponse) extraFields(fields map[string]json.RawMessage) error {
+	// The alias drops MarshalJSON so the extras encode with the default rules.
+	type extras getKeysResponse
+	extrasJSON, err := json.Marshal(extras(r))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(extrasJSON, &fields)
+}
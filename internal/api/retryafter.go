@@ -0,0 +1,49 @@
+// --- File: internal/api/retryafter.go ---
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultRetryAfter is the back-off suggested to throttled clients when
+// none is configured and the wait is not otherwise known.
+const DefaultRetryAfter = 30 * time.Second
+
+// writeRetryableError responds with statusCode, which should be 429 or
+// 503, telling the client to retry after wait. Every throttled response
+// goes through here so none is sent without a Retry-After header.
+func writeRetryableError(w http.ResponseWriter, statusCode int, code, message string, wait time.Duration) {
+	w.Header().Set("Retry-After", retryAfterSeconds(wait))
+	writeJSONErrorWithCode(w, statusCode, code, message)
+}
+
+// retryAfterSeconds formats wait as Retry-After delta-seconds, rounded up
+// so clients never come back early, and at least 1.
+func retryAfterSeconds(wait time.Duration) string {
+	return strconv.Itoa(max(1, int(math.Ceil(wait.Seconds()))))
+}
+
+// retryAfter is the configured back-off for throttled responses.
+func (a *API) retryAfter() time.Duration {
+	if a.RetryAfter > 0 {
+		return a.RetryAfter
+	}
+	return DefaultRetryAfter
+}
+
+// isBackendOverloaded reports whether a store error means the backend is
+// shedding load or briefly unreachable, so the request is worth retrying
+// later rather than being a fault.
+func isBackendOverloaded(err error) bool {
+	switch status.Code(err) {
+	case codes.ResourceExhausted, codes.Unavailable:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+// --- File: internal/api/writelock_test.go ---
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func TestWriteLock(t *testing.T) {
+	logger := newTestLogger()
+	store := inmemory.New()
+	userURN, err := urn.New(urn.SecureMessaging, "user", "alice")
+	require.NoError(t, err)
+	require.NoError(t, store.StorePublicKeys(context.Background(), userURN, keys.PublicKeys{EncKey: []byte{1}, SigKey: []byte{2}}))
+
+	apiHandler := &api.API{
+		Store:        store,
+		Logger:       logger,
+		AdminUserIDs: []string{"admin-user"},
+		WriteLock:    &api.WriteLock{},
+	}
+	writeLock := api.RejectWhenWriteLocked(apiHandler.WriteLock, 0, logger)
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /keys/{entityURN}", writeLock(http.HandlerFunc(apiHandler.StoreKeysHandler)))
+	mux.HandleFunc("GET /keys/{entityURN}", apiHandler.GetKeysHandler)
+	mux.HandleFunc("POST /admin/lock", apiHandler.LockWritesHandler)
+	mux.HandleFunc("POST /admin/unlock", apiHandler.UnlockWritesHandler)
+
+	// do sends a request as the given user (empty for anonymous).
+	do := func(method, path, userID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if userID != "" {
+			req = req.WithContext(middleware.ContextWithUserID(req.Context(), userID))
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	storeBody := `{"encKey":"AQID","sigKey":"BAUG"}`
+
+	// Act & Assert: Only admins can take the lock
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/admin/lock", "alice", "").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/admin/lock", "admin-user", "").Code)
+	assert.True(t, apiHandler.WriteLock.Locked())
+
+	// Act & Assert: While locked, writes are rejected and reads still succeed
+	rr := do(http.MethodPost, "/keys/"+userURN.String(), "alice", storeBody)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Contains(t, rr.Body.String(), api.ErrCodeWritesLocked)
+	assert.Equal(t, strconv.Itoa(int(api.DefaultRetryAfter.Seconds())), rr.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/keys/"+userURN.String(), "", "").Code)
+
+	// Act & Assert: After unlocking, writes succeed again
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/admin/unlock", "admin-user", "").Code)
+	assert.False(t, apiHandler.WriteLock.Locked())
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/keys/"+userURN.String(), "alice", storeBody).Code)
+}
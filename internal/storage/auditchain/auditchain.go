@@ -0,0 +1,264 @@
+// --- File: internal/storage/auditchain/auditchain.go ---
+// Package auditchain provides a keystore.Store wrapper that records every
+// write in a tamper-evident hash chain, so an audit log can be shown not
+// to have been altered after the fact.
+package auditchain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// ErrChainBroken is returned by VerifyChain when an entry was altered,
+// removed or inserted.
+var ErrChainBroken = errors.New("audit chain broken")
+
+// Entry is one write in the chain.
+type Entry struct {
+	// Seq numbers entries from 1 with no gaps.
+	Seq uint64 `json:"seq"`
+	// Op is the store operation, e.g. keystore.OpStorePublicKeys.
+	Op  string `json:"op"`
+	URN string `json:"urn"`
+	// Detail records what was written: the keys' fingerprint for a store,
+	// the new epoch for a bump, the reason for a compromise flag.
+	Detail string    `json:"detail,omitempty"`
+	At     time.Time `json:"at"`
+	// PrevHash is the previous entry's Hash, or empty for the first entry.
+	PrevHash string `json:"prevHash"`
+	// Hash is the hex SHA-256 over this entry's other fields (see Digest).
+	Hash string `json:"hash"`
+}
+
+// Digest returns the hash the entry should carry: the hex SHA-256 of the
+// compact JSON encoding of every field except Hash, in declaration order
+// with At in RFC 3339 UTC. Because PrevHash is covered, changing any
+// entry changes every hash after it.
+func (e Entry) Digest() string {
+	payload, _ := json.Marshal(struct {
+		Seq      uint64    `json:"seq"`
+		Op       string    `json:"op"`
+		URN      string    `json:"urn"`
+		Detail   string    `json:"detail,omitempty"`
+		At       time.Time `json:"at"`
+		PrevHash string    `json:"prevHash"`
+	}{e.Seq, e.Op, e.URN, e.Detail, e.At.UTC(), e.PrevHash})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Log is an append-only store of entries. Implementations must return
+// entries in the order they were appended.
+type Log interface {
+	Append(ctx context.Context, entry Entry) error
+	Entries(ctx context.Context) ([]Entry, error)
+}
+
+// MemoryLog is a Log held in process memory. It does not survive a
+// restart, so it suits a single instance or tests; durable deployments
+// should supply a Log backed by write-once storage.
+type MemoryLog struct {
+	mu      sync.RWMutex
+	entries []Entry
+}
+
+// Append adds entry to the end of the log.
+func (l *MemoryLog) Append(_ context.Context, entry Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+// Entries returns a copy of every entry, oldest first.
+func (l *MemoryLog) Entries(context.Context) ([]Entry, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]Entry(nil), l.entries...), nil
+}
+
+// Store wraps a keystore.Store and appends an entry to the chain after
+// every successful write. Appends are serialized so the chain has one
+// order. Recording is best effort: the write has already been committed,
+// so an append failure is logged and not returned, and VerifyChain will
+// not see the write. Writes made through a keystore.Transactor on the
+// backend bypass the wrapper and are not chained.
+type Store struct {
+	keystore.Store
+
+	log    Log
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu       sync.Mutex
+	seq      uint64
+	lastHash string
+}
+
+// New wraps next, chaining its writes into log. Entries already in log
+// are continued from, so the chain spans restarts with a durable Log.
+func New(ctx context.Context, next keystore.Store, log Log, logger *slog.Logger) (*Store, error) {
+	existing, err := log.Entries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit chain: %w", err)
+	}
+	s := &Store{
+		Store:  next,
+		log:    log,
+		logger: logger.With("component", "audit_chain"),
+		now:    time.Now,
+	}
+	if n := len(existing); n > 0 {
+		s.seq, s.lastHash = existing[n-1].Seq, existing[n-1].Hash
+	}
+	return s, nil
+}
+
+// VerifyChain reads the whole log and checks that entries are numbered
+// from 1 without gaps, that each links to the one before, and that each
+// hash matches its contents. It returns an error wrapping ErrChainBroken
+// at the first entry that fails.
+func (s *Store) VerifyChain(ctx context.Context) error {
+	entries, err := s.log.Entries(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read audit chain: %w", err)
+	}
+	return Verify(entries)
+}
+
+// Verify checks a sequence of entries as VerifyChain does.
+func Verify(entries []Entry) error {
+	prevHash := ""
+	for i, entry := range entries {
+		if want := uint64(i + 1); entry.Seq != want {
+			return fmt.Errorf("%w: entry %d has sequence number %d", ErrChainBroken, want, entry.Seq)
+		}
+		if entry.PrevHash != prevHash {
+			return fmt.Errorf("%w: entry %d does not link to entry %d", ErrChainBroken, entry.Seq, entry.Seq-1)
+		}
+		if entry.Digest() != entry.Hash {
+			return fmt.Errorf("%w: entry %d does not match its hash", ErrChainBroken, entry.Seq)
+		}
+		prevHash = entry.Hash
+	}
+	return nil
+}
+
+// StorePublicKeys stores the keys, then records the write.
+func (s *Store) StorePublicKeys(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys) error {
+	if err := s.Store.StorePublicKeys(ctx, entityURN, pk); err != nil {
+		return err
+	}
+	s.record(ctx, keystore.OpStorePublicKeys, entityURN, keystore.Fingerprint(pk))
+	return nil
+}
+
+// StorePublicKeysWithMetadata stores the keys, then records the write.
+func (s *Store) StorePublicKeysWithMetadata(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys, meta keystore.Metadata) error {
+	if err := s.Store.StorePublicKeysWithMetadata(ctx, entityURN, pk, meta); err != nil {
+		return err
+	}
+	s.record(ctx, keystore.OpStorePublicKeysWithMetadata, entityURN, keystore.Fingerprint(pk))
+	return nil
+}
+
+// GetOrCreatePublicKeys records the write only when the candidate was stored.
+func (s *Store) GetOrCreatePublicKeys(ctx context.Context, entityURN urn.URN, candidate keys.PublicKeys) (keys.PublicKeys, bool, error) {
+	effective, created, err := s.Store.GetOrCreatePublicKeys(ctx, entityURN, candidate)
+	if err == nil && created {
+		s.record(ctx, keystore.OpGetOrCreatePublicKeys, entityURN, keystore.Fingerprint(effective))
+	}
+	return effective, created, err
+}
+
+// SwapPublicKeys swaps the keys, then records the write.
+func (s *Store) SwapPublicKeys(ctx context.Context, entityURN urn.URN, newKeys keys.PublicKeys) (keys.PublicKeys, error) {
+	old, err := s.Store.SwapPublicKeys(ctx, entityURN, newKeys)
+	if err == nil {
+		s.record(ctx, keystore.OpSwapPublicKeys, entityURN, keystore.Fingerprint(newKeys))
+	}
+	return old, err
+}
+
+// BumpEpoch advances the epoch, then records the new value.
+func (s *Store) BumpEpoch(ctx context.Context, entityURN urn.URN) (uint64, error) {
+	epoch, err := s.Store.BumpEpoch(ctx, entityURN)
+	if err == nil {
+		s.record(ctx, keystore.OpBumpEpoch, entityURN, "epoch="+strconv.FormatUint(epoch, 10))
+	}
+	return epoch, err
+}
+
+// MarkCompromised flags the keys, then records the flag and its reason.
+func (s *Store) MarkCompromised(ctx context.Context, entityURN urn.URN, reason string) error {
+	if err := s.Store.MarkCompromised(ctx, entityURN, reason); err != nil {
+		return err
+	}
+	s.record(ctx, keystore.OpMarkCompromised, entityURN, reason)
+	return nil
+}
+
+// ClearCompromised clears the flag, then records that it was cleared.
+func (s *Store) ClearCompromised(ctx context.Context, entityURN urn.URN) error {
+	if err := s.Store.ClearCompromised(ctx, entityURN); err != nil {
+		return err
+	}
+	s.record(ctx, keystore.OpClearCompromised, entityURN, "")
+	return nil
+}
+
+// DeletePublicKeys deletes the keys, then records the deletion if there
+// were keys to delete.
+func (s *Store) DeletePublicKeys(ctx context.Context, entityURN urn.URN) (bool, error) {
+	deleted, err := s.Store.DeletePublicKeys(ctx, entityURN)
+	if err == nil && deleted {
+		s.record(ctx, keystore.OpDeletePublicKeys, entityURN, "")
+	}
+	return deleted, err
+}
+
+// DeleteOlderThan deletes the old entities, then records the cleanup with
+// the zero URN, since it spans the store.
+func (s *Store) DeleteOlderThan(ctx context.Context, age time.Duration) (int, error) {
+	deleted, err := s.Store.DeleteOlderThan(ctx, age)
+	if deleted > 0 {
+		s.record(ctx, keystore.OpDeleteOlderThan, urn.URN{}, fmt.Sprintf("age=%s deleted=%d", age, deleted))
+	}
+	return deleted, err
+}
+
+// record appends the next entry to the chain.
+func (s *Store) record(ctx context.Context, op string, entityURN urn.URN, detail string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := Entry{
+		Seq:      s.seq + 1,
+		Op:       op,
+		URN:      entityURN.String(),
+		Detail:   detail,
+		At:       s.now().UTC(),
+		PrevHash: s.lastHash,
+	}
+	entry.Hash = entry.Digest()
+	// The write has already succeeded, so do not let the caller's
+	// cancellation leave it out of the chain.
+	if err := s.log.Append(context.WithoutCancel(ctx), entry); err != nil {
+		s.logger.Error("Failed to append to the audit chain; the write is unrecorded",
+			"op", op, "entity_urn", entry.URN, "err", err)
+		return
+	}
+	s.seq, s.lastHash = entry.Seq, entry.Hash
+}
@@ -0,0 +1,169 @@
+// --- File: internal/storage/auditchain/auditchain_test.go ---
+package auditchain_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/storage/auditchain"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// sliceLog is a Log whose entries the test can tamper with directly.
+type sliceLog struct {
+	err     error
+	entries []auditchain.Entry
+}
+
+func (l *sliceLog) Append(_ context.Context, entry auditchain.Entry) error {
+	if l.err != nil {
+		return l.err
+	}
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+func (l *sliceLog) Entries(context.Context) ([]auditchain.Entry, error) {
+	return append([]auditchain.Entry(nil), l.entries...), nil
+}
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// writeChain makes three writes through a new chained store.
+func writeChain(t *testing.T, log auditchain.Log) *auditchain.Store {
+	t.Helper()
+	ctx := context.Background()
+	entityURN, err := urn.Parse("urn:sm:user:audited")
+	require.NoError(t, err)
+
+	store, err := auditchain.New(ctx, inmemory.New(), log, newTestLogger())
+	require.NoError(t, err)
+	require.NoError(t, store.StorePublicKeys(ctx, entityURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+	_, err = store.BumpEpoch(ctx, entityURN)
+	require.NoError(t, err)
+	require.NoError(t, store.MarkCompromised(ctx, entityURN, "leaked"))
+	return store
+}
+
+func TestStore_VerifyChain(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success - intact chain verifies", func(t *testing.T) {
+		// Arrange
+		log := &sliceLog{}
+		store := writeChain(t, log)
+
+		// Act
+		err := store.VerifyChain(ctx)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, log.entries, 3)
+		assert.Equal(t, keystore.OpStorePublicKeys, log.entries[0].Op)
+		assert.Empty(t, log.entries[0].PrevHash)
+		assert.Equal(t, log.entries[0].Hash, log.entries[1].PrevHash)
+		assert.Equal(t, "epoch=1", log.entries[1].Detail)
+		assert.Equal(t, "leaked", log.entries[2].Detail)
+	})
+
+	t.Run("Success - chain continues across a new wrapper", func(t *testing.T) {
+		// Arrange
+		log := &sliceLog{}
+		writeChain(t, log)
+
+		// Act
+		store := writeChain(t, log)
+
+		// Assert
+		require.NoError(t, store.VerifyChain(ctx))
+		assert.Len(t, log.entries, 6)
+	})
+
+	t.Run("Success - failed writes are not recorded", func(t *testing.T) {
+		// Arrange
+		log := &sliceLog{}
+		store, err := auditchain.New(ctx, inmemory.New(), log, newTestLogger())
+		require.NoError(t, err)
+		missing, err := urn.Parse("urn:sm:user:missing")
+		require.NoError(t, err)
+
+		// Act
+		err = store.MarkCompromised(ctx, missing, "leaked")
+
+		// Assert
+		require.Error(t, err)
+		assert.Empty(t, log.entries)
+	})
+
+	t.Run("Failure - altered entry is detected", func(t *testing.T) {
+		// Arrange
+		log := &sliceLog{}
+		store := writeChain(t, log)
+		log.entries[1].Detail = "epoch=7"
+
+		// Act
+		err := store.VerifyChain(ctx)
+
+		// Assert
+		require.ErrorIs(t, err, auditchain.ErrChainBroken)
+		assert.Contains(t, err.Error(), "entry 2")
+	})
+
+	t.Run("Failure - rehashed entry breaks the next link", func(t *testing.T) {
+		// Arrange
+		log := &sliceLog{}
+		store := writeChain(t, log)
+		log.entries[1].Detail = "epoch=7"
+		log.entries[1].Hash = log.entries[1].Digest()
+
+		// Act
+		err := store.VerifyChain(ctx)
+
+		// Assert
+		require.ErrorIs(t, err, auditchain.ErrChainBroken)
+		assert.Contains(t, err.Error(), "entry 3")
+	})
+
+	t.Run("Failure - removed entry is detected as a gap", func(t *testing.T) {
+		// Arrange
+		log := &sliceLog{}
+		store := writeChain(t, log)
+		log.entries = append(log.entries[:1], log.entries[2:]...)
+
+		// Act
+		err := store.VerifyChain(ctx)
+
+		// Assert
+		require.ErrorIs(t, err, auditchain.ErrChainBroken)
+		assert.Contains(t, err.Error(), "sequence number 3")
+	})
+
+	t.Run("Failure - append error leaves the chain intact", func(t *testing.T) {
+		// Arrange
+		log := &sliceLog{}
+		store := writeChain(t, log)
+		log.err = errors.New("log unavailable")
+		entityURN, err := urn.Parse("urn:sm:user:audited")
+		require.NoError(t, err)
+
+		// Act
+		require.NoError(t, store.ClearCompromised(ctx, entityURN))
+		log.err = nil
+		require.NoError(t, store.ClearCompromised(ctx, entityURN))
+
+		// Assert
+		require.NoError(t, store.VerifyChain(ctx))
+		assert.Len(t, log.entries, 4)
+	})
+}
@@ -0,0 +1,181 @@
+// --- File: internal/storage/budget/budget.go ---
+// Package budget provides a keystore.Store wrapper that caps the total
+// bytes the store may hold, so storage costs cannot grow without bound.
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// ErrExceeded is returned, wrapped in a keystore.StoreError, when a write
+// would take the store's usage past its budget.
+var ErrExceeded = errors.New("storage budget exceeded")
+
+// Store wraps a keystore.Store and tracks the approximate bytes it holds,
+// counting each record as its URN, keys and signatures the way the
+// in-memory store's Stats does. A write that would grow usage past the
+// limit is rejected; writes that keep usage the same or shrink it are
+// always let through. Usage is seeded from the backend's Stats and then
+// kept as a running total, so it drifts if the backend is written other
+// than through this wrapper, and concurrent writes to one entity may each
+// count the same previous record. It is an estimate, not an exact quota.
+type Store struct {
+	keystore.Store
+
+	limit  int64
+	logger *slog.Logger
+
+	mu   sync.Mutex
+	used int64
+}
+
+// New wraps next with a budget of limit bytes, reading the backend's
+// current usage from its Stats.
+func New(ctx context.Context, next keystore.Store, limit int64, logger *slog.Logger) (*Store, error) {
+	stats, err := next.Stats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store usage: %w", err)
+	}
+	return &Store{
+		Store:  next,
+		limit:  limit,
+		logger: logger.With("component", "storage_budget"),
+		used:   stats.ApproxBytes,
+	}, nil
+}
+
+// Used returns the approximate bytes currently stored.
+func (s *Store) Used() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.used
+}
+
+// StorePublicKeys stores the keys if the budget allows the change in size.
+func (s *Store) StorePublicKeys(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys) error {
+	return s.replace(ctx, keystore.OpStorePublicKeys, entityURN, recordBytes(entityURN, pk, keystore.Metadata{}), func() error {
+		return s.Store.StorePublicKeys(ctx, entityURN, pk)
+	})
+}
+
+// StorePublicKeysWithMetadata stores the keys and metadata if the budget
+// allows the change in size.
+func (s *Store) StorePublicKeysWithMetadata(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys, meta keystore.Metadata) error {
+	return s.replace(ctx, keystore.OpStorePublicKeysWithMetadata, entityURN, recordBytes(entityURN, pk, meta), func() error {
+		return s.Store.StorePublicKeysWithMetadata(ctx, entityURN, pk, meta)
+	})
+}
+
+// GetOrCreatePublicKeys reserves room for the candidate before the call
+// and gives it back if existing keys were returned instead.
+func (s *Store) GetOrCreatePublicKeys(ctx context.Context, entityURN urn.URN, candidate keys.PublicKeys) (keys.PublicKeys, bool, error) {
+	delta := recordBytes(entityURN, candidate, keystore.Metadata{})
+	if err := s.reserve(keystore.OpGetOrCreatePublicKeys, entityURN, delta); err != nil {
+		return keys.PublicKeys{}, false, err
+	}
+	effective, created, err := s.Store.GetOrCreatePublicKeys(ctx, entityURN, candidate)
+	if err != nil || !created {
+		s.release(delta)
+	}
+	return effective, created, err
+}
+
+// SwapPublicKeys swaps the keys if the budget allows the change in size.
+func (s *Store) SwapPublicKeys(ctx context.Context, entityURN urn.URN, newKeys keys.PublicKeys) (keys.PublicKeys, error) {
+	var old keys.PublicKeys
+	err := s.replace(ctx, keystore.OpSwapPublicKeys, entityURN, recordBytes(entityURN, newKeys, keystore.Metadata{}), func() error {
+		var err error
+		old, err = s.Store.SwapPublicKeys(ctx, entityURN, newKeys)
+		return err
+	})
+	return old, err
+}
+
+// DeletePublicKeys deletes the keys, then gives back the room the record
+// took up. As with writes, a failed lookup is treated as no existing
+// record, which can only understate what is freed.
+func (s *Store) DeletePublicKeys(ctx context.Context, entityURN urn.URN) (bool, error) {
+	var size int64
+	if existing, err := s.Store.GetKeyRecord(ctx, entityURN); err == nil {
+		size = recordBytes(entityURN, existing.Keys, existing.Metadata)
+	}
+	deleted, err := s.Store.DeletePublicKeys(ctx, entityURN)
+	if err == nil && deleted {
+		s.release(size)
+	}
+	return deleted, err
+}
+
+// DeleteOlderThan deletes the old entities, then re-reads usage from the
+// backend's Stats, since the records removed are not known here.
+func (s *Store) DeleteOlderThan(ctx context.Context, age time.Duration) (int, error) {
+	deleted, err := s.Store.DeleteOlderThan(ctx, age)
+	if deleted > 0 {
+		stats, statsErr := s.Store.Stats(ctx)
+		if statsErr != nil {
+			s.logger.Warn("Failed to re-read usage after a cleanup; freed space is not yet available", "err", statsErr)
+			return deleted, err
+		}
+		s.mu.Lock()
+		s.used = stats.ApproxBytes
+		s.mu.Unlock()
+	}
+	return deleted, err
+}
+
+// replace runs write, which overwrites the entity's record with one of
+// size bytes, once the growth over the current record has been reserved.
+func (s *Store) replace(ctx context.Context, op string, entityURN urn.URN, size int64, write func() error) error {
+	// A failed lookup is treated as no existing record, which can only
+	// overstate the growth.
+	delta := size
+	if existing, err := s.Store.GetKeyRecord(ctx, entityURN); err == nil {
+		delta -= recordBytes(entityURN, existing.Keys, existing.Metadata)
+	}
+	if err := s.reserve(op, entityURN, delta); err != nil {
+		return err
+	}
+	if err := write(); err != nil {
+		s.release(delta)
+		return err
+	}
+	return nil
+}
+
+// reserve adds delta to the usage, or returns ErrExceeded if a positive
+// delta would take it past the limit. A zero or negative delta always
+// succeeds, so overwrites that do not grow usage are never rejected.
+func (s *Store) reserve(op string, entityURN urn.URN, delta int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if delta > 0 && s.used+delta > s.limit {
+		s.logger.Warn("Rejected write over the storage budget",
+			"op", op, "entity_urn", entityURN.String(), "used", s.used, "growth", delta, "limit", s.limit)
+		return &keystore.StoreError{Op: op, URN: entityURN, Err: ErrExceeded}
+	}
+	s.used += delta
+	return nil
+}
+
+// release undoes a reservation whose write did not happen, or frees the
+// room of a deleted record.
+func (s *Store) release(delta int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.used -= delta
+}
+
+// recordBytes is the size the budget counts for one record.
+func recordBytes(entityURN urn.URN, pk keys.PublicKeys, meta keystore.Metadata) int64 {
+	return int64(len(entityURN.String()) + len(pk.EncKey) + len(pk.SigKey) +
+		len(meta.EncKeySignature) + len(meta.ProvisioningSignature))
+}
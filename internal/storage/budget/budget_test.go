@@ -0,0 +1,153 @@
+// --- File: internal/storage/budget/budget_test.go ---
+package budget_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/storage/budget"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// keysOfSize returns keys that, stored for a 14-byte URN such as
+// urn:sm:user:u1, make a record of size bytes.
+func keysOfSize(size int) keys.PublicKeys {
+	n := size - 14
+	return keys.PublicKeys{EncKey: make([]byte, n/2), SigKey: make([]byte, n-n/2)}
+}
+
+func TestStore_Budget(t *testing.T) {
+	ctx := context.Background()
+	u1, err := urn.Parse("urn:sm:user:u1")
+	require.NoError(t, err)
+	u2, err := urn.Parse("urn:sm:user:u2")
+	require.NoError(t, err)
+
+	t.Run("Success - writes up to the budget are accepted", func(t *testing.T) {
+		// Arrange
+		store, err := budget.New(ctx, inmemory.New(), 200, newTestLogger())
+		require.NoError(t, err)
+
+		// Act
+		err1 := store.StorePublicKeys(ctx, u1, keysOfSize(100))
+		err2 := store.StorePublicKeys(ctx, u2, keysOfSize(100))
+
+		// Assert
+		require.NoError(t, err1)
+		require.NoError(t, err2)
+		assert.Equal(t, int64(200), store.Used())
+	})
+
+	t.Run("Failure - write beyond the budget is rejected", func(t *testing.T) {
+		// Arrange
+		backend := inmemory.New()
+		store, err := budget.New(ctx, backend, 150, newTestLogger())
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, u1, keysOfSize(100)))
+
+		// Act
+		err = store.StorePublicKeys(ctx, u2, keysOfSize(100))
+
+		// Assert
+		require.ErrorIs(t, err, budget.ErrExceeded)
+		var storeErr *keystore.StoreError
+		require.ErrorAs(t, err, &storeErr)
+		assert.Equal(t, keystore.OpStorePublicKeys, storeErr.Op)
+		assert.Equal(t, int64(100), store.Used())
+		_, err = backend.GetPublicKeys(ctx, u2)
+		assert.Error(t, err, "rejected write must not reach the backend")
+	})
+
+	t.Run("Success - overwrite that does not grow usage is accepted at the limit", func(t *testing.T) {
+		// Arrange
+		store, err := budget.New(ctx, inmemory.New(), 100, newTestLogger())
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, u1, keysOfSize(100)))
+		replacement := keysOfSize(100)
+		replacement.EncKey[0] = 1
+
+		// Act
+		err = store.StorePublicKeys(ctx, u1, replacement)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), store.Used())
+	})
+
+	t.Run("Success - shrinking a record frees space for another", func(t *testing.T) {
+		// Arrange
+		store, err := budget.New(ctx, inmemory.New(), 200, newTestLogger())
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, u1, keysOfSize(150)))
+		require.ErrorIs(t, store.StorePublicKeys(ctx, u2, keysOfSize(100)), budget.ErrExceeded)
+
+		// Act
+		require.NoError(t, store.StorePublicKeys(ctx, u1, keysOfSize(100)))
+		err = store.StorePublicKeys(ctx, u2, keysOfSize(100))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, int64(200), store.Used())
+	})
+
+	t.Run("Success - deleting a record frees its space", func(t *testing.T) {
+		// Arrange
+		store, err := budget.New(ctx, inmemory.New(), 150, newTestLogger())
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, u1, keysOfSize(100)))
+		require.ErrorIs(t, store.StorePublicKeys(ctx, u2, keysOfSize(100)), budget.ErrExceeded)
+
+		// Act
+		deleted, err := store.DeletePublicKeys(ctx, u1)
+		require.NoError(t, err)
+		err = store.StorePublicKeys(ctx, u2, keysOfSize(100))
+
+		// Assert
+		assert.True(t, deleted)
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), store.Used())
+	})
+
+	t.Run("Success - usage is seeded from the backend", func(t *testing.T) {
+		// Arrange
+		backend := inmemory.New()
+		require.NoError(t, backend.StorePublicKeys(ctx, u1, keysOfSize(100)))
+
+		// Act
+		store, err := budget.New(ctx, backend, 150, newTestLogger())
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, int64(100), store.Used())
+		assert.ErrorIs(t, store.StorePublicKeys(ctx, u2, keysOfSize(100)), budget.ErrExceeded)
+	})
+
+	t.Run("Success - get-or-create of existing keys uses no budget", func(t *testing.T) {
+		// Arrange
+		store, err := budget.New(ctx, inmemory.New(), 150, newTestLogger())
+		require.NoError(t, err)
+		_, created, err := store.GetOrCreatePublicKeys(ctx, u1, keysOfSize(100))
+		require.NoError(t, err)
+		require.True(t, created)
+
+		// Act
+		_, created, err = store.GetOrCreatePublicKeys(ctx, u1, keysOfSize(40))
+
+		// Assert
+		require.NoError(t, err)
+		assert.False(t, created)
+		assert.Equal(t, int64(100), store.Used())
+	})
+}
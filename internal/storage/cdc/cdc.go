@@ -0,0 +1,350 @@
+// --- File: internal/storage/cdc/cdc.go ---
+// Package cdc provides a keystore.Store wrapper that emits a
+// change-data-capture record for every write, for downstream data
+// pipelines. Each record carries before- and after-images of the entity
+// with the key bytes redacted to a fingerprint and lengths.
+package cdc
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// emitTimeout bounds how long a write waits for its record to be accepted.
+const emitTimeout = 5 * time.Second
+
+// Operation names the kind of change a Record describes.
+type Operation string
+
+const (
+	// OperationCreate is the first store of an entity's keys.
+	OperationCreate Operation = "create"
+	// OperationUpdate replaces an entity's keys or metadata, or changes
+	// its compromised flag.
+	OperationUpdate Operation = "update"
+	// OperationRotate swaps an entity's keys or advances its epoch.
+	OperationRotate Operation = "rotate"
+	// OperationDelete removes an entity.
+	OperationDelete Operation = "delete"
+)
+
+// Record is one change to one entity.
+type Record struct {
+	ID        string    `json:"id"`
+	Operation Operation `json:"op"`
+	URN       string    `json:"urn"`
+	// Actor is the user the change was made for, when known.
+	Actor string    `json:"actor,omitempty"`
+	Time  time.Time `json:"ts"`
+	// Before is the entity as it was, nil if it did not exist. After is
+	// the entity as written, nil once deleted.
+	Before *Image `json:"before"`
+	After  *Image `json:"after"`
+}
+
+// Image is an entity's state with the key bytes left out. Fingerprint
+// and the key lengths are enough to tell whether keys changed.
+type Image struct {
+	Fingerprint              string                     `json:"fingerprint"`
+	EncKeyBytes              int                        `json:"encKeyBytes"`
+	SigKeyBytes              int                        `json:"sigKeyBytes"`
+	RotationHint             time.Time                  `json:"rotationHint,omitzero"`
+	HasEncKeySignature       bool                       `json:"hasEncKeySignature,omitempty"`
+	HasProvisioningSignature bool                       `json:"hasProvisioningSignature,omitempty"`
+	UsageRestrictions        keystore.UsageRestrictions `json:"usageRestrictions,omitzero"`
+	Epoch                    uint64                     `json:"epoch,omitempty"`
+	Compromised              bool                       `json:"compromised,omitempty"`
+	CompromisedReason        string                     `json:"compromisedReason,omitempty"`
+}
+
+// newImage redacts record, or returns nil for no record.
+func newImage(record *keystore.KeyRecord) *Image {
+	if record == nil {
+		return nil
+	}
+	return &Image{
+		Fingerprint:              keystore.Fingerprint(record.Keys),
+		EncKeyBytes:              len(record.Keys.EncKey),
+		SigKeyBytes:              len(record.Keys.SigKey),
+		RotationHint:             record.Metadata.RotationHint,
+		HasEncKeySignature:       len(record.Metadata.EncKeySignature) > 0,
+		HasProvisioningSignature: len(record.Metadata.ProvisioningSignature) > 0,
+		UsageRestrictions:        record.Metadata.UsageRestrictions,
+		Epoch:                    record.Epoch,
+		Compromised:              record.Compromised,
+		CompromisedReason:        record.CompromisedReason,
+	}
+}
+
+// Store wraps a keystore.Store and emits a Record after every successful
+// write. Emitting is best effort: a failure is logged and never fails the
+// write, which has already been committed.
+//
+// When the wrapped store is a keystore.Transactor, key stores read the
+// before-image in the same transaction as the write. Other writes, and
+// every write to a store that is not a Transactor, read it just before
+// writing, so a concurrent write in between can make it stale. As
+// elsewhere in the service, a failed read is taken to mean the entity
+// did not exist. Wrap the backend directly so the Transactor is visible.
+type Store struct {
+	keystore.Store
+	sink   Sink
+	logger *slog.Logger
+	now    func() time.Time
+	actor  func(ctx context.Context) string
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithActor sets how the acting user is read from a write's context.
+// Without it records carry no actor.
+func WithActor(actor func(ctx context.Context) string) Option {
+	return func(s *Store) {
+		s.actor = actor
+	}
+}
+
+// WithClock makes the store stamp records, and judge DeleteOlderThan's
+// cutoff, with now instead of time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(s *Store) {
+		s.now = now
+	}
+}
+
+// New wraps next, emitting change records to sink.
+func New(next keystore.Store, sink Sink, logger *slog.Logger, opts ...Option) *Store {
+	s := &Store{
+		Store:  next,
+		sink:   sink,
+		logger: logger.With("component", "cdc"),
+		now:    time.Now,
+		actor:  func(context.Context) string { return "" },
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// StorePublicKeys stores the keys with no metadata, then emits a create
+// or update record.
+func (s *Store) StorePublicKeys(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys) error {
+	return s.StorePublicKeysWithMetadata(ctx, entityURN, pk, keystore.Metadata{})
+}
+
+// StorePublicKeysWithMetadata stores the keys, then emits a create or
+// update record.
+func (s *Store) StorePublicKeysWithMetadata(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys, meta keystore.Metadata) error {
+	before, err := s.store(ctx, entityURN, pk, meta, func() error {
+		return s.Store.StorePublicKeysWithMetadata(ctx, entityURN, pk, meta)
+	})
+	if err != nil {
+		return err
+	}
+	op := OperationCreate
+	if before != nil {
+		op = OperationUpdate
+	}
+	s.emit(ctx, op, entityURN, before, stored(before, pk, meta))
+	return nil
+}
+
+// GetOrCreatePublicKeys emits a create record only when the candidate
+// keys were actually created. The entity had no keys before, by definition.
+func (s *Store) GetOrCreatePublicKeys(ctx context.Context, entityURN urn.URN, candidate keys.PublicKeys) (keys.PublicKeys, bool, error) {
+	effective, created, err := s.Store.GetOrCreatePublicKeys(ctx, entityURN, candidate)
+	if err == nil && created {
+		s.emit(ctx, OperationCreate, entityURN, nil, stored(nil, candidate, keystore.Metadata{}))
+	}
+	return effective, created, err
+}
+
+// SwapPublicKeys swaps the keys, then emits a rotate record, or a create
+// record if the entity had no keys.
+func (s *Store) SwapPublicKeys(ctx context.Context, entityURN urn.URN, newKeys keys.PublicKeys) (keys.PublicKeys, error) {
+	var old keys.PublicKeys
+	swapped := false
+	before, err := s.store(ctx, entityURN, newKeys, keystore.Metadata{}, func() error {
+		var err error
+		old, err = s.Store.SwapPublicKeys(ctx, entityURN, newKeys)
+		swapped = true
+		return err
+	})
+	if err != nil {
+		return keys.PublicKeys{}, err
+	}
+	op := OperationCreate
+	if before != nil {
+		op = OperationRotate
+		// In a transaction the displaced keys are the before-image's.
+		if !swapped {
+			old = before.Keys
+		}
+	}
+	s.emit(ctx, op, entityURN, before, stored(before, newKeys, keystore.Metadata{}))
+	return old, nil
+}
+
+// BumpEpoch advances the epoch, then emits a rotate record.
+func (s *Store) BumpEpoch(ctx context.Context, entityURN urn.URN) (uint64, error) {
+	before := s.read(ctx, entityURN)
+	epoch, err := s.Store.BumpEpoch(ctx, entityURN)
+	if err != nil {
+		return 0, err
+	}
+	s.emit(ctx, OperationRotate, entityURN, before, changed(before, func(after *keystore.KeyRecord) {
+		after.Epoch = epoch
+	}))
+	return epoch, nil
+}
+
+// MarkCompromised flags the keys, then emits an update record.
+func (s *Store) MarkCompromised(ctx context.Context, entityURN urn.URN, reason string) error {
+	before := s.read(ctx, entityURN)
+	if err := s.Store.MarkCompromised(ctx, entityURN, reason); err != nil {
+		return err
+	}
+	s.emit(ctx, OperationUpdate, entityURN, before, changed(before, func(after *keystore.KeyRecord) {
+		after.Compromised, after.CompromisedReason = true, reason
+	}))
+	return nil
+}
+
+// ClearCompromised clears the flag, then emits an update record if it
+// was set.
+func (s *Store) ClearCompromised(ctx context.Context, entityURN urn.URN) error {
+	before := s.read(ctx, entityURN)
+	if err := s.Store.ClearCompromised(ctx, entityURN); err != nil {
+		return err
+	}
+	if before != nil && !before.Compromised {
+		return nil
+	}
+	s.emit(ctx, OperationUpdate, entityURN, before, changed(before, func(after *keystore.KeyRecord) {
+		after.Compromised, after.CompromisedReason = false, ""
+	}))
+	return nil
+}
+
+// DeletePublicKeys deletes the keys, then emits a delete record if there
+// were keys to delete.
+func (s *Store) DeletePublicKeys(ctx context.Context, entityURN urn.URN) (bool, error) {
+	before := s.read(ctx, entityURN)
+	deleted, err := s.Store.DeletePublicKeys(ctx, entityURN)
+	if err != nil || !deleted {
+		return deleted, err
+	}
+	s.emit(ctx, OperationDelete, entityURN, before, nil)
+	return true, nil
+}
+
+// DeleteOlderThan deletes the old entities, then emits a delete record
+// for each. The before-images are read first, judging age by this
+// store's clock; an entity the backend deletes by its own clock that
+// this one thought too young goes unrecorded. If the before-images
+// cannot be read nothing is deleted.
+func (s *Store) DeleteOlderThan(ctx context.Context, age time.Duration) (int, error) {
+	cutoff := s.now().Add(-age)
+	candidates := make(map[urn.URN]keystore.KeyRecord)
+	err := s.Store.IterateKeyRecords(ctx, func(entityURN urn.URN, record keystore.KeyRecord) error {
+		if record.UpdatedAt.Before(cutoff) {
+			candidates[entityURN] = record
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, &keystore.StoreError{Op: keystore.OpDeleteOlderThan, Err: err}
+	}
+
+	deleted, err := s.Store.DeleteOlderThan(ctx, age)
+	if deleted == 0 {
+		return deleted, err
+	}
+	for entityURN, record := range candidates {
+		if s.read(ctx, entityURN) == nil {
+			s.emit(ctx, OperationDelete, entityURN, &record, nil)
+		}
+	}
+	return deleted, err
+}
+
+// store runs write, which stores pk and meta, and returns the entity's
+// record from before it, or nil if there was none. With a Transactor the
+// read and the write share a transaction and write is not called.
+func (s *Store) store(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys, meta keystore.Metadata, write func() error) (*keystore.KeyRecord, error) {
+	transactor, ok := s.Store.(keystore.Transactor)
+	if !ok {
+		before := s.read(ctx, entityURN)
+		return before, write()
+	}
+	var before *keystore.KeyRecord
+	err := transactor.RunInTransaction(ctx, func(tx keystore.StoreTx) error {
+		// The transaction may be retried; only the last attempt counts.
+		before = nil
+		if record, err := tx.GetKeyRecord(entityURN); err == nil {
+			before = &record
+		}
+		return tx.StorePublicKeysWithMetadata(entityURN, pk, meta)
+	})
+	return before, err
+}
+
+// read returns the entity's current record, or nil if it has none.
+func (s *Store) read(ctx context.Context, entityURN urn.URN) *keystore.KeyRecord {
+	record, err := s.Store.GetKeyRecord(ctx, entityURN)
+	if err != nil {
+		return nil
+	}
+	return &record
+}
+
+// stored is the record a key store leaves: the new keys and metadata,
+// with the epoch kept and the compromised flag and access count cleared.
+func stored(before *keystore.KeyRecord, pk keys.PublicKeys, meta keystore.Metadata) *keystore.KeyRecord {
+	after := &keystore.KeyRecord{Keys: pk, Metadata: meta}
+	if before != nil {
+		after.Epoch = before.Epoch
+	}
+	return after
+}
+
+// changed applies change to a copy of before. Without a before-image the
+// after-image is unknown and nil is returned.
+func changed(before *keystore.KeyRecord, change func(after *keystore.KeyRecord)) *keystore.KeyRecord {
+	if before == nil {
+		return nil
+	}
+	after := *before
+	change(&after)
+	return &after
+}
+
+// emit sends a record of one change to the sink.
+func (s *Store) emit(ctx context.Context, op Operation, entityURN urn.URN, before, after *keystore.KeyRecord) {
+	record := Record{
+		ID:        uuid.NewString(),
+		Operation: op,
+		URN:       entityURN.String(),
+		Actor:     s.actor(ctx),
+		Time:      s.now().UTC(),
+		Before:    newImage(before),
+		After:     newImage(after),
+	}
+	// The write has landed, so the record should go out even if the
+	// client has since disconnected.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), emitTimeout)
+	defer cancel()
+	if err := s.sink.Emit(ctx, record); err != nil {
+		s.logger.Warn("Failed to emit change record", "op", op, "entity_urn", record.URN, "err", err)
+		return
+	}
+	s.logger.Debug("Emitted change record", "op", op, "entity_urn", record.URN, "record_id", record.ID)
+}
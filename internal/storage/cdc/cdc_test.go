@@ -0,0 +1,253 @@
+// --- File: internal/storage/cdc/cdc_test.go ---
+package cdc_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/storage/cdc"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// recordingSink captures emitted records, failing with err if set.
+type recordingSink struct {
+	mu      sync.Mutex
+	err     error
+	records []cdc.Record
+}
+
+func (s *recordingSink) Emit(_ context.Context, record cdc.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.records = append(s.records, record)
+	return nil
+}
+
+// plainStore hides the backend's Transactor, so the wrapper falls back to
+// reading before it writes.
+type plainStore struct {
+	keystore.Store
+}
+
+type actorKey struct{}
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestStore_EmitsChangeRecords(t *testing.T) {
+	ctx := context.WithValue(context.Background(), actorKey{}, "alice")
+	entityURN, err := urn.Parse("urn:sm:user:captured")
+	require.NoError(t, err)
+	oldKeys := keys.PublicKeys{EncKey: []byte("enc-1"), SigKey: []byte("sig-1")}
+	newKeys := keys.PublicKeys{EncKey: []byte("enc-2"), SigKey: []byte("sig-2")}
+	withActor := cdc.WithActor(func(ctx context.Context) string {
+		actor, _ := ctx.Value(actorKey{}).(string)
+		return actor
+	})
+
+	t.Run("Success - create has no before-image", func(t *testing.T) {
+		// Arrange
+		sink := &recordingSink{}
+		store := cdc.New(inmemory.New(), sink, newTestLogger(), withActor)
+
+		// Act
+		err := store.StorePublicKeysWithMetadata(ctx, entityURN, oldKeys, keystore.Metadata{EncKeySignature: []byte("binding")})
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, sink.records, 1)
+		record := sink.records[0]
+		assert.Equal(t, cdc.OperationCreate, record.Operation)
+		assert.Equal(t, entityURN.String(), record.URN)
+		assert.Equal(t, "alice", record.Actor)
+		assert.NotEmpty(t, record.ID)
+		assert.Nil(t, record.Before)
+		require.NotNil(t, record.After)
+		assert.Equal(t, keystore.Fingerprint(oldKeys), record.After.Fingerprint)
+		assert.Equal(t, len(oldKeys.EncKey), record.After.EncKeyBytes)
+		assert.True(t, record.After.HasEncKeySignature)
+	})
+
+	t.Run("Success - update carries the prior state as its before-image", func(t *testing.T) {
+		// Arrange
+		backend := inmemory.New()
+		require.NoError(t, backend.StorePublicKeys(ctx, entityURN, oldKeys))
+		_, err := backend.BumpEpoch(ctx, entityURN)
+		require.NoError(t, err)
+		require.NoError(t, backend.MarkCompromised(ctx, entityURN, "leaked"))
+		sink := &recordingSink{}
+		store := cdc.New(backend, sink, newTestLogger())
+
+		// Act
+		err = store.StorePublicKeys(ctx, entityURN, newKeys)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, sink.records, 1)
+		record := sink.records[0]
+		assert.Equal(t, cdc.OperationUpdate, record.Operation)
+		require.NotNil(t, record.Before)
+		assert.Equal(t, keystore.Fingerprint(oldKeys), record.Before.Fingerprint)
+		assert.EqualValues(t, 1, record.Before.Epoch)
+		assert.True(t, record.Before.Compromised)
+		assert.Equal(t, "leaked", record.Before.CompromisedReason)
+		require.NotNil(t, record.After)
+		assert.Equal(t, keystore.Fingerprint(newKeys), record.After.Fingerprint)
+		assert.EqualValues(t, 1, record.After.Epoch, "storing keys keeps the epoch")
+		assert.False(t, record.After.Compromised, "storing keys clears the flag")
+		stored, err := backend.GetPublicKeys(ctx, entityURN)
+		require.NoError(t, err)
+		assert.Equal(t, newKeys, stored)
+	})
+
+	t.Run("Success - update without transactions reads the before-image first", func(t *testing.T) {
+		// Arrange
+		backend := inmemory.New()
+		require.NoError(t, backend.StorePublicKeys(ctx, entityURN, oldKeys))
+		sink := &recordingSink{}
+		store := cdc.New(plainStore{backend}, sink, newTestLogger())
+
+		// Act
+		old, err := store.SwapPublicKeys(ctx, entityURN, newKeys)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, oldKeys, old)
+		require.Len(t, sink.records, 1)
+		record := sink.records[0]
+		assert.Equal(t, cdc.OperationRotate, record.Operation)
+		require.NotNil(t, record.Before)
+		assert.Equal(t, keystore.Fingerprint(oldKeys), record.Before.Fingerprint)
+		assert.Equal(t, keystore.Fingerprint(newKeys), record.After.Fingerprint)
+	})
+
+	t.Run("Success - delete has no after-image", func(t *testing.T) {
+		// Arrange
+		start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		now := start.Add(-48 * time.Hour)
+		clock := func() time.Time { return now }
+		backend := inmemory.New(inmemory.WithClock(clock))
+		require.NoError(t, backend.StorePublicKeys(ctx, entityURN, oldKeys))
+		now = start
+		sink := &recordingSink{}
+		store := cdc.New(backend, sink, newTestLogger(), cdc.WithClock(clock))
+
+		// Act
+		deleted, err := store.DeleteOlderThan(ctx, 24*time.Hour)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+		require.Len(t, sink.records, 1)
+		record := sink.records[0]
+		assert.Equal(t, cdc.OperationDelete, record.Operation)
+		require.NotNil(t, record.Before)
+		assert.Equal(t, keystore.Fingerprint(oldKeys), record.Before.Fingerprint)
+		assert.Nil(t, record.After)
+	})
+
+	t.Run("Success - deleting keys emits a delete record", func(t *testing.T) {
+		// Arrange
+		backend := inmemory.New()
+		require.NoError(t, backend.StorePublicKeys(ctx, entityURN, oldKeys))
+		sink := &recordingSink{}
+		store := cdc.New(backend, sink, newTestLogger(), withActor)
+
+		// Act
+		deleted, err := store.DeletePublicKeys(ctx, entityURN)
+		require.NoError(t, err)
+		again, err := store.DeletePublicKeys(ctx, entityURN)
+		require.NoError(t, err)
+
+		// Assert
+		assert.True(t, deleted)
+		assert.False(t, again)
+		require.Len(t, sink.records, 1, "deleting nothing is not a change")
+		record := sink.records[0]
+		assert.Equal(t, cdc.OperationDelete, record.Operation)
+		assert.Equal(t, "alice", record.Actor)
+		require.NotNil(t, record.Before)
+		assert.Equal(t, keystore.Fingerprint(oldKeys), record.Before.Fingerprint)
+		assert.Nil(t, record.After)
+	})
+
+	t.Run("Success - sink failure does not fail the write", func(t *testing.T) {
+		// Arrange
+		backend := inmemory.New()
+		store := cdc.New(backend, &recordingSink{err: errors.New("sink unavailable")}, newTestLogger())
+
+		// Act
+		err := store.StorePublicKeys(ctx, entityURN, oldKeys)
+
+		// Assert
+		require.NoError(t, err)
+		stored, err := backend.GetPublicKeys(ctx, entityURN)
+		require.NoError(t, err)
+		assert.Equal(t, oldKeys, stored)
+	})
+
+	t.Run("Success - no record for writes that change nothing", func(t *testing.T) {
+		// Arrange
+		backend := inmemory.New()
+		require.NoError(t, backend.StorePublicKeys(ctx, entityURN, oldKeys))
+		sink := &recordingSink{}
+		store := cdc.New(backend, sink, newTestLogger())
+
+		// Act
+		_, created, err := store.GetOrCreatePublicKeys(ctx, entityURN, newKeys)
+		require.NoError(t, err)
+		require.False(t, created)
+		require.NoError(t, store.ClearCompromised(ctx, entityURN))
+
+		// Assert
+		assert.Empty(t, sink.records)
+	})
+}
+
+func TestFileSink(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "cdc.jsonl")
+	sink, err := cdc.NewFileSink(path)
+	require.NoError(t, err)
+	entityURN, err := urn.Parse("urn:sm:user:filed")
+	require.NoError(t, err)
+	store := cdc.New(inmemory.New(), sink, newTestLogger())
+
+	// Act
+	require.NoError(t, store.StorePublicKeys(context.Background(), entityURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+	require.NoError(t, store.StorePublicKeys(context.Background(), entityURN, keys.PublicKeys{EncKey: []byte("enc-2"), SigKey: []byte("sig")}))
+	require.NoError(t, sink.Close())
+
+	// Assert
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	var ops []cdc.Operation
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record cdc.Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		assert.NotContains(t, scanner.Text(), `"ZW5j`, "key bytes must not be written")
+		ops = append(ops, record.Operation)
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, []cdc.Operation{cdc.OperationCreate, cdc.OperationUpdate}, ops)
+}
@@ -0,0 +1,43 @@
+// --- File: internal/storage/cdc/pubsub.go ---
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+
+	"cloud.google.com/go/pubsub/v2"
+)
+
+// PubSubSink publishes each record to a Pub/Sub topic as JSON, with the
+// operation and URN also set as attributes for subscription filters.
+type PubSubSink struct {
+	publisher *pubsub.Publisher
+}
+
+// NewPubSubSink creates a sink for the given topic ID or full topic name.
+// Call Stop on shutdown to flush pending messages.
+func NewPubSubSink(client *pubsub.Client, topic string) *PubSubSink {
+	return &PubSubSink{publisher: client.Publisher(topic)}
+}
+
+// Emit publishes record and waits until Pub/Sub has accepted it.
+func (s *PubSubSink) Emit(ctx context.Context, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	msg := &pubsub.Message{
+		Data: data,
+		Attributes: map[string]string{
+			"op":  string(record.Operation),
+			"urn": record.URN,
+		},
+	}
+	_, err = s.publisher.Publish(ctx, msg).Get(ctx)
+	return err
+}
+
+// Stop flushes pending messages and stops the underlying publisher.
+func (s *PubSubSink) Stop() {
+	s.publisher.Stop()
+}
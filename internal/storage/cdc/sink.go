@@ -0,0 +1,57 @@
+// --- File: internal/storage/cdc/sink.go ---
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Sink receives change records.
+type Sink interface {
+	Emit(ctx context.Context, record Record) error
+}
+
+// SinkFunc adapts a function to a Sink, for callers that handle records
+// in process.
+type SinkFunc func(ctx context.Context, record Record) error
+
+// Emit calls f.
+func (f SinkFunc) Emit(ctx context.Context, record Record) error {
+	return f(ctx, record)
+}
+
+// FileSink appends each record to a file as one line of JSON.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens path for appending, creating it if needed.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CDC file: %w", err)
+	}
+	return &FileSink{file: file}, nil
+}
+
+// Emit writes record as a single line. Lines from concurrent writes are
+// never interleaved.
+func (s *FileSink) Emit(_ context.Context, record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
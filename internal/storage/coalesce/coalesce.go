@@ -0,0 +1,68 @@
+// --- File: internal/storage/coalesce/coalesce.go ---
+// Package coalesce provides a keystore.Store wrapper that merges concurrent
+// identical reads into a single backend call.
+package coalesce
+
+import (
+	"context"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// Store wraps a keystore.Store so that concurrent GetPublicKeys or
+// GetKeyRecord calls for the same entity share one backend read. This
+// flattens the load spike when many clients miss their caches for a
+// popular entity at once. Writes and other reads pass straight through.
+//
+// Callers of a shared read receive the same key slices and must treat
+// them as read-only.
+type Store struct {
+	keystore.Store
+
+	group singleflight.Group
+}
+
+// New wraps next, coalescing concurrent reads of the same entity.
+func New(next keystore.Store) *Store {
+	return &Store{Store: next}
+}
+
+// GetPublicKeys reads from the wrapped store, sharing the read with any
+// concurrent caller asking for the same entity.
+func (s *Store) GetPublicKeys(ctx context.Context, entityURN urn.URN) (keys.PublicKeys, error) {
+	v, err := s.do(ctx, keystore.OpGetPublicKeys, entityURN, func(ctx context.Context) (any, error) {
+		return s.Store.GetPublicKeys(ctx, entityURN)
+	})
+	pk, _ := v.(keys.PublicKeys)
+	return pk, err
+}
+
+// GetKeyRecord reads from the wrapped store, sharing the read with any
+// concurrent caller asking for the same entity.
+func (s *Store) GetKeyRecord(ctx context.Context, entityURN urn.URN) (keystore.KeyRecord, error) {
+	v, err := s.do(ctx, keystore.OpGetKeyRecord, entityURN, func(ctx context.Context) (any, error) {
+		return s.Store.GetKeyRecord(ctx, entityURN)
+	})
+	record, _ := v.(keystore.KeyRecord)
+	return record, err
+}
+
+// do runs read once per op and entity among concurrent callers. The shared
+// read is detached from any one caller's cancellation, so a caller that
+// gives up does not fail the others; it simply stops waiting.
+func (s *Store) do(ctx context.Context, op string, entityURN urn.URN, read func(context.Context) (any, error)) (any, error) {
+	shared := context.WithoutCancel(ctx)
+	ch := s.group.DoChan(op+" "+entityURN.String(), func() (any, error) {
+		return read(shared)
+	})
+	select {
+	case res := <-ch:
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, &keystore.StoreError{Op: op, URN: entityURN, Err: ctx.Err()}
+	}
+}
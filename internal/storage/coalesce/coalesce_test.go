@@ -0,0 +1,100 @@
+// --- File: internal/storage/coalesce/coalesce_test.go ---
+package coalesce_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/storage/coalesce"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// gatedStore counts reads and holds each one until release is closed.
+type gatedStore struct {
+	keystore.Store
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (s *gatedStore) GetPublicKeys(ctx context.Context, entityURN urn.URN) (keys.PublicKeys, error) {
+	s.calls.Add(1)
+	<-s.release
+	return s.Store.GetPublicKeys(ctx, entityURN)
+}
+
+func TestStore_GetPublicKeys(t *testing.T) {
+	ctx := context.Background()
+	entityURN, err := urn.Parse("urn:sm:user:popular")
+	require.NoError(t, err)
+	stored := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+
+	t.Run("Success - concurrent gets share one backend read", func(t *testing.T) {
+		// Arrange
+		backend := &gatedStore{Store: inmemory.New(), release: make(chan struct{})}
+		require.NoError(t, backend.StorePublicKeys(ctx, entityURN, stored))
+		store := coalesce.New(backend)
+		const callers = 50
+
+		// Act
+		var wg sync.WaitGroup
+		results := make(chan keys.PublicKeys, callers)
+		for range callers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				pk, err := store.GetPublicKeys(ctx, entityURN)
+				assert.NoError(t, err)
+				results <- pk
+			}()
+		}
+		// Give every caller time to join the in-flight read before it completes.
+		require.Eventually(t, func() bool { return backend.calls.Load() == 1 }, time.Second, time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		close(backend.release)
+		wg.Wait()
+		close(results)
+
+		// Assert
+		assert.EqualValues(t, 1, backend.calls.Load())
+		for pk := range results {
+			assert.Equal(t, stored, pk)
+		}
+	})
+
+	t.Run("Failure - cancelled caller stops waiting without failing others", func(t *testing.T) {
+		// Arrange
+		backend := &gatedStore{Store: inmemory.New(), release: make(chan struct{})}
+		require.NoError(t, backend.StorePublicKeys(ctx, entityURN, stored))
+		store := coalesce.New(backend)
+		cancelCtx, cancel := context.WithCancel(ctx)
+
+		first := make(chan error, 1)
+		go func() {
+			_, err := store.GetPublicKeys(cancelCtx, entityURN)
+			first <- err
+		}()
+		require.Eventually(t, func() bool { return backend.calls.Load() == 1 }, time.Second, time.Millisecond)
+		second := make(chan error, 1)
+		go func() {
+			_, err := store.GetPublicKeys(ctx, entityURN)
+			second <- err
+		}()
+
+		// Act
+		cancel()
+
+		// Assert
+		assert.ErrorIs(t, <-first, context.Canceled)
+		close(backend.release)
+		assert.NoError(t, <-second)
+	})
+}
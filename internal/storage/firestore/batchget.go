@@ -0,0 +1,61 @@
+// --- File: internal/storage/firestore/batchget.go ---
+package firestore
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// GetPublicKeysBatch reads every entity in one batched get rather than a
+// round trip each. Like GetFingerprintsBatch, it leaves out URNs that
+// cannot be document IDs, and documents that hold no keys, as absent.
+func (s *Store) GetPublicKeysBatch(ctx context.Context, entityURNs []urn.URN) (map[string]keystore.KeyRecord, error) {
+	refs := make([]*firestore.DocumentRef, 0, len(entityURNs))
+	seen := make(map[string]bool, len(entityURNs))
+	for _, entityURN := range entityURNs {
+		entityKey := entityURN.String()
+		if seen[entityKey] || validateDocumentID(entityKey) != nil {
+			continue
+		}
+		seen[entityKey] = true
+		refs = append(refs, s.collection.Doc(entityKey))
+	}
+	records := make(map[string]keystore.KeyRecord, len(refs))
+	if len(refs) == 0 {
+		return records, nil
+	}
+
+	if err := s.sem.acquire(ctx); err != nil {
+		return nil, &keystore.StoreError{Op: keystore.OpGetPublicKeysBatch, Err: err}
+	}
+	snaps, err := s.client.GetAll(ctx, refs)
+	s.sem.release()
+	if err != nil {
+		s.logger.Error("Failed to get key documents", "err", err)
+		return nil, &keystore.StoreError{
+			Op:  keystore.OpGetPublicKeysBatch,
+			Err: fmt.Errorf("failed to get key documents: %w", err),
+		}
+	}
+
+	for _, snap := range snaps {
+		if !snap.Exists() {
+			continue
+		}
+		kDoc, err := decodeKeyDocument(snap)
+		if err != nil || (kDoc.EncKey == nil && kDoc.SigKey == nil) {
+			s.logger.Warn("Skipping undecodable key document", "key", snap.Ref.ID, "err", err)
+			continue
+		}
+		records[snap.Ref.ID] = kDoc.keyRecord()
+		if s.countAccess {
+			go s.incrementAccessCount(context.WithoutCancel(ctx), snap.Ref)
+		}
+	}
+	return records, nil
+}
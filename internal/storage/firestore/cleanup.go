@@ -0,0 +1,81 @@
+// --- File: internal/storage/firestore/cleanup.go ---
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+)
+
+// DeleteOlderThan queries for documents whose updatedAt is before the
+// cutoff, selecting no fields, and deletes them with a BulkWriter. Each
+// delete is conditioned on the document's update time as the query saw
+// it, so keys stored again after the query are kept rather than removed;
+// those are not counted and are not an error. Version 1 documents with no
+// updatedAt field never match the query and are kept.
+func (s *Store) DeleteOlderThan(ctx context.Context, age time.Duration) (int, error) {
+	cutoff := time.Now().UTC().Add(-age)
+	s.logger.Debug("Deleting old keys", "cutoff", cutoff)
+
+	if err := s.sem.acquire(ctx); err != nil {
+		return 0, &keystore.StoreError{Op: keystore.OpDeleteOlderThan, Err: err}
+	}
+	defer s.sem.release()
+
+	iter := s.olderThanQuery(cutoff).Documents(ctx)
+	defer iter.Stop()
+
+	// Deletes already queued still run if the query fails part way, so
+	// the count is reported alongside the error.
+	bw := s.client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	var firstErr error
+	for {
+		doc, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		var job *firestore.BulkWriterJob
+		if err == nil {
+			job, err = bw.Delete(doc.Ref, firestore.LastUpdateTime(doc.UpdateTime))
+		}
+		if err != nil {
+			firstErr = fmt.Errorf("failed to query old key documents: %w", err)
+			break
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+
+	deleted := 0
+	for _, job := range jobs {
+		_, err := job.Results()
+		switch {
+		case err == nil:
+			deleted++
+		case status.Code(err) == codes.FailedPrecondition:
+			// Stored again since the query; no longer old.
+		case firstErr == nil:
+			firstErr = fmt.Errorf("failed to delete old key documents: %w", err)
+		}
+	}
+	if firstErr != nil {
+		s.logger.Error("Failed to delete some old keys", "deleted", deleted, "err", firstErr)
+		return deleted, &keystore.StoreError{Op: keystore.OpDeleteOlderThan, Err: firstErr}
+	}
+	s.logger.Info("Deleted old keys", "deleted", deleted, "cutoff", cutoff)
+	return deleted, nil
+}
+
+// olderThanQuery matches documents last stored before cutoff.
+func (s *Store) olderThanQuery(cutoff time.Time) firestore.Query {
+	return s.collection.Where("updatedAt", "<", cutoff).Select()
+}
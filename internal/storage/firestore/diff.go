@@ -0,0 +1,62 @@
+// --- File: internal/storage/firestore/diff.go ---
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// DiffPublicKeys reads both documents in one batched get, so they are
+// compared as of the same read time.
+func (s *Store) DiffPublicKeys(ctx context.Context, a, b urn.URN) (keystore.KeyDiff, error) {
+	entityURNs := []urn.URN{a, b}
+	refs := make([]*firestore.DocumentRef, 0, len(entityURNs))
+	for _, entityURN := range entityURNs {
+		ref, err := s.docRef(keystore.OpDiffPublicKeys, entityURN)
+		if err != nil {
+			return keystore.KeyDiff{}, err
+		}
+		refs = append(refs, ref)
+	}
+
+	if err := s.sem.acquire(ctx); err != nil {
+		return keystore.KeyDiff{}, &keystore.StoreError{Op: keystore.OpDiffPublicKeys, Err: err}
+	}
+	snaps, err := s.client.GetAll(ctx, refs)
+	s.sem.release()
+	if err != nil {
+		s.logger.Warn("Failed to get key documents", "err", err)
+		return keystore.KeyDiff{}, &keystore.StoreError{
+			Op:  keystore.OpDiffPublicKeys,
+			Err: fmt.Errorf("failed to get key documents: %w", err),
+		}
+	}
+
+	docs := make([]KeyDocument, len(snaps))
+	for i, snap := range snaps {
+		if !snap.Exists() {
+			s.logger.Debug("Keys not found", "key", entityURNs[i].String())
+			return keystore.KeyDiff{}, &keystore.StoreError{
+				Op:  keystore.OpDiffPublicKeys,
+				URN: entityURNs[i],
+				Err: keystore.ErrKeyNotFound,
+			}
+		}
+		kDoc, err := decodeKeyDocument(snap)
+		if err != nil || (kDoc.EncKey == nil && kDoc.SigKey == nil) {
+			return keystore.KeyDiff{}, &keystore.StoreError{
+				Op:  keystore.OpDiffPublicKeys,
+				URN: entityURNs[i],
+				Err: errors.New("failed to parse key document: unknown format"),
+			}
+		}
+		docs[i] = kDoc
+	}
+	return keystore.DiffKeys(docs[0].publicKeys(), docs[1].publicKeys()), nil
+}
@@ -0,0 +1,50 @@
+// --- File: internal/storage/firestore/documentid.go ---
+package firestore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// maxDocumentIDBytes is Firestore's limit on the size of a document ID.
+const maxDocumentIDBytes = 1500
+
+// ErrInvalidDocumentID is returned when an entity URN cannot be used as a
+// Firestore document ID. It is reported before any request is sent.
+var ErrInvalidDocumentID = errors.New("urn is not a valid firestore document id")
+
+// validateDocumentID checks id against Firestore's document ID constraints.
+func validateDocumentID(id string) error {
+	switch {
+	case id == "":
+		return fmt.Errorf("%w: empty", ErrInvalidDocumentID)
+	case len(id) > maxDocumentIDBytes:
+		return fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrInvalidDocumentID, len(id), maxDocumentIDBytes)
+	case strings.Contains(id, "/"):
+		return fmt.Errorf("%w: contains '/'", ErrInvalidDocumentID)
+	case id == "." || id == "..":
+		return fmt.Errorf("%w: %q is reserved", ErrInvalidDocumentID, id)
+	case len(id) >= 4 && strings.HasPrefix(id, "__") && strings.HasSuffix(id, "__"):
+		return fmt.Errorf("%w: names matching __.*__ are reserved", ErrInvalidDocumentID)
+	}
+	return nil
+}
+
+// docRef returns the document for an entity, or a StoreError for op if the
+// URN cannot be used as a document ID. Collection.Doc would otherwise
+// return nil for an ID containing '/', or Firestore would reject it with an
+// opaque InvalidArgument.
+func (s *Store) docRef(op string, entityURN urn.URN) (*firestore.DocumentRef, error) {
+	entityKey := entityURN.String()
+	if err := validateDocumentID(entityKey); err != nil {
+		s.logger.Warn("Rejected URN unusable as a document ID", "err", err)
+		return nil, &keystore.StoreError{Op: op, URN: entityURN, Err: err}
+	}
+	return s.collection.Doc(entityKey), nil
+}
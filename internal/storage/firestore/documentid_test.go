@@ -0,0 +1,71 @@
+// --- File: internal/storage/firestore/documentid_test.go ---
+package firestore
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func TestValidateDocumentID(t *testing.T) {
+	testCases := []struct {
+		name  string
+		id    string
+		valid bool
+	}{
+		{name: "canonical urn", id: "urn:sm:user:alice", valid: true},
+		{name: "limit-sized id", id: strings.Repeat("a", maxDocumentIDBytes), valid: true},
+		{name: "underscores inside", id: "urn:sm:user:__alice__", valid: true},
+		{name: "empty", id: ""},
+		{name: "over-long", id: strings.Repeat("a", maxDocumentIDBytes+1)},
+		{name: "slash", id: "urn:sm:user:a/b"},
+		{name: "dot", id: "."},
+		{name: "dot dot", id: ".."},
+		{name: "reserved pattern", id: "__alice__"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateDocumentID(tc.id)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidDocumentID)
+			}
+		})
+	}
+}
+
+func TestStore_RejectsInvalidDocumentIDs(t *testing.T) {
+	// No client: the guard must fail before Firestore is touched.
+	store := &Store{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	ctx := context.Background()
+	pk := keys.PublicKeys{EncKey: []byte{1}, SigKey: []byte{2}}
+
+	overLong, err := urn.Parse("urn:sm:user:" + strings.Repeat("a", maxDocumentIDBytes))
+	require.NoError(t, err)
+	slashed, err := urn.Parse("urn:sm:user:a/b")
+	require.NoError(t, err)
+
+	for _, entityURN := range []urn.URN{overLong, slashed} {
+		err := store.StorePublicKeys(ctx, entityURN, pk)
+		assert.ErrorIs(t, err, ErrInvalidDocumentID)
+		var storeErr *keystore.StoreError
+		require.ErrorAs(t, err, &storeErr)
+		assert.Equal(t, keystore.OpStorePublicKeys, storeErr.Op)
+
+		_, err = store.GetPublicKeys(ctx, entityURN)
+		assert.ErrorIs(t, err, ErrInvalidDocumentID)
+
+		_, _, err = store.GetOrCreatePublicKeys(ctx, entityURN, pk)
+		assert.ErrorIs(t, err, ErrInvalidDocumentID)
+	}
+}
@@ -0,0 +1,74 @@
+// --- File: internal/storage/firestore/fingerprints.go ---
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// maxDocumentIDsPerQuery is Firestore's limit on values in an "in" filter.
+const maxDocumentIDsPerQuery = 30
+
+// GetFingerprintsBatch reads the entities with document-ID "in" queries
+// of up to maxDocumentIDsPerQuery IDs each. The queries select only the
+// key fields, so metadata is never transferred. URNs that cannot be
+// document IDs cannot have keys, so they are left out like absent ones.
+func (s *Store) GetFingerprintsBatch(ctx context.Context, entityURNs []urn.URN) (map[string]string, error) {
+	refs := make([]*firestore.DocumentRef, 0, len(entityURNs))
+	seen := make(map[string]bool, len(entityURNs))
+	for _, entityURN := range entityURNs {
+		entityKey := entityURN.String()
+		if seen[entityKey] || validateDocumentID(entityKey) != nil {
+			continue
+		}
+		seen[entityKey] = true
+		refs = append(refs, s.collection.Doc(entityKey))
+	}
+
+	if err := s.sem.acquire(ctx); err != nil {
+		return nil, &keystore.StoreError{Op: keystore.OpGetFingerprintsBatch, Err: err}
+	}
+	defer s.sem.release()
+
+	fingerprints := make(map[string]string, len(refs))
+	for start := 0; start < len(refs); start += maxDocumentIDsPerQuery {
+		chunk := refs[start:min(start+maxDocumentIDsPerQuery, len(refs))]
+		iter := s.collection.Where(firestore.DocumentID, "in", chunk).Select("encKey", "sigKey").Documents(ctx)
+		err := collectFingerprints(iter, fingerprints)
+		iter.Stop()
+		if err != nil {
+			s.logger.Error("Failed to read key fingerprints", "err", err)
+			return nil, &keystore.StoreError{
+				Op:  keystore.OpGetFingerprintsBatch,
+				Err: fmt.Errorf("failed to read key documents: %w", err),
+			}
+		}
+	}
+	return fingerprints, nil
+}
+
+// collectFingerprints adds the fingerprint of every document in iter that
+// holds keys, keyed by document ID.
+func collectFingerprints(iter *firestore.DocumentIterator, fingerprints map[string]string) error {
+	for {
+		doc, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		kDoc, err := decodeKeyDocument(doc)
+		if err != nil || (kDoc.EncKey == nil && kDoc.SigKey == nil) {
+			continue
+		}
+		fingerprints[doc.Ref.ID] = keystore.Fingerprint(kDoc.publicKeys())
+	}
+}
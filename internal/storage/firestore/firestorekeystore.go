@@ -4,13 +4,17 @@ package firestore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
 	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
 	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
 )
@@ -18,8 +22,62 @@ import (
 // KeyDocument defines the Firestore schema for storing public keys.
 // This is an internal implementation detail of the firestore package.
 type KeyDocument struct {
-	EncKey []byte `firestore:"encKey"`
-	SigKey []byte `firestore:"sigKey"`
+	EncKey    []byte    `firestore:"encKey"`
+	SigKey    []byte    `firestore:"sigKey"`
+	UpdatedAt time.Time `firestore:"updatedAt"`
+	// RotationHint is the owner's advisory next-rotation time, if given.
+	RotationHint time.Time `firestore:"rotationHint,omitempty"`
+	// EncKeySignature binds EncKey to SigKey, if the owner supplied one.
+	EncKeySignature []byte `firestore:"encKeySignature,omitempty"`
+	// ProvisioningSignature vouches for the keys, if a provisioner signed them.
+	ProvisioningSignature []byte `firestore:"provisioningSignature,omitempty"`
+	// Usage, NotBefore and NotAfter are the owner's usage restrictions, if
+	// any were declared.
+	Usage     string    `firestore:"usage,omitempty"`
+	NotBefore time.Time `firestore:"notBefore,omitempty"`
+	NotAfter  time.Time `firestore:"notAfter,omitempty"`
+	// AccessCount is maintained by the store when access counting is on.
+	// Storing keys resets it.
+	AccessCount int64 `firestore:"accessCount,omitempty"`
+	// Epoch is the rotation epoch, advanced only by BumpEpoch. Storing
+	// keys leaves it unchanged.
+	Epoch int64 `firestore:"epoch,omitempty"`
+	// Compromised flags the keys as compromised, with CompromisedReason
+	// explaining why. Storing keys clears both.
+	Compromised       bool   `firestore:"compromised,omitempty"`
+	CompromisedReason string `firestore:"compromisedReason,omitempty"`
+	// SchemaVersion is the schema the document was last stored at; reads
+	// migrate older documents to the current one (see SchemaVersion).
+	SchemaVersion int64 `firestore:"schemaVersion,omitempty"`
+}
+
+// publicKeys converts the document back into the domain struct.
+func (d KeyDocument) publicKeys() keys.PublicKeys {
+	return keys.PublicKeys{
+		EncKey: d.EncKey,
+		SigKey: d.SigKey,
+	}
+}
+
+func (d KeyDocument) keyRecord() keystore.KeyRecord {
+	return keystore.KeyRecord{
+		Keys: d.publicKeys(),
+		Metadata: keystore.Metadata{
+			RotationHint:          d.RotationHint,
+			EncKeySignature:       d.EncKeySignature,
+			ProvisioningSignature: d.ProvisioningSignature,
+			UsageRestrictions: keystore.UsageRestrictions{
+				Usage:     keystore.KeyUsage(d.Usage),
+				NotBefore: d.NotBefore,
+				NotAfter:  d.NotAfter,
+			},
+		},
+		UpdatedAt:         d.UpdatedAt,
+		AccessCount:       d.AccessCount,
+		Epoch:             uint64(d.Epoch),
+		Compromised:       d.Compromised,
+		CompromisedReason: d.CompromisedReason,
+	}
 }
 
 // Store is a concrete implementation of the keyservice.Store interface using Firestore.
@@ -28,66 +86,532 @@ type Store struct {
 	client     *firestore.Client
 	collection *firestore.CollectionRef
 	logger     *slog.Logger
+	sem        *semaphore
+
+	countAccess bool
+}
+
+// Option configures optional behaviour of the Firestore store.
+type Option func(*Store)
+
+// WithMaxConcurrency limits the number of Firestore operations the store runs
+// at once. Callers beyond the limit block until a slot frees or their context
+// is done. A value of zero or less means no limit.
+func WithMaxConcurrency(max int) Option {
+	return func(s *Store) {
+		s.sem = newSemaphore(max)
+	}
+}
+
+// WithAccessCounting increments an entity's accessCount field after every
+// successful read of its keys. The increment is sent in the background so
+// it never delays or fails the read; it costs one extra write per read.
+func WithAccessCounting() Option {
+	return func(s *Store) {
+		s.countAccess = true
+	}
 }
 
 // NewFirestoreStore creates a new Firestore-backed store.
-func NewFirestoreStore(client *firestore.Client, collectionName string, logger *slog.Logger) *Store {
-	return &Store{
+func NewFirestoreStore(client *firestore.Client, collectionName string, logger *slog.Logger, opts ...Option) *Store {
+	s := &Store{
 		client:     client,
 		collection: client.Collection(collectionName),
 		logger:     logger.With("component", "firestore_store", "collection", collectionName),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // StorePublicKeys creates or overwrites a document in Firestore with the
 // provided PublicKeys struct. The document ID is the URN's string representation.
 func (s *Store) StorePublicKeys(ctx context.Context, entityURN urn.URN, keys keys.PublicKeys) error {
+	return s.storeKeyDocument(ctx, keystore.OpStorePublicKeys, entityURN, keys, keystore.Metadata{})
+}
+
+// StorePublicKeysWithMetadata creates or overwrites a document in Firestore
+// with the provided keys and metadata.
+func (s *Store) StorePublicKeysWithMetadata(ctx context.Context, entityURN urn.URN, keys keys.PublicKeys, meta keystore.Metadata) error {
+	return s.storeKeyDocument(ctx, keystore.OpStorePublicKeysWithMetadata, entityURN, keys, meta)
+}
+
+// storeKeyDocument rewrites the entity's document, keeping only its epoch.
+// Metadata not present in meta is cleared and the access count is reset.
+func (s *Store) storeKeyDocument(ctx context.Context, op string, entityURN urn.URN, keys keys.PublicKeys, meta keystore.Metadata) error {
 	entityKey := entityURN.String()
-	doc := s.collection.Doc(entityKey)
+	doc, err := s.docRef(op, entityURN)
+	if err != nil {
+		return err
+	}
 	s.logger.Debug("Storing keys", "key", entityKey)
 
-	docData := KeyDocument{
-		EncKey: keys.EncKey,
-		SigKey: keys.SigKey,
+	docData := keyDocumentData(keys, meta)
+
+	if err := s.sem.acquire(ctx); err != nil {
+		s.logger.Warn("Gave up waiting for a Firestore slot", "key", entityKey, "err", err)
+		return &keystore.StoreError{Op: op, URN: entityURN, Err: err}
 	}
+	defer s.sem.release()
 
-	_, err := doc.Set(ctx, docData)
+	_, err = doc.Set(ctx, docData, firestore.MergeAll)
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		// The caller went away. Set writes the document atomically, so
+		// the write either fully landed or not at all; a retry is always safe.
+		// gRPC reports this as its own status, so re-attach context.Canceled.
+		s.logger.Warn("Store keys cancelled by caller", "key", entityKey, "err", err)
+		return &keystore.StoreError{
+			Op:  op,
+			URN: entityURN,
+			Err: fmt.Errorf("store public keys cancelled: %w: %w", context.Canceled, err),
+		}
+	}
 	if err != nil {
 		s.logger.Error("Failed to store keys", "key", entityKey, "err", err)
-		return fmt.Errorf("failed to store public keys for entity %s: %w", entityKey, err)
+		return &keystore.StoreError{
+			Op:  op,
+			URN: entityURN,
+			Err: fmt.Errorf("failed to store public keys: %w", err),
+		}
 	}
 	s.logger.Debug("Successfully stored keys", "key", entityKey)
 	return nil
 }
 
+// keyDocumentData is the document data for a store, to be written with
+// firestore.MergeAll. Merging rather than replacing lets the epoch survive;
+// every other field is named so that anything not being written is deleted.
+func keyDocumentData(keys keys.PublicKeys, meta keystore.Metadata) map[string]any {
+	data := map[string]any{
+		"encKey":                keys.EncKey,
+		"sigKey":                keys.SigKey,
+		"updatedAt":             time.Now().UTC(),
+		"schemaVersion":         SchemaVersion,
+		"rotationHint":          firestore.Delete,
+		"encKeySignature":       firestore.Delete,
+		"provisioningSignature": firestore.Delete,
+		"usage":                 firestore.Delete,
+		"notBefore":             firestore.Delete,
+		"notAfter":              firestore.Delete,
+		"accessCount":           firestore.Delete,
+		// New keys are not known to be compromised.
+		"compromised":       firestore.Delete,
+		"compromisedReason": firestore.Delete,
+	}
+	if !meta.RotationHint.IsZero() {
+		data["rotationHint"] = meta.RotationHint
+	}
+	if len(meta.EncKeySignature) > 0 {
+		data["encKeySignature"] = meta.EncKeySignature
+	}
+	if len(meta.ProvisioningSignature) > 0 {
+		data["provisioningSignature"] = meta.ProvisioningSignature
+	}
+	usage := meta.UsageRestrictions
+	if usage.Usage != keystore.KeyUsageAny {
+		data["usage"] = string(usage.Usage)
+	}
+	if !usage.NotBefore.IsZero() {
+		data["notBefore"] = usage.NotBefore
+	}
+	if !usage.NotAfter.IsZero() {
+		data["notAfter"] = usage.NotAfter
+	}
+	return data
+}
+
+// GetOrCreatePublicKeys stores candidate only if the entity's document does
+// not exist yet, inside a transaction so concurrent first registrations
+// agree on a single winner. It returns whichever keys are in effect.
+func (s *Store) GetOrCreatePublicKeys(ctx context.Context, entityURN urn.URN, candidate keys.PublicKeys) (keys.PublicKeys, bool, error) {
+	entityKey := entityURN.String()
+	doc, err := s.docRef(keystore.OpGetOrCreatePublicKeys, entityURN)
+	if err != nil {
+		return keys.PublicKeys{}, false, err
+	}
+	s.logger.Debug("Getting or creating keys", "key", entityKey)
+
+	if err := s.sem.acquire(ctx); err != nil {
+		s.logger.Warn("Gave up waiting for a Firestore slot", "key", entityKey, "err", err)
+		return keys.PublicKeys{}, false, &keystore.StoreError{Op: keystore.OpGetOrCreatePublicKeys, URN: entityURN, Err: err}
+	}
+	defer s.sem.release()
+
+	var effective keys.PublicKeys
+	var created bool
+	err = s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// The function may be retried, so reset the results on every attempt.
+		effective, created = keys.PublicKeys{}, false
+
+		snap, err := tx.Get(doc)
+		if status.Code(err) == codes.NotFound {
+			created = true
+			effective = candidate
+			return tx.Create(doc, KeyDocument{
+				EncKey:        candidate.EncKey,
+				SigKey:        candidate.SigKey,
+				UpdatedAt:     time.Now().UTC(),
+				SchemaVersion: SchemaVersion,
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		kDoc, err := decodeKeyDocument(snap)
+		if err != nil {
+			return fmt.Errorf("failed to parse key document: %w", err)
+		}
+		effective = kDoc.publicKeys()
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to get or create keys", "key", entityKey, "err", err)
+		return keys.PublicKeys{}, false, &keystore.StoreError{
+			Op:  keystore.OpGetOrCreatePublicKeys,
+			URN: entityURN,
+			Err: fmt.Errorf("get-or-create transaction failed: %w", err),
+		}
+	}
+	s.logger.Debug("Got or created keys", "key", entityKey, "created", created)
+	return effective, created, nil
+}
+
+// SwapPublicKeys reads the entity's document and rewrites it with newKeys
+// in one transaction, so no other write can land between the two and the
+// keys returned are exactly the ones replaced.
+func (s *Store) SwapPublicKeys(ctx context.Context, entityURN urn.URN, newKeys keys.PublicKeys) (keys.PublicKeys, error) {
+	entityKey := entityURN.String()
+	doc, err := s.docRef(keystore.OpSwapPublicKeys, entityURN)
+	if err != nil {
+		return keys.PublicKeys{}, err
+	}
+	s.logger.Debug("Swapping keys", "key", entityKey)
+
+	if err := s.sem.acquire(ctx); err != nil {
+		s.logger.Warn("Gave up waiting for a Firestore slot", "key", entityKey, "err", err)
+		return keys.PublicKeys{}, &keystore.StoreError{Op: keystore.OpSwapPublicKeys, URN: entityURN, Err: err}
+	}
+	defer s.sem.release()
+
+	var old keys.PublicKeys
+	err = s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// The function may be retried, so reset the result on every attempt.
+		old = keys.PublicKeys{}
+
+		snap, err := tx.Get(doc)
+		switch {
+		case status.Code(err) == codes.NotFound:
+		case err != nil:
+			return err
+		default:
+			kDoc, err := decodeKeyDocument(snap)
+			if err != nil {
+				return fmt.Errorf("failed to parse key document: %w", err)
+			}
+			old = kDoc.publicKeys()
+		}
+		return tx.Set(doc, keyDocumentData(newKeys, keystore.Metadata{}), firestore.MergeAll)
+	})
+	if err != nil {
+		s.logger.Error("Failed to swap keys", "key", entityKey, "err", err)
+		return keys.PublicKeys{}, &keystore.StoreError{
+			Op:  keystore.OpSwapPublicKeys,
+			URN: entityURN,
+			Err: fmt.Errorf("swap transaction failed: %w", err),
+		}
+	}
+	s.logger.Debug("Swapped keys", "key", entityKey)
+	return old, nil
+}
+
+// BumpEpoch increments the entity's epoch in a transaction, so concurrent
+// bumps each return a distinct value. It does not touch updatedAt.
+func (s *Store) BumpEpoch(ctx context.Context, entityURN urn.URN) (uint64, error) {
+	entityKey := entityURN.String()
+	doc, err := s.docRef(keystore.OpBumpEpoch, entityURN)
+	if err != nil {
+		return 0, err
+	}
+	s.logger.Debug("Bumping epoch", "key", entityKey)
+
+	if err := s.sem.acquire(ctx); err != nil {
+		s.logger.Warn("Gave up waiting for a Firestore slot", "key", entityKey, "err", err)
+		return 0, &keystore.StoreError{Op: keystore.OpBumpEpoch, URN: entityURN, Err: err}
+	}
+	defer s.sem.release()
+
+	var epoch int64
+	err = s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(doc)
+		if err != nil {
+			return err
+		}
+		kDoc, err := decodeKeyDocument(snap)
+		if err != nil {
+			return fmt.Errorf("failed to parse key document: %w", err)
+		}
+		epoch = kDoc.Epoch + 1
+		return tx.Update(doc, []firestore.Update{{Path: "epoch", Value: epoch}})
+	})
+	if status.Code(err) == codes.NotFound {
+		s.logger.Debug("Keys not found", "key", entityKey)
+		return 0, &keystore.StoreError{Op: keystore.OpBumpEpoch, URN: entityURN, Err: keystore.ErrKeyNotFound}
+	}
+	if err != nil {
+		s.logger.Error("Failed to bump epoch", "key", entityKey, "err", err)
+		return 0, &keystore.StoreError{
+			Op:  keystore.OpBumpEpoch,
+			URN: entityURN,
+			Err: fmt.Errorf("bump epoch transaction failed: %w", err),
+		}
+	}
+	s.logger.Debug("Bumped epoch", "key", entityKey, "epoch", epoch)
+	return uint64(epoch), nil
+}
+
+// MarkCompromised sets the compromised flag and reason in a single update.
+// The update requires the document to exist, so a missing entity is not
+// created.
+func (s *Store) MarkCompromised(ctx context.Context, entityURN urn.URN, reason string) error {
+	return s.updateCompromised(ctx, keystore.OpMarkCompromised, entityURN, []firestore.Update{
+		{Path: "compromised", Value: true},
+		{Path: "compromisedReason", Value: reason},
+	})
+}
+
+// ClearCompromised deletes the compromised flag and reason in a single update.
+func (s *Store) ClearCompromised(ctx context.Context, entityURN urn.URN) error {
+	return s.updateCompromised(ctx, keystore.OpClearCompromised, entityURN, []firestore.Update{
+		{Path: "compromised", Value: firestore.Delete},
+		{Path: "compromisedReason", Value: firestore.Delete},
+	})
+}
+
+func (s *Store) updateCompromised(ctx context.Context, op string, entityURN urn.URN, updates []firestore.Update) error {
+	entityKey := entityURN.String()
+	doc, err := s.docRef(op, entityURN)
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("Updating compromised flag", "key", entityKey, "op", op)
+
+	if err := s.sem.acquire(ctx); err != nil {
+		s.logger.Warn("Gave up waiting for a Firestore slot", "key", entityKey, "err", err)
+		return &keystore.StoreError{Op: op, URN: entityURN, Err: err}
+	}
+	defer s.sem.release()
+
+	_, err = doc.Update(ctx, updates)
+	if status.Code(err) == codes.NotFound {
+		s.logger.Debug("Keys not found", "key", entityKey)
+		return &keystore.StoreError{Op: op, URN: entityURN, Err: keystore.ErrKeyNotFound}
+	}
+	if err != nil {
+		s.logger.Error("Failed to update compromised flag", "key", entityKey, "err", err)
+		return &keystore.StoreError{
+			Op:  op,
+			URN: entityURN,
+			Err: fmt.Errorf("failed to update compromised flag: %w", err),
+		}
+	}
+	return nil
+}
+
+// DeletePublicKeys deletes the entity's document. The delete requires the
+// document to exist, so a missing entity is reported as not deleted
+// rather than silently succeeding.
+func (s *Store) DeletePublicKeys(ctx context.Context, entityURN urn.URN) (bool, error) {
+	entityKey := entityURN.String()
+	doc, err := s.docRef(keystore.OpDeletePublicKeys, entityURN)
+	if err != nil {
+		return false, err
+	}
+	s.logger.Debug("Deleting keys", "key", entityKey)
+
+	if err := s.sem.acquire(ctx); err != nil {
+		s.logger.Warn("Gave up waiting for a Firestore slot", "key", entityKey, "err", err)
+		return false, &keystore.StoreError{Op: keystore.OpDeletePublicKeys, URN: entityURN, Err: err}
+	}
+	defer s.sem.release()
+
+	_, err = doc.Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		s.logger.Debug("Keys not found", "key", entityKey)
+		return false, nil
+	}
+	if err != nil {
+		s.logger.Error("Failed to delete key document", "key", entityKey, "err", err)
+		return false, &keystore.StoreError{
+			Op:  keystore.OpDeletePublicKeys,
+			URN: entityURN,
+			Err: fmt.Errorf("failed to delete key document: %w", err),
+		}
+	}
+	s.logger.Debug("Successfully deleted keys", "key", entityKey)
+	return true, nil
+}
+
 // GetPublicKeys retrieves a PublicKeys struct from a Firestore document.
 // It returns an error if the document is not found or cannot be parsed.
 func (s *Store) GetPublicKeys(ctx context.Context, entityURN urn.URN) (keys.PublicKeys, error) {
+	kDoc, err := s.getKeyDocument(ctx, keystore.OpGetPublicKeys, entityURN)
+	if err != nil {
+		return keys.PublicKeys{}, err
+	}
+	return kDoc.publicKeys(), nil
+}
+
+// GetKeyRecord retrieves the keys along with their metadata and update time.
+func (s *Store) GetKeyRecord(ctx context.Context, entityURN urn.URN) (keystore.KeyRecord, error) {
+	kDoc, err := s.getKeyDocument(ctx, keystore.OpGetKeyRecord, entityURN)
+	if err != nil {
+		return keystore.KeyRecord{}, err
+	}
+	return kDoc.keyRecord(), nil
+}
+
+// GetPublicKeysIfModifiedSince retrieves the keys only if their document was
+// updated after `since`. Documents written before updatedAt was recorded are
+// judged by their Firestore update time instead (see migrate).
+func (s *Store) GetPublicKeysIfModifiedSince(ctx context.Context, entityURN urn.URN, since time.Time) (keys.PublicKeys, bool, error) {
+	kDoc, err := s.getKeyDocument(ctx, keystore.OpGetPublicKeysIfModifiedSince, entityURN)
+	if err != nil {
+		return keys.PublicKeys{}, false, err
+	}
+	if !kDoc.UpdatedAt.IsZero() && !kDoc.UpdatedAt.After(since) {
+		s.logger.Debug("Keys not modified", "key", entityURN.String(), "since", since)
+		return keys.PublicKeys{}, false, nil
+	}
+	return kDoc.publicKeys(), true, nil
+}
+
+// IterateAll streams every document in the collection and calls fn for each.
+// Documents whose ID is not a valid URN or whose data cannot be decoded are
+// logged and skipped rather than aborting the whole iteration.
+func (s *Store) IterateAll(ctx context.Context, fn func(entityURN urn.URN, keys keys.PublicKeys) error) error {
+	return s.iterateKeyDocuments(ctx, keystore.OpIterateAll, func(entityURN urn.URN, kDoc KeyDocument) error {
+		return fn(entityURN, kDoc.publicKeys())
+	})
+}
+
+// IterateKeyRecords is IterateAll with each entity's metadata and update time.
+func (s *Store) IterateKeyRecords(ctx context.Context, fn func(entityURN urn.URN, record keystore.KeyRecord) error) error {
+	return s.iterateKeyDocuments(ctx, keystore.OpIterateKeyRecords, func(entityURN urn.URN, kDoc KeyDocument) error {
+		return fn(entityURN, kDoc.keyRecord())
+	})
+}
+
+// iterateKeyDocuments streams every decodable document in the collection.
+func (s *Store) iterateKeyDocuments(ctx context.Context, op string, fn func(entityURN urn.URN, kDoc KeyDocument) error) error {
+	s.logger.Debug("Iterating all keys")
+
+	if err := s.sem.acquire(ctx); err != nil {
+		return &keystore.StoreError{Op: op, Err: err}
+	}
+	defer s.sem.release()
+
+	iter := s.collection.Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			return nil
+		}
+		if err != nil {
+			s.logger.Error("Failed to iterate key documents", "err", err)
+			return &keystore.StoreError{
+				Op:  op,
+				Err: fmt.Errorf("failed to iterate key documents: %w", err),
+			}
+		}
+
+		entityURN, err := urn.Parse(doc.Ref.ID)
+		if err != nil {
+			s.logger.Warn("Skipping document with invalid URN ID", "key", doc.Ref.ID, "err", err)
+			continue
+		}
+		kDoc, err := decodeKeyDocument(doc)
+		if err != nil {
+			s.logger.Warn("Skipping undecodable key document", "key", doc.Ref.ID, "err", err)
+			continue
+		}
+		if err := fn(entityURN, kDoc); err != nil {
+			return err
+		}
+	}
+}
+
+// getKeyDocument fetches and decodes the key document for an entity,
+// reporting failures as a StoreError for the given operation.
+func (s *Store) getKeyDocument(ctx context.Context, op string, entityURN urn.URN) (KeyDocument, error) {
 	entityKey := entityURN.String()
+	ref, err := s.docRef(op, entityURN)
+	if err != nil {
+		return KeyDocument{}, err
+	}
 	s.logger.Debug("Getting keys", "key", entityKey)
 
-	doc, err := s.collection.Doc(entityKey).Get(ctx)
+	if err := s.sem.acquire(ctx); err != nil {
+		s.logger.Warn("Gave up waiting for a Firestore slot", "key", entityKey, "err", err)
+		return KeyDocument{}, &keystore.StoreError{Op: op, URN: entityURN, Err: err}
+	}
+	doc, err := ref.Get(ctx)
+	s.sem.release()
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
 			s.logger.Debug("Keys not found", "key", entityKey)
-			return keys.PublicKeys{}, fmt.Errorf("key for entity %s not found", entityKey)
+			return KeyDocument{}, &keystore.StoreError{
+				Op:  op,
+				URN: entityURN,
+				Err: keystore.ErrKeyNotFound,
+			}
 		}
 		s.logger.Warn("Failed to get key document", "key", entityKey, "err", err)
-		return keys.PublicKeys{}, fmt.Errorf("failed to get key for entity %s: %w", entityKey, err)
+		return KeyDocument{}, &keystore.StoreError{
+			Op:  op,
+			URN: entityURN,
+			Err: fmt.Errorf("failed to get key document: %w", err),
+		}
 	}
 
-	var kDoc KeyDocument
-	if err := doc.DataTo(&kDoc); err == nil {
+	if kDoc, err := decodeKeyDocument(doc); err == nil {
 		// Success: Check if it's a real doc (has non-nil EncKey or SigKey)
 		if kDoc.EncKey != nil || kDoc.SigKey != nil {
 			s.logger.Debug("Successfully retrieved keys ", "key", entityKey)
-			return keys.PublicKeys{
-				EncKey: kDoc.EncKey,
-				SigKey: kDoc.SigKey,
-			}, nil
+			if s.countAccess {
+				go s.incrementAccessCount(context.WithoutCancel(ctx), ref)
+			}
+			return kDoc, nil
 		}
 	}
 
 	// This case handles a document that exists but doesn't match our struct
-	return keys.PublicKeys{}, fmt.Errorf("failed to parse key document for entity %s: unknown format", entityKey)
+	return KeyDocument{}, &keystore.StoreError{
+		Op:  op,
+		URN: entityURN,
+		Err: errors.New("failed to parse key document: unknown format"),
+	}
+}
+
+// accessCountTimeout bounds a background access-count increment.
+const accessCountTimeout = 10 * time.Second
+
+// incrementAccessCount atomically bumps a document's accessCount. It is
+// best effort: failures are logged and otherwise ignored.
+func (s *Store) incrementAccessCount(ctx context.Context, ref *firestore.DocumentRef) {
+	ctx, cancel := context.WithTimeout(ctx, accessCountTimeout)
+	defer cancel()
+
+	if err := s.sem.acquire(ctx); err != nil {
+		s.logger.Debug("Skipped access count increment", "key", ref.ID, "err", err)
+		return
+	}
+	defer s.sem.release()
+
+	_, err := ref.Update(ctx, []firestore.Update{{Path: "accessCount", Value: firestore.Increment(1)}})
+	if err != nil {
+		s.logger.Warn("Failed to increment access count", "key", ref.ID, "err", err)
+	}
 }
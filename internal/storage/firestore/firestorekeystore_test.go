@@ -5,8 +5,11 @@ package firestore_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"sync"
 	"testing"
 	"time"
 
@@ -74,5 +77,708 @@ func TestFirestoreStore_Integration(t *testing.T) {
 	nonExistentURN, err := urn.New(urn.SecureMessaging, "user", "not-found")
 	require.NoError(t, err)
 	_, err = store.GetPublicKeys(ctx, nonExistentURN)
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, keystore.ErrKeyNotFound)
+	_, err = store.GetKeyRecord(ctx, nonExistentURN)
+	assert.ErrorIs(t, err, keystore.ErrKeyNotFound)
+
+	// Assert: The error carries the operation and URN
+	var storeErr *keystore.StoreError
+	require.True(t, errors.As(err, &storeErr))
+	assert.Equal(t, keystore.OpGetPublicKeys, storeErr.Op)
+	assert.Equal(t, nonExistentURN, storeErr.URN)
+}
+
+func TestFirestoreStore_GetPublicKeysIfModifiedSince(t *testing.T) {
+	ctx, _, store := setupSuite(t)
+
+	// Arrange
+	userURN, err := urn.New(urn.SecureMessaging, "user", "user-modified")
+	require.NoError(t, err)
+	testKeys := keys.PublicKeys{
+		EncKey: []byte("test-enc-key"),
+		SigKey: []byte("test-sig-key"),
+	}
+
+	beforeStore := time.Now().Add(-time.Minute)
+	err = store.StorePublicKeys(ctx, userURN, testKeys)
+	require.NoError(t, err)
+	afterStore := time.Now().Add(time.Minute)
+
+	// Act & Assert: Modified since a time before the write
+	retrievedKeys, modified, err := store.GetPublicKeysIfModifiedSince(ctx, userURN, beforeStore)
+	require.NoError(t, err)
+	assert.True(t, modified)
+	assert.Equal(t, testKeys, retrievedKeys)
+
+	// Act & Assert: Not modified since a time after the write
+	retrievedKeys, modified, err = store.GetPublicKeysIfModifiedSince(ctx, userURN, afterStore)
+	require.NoError(t, err)
+	assert.False(t, modified)
+	assert.Empty(t, retrievedKeys)
+}
+
+func TestFirestoreStore_RotationHint(t *testing.T) {
+	ctx, _, store := setupSuite(t)
+
+	// Arrange
+	userURN, err := urn.New(urn.SecureMessaging, "user", "user-123")
+	require.NoError(t, err)
+	testKeys := keys.PublicKeys{
+		EncKey: []byte("enc-key"),
+		SigKey: []byte("sig-key"),
+	}
+	hint := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// Act: Store with a hint and read it back
+	err = store.StorePublicKeysWithMetadata(ctx, userURN, testKeys, keystore.Metadata{RotationHint: hint})
+	require.NoError(t, err)
+	record, err := store.GetKeyRecord(ctx, userURN)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, testKeys, record.Keys)
+	assert.True(t, hint.Equal(record.Metadata.RotationHint))
+	assert.False(t, record.UpdatedAt.IsZero())
+
+	// Act & Assert: A plain store clears the hint
+	require.NoError(t, store.StorePublicKeys(ctx, userURN, testKeys))
+	record, err = store.GetKeyRecord(ctx, userURN)
+	require.NoError(t, err)
+	assert.True(t, record.Metadata.RotationHint.IsZero())
+}
+
+func TestFirestoreStore_UsageRestrictions(t *testing.T) {
+	ctx, _, store := setupSuite(t)
+
+	// Arrange
+	userURN, err := urn.New(urn.SecureMessaging, "user", "user-123")
+	require.NoError(t, err)
+	testKeys := keys.PublicKeys{
+		EncKey: []byte("enc-key"),
+		SigKey: []byte("sig-key"),
+	}
+	usage := keystore.UsageRestrictions{
+		Usage:     keystore.KeyUsageEncryptOnly,
+		NotBefore: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:  time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	// Act: Store with restrictions and read them back
+	err = store.StorePublicKeysWithMetadata(ctx, userURN, testKeys, keystore.Metadata{UsageRestrictions: usage})
+	require.NoError(t, err)
+	record, err := store.GetKeyRecord(ctx, userURN)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, usage.Equal(record.Metadata.UsageRestrictions), "got %+v", record.Metadata.UsageRestrictions)
+
+	// Act & Assert: A plain store clears them
+	require.NoError(t, store.StorePublicKeys(ctx, userURN, testKeys))
+	record, err = store.GetKeyRecord(ctx, userURN)
+	require.NoError(t, err)
+	assert.True(t, record.Metadata.UsageRestrictions.IsZero())
+}
+
+func TestFirestoreStore_GetOrCreatePublicKeys(t *testing.T) {
+	ctx, _, store := setupSuite(t)
+
+	t.Run("Create - entity is new", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "new-user")
+		require.NoError(t, err)
+		candidate := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+
+		// Act
+		effective, created, err := store.GetOrCreatePublicKeys(ctx, userURN, candidate)
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, created)
+		assert.Equal(t, candidate, effective)
+		stored, err := store.GetPublicKeys(ctx, userURN)
+		require.NoError(t, err)
+		assert.Equal(t, candidate, stored)
+	})
+
+	t.Run("Exists - existing keys are kept", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "existing-user")
+		require.NoError(t, err)
+		existing := keys.PublicKeys{EncKey: []byte("old-enc"), SigKey: []byte("old-sig")}
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, existing))
+
+		// Act
+		effective, created, err := store.GetOrCreatePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte("new-enc"), SigKey: []byte("new-sig")})
+
+		// Assert
+		require.NoError(t, err)
+		assert.False(t, created)
+		assert.Equal(t, existing, effective)
+	})
+
+	t.Run("Concurrent - exactly one caller wins", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "raced-user")
+		require.NoError(t, err)
+		const callers = 8
+		type result struct {
+			effective keys.PublicKeys
+			created   bool
+			err       error
+		}
+		results := make(chan result, callers)
+
+		// Act
+		var wg sync.WaitGroup
+		for i := range callers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				candidate := keys.PublicKeys{EncKey: []byte{byte(i)}, SigKey: []byte{byte(i)}}
+				effective, created, err := store.GetOrCreatePublicKeys(ctx, userURN, candidate)
+				results <- result{effective, created, err}
+			}()
+		}
+		wg.Wait()
+		close(results)
+
+		// Assert: one creator, and every caller sees the same effective keys
+		winners := 0
+		var first *keys.PublicKeys
+		for res := range results {
+			require.NoError(t, res.err)
+			if res.created {
+				winners++
+			}
+			if first == nil {
+				first = &res.effective
+			}
+			assert.Equal(t, *first, res.effective)
+		}
+		assert.Equal(t, 1, winners)
+	})
+}
+
+func TestFirestoreStore_EncKeySignature(t *testing.T) {
+	ctx, _, store := setupSuite(t)
+
+	// Arrange
+	userURN, err := urn.New(urn.SecureMessaging, "user", "user-signed")
+	require.NoError(t, err)
+	testKeys := keys.PublicKeys{EncKey: []byte("enc-key"), SigKey: []byte("sig-key")}
+	meta := keystore.Metadata{EncKeySignature: []byte("signature"), ProvisioningSignature: []byte("provisioned")}
+
+	// Act
+	require.NoError(t, store.StorePublicKeysWithMetadata(ctx, userURN, testKeys, meta))
+	record, err := store.GetKeyRecord(ctx, userURN)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, meta.EncKeySignature, record.Metadata.EncKeySignature)
+	assert.Equal(t, meta.ProvisioningSignature, record.Metadata.ProvisioningSignature)
+}
+
+func TestFirestoreStore_Stats(t *testing.T) {
+	ctx, _, store := setupSuite(t)
+
+	// Arrange
+	for _, id := range []string{"stats-a", "stats-b", "stats-c"} {
+		entityURN, err := urn.New(urn.SecureMessaging, "user", id)
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, keys.PublicKeys{EncKey: []byte("enc-key"), SigKey: []byte("sig-key")}))
+	}
+
+	// Act
+	stats, err := store.Stats(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, stats.Entities)
+	assert.Positive(t, stats.ApproxBytes)
+}
+
+func TestFirestoreStore_ListEntitiesMissingSigKey(t *testing.T) {
+	ctx, _, store := setupSuite(t)
+
+	// Arrange: a mix of complete and encryption-only entities
+	stored := map[string]keys.PublicKeys{
+		"complete-1": {EncKey: []byte("enc"), SigKey: []byte("sig")},
+		"enc-only-1": {EncKey: []byte("enc")},
+		"complete-2": {EncKey: []byte("enc"), SigKey: []byte("sig")},
+		"enc-only-2": {EncKey: []byte("enc"), SigKey: []byte{}},
+	}
+	for id, pk := range stored {
+		entityURN, err := urn.New(urn.SecureMessaging, "user", id)
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, pk))
+	}
+
+	// Act
+	missing, err := store.ListEntitiesMissingSigKey(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	var got []string
+	for _, entityURN := range missing {
+		got = append(got, entityURN.String())
+	}
+	assert.Equal(t, []string{"urn:sm:user:enc-only-1", "urn:sm:user:enc-only-2"}, got)
+}
+
+func TestFirestoreStore_SchemaVersion(t *testing.T) {
+	ctx, fsClient, store := setupSuite(t)
+
+	t.Run("Success - version 1 document migrates on read", func(t *testing.T) {
+		// Arrange: a document as the first schema wrote it
+		userURN, err := urn.New(urn.SecureMessaging, "user", "schema-v1")
+		require.NoError(t, err)
+		ref := fsClient.Collection("public-keys").Doc(userURN.String())
+		wr, err := ref.Set(ctx, map[string]any{"encKey": []byte("enc"), "sigKey": []byte("sig")})
+		require.NoError(t, err)
+
+		// Act
+		record, err := store.GetKeyRecord(ctx, userURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}, record.Keys)
+		assert.True(t, wr.UpdateTime.Equal(record.UpdatedAt), "updatedAt should default to the document's update time")
+		_, modified, err := store.GetPublicKeysIfModifiedSince(ctx, userURN, wr.UpdateTime)
+		require.NoError(t, err)
+		assert.False(t, modified)
+	})
+
+	t.Run("Success - storing writes the current version", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "schema-current")
+		require.NoError(t, err)
+
+		// Act
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+
+		// Assert
+		snap, err := fsClient.Collection("public-keys").Doc(userURN.String()).Get(ctx)
+		require.NoError(t, err)
+		version, err := snap.DataAt("schemaVersion")
+		require.NoError(t, err)
+		assert.EqualValues(t, fsAdapter.SchemaVersion, version)
+	})
+}
+
+func TestFirestoreStore_DeleteOlderThan(t *testing.T) {
+	ctx, fsClient, store := setupSuite(t)
+
+	// Arrange: old documents are written directly with a back-dated
+	// updatedAt, since the store always stamps the current time.
+	entityURNs := map[string]urn.URN{}
+	for id, age := range map[string]time.Duration{"stale": 48 * time.Hour, "ancient": 400 * time.Hour} {
+		entityURN, err := urn.New(urn.SecureMessaging, "user", id)
+		require.NoError(t, err)
+		_, err = fsClient.Collection("public-keys").Doc(entityURN.String()).Set(ctx, fsAdapter.KeyDocument{
+			EncKey:    []byte("enc"),
+			SigKey:    []byte("sig"),
+			UpdatedAt: time.Now().UTC().Add(-age),
+		})
+		require.NoError(t, err)
+		entityURNs[id] = entityURN
+	}
+	freshURN, err := urn.New(urn.SecureMessaging, "user", "fresh")
+	require.NoError(t, err)
+	require.NoError(t, store.StorePublicKeys(ctx, freshURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+
+	// Act
+	deleted, err := store.DeleteOlderThan(ctx, 24*time.Hour)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	_, err = store.GetPublicKeys(ctx, freshURN)
+	assert.NoError(t, err)
+	for id, entityURN := range entityURNs {
+		_, err = store.GetPublicKeys(ctx, entityURN)
+		assert.Error(t, err, id)
+	}
+}
+
+func TestFirestoreStore_GetFingerprintsBatch(t *testing.T) {
+	ctx, _, store := setupSuite(t)
+
+	// Arrange: more entities than fit in one "in" query
+	want := map[string]string{}
+	var entityURNs []urn.URN
+	for i := range 35 {
+		entityURN, err := urn.New(urn.SecureMessaging, "user", fmt.Sprintf("fingerprint-%d", i))
+		require.NoError(t, err)
+		entityURNs = append(entityURNs, entityURN)
+		if i%5 == 0 {
+			continue // left absent
+		}
+		pk := keys.PublicKeys{EncKey: []byte(fmt.Sprintf("enc-%d", i)), SigKey: []byte("sig")}
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, pk))
+		want[entityURN.String()] = keystore.Fingerprint(pk)
+	}
+
+	// Act
+	fingerprints, err := store.GetFingerprintsBatch(ctx, entityURNs)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, want, fingerprints)
+}
+
+func TestFirestoreStore_GetPublicKeysBatch(t *testing.T) {
+	ctx, _, store := setupSuite(t)
+
+	// Arrange
+	want := map[string]keys.PublicKeys{}
+	var entityURNs []urn.URN
+	for i := range 10 {
+		entityURN, err := urn.New(urn.SecureMessaging, "user", fmt.Sprintf("batch-%d", i))
+		require.NoError(t, err)
+		entityURNs = append(entityURNs, entityURN)
+		if i%3 == 0 {
+			continue // left absent
+		}
+		pk := keys.PublicKeys{EncKey: []byte(fmt.Sprintf("enc-%d", i)), SigKey: []byte("sig")}
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, pk))
+		want[entityURN.String()] = pk
+	}
+
+	// Act
+	records, err := store.GetPublicKeysBatch(ctx, append(entityURNs, entityURNs[1]))
+
+	// Assert
+	require.NoError(t, err)
+	got := map[string]keys.PublicKeys{}
+	for id, record := range records {
+		got[id] = record.Keys
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestFirestoreStore_DiffPublicKeys(t *testing.T) {
+	ctx, _, store := setupSuite(t)
+
+	// Arrange
+	shared := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+	aURN, err := urn.New(urn.SecureMessaging, "user", "diff-a")
+	require.NoError(t, err)
+	bURN, err := urn.New(urn.SecureMessaging, "user", "diff-b")
+	require.NoError(t, err)
+	cURN, err := urn.New(urn.SecureMessaging, "user", "diff-c")
+	require.NoError(t, err)
+	absentURN, err := urn.New(urn.SecureMessaging, "user", "diff-missing")
+	require.NoError(t, err)
+	require.NoError(t, store.StorePublicKeys(ctx, aURN, shared))
+	require.NoError(t, store.StorePublicKeys(ctx, bURN, shared))
+	require.NoError(t, store.StorePublicKeys(ctx, cURN, keys.PublicKeys{EncKey: []byte("other"), SigKey: []byte("sig")}))
+
+	// Act & Assert
+	diff, err := store.DiffPublicKeys(ctx, aURN, bURN)
+	require.NoError(t, err)
+	assert.True(t, diff.Identical())
+
+	diff, err = store.DiffPublicKeys(ctx, aURN, cURN)
+	require.NoError(t, err)
+	assert.Equal(t, keystore.KeyDiff{EncKeyEqual: false, SigKeyEqual: true}, diff)
+
+	_, err = store.DiffPublicKeys(ctx, absentURN, aURN)
+	assert.ErrorIs(t, err, keystore.ErrKeyNotFound)
+	var storeErr *keystore.StoreError
+	require.ErrorAs(t, err, &storeErr)
+	assert.Equal(t, absentURN, storeErr.URN)
+}
+
+func TestFirestoreStore_AccessCounting(t *testing.T) {
+	ctx, fsClient, _ := setupSuite(t)
+	store := fsAdapter.NewFirestoreStore(fsClient, "public-keys", newTestLogger(), fsAdapter.WithAccessCounting())
+
+	// Arrange
+	userURN, err := urn.New(urn.SecureMessaging, "user", "user-counted")
+	require.NoError(t, err)
+	require.NoError(t, store.StorePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+
+	// Act
+	for range 3 {
+		_, err := store.GetPublicKeys(ctx, userURN)
+		require.NoError(t, err)
+	}
+
+	// Assert: Increments land asynchronously
+	plain := fsAdapter.NewFirestoreStore(fsClient, "public-keys", newTestLogger())
+	assert.Eventually(t, func() bool {
+		record, err := plain.GetKeyRecord(ctx, userURN)
+		return err == nil && record.AccessCount == 3
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+func TestFirestoreStore_SwapPublicKeys(t *testing.T) {
+	ctx, _, store := setupSuite(t)
+
+	t.Run("Success - returns the displaced keys", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "swap-user")
+		require.NoError(t, err)
+		oldKeys := keys.PublicKeys{EncKey: []byte("old-enc"), SigKey: []byte("old-sig")}
+		newKeys := keys.PublicKeys{EncKey: []byte("new-enc"), SigKey: []byte("new-sig")}
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, oldKeys))
+		_, err = store.BumpEpoch(ctx, userURN)
+		require.NoError(t, err)
+
+		// Act
+		displaced, err := store.SwapPublicKeys(ctx, userURN, newKeys)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, oldKeys, displaced)
+		record, err := store.GetKeyRecord(ctx, userURN)
+		require.NoError(t, err)
+		assert.Equal(t, newKeys, record.Keys)
+		assert.Equal(t, uint64(1), record.Epoch, "swapping keys must keep the epoch")
+	})
+
+	t.Run("Success - new entity displaces nothing", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "swap-new-user")
+		require.NoError(t, err)
+		newKeys := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+
+		// Act
+		displaced, err := store.SwapPublicKeys(ctx, userURN, newKeys)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, keys.PublicKeys{}, displaced)
+		stored, err := store.GetPublicKeys(ctx, userURN)
+		require.NoError(t, err)
+		assert.Equal(t, newKeys, stored)
+	})
+}
+
+func TestFirestoreStore_DeletePublicKeys(t *testing.T) {
+	ctx, _, store := setupSuite(t)
+
+	t.Run("Success - removes the document", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "delete-user")
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+
+		// Act
+		deleted, err := store.DeletePublicKeys(ctx, userURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, deleted)
+		_, err = store.GetKeyRecord(ctx, userURN)
+		assert.Error(t, err)
+	})
+
+	t.Run("Success - missing document is not deleted", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "delete-missing")
+		require.NoError(t, err)
+
+		// Act
+		deleted, err := store.DeletePublicKeys(ctx, userURN)
+
+		// Assert
+		require.NoError(t, err, "a missing document is not a failure")
+		assert.False(t, deleted)
+	})
+}
+
+func TestFirestoreStore_BumpEpoch(t *testing.T) {
+	ctx, _, store := setupSuite(t)
+
+	t.Run("Concurrent - every bump gets a distinct, increasing epoch", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "epoch-user")
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+		// Kept small: contended transactions are retried a bounded number of times.
+		const callers = 4
+		epochs := make(chan uint64, callers)
+
+		// Act
+		var wg sync.WaitGroup
+		for range callers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				epoch, err := store.BumpEpoch(ctx, userURN)
+				assert.NoError(t, err)
+				epochs <- epoch
+			}()
+		}
+		wg.Wait()
+		close(epochs)
+
+		// Assert: the bumps were serialized into 1..callers
+		seen := make(map[uint64]bool)
+		for epoch := range epochs {
+			seen[epoch] = true
+		}
+		for want := uint64(1); want <= callers; want++ {
+			assert.True(t, seen[want], "missing epoch %d", want)
+		}
+		record, err := store.GetKeyRecord(ctx, userURN)
+		require.NoError(t, err)
+		assert.EqualValues(t, callers, record.Epoch)
+	})
+
+	t.Run("Success - storing keys keeps the epoch", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "epoch-kept")
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+		_, err = store.BumpEpoch(ctx, userURN)
+		require.NoError(t, err)
+
+		// Act
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte("enc2"), SigKey: []byte("sig2")}))
+		next, err := store.BumpEpoch(ctx, userURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, next)
+	})
+
+	t.Run("Failure - unknown entity", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "epoch-missing")
+		require.NoError(t, err)
+
+		// Act
+		_, err = store.BumpEpoch(ctx, userURN)
+
+		// Assert
+		var storeErr *keystore.StoreError
+		require.ErrorAs(t, err, &storeErr)
+		assert.Equal(t, keystore.OpBumpEpoch, storeErr.Op)
+	})
+}
+
+func TestFirestoreStore_RunInTransaction(t *testing.T) {
+	ctx, fsClient, _ := setupSuite(t)
+	store := fsAdapter.NewFirestoreStore(fsClient, "public-keys", newTestLogger())
+	userURN, err := urn.New(urn.SecureMessaging, "user", "tx-user")
+	require.NoError(t, err)
+	deviceURN, err := urn.New(urn.SecureMessaging, "device", "tx-device")
+	require.NoError(t, err)
+	original := keys.PublicKeys{EncKey: []byte("enc-v1"), SigKey: []byte("sig-v1")}
+	rotated := keys.PublicKeys{EncKey: []byte("enc-v2"), SigKey: []byte("sig-v2")}
+	require.NoError(t, store.StorePublicKeys(ctx, userURN, original))
+	require.NoError(t, store.StorePublicKeys(ctx, deviceURN, original))
+
+	t.Run("Rollback - an error discards every write", func(t *testing.T) {
+		// Arrange
+		errAbort := errors.New("abort")
+
+		// Act
+		err := store.RunInTransaction(ctx, func(tx keystore.StoreTx) error {
+			if _, err := tx.GetKeyRecord(userURN); err != nil {
+				return err
+			}
+			if err := tx.StorePublicKeysWithMetadata(userURN, rotated, keystore.Metadata{}); err != nil {
+				return err
+			}
+			if err := tx.StorePublicKeysWithMetadata(deviceURN, rotated, keystore.Metadata{}); err != nil {
+				return err
+			}
+			return errAbort
+		})
+
+		// Assert
+		assert.ErrorIs(t, err, errAbort)
+		for _, entityURN := range []urn.URN{userURN, deviceURN} {
+			got, err := store.GetPublicKeys(ctx, entityURN)
+			require.NoError(t, err)
+			assert.Equal(t, original, got)
+		}
+	})
+
+	t.Run("Commit - every write lands together", func(t *testing.T) {
+		// Act
+		err := store.RunInTransaction(ctx, func(tx keystore.StoreTx) error {
+			record, err := tx.GetKeyRecord(userURN)
+			if err != nil {
+				return err
+			}
+			if err := tx.StorePublicKeysWithMetadata(userURN, rotated, record.Metadata); err != nil {
+				return err
+			}
+			return tx.StorePublicKeysWithMetadata(deviceURN, rotated, keystore.Metadata{})
+		})
+
+		// Assert
+		require.NoError(t, err)
+		for _, entityURN := range []urn.URN{userURN, deviceURN} {
+			got, err := store.GetPublicKeys(ctx, entityURN)
+			require.NoError(t, err)
+			assert.Equal(t, rotated, got)
+		}
+	})
+}
+
+func TestFirestoreStore_Compromised(t *testing.T) {
+	ctx, _, store := setupSuite(t)
+	testKeys := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+
+	t.Run("Success - flag surfaces on the record and can be cleared", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "compromised-user")
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, testKeys))
+		before, err := store.GetKeyRecord(ctx, userURN)
+		require.NoError(t, err)
+
+		// Act
+		require.NoError(t, store.MarkCompromised(ctx, userURN, "device stolen"))
+		flagged, err := store.GetKeyRecord(ctx, userURN)
+		require.NoError(t, err)
+		require.NoError(t, store.ClearCompromised(ctx, userURN))
+		cleared, err := store.GetKeyRecord(ctx, userURN)
+		require.NoError(t, err)
+
+		// Assert
+		assert.True(t, flagged.Compromised)
+		assert.Equal(t, "device stolen", flagged.CompromisedReason)
+		assert.Equal(t, testKeys, flagged.Keys)
+		assert.True(t, before.UpdatedAt.Equal(flagged.UpdatedAt), "flagging must not touch updatedAt")
+		assert.False(t, cleared.Compromised)
+		assert.Empty(t, cleared.CompromisedReason)
+	})
+
+	t.Run("Success - storing new keys clears the flag", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "compromised-rotated")
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, testKeys))
+		require.NoError(t, store.MarkCompromised(ctx, userURN, "leaked"))
+
+		// Act
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte("enc2"), SigKey: []byte("sig2")}))
+
+		// Assert
+		record, err := store.GetKeyRecord(ctx, userURN)
+		require.NoError(t, err)
+		assert.False(t, record.Compromised)
+	})
+
+	t.Run("Failure - unknown entity", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "compromised-missing")
+		require.NoError(t, err)
+
+		// Act
+		err = store.MarkCompromised(ctx, userURN, "leaked")
+
+		// Assert
+		var storeErr *keystore.StoreError
+		require.ErrorAs(t, err, &storeErr)
+		assert.Equal(t, keystore.OpMarkCompromised, storeErr.Op)
+		_, err = store.GetKeyRecord(ctx, userURN)
+		assert.Error(t, err, "marking must not create the entity")
+	})
 }
@@ -0,0 +1,87 @@
+// --- File: internal/storage/firestore/indexes.go ---
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// IndexFeatures selects the optional features whose queries the service
+// will run. Key reads and writes go by document ID and need no index.
+type IndexFeatures struct {
+	// MissingSigKey is the admin listing of entities without a signing key.
+	MissingSigKey bool
+	// DeleteOlderThan is the admin age-based cleanup.
+	DeleteOlderThan bool
+}
+
+// IndexRequirement is a Firestore index one of the store's queries needs.
+// Each is a single-field index, which Firestore builds automatically, so
+// it only goes missing when an index exemption has turned it off.
+type IndexRequirement struct {
+	// Feature names the store method whose query needs the index.
+	Feature string
+	// Field is the indexed field.
+	Field string
+	// Command is the gcloud command that restores the index.
+	Command string
+
+	// query builds the feature's query, so a probe matches it exactly.
+	query func() firestore.Query
+}
+
+// RequiredIndexes returns the indexes the enabled features need, in a
+// stable order.
+func (s *Store) RequiredIndexes(features IndexFeatures) []IndexRequirement {
+	var reqs []IndexRequirement
+	if features.MissingSigKey {
+		reqs = append(reqs, s.indexRequirement("ListEntitiesMissingSigKey", "sigKey", s.missingSigKeyQuery))
+	}
+	if features.DeleteOlderThan {
+		reqs = append(reqs, s.indexRequirement("DeleteOlderThan", "updatedAt", func() firestore.Query {
+			return s.olderThanQuery(time.Now().UTC())
+		}))
+	}
+	return reqs
+}
+
+// indexRequirement describes the ascending single-field index on field.
+func (s *Store) indexRequirement(feature, field string, query func() firestore.Query) IndexRequirement {
+	return IndexRequirement{
+		Feature: feature,
+		Field:   field,
+		Command: fmt.Sprintf("gcloud firestore indexes fields update %s --collection-group=%s --index=order=ascending",
+			field, s.collection.ID),
+		query: query,
+	}
+}
+
+// VerifyIndexes probes each requirement by running its query for at most
+// one document. Firestore rejects a query whose index is missing with
+// FailedPrecondition; those are reported together, each with the command
+// that fixes it. Any other failure means the indexes could not be checked.
+func (s *Store) VerifyIndexes(ctx context.Context, reqs []IndexRequirement) error {
+	var missing []error
+	for _, req := range reqs {
+		iter := req.query().Limit(1).Documents(ctx)
+		_, err := iter.Next()
+		iter.Stop()
+		switch {
+		case err == nil, errors.Is(err, iterator.Done):
+			s.logger.Debug("Firestore index present", "feature", req.Feature, "field", req.Field)
+		case status.Code(err) == codes.FailedPrecondition:
+			missing = append(missing, fmt.Errorf("%s needs an index on %s; create it with: %s: %w",
+				req.Feature, req.Field, req.Command, err))
+		default:
+			return fmt.Errorf("failed to probe the %s index for %s: %w", req.Field, req.Feature, err)
+		}
+	}
+	return errors.Join(missing...)
+}
@@ -0,0 +1,44 @@
+// --- File: internal/storage/firestore/indexes_test.go ---
+package firestore
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+func TestStore_RequiredIndexes(t *testing.T) {
+	// The client connects lazily, so no Firestore is needed to list indexes.
+	client, err := firestore.NewClient(context.Background(), "test-project",
+		option.WithoutAuthentication(), option.WithEndpoint("localhost:1"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	store := NewFirestoreStore(client, "public-keys", slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	t.Run("Success - guidance for each enabled feature", func(t *testing.T) {
+		// Act
+		reqs := store.RequiredIndexes(IndexFeatures{MissingSigKey: true, DeleteOlderThan: true})
+
+		// Assert
+		require.Len(t, reqs, 2)
+		assert.Equal(t, "ListEntitiesMissingSigKey", reqs[0].Feature)
+		assert.Equal(t, "sigKey", reqs[0].Field)
+		assert.Equal(t, "gcloud firestore indexes fields update sigKey --collection-group=public-keys --index=order=ascending", reqs[0].Command)
+		assert.Equal(t, "DeleteOlderThan", reqs[1].Feature)
+		assert.Equal(t, "gcloud firestore indexes fields update updatedAt --collection-group=public-keys --index=order=ascending", reqs[1].Command)
+	})
+
+	t.Run("Success - nothing needed when features are off", func(t *testing.T) {
+		// Act
+		reqs := store.RequiredIndexes(IndexFeatures{})
+
+		// Assert
+		assert.Empty(t, reqs)
+	})
+}
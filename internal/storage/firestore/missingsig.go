@@ -0,0 +1,61 @@
+// --- File: internal/storage/firestore/missingsig.go ---
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// ListEntitiesMissingSigKey queries for documents whose sigKey is null or
+// empty bytes, selecting no fields so only document names are read. Every
+// write sets sigKey, so a missing signing key is always one of the two;
+// Firestore cannot match a field that is absent altogether.
+func (s *Store) ListEntitiesMissingSigKey(ctx context.Context) ([]urn.URN, error) {
+	if err := s.sem.acquire(ctx); err != nil {
+		return nil, &keystore.StoreError{Op: keystore.OpListEntitiesMissingSigKey, Err: err}
+	}
+	defer s.sem.release()
+
+	iter := s.missingSigKeyQuery().Documents(ctx)
+	defer iter.Stop()
+
+	var entityURNs []urn.URN
+	for {
+		doc, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			s.logger.Error("Failed to query entities missing a signing key", "err", err)
+			return nil, &keystore.StoreError{
+				Op:  keystore.OpListEntitiesMissingSigKey,
+				Err: fmt.Errorf("failed to query key documents: %w", err),
+			}
+		}
+		entityURN, err := urn.Parse(doc.Ref.ID)
+		if err != nil {
+			s.logger.Warn("Skipping document with invalid URN ID", "key", doc.Ref.ID, "err", err)
+			continue
+		}
+		entityURNs = append(entityURNs, entityURN)
+	}
+	slices.SortFunc(entityURNs, func(x, y urn.URN) int { return strings.Compare(x.String(), y.String()) })
+	return entityURNs, nil
+}
+
+// missingSigKeyQuery matches documents whose sigKey is null or empty.
+func (s *Store) missingSigKeyQuery() firestore.Query {
+	return s.collection.WhereEntity(firestore.OrFilter{Filters: []firestore.EntityFilter{
+		firestore.PropertyFilter{Path: "sigKey", Operator: "==", Value: nil},
+		firestore.PropertyFilter{Path: "sigKey", Operator: "==", Value: []byte{}},
+	}}).Select()
+}
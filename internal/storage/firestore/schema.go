@@ -0,0 +1,39 @@
+// --- File: internal/storage/firestore/schema.go ---
+package firestore
+
+import (
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// SchemaVersion is the KeyDocument schema this store writes. Documents
+// without a schemaVersion field are version 1: they predate the field and
+// may also predate updatedAt.
+const SchemaVersion = 2
+
+// decodeKeyDocument decodes snap and migrates it to SchemaVersion.
+func decodeKeyDocument(snap *firestore.DocumentSnapshot) (KeyDocument, error) {
+	var kDoc KeyDocument
+	if err := snap.DataTo(&kDoc); err != nil {
+		return KeyDocument{}, err
+	}
+	kDoc.migrate(snap.UpdateTime)
+	return kDoc, nil
+}
+
+// migrate upgrades a document read at an older schema version to
+// SchemaVersion in memory, filling defaults for fields that version lacked.
+// The stored document is left as it is until its keys are next stored.
+// updateTime is the document's Firestore update time. Documents from a
+// newer version are read as they are.
+func (d *KeyDocument) migrate(updateTime time.Time) {
+	if d.SchemaVersion < 2 {
+		// Version 1 may lack updatedAt. The last write to the document
+		// happened no earlier than the keys were stored, so it stands in.
+		if d.UpdatedAt.IsZero() {
+			d.UpdatedAt = updateTime.UTC()
+		}
+		d.SchemaVersion = 2
+	}
+}
@@ -0,0 +1,51 @@
+// --- File: internal/storage/firestore/schema_test.go ---
+package firestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyDocument_Migrate(t *testing.T) {
+	writtenAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Success - version 1 without updatedAt takes the update time", func(t *testing.T) {
+		// Arrange
+		kDoc := KeyDocument{EncKey: []byte("enc"), SigKey: []byte("sig")}
+
+		// Act
+		kDoc.migrate(writtenAt)
+
+		// Assert
+		assert.Equal(t, int64(SchemaVersion), kDoc.SchemaVersion)
+		assert.True(t, writtenAt.Equal(kDoc.UpdatedAt))
+		assert.Equal(t, []byte("enc"), kDoc.EncKey)
+	})
+
+	t.Run("Success - recorded updatedAt is kept", func(t *testing.T) {
+		// Arrange
+		storedAt := writtenAt.Add(-time.Hour)
+		kDoc := KeyDocument{EncKey: []byte("enc"), UpdatedAt: storedAt}
+
+		// Act
+		kDoc.migrate(writtenAt)
+
+		// Assert
+		assert.Equal(t, int64(SchemaVersion), kDoc.SchemaVersion)
+		assert.True(t, storedAt.Equal(kDoc.UpdatedAt))
+	})
+
+	t.Run("Success - current version is unchanged", func(t *testing.T) {
+		// Arrange
+		kDoc := KeyDocument{EncKey: []byte("enc"), SchemaVersion: SchemaVersion}
+		want := kDoc
+
+		// Act
+		kDoc.migrate(writtenAt)
+
+		// Assert
+		assert.Equal(t, want, kDoc)
+	})
+}
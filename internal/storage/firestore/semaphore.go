@@ -0,0 +1,51 @@
+// --- File: internal/storage/firestore/semaphore.go ---
+package firestore
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// inFlightOperations reports how many Firestore operations are currently running.
+var inFlightOperations = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "keyservice_firestore_inflight_operations",
+	Help: "Number of Firestore operations currently in flight.",
+})
+
+// semaphore bounds the number of Firestore operations in flight at once.
+// A nil semaphore places no limit, but still reports the in-flight gauge.
+type semaphore struct {
+	slots chan struct{}
+}
+
+// newSemaphore returns a semaphore allowing up to max concurrent holders,
+// or nil if max is not positive.
+func newSemaphore(max int) *semaphore {
+	if max <= 0 {
+		return nil
+	}
+	return &semaphore{slots: make(chan struct{}, max)}
+}
+
+// acquire blocks until a slot is free or the context is done.
+func (s *semaphore) acquire(ctx context.Context) error {
+	if s != nil {
+		select {
+		case s.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	inFlightOperations.Inc()
+	return nil
+}
+
+// release frees a slot taken by a successful acquire.
+func (s *semaphore) release() {
+	inFlightOperations.Dec()
+	if s != nil {
+		<-s.slots
+	}
+}
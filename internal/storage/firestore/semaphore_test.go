@@ -0,0 +1,95 @@
+// --- File: internal/storage/firestore/semaphore_test.go ---
+package firestore
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemaphore_LimitsConcurrency(t *testing.T) {
+	const limit = 3
+	const workers = 12
+
+	sem := newSemaphore(limit)
+	ctx := context.Background()
+
+	var current, peak atomic.Int32
+	barrier := make(chan struct{})
+	entered := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !assert.NoError(t, sem.acquire(ctx)) {
+				return
+			}
+			defer sem.release()
+
+			n := current.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			entered <- struct{}{}
+			<-barrier // Hold the slot until the test lets everyone go.
+			current.Add(-1)
+		}()
+	}
+
+	// Exactly `limit` workers should get in; the rest must be blocked.
+	for range limit {
+		select {
+		case <-entered:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for workers to acquire")
+		}
+	}
+	select {
+	case <-entered:
+		t.Fatal("more workers acquired the semaphore than the limit allows")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.Equal(t, float64(limit), testutil.ToFloat64(inFlightOperations))
+
+	close(barrier)
+	wg.Wait()
+
+	assert.Equal(t, int32(limit), peak.Load())
+	assert.Equal(t, float64(0), testutil.ToFloat64(inFlightOperations))
+}
+
+func TestSemaphore_AcquireRespectsContext(t *testing.T) {
+	sem := newSemaphore(1)
+	require.NoError(t, sem.acquire(context.Background()))
+	defer sem.release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := sem.acquire(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSemaphore_NilIsUnlimited(t *testing.T) {
+	sem := newSemaphore(0)
+	assert.Nil(t, sem)
+
+	for range 100 {
+		require.NoError(t, sem.acquire(context.Background()))
+	}
+	for range 100 {
+		sem.release()
+	}
+}
@@ -0,0 +1,114 @@
+// --- File: internal/storage/firestore/stats.go ---
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"google.golang.org/api/iterator"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+)
+
+// statsSampleSize is how many documents Stats reads to estimate the
+// average document size.
+const statsSampleSize = 100
+
+// Stats counts the collection's documents with an aggregation query, so
+// the count costs a small fraction of reading every document. ApproxBytes
+// is that count times the average size of a sample of up to
+// statsSampleSize documents, each sized by Firestore's storage size rules
+// (see documentSize).
+func (s *Store) Stats(ctx context.Context) (keystore.StoreStats, error) {
+	if err := s.sem.acquire(ctx); err != nil {
+		return keystore.StoreStats{}, &keystore.StoreError{Op: keystore.OpStats, Err: err}
+	}
+	defer s.sem.release()
+
+	result, err := s.collection.NewAggregationQuery().WithCount("count").Get(ctx)
+	if err != nil {
+		s.logger.Error("Failed to count key documents", "err", err)
+		return keystore.StoreStats{}, &keystore.StoreError{
+			Op:  keystore.OpStats,
+			Err: fmt.Errorf("failed to count key documents: %w", err),
+		}
+	}
+	count, ok := result["count"].(*firestorepb.Value)
+	if !ok {
+		return keystore.StoreStats{}, &keystore.StoreError{
+			Op:  keystore.OpStats,
+			Err: fmt.Errorf("unexpected count result %T", result["count"]),
+		}
+	}
+	stats := keystore.StoreStats{Entities: count.GetIntegerValue()}
+	if stats.Entities == 0 {
+		return stats, nil
+	}
+
+	var sampled, sampledBytes int64
+	iter := s.collection.Limit(statsSampleSize).Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			s.logger.Error("Failed to sample key documents", "err", err)
+			return keystore.StoreStats{}, &keystore.StoreError{
+				Op:  keystore.OpStats,
+				Err: fmt.Errorf("failed to sample key documents: %w", err),
+			}
+		}
+		sampled++
+		sampledBytes += documentSize(s.collection.ID, doc.Ref.ID, doc.Data())
+	}
+	if sampled > 0 {
+		stats.ApproxBytes = sampledBytes * stats.Entities / sampled
+	}
+	return stats, nil
+}
+
+// documentSize estimates a document's storage size following Firestore's
+// documented rules: the name's path segments plus 16 bytes, each field's
+// name plus its value, and 32 bytes of overhead. Index entries are not
+// counted.
+func documentSize(collectionID, docID string, data map[string]any) int64 {
+	size := int64(len(collectionID)+1) + int64(len(docID)+1) + 16 + 32
+	for name, value := range data {
+		size += int64(len(name)+1) + valueSize(value)
+	}
+	return size
+}
+
+// valueSize is the storage size of one field value.
+func valueSize(value any) int64 {
+	switch v := value.(type) {
+	case nil, bool:
+		return 1
+	case string:
+		return int64(len(v) + 1)
+	case []byte:
+		return int64(len(v))
+	case int64, float64, time.Time:
+		return 8
+	case []any:
+		var size int64
+		for _, elem := range v {
+			size += valueSize(elem)
+		}
+		return size
+	case map[string]any:
+		var size int64
+		for name, elem := range v {
+			size += int64(len(name)+1) + valueSize(elem)
+		}
+		return size
+	default:
+		// Geo points, references and other types this store never writes.
+		return 16
+	}
+}
@@ -0,0 +1,24 @@
+// --- File: internal/storage/firestore/stats_test.go ---
+package firestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentSize(t *testing.T) {
+	// Arrange
+	data := map[string]any{
+		"encKey":    []byte{1, 2, 3},
+		"sigKey":    []byte{4, 5, 6},
+		"updatedAt": time.Now(),
+	}
+
+	// Act
+	size := documentSize("public-keys", "urn:sm:user:alice", data)
+
+	// Assert: name (12+18+16), fields (7+3, 7+3, 10+8) and 32 overhead.
+	assert.EqualValues(t, 46+38+32, size)
+}
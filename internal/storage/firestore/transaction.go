@@ -0,0 +1,77 @@
+// --- File: internal/storage/firestore/transaction.go ---
+package firestore
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// RunInTransaction runs fn in a Firestore transaction. Firestore retries
+// the transaction on contention, so fn may run more than once.
+func (s *Store) RunInTransaction(ctx context.Context, fn func(tx keystore.StoreTx) error) error {
+	if err := s.sem.acquire(ctx); err != nil {
+		s.logger.Warn("Gave up waiting for a Firestore slot", "err", err)
+		return &keystore.StoreError{Op: keystore.OpRunInTransaction, Err: err}
+	}
+	defer s.sem.release()
+
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		return fn(&storeTx{store: s, tx: tx})
+	})
+	if err != nil {
+		s.logger.Warn("Transaction did not commit", "err", err)
+		return &keystore.StoreError{Op: keystore.OpRunInTransaction, Err: err}
+	}
+	return nil
+}
+
+// storeTx is the Firestore StoreTx, a thin view over one transaction.
+type storeTx struct {
+	store *Store
+	tx    *firestore.Transaction
+}
+
+// GetKeyRecord reads the entity's document within the transaction.
+func (t *storeTx) GetKeyRecord(entityURN urn.URN) (keystore.KeyRecord, error) {
+	doc, err := t.store.docRef(keystore.OpGetKeyRecord, entityURN)
+	if err != nil {
+		return keystore.KeyRecord{}, err
+	}
+	snap, err := t.tx.Get(doc)
+	if status.Code(err) == codes.NotFound {
+		return keystore.KeyRecord{}, &keystore.StoreError{Op: keystore.OpGetKeyRecord, URN: entityURN, Err: keystore.ErrKeyNotFound}
+	}
+	if err != nil {
+		return keystore.KeyRecord{}, &keystore.StoreError{
+			Op:  keystore.OpGetKeyRecord,
+			URN: entityURN,
+			Err: fmt.Errorf("failed to get key document: %w", err),
+		}
+	}
+	kDoc, err := decodeKeyDocument(snap)
+	if err != nil {
+		return keystore.KeyRecord{}, &keystore.StoreError{
+			Op:  keystore.OpGetKeyRecord,
+			URN: entityURN,
+			Err: fmt.Errorf("failed to parse key document: %w", err),
+		}
+	}
+	return kDoc.keyRecord(), nil
+}
+
+// StorePublicKeysWithMetadata stages a write of the entity's document.
+func (t *storeTx) StorePublicKeysWithMetadata(entityURN urn.URN, keys keys.PublicKeys, meta keystore.Metadata) error {
+	doc, err := t.store.docRef(keystore.OpStorePublicKeysWithMetadata, entityURN)
+	if err != nil {
+		return err
+	}
+	return t.tx.Set(doc, keyDocumentData(keys, meta), firestore.MergeAll)
+}
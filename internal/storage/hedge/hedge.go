@@ -0,0 +1,109 @@
+// --- File: internal/storage/hedge/hedge.go ---
+// Package hedge provides a keystore.Store wrapper that hedges slow reads:
+// if a read has not returned after a delay, a second attempt is started and
+// whichever finishes first wins.
+package hedge
+
+import (
+	"context"
+	"time"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// DefaultMaxInFlight caps concurrent hedge attempts when no limit is given.
+const DefaultMaxInFlight = 16
+
+// Store wraps a keystore.Store and hedges GetPublicKeys and GetKeyRecord.
+// Writes and other reads pass straight through.
+//
+// At most maxInFlight hedge attempts run at once across all callers; when
+// that budget is spent, slow reads simply wait for their first attempt.
+// This bounds the extra load to a fixed number of requests rather than
+// letting a slow backend double its own traffic.
+type Store struct {
+	keystore.Store
+
+	delay  time.Duration
+	hedges chan struct{}
+}
+
+// New wraps next, hedging reads still outstanding after delay. A
+// non-positive maxInFlight selects DefaultMaxInFlight.
+func New(next keystore.Store, delay time.Duration, maxInFlight int) *Store {
+	if maxInFlight <= 0 {
+		maxInFlight = DefaultMaxInFlight
+	}
+	return &Store{
+		Store:  next,
+		delay:  delay,
+		hedges: make(chan struct{}, maxInFlight),
+	}
+}
+
+// GetPublicKeys reads from the wrapped store, hedging if it is slow.
+func (s *Store) GetPublicKeys(ctx context.Context, entityURN urn.URN) (keys.PublicKeys, error) {
+	return hedged(ctx, s, func(ctx context.Context) (keys.PublicKeys, error) {
+		return s.Store.GetPublicKeys(ctx, entityURN)
+	})
+}
+
+// GetKeyRecord reads from the wrapped store, hedging if it is slow.
+func (s *Store) GetKeyRecord(ctx context.Context, entityURN urn.URN) (keystore.KeyRecord, error) {
+	return hedged(ctx, s, func(ctx context.Context) (keystore.KeyRecord, error) {
+		return s.Store.GetKeyRecord(ctx, entityURN)
+	})
+}
+
+type result[T any] struct {
+	value T
+	err   error
+}
+
+// hedged runs call, and runs it again if the first attempt is still
+// outstanding after s.delay and a hedge slot is free. The first success
+// wins; an error is only returned once no attempt is left that could
+// still succeed. Returning cancels whichever attempt lost.
+func hedged[T any](ctx context.Context, s *Store, call func(context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Buffered so a losing attempt never blocks after we have returned.
+	results := make(chan result[T], 2)
+	attempt := func() {
+		value, err := call(ctx)
+		results <- result[T]{value, err}
+	}
+
+	go attempt()
+	outstanding := 1
+
+	timer := time.NewTimer(s.delay)
+	defer timer.Stop()
+	select {
+	case res := <-results:
+		return res.value, res.err
+	case <-timer.C:
+		select {
+		case s.hedges <- struct{}{}:
+			outstanding++
+			go func() {
+				defer func() { <-s.hedges }()
+				attempt()
+			}()
+		default:
+			// Hedge budget exhausted; keep waiting on the first attempt.
+		}
+	}
+
+	var res result[T]
+	for ; outstanding > 0; outstanding-- {
+		res = <-results
+		if res.err == nil {
+			return res.value, nil
+		}
+	}
+	return res.value, res.err
+}
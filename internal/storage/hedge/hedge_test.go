@@ -0,0 +1,128 @@
+// --- File: internal/storage/hedge/hedge_test.go ---
+package hedge_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/storage/hedge"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// slowFirstStore stalls its first read until the caller gives up on it,
+// then answers every later read immediately.
+type slowFirstStore struct {
+	keystore.Store
+	calls     atomic.Int32
+	cancelled chan struct{}
+}
+
+func newSlowFirstStore() *slowFirstStore {
+	return &slowFirstStore{Store: inmemory.New(), cancelled: make(chan struct{})}
+}
+
+func (s *slowFirstStore) GetPublicKeys(ctx context.Context, entityURN urn.URN) (keys.PublicKeys, error) {
+	if s.calls.Add(1) == 1 {
+		select {
+		case <-ctx.Done():
+			close(s.cancelled)
+			return keys.PublicKeys{}, ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+	return s.Store.GetPublicKeys(ctx, entityURN)
+}
+
+func TestStore_GetPublicKeys(t *testing.T) {
+	ctx := context.Background()
+	entityURN, err := urn.Parse("urn:sm:user:hedged")
+	require.NoError(t, err)
+	stored := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+
+	t.Run("Success - Hedge answers a slow first attempt", func(t *testing.T) {
+		// Arrange
+		backend := newSlowFirstStore()
+		require.NoError(t, backend.StorePublicKeys(ctx, entityURN, stored))
+		store := hedge.New(backend, 10*time.Millisecond, 1)
+
+		// Act
+		start := time.Now()
+		got, err := store.GetPublicKeys(ctx, entityURN)
+		elapsed := time.Since(start)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, stored, got)
+		assert.Less(t, elapsed, time.Second, "hedged read should not wait for the slow attempt")
+		assert.EqualValues(t, 2, backend.calls.Load())
+		select {
+		case <-backend.cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("losing attempt was not cancelled")
+		}
+	})
+
+	t.Run("Success - Fast read is not hedged", func(t *testing.T) {
+		// Arrange
+		backend := newSlowFirstStore()
+		backend.calls.Store(1) // skip the stall
+		require.NoError(t, backend.StorePublicKeys(ctx, entityURN, stored))
+		store := hedge.New(backend, time.Second, 1)
+
+		// Act
+		got, err := store.GetPublicKeys(ctx, entityURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, stored, got)
+		assert.EqualValues(t, 2, backend.calls.Load())
+	})
+
+	t.Run("Success - No hedge once the in-flight budget is spent", func(t *testing.T) {
+		// Arrange: a stalled read and its hedge take the only hedge slot.
+		blocking := &blockingStore{Store: inmemory.New(), release: make(chan struct{})}
+		require.NoError(t, blocking.StorePublicKeys(ctx, entityURN, stored))
+		store := hedge.New(blocking, 5*time.Millisecond, 1)
+		first := make(chan error, 1)
+		go func() {
+			_, err := store.GetPublicKeys(ctx, entityURN)
+			first <- err
+		}()
+		require.Eventually(t, func() bool { return blocking.calls.Load() == 2 }, time.Second, time.Millisecond)
+
+		// Act: a second slow read must not start a third concurrent hedge.
+		second := make(chan error, 1)
+		go func() {
+			_, err := store.GetPublicKeys(ctx, entityURN)
+			second <- err
+		}()
+		time.Sleep(50 * time.Millisecond)
+
+		// Assert
+		assert.EqualValues(t, 3, blocking.calls.Load())
+		close(blocking.release)
+		assert.NoError(t, <-first)
+		assert.NoError(t, <-second)
+	})
+}
+
+// blockingStore holds every read until release is closed.
+type blockingStore struct {
+	keystore.Store
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (s *blockingStore) GetPublicKeys(ctx context.Context, entityURN urn.URN) (keys.PublicKeys, error) {
+	s.calls.Add(1)
+	<-s.release
+	return s.Store.GetPublicKeys(ctx, entityURN)
+}
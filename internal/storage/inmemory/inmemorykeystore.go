@@ -4,42 +4,372 @@ package inmemory
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
 	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
 	"github.com/tinywideclouds/go-platform/pkg/net/v1"
 )
 
+// record is a stored key set together with its metadata and the time it was written.
+type record struct {
+	keys      keys.PublicKeys
+	meta      keystore.Metadata
+	updatedAt time.Time
+	epoch     uint64
+	// compromised holds the reason given to MarkCompromised, if flagged.
+	compromised *string
+	// accesses is shared by every copy of the record, so reads can count
+	// under the read lock.
+	accesses *atomic.Int64
+}
+
+func (s *Store) newRecord(keys keys.PublicKeys, meta keystore.Metadata) record {
+	return record{keys: keys, meta: meta, updatedAt: s.now().UTC(), accesses: new(atomic.Int64)}
+}
+
+func (r record) keyRecord() keystore.KeyRecord {
+	rec := keystore.KeyRecord{
+		Keys:        r.keys,
+		Metadata:    r.meta,
+		UpdatedAt:   r.updatedAt,
+		AccessCount: r.accesses.Load(),
+		Epoch:       r.epoch,
+	}
+	if r.compromised != nil {
+		rec.Compromised = true
+		rec.CompromisedReason = *r.compromised
+	}
+	return rec
+}
+
 // Store is a concrete, thread-safe in-memory implementation of the keystore.Store interface.
 type Store struct {
 	sync.RWMutex
-	keys map[string]keys.PublicKeys
+	keys map[string]record
+
+	countAccess bool
+	clock       func() time.Time
+
+	statsLogger   *slog.Logger
+	statsInterval time.Duration
+	statsTicks    <-chan time.Time
+	done          chan struct{}
+	closeOnce     sync.Once
+	wg            sync.WaitGroup
+}
+
+// Option configures optional behaviour of the in-memory store.
+type Option func(*Store)
+
+// WithAccessCounting counts every successful read of an entity's keys.
+// The count is reported in KeyRecord.AccessCount and resets when the keys
+// are stored again.
+func WithAccessCounting() Option {
+	return func(s *Store) {
+		s.countAccess = true
+	}
+}
+
+// WithClock makes the store stamp records with now instead of time.Now,
+// so tests can control how old records appear.
+func WithClock(now func() time.Time) Option {
+	return func(s *Store) {
+		s.clock = now
+	}
+}
+
+// now returns the store's current time.
+func (s *Store) now() time.Time {
+	if s.clock != nil {
+		return s.clock()
+	}
+	return time.Now()
 }
 
 // New creates a new, initialized in-memory key store.
-func New() *Store {
-	return &Store{keys: make(map[string]keys.PublicKeys)}
+func New(opts ...Option) *Store {
+	s := &Store{keys: make(map[string]record), done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.statsInterval > 0 {
+		s.wg.Add(1)
+		go s.logStats()
+	}
+	return s
 }
 
 // StorePublicKeys stores the PublicKeys struct in the map, keyed by the URN's string representation.
 // This operation is thread-safe.
 func (s *Store) StorePublicKeys(ctx context.Context, entityURN urn.URN, keys keys.PublicKeys) error {
+	return s.StorePublicKeysWithMetadata(ctx, entityURN, keys, keystore.Metadata{})
+}
+
+// StorePublicKeysWithMetadata stores the PublicKeys struct and its metadata,
+// replacing any existing entry but keeping its epoch. This operation is thread-safe.
+func (s *Store) StorePublicKeysWithMetadata(ctx context.Context, entityURN urn.URN, keys keys.PublicKeys, meta keystore.Metadata) error {
+	s.Lock()
+	defer s.Unlock()
+	rec := s.newRecord(keys, meta)
+	rec.epoch = s.keys[entityURN.String()].epoch
+	s.keys[entityURN.String()] = rec
+	return nil
+}
+
+// GetOrCreatePublicKeys stores candidate only if no entry exists for the URN,
+// and returns whichever keys are in effect afterwards.
+// The check and the write happen under one lock, so concurrent callers
+// agree on a single winner.
+func (s *Store) GetOrCreatePublicKeys(ctx context.Context, entityURN urn.URN, candidate keys.PublicKeys) (keys.PublicKeys, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	if rec, ok := s.keys[entityURN.String()]; ok {
+		return rec.keys, false, nil
+	}
+	s.keys[entityURN.String()] = s.newRecord(candidate, keystore.Metadata{})
+	return candidate, true, nil
+}
+
+// SwapPublicKeys replaces the entity's keys, keeping its epoch, and
+// returns the previous keys. The read and the write happen under one lock.
+func (s *Store) SwapPublicKeys(ctx context.Context, entityURN urn.URN, newKeys keys.PublicKeys) (keys.PublicKeys, error) {
+	s.Lock()
+	defer s.Unlock()
+	prev := s.keys[entityURN.String()]
+	rec := s.newRecord(newKeys, keystore.Metadata{})
+	rec.epoch = prev.epoch
+	s.keys[entityURN.String()] = rec
+	return prev.keys, nil
+}
+
+// BumpEpoch increments the entity's epoch under the write lock, so
+// concurrent bumps each observe a distinct value.
+func (s *Store) BumpEpoch(ctx context.Context, entityURN urn.URN) (uint64, error) {
+	s.Lock()
+	defer s.Unlock()
+	rec, ok := s.keys[entityURN.String()]
+	if !ok {
+		return 0, &keystore.StoreError{
+			Op:  keystore.OpBumpEpoch,
+			URN: entityURN,
+			Err: keystore.ErrKeyNotFound,
+		}
+	}
+	rec.epoch++
+	s.keys[entityURN.String()] = rec
+	return rec.epoch, nil
+}
+
+// MarkCompromised flags the entity's keys under the write lock.
+func (s *Store) MarkCompromised(ctx context.Context, entityURN urn.URN, reason string) error {
+	return s.setCompromised(keystore.OpMarkCompromised, entityURN, &reason)
+}
+
+// ClearCompromised removes the entity's compromised flag under the write lock.
+func (s *Store) ClearCompromised(ctx context.Context, entityURN urn.URN) error {
+	return s.setCompromised(keystore.OpClearCompromised, entityURN, nil)
+}
+
+func (s *Store) setCompromised(op string, entityURN urn.URN, reason *string) error {
 	s.Lock()
 	defer s.Unlock()
-	s.keys[entityURN.String()] = keys
+	rec, ok := s.keys[entityURN.String()]
+	if !ok {
+		return &keystore.StoreError{Op: op, URN: entityURN, Err: keystore.ErrKeyNotFound}
+	}
+	rec.compromised = reason
+	s.keys[entityURN.String()] = rec
 	return nil
 }
 
+// DeletePublicKeys removes the entity's record under the write lock.
+func (s *Store) DeletePublicKeys(ctx context.Context, entityURN urn.URN) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.keys[entityURN.String()]; !ok {
+		return false, nil
+	}
+	delete(s.keys, entityURN.String())
+	return true, nil
+}
+
 // GetPublicKeys retrieves the PublicKeys struct from the map.
 // It returns an error if no key is found for the given URN.
 // This operation is thread-safe.
 func (s *Store) GetPublicKeys(ctx context.Context, entityURN urn.URN) (keys.PublicKeys, error) {
+	rec, err := s.get(keystore.OpGetPublicKeys, entityURN)
+	if err != nil {
+		return keys.PublicKeys{}, err
+	}
+	return rec.keys, nil
+}
+
+// GetKeyRecord retrieves the keys along with their metadata and update time.
+// It returns an error if no key is found for the given URN.
+// This operation is thread-safe.
+func (s *Store) GetKeyRecord(ctx context.Context, entityURN urn.URN) (keystore.KeyRecord, error) {
+	rec, err := s.get(keystore.OpGetKeyRecord, entityURN)
+	if err != nil {
+		return keystore.KeyRecord{}, err
+	}
+	return rec.keyRecord(), nil
+}
+
+// GetPublicKeysIfModifiedSince retrieves the PublicKeys struct only if it was
+// stored after `since`. The bool reports whether it was.
+// This operation is thread-safe.
+func (s *Store) GetPublicKeysIfModifiedSince(ctx context.Context, entityURN urn.URN, since time.Time) (keys.PublicKeys, bool, error) {
+	rec, err := s.get(keystore.OpGetPublicKeysIfModifiedSince, entityURN)
+	if err != nil {
+		return keys.PublicKeys{}, false, err
+	}
+	if !rec.updatedAt.After(since) {
+		return keys.PublicKeys{}, false, nil
+	}
+	return rec.keys, true, nil
+}
+
+// IterateAll calls fn for a snapshot of every stored entity. The snapshot is
+// taken under a read lock, so fn may safely call back into the store.
+func (s *Store) IterateAll(ctx context.Context, fn func(entityURN urn.URN, keys keys.PublicKeys) error) error {
+	return s.iterate(keystore.OpIterateAll, func(entityURN urn.URN, rec record) error {
+		return fn(entityURN, rec.keys)
+	})
+}
+
+// IterateKeyRecords is IterateAll with each entity's metadata and update time.
+func (s *Store) IterateKeyRecords(ctx context.Context, fn func(entityURN urn.URN, record keystore.KeyRecord) error) error {
+	return s.iterate(keystore.OpIterateKeyRecords, func(entityURN urn.URN, rec record) error {
+		return fn(entityURN, rec.keyRecord())
+	})
+}
+
+// iterate calls fn for a snapshot of every record, taken under a read lock.
+func (s *Store) iterate(op string, fn func(entityURN urn.URN, rec record) error) error {
+	s.RLock()
+	snapshot := make(map[string]record, len(s.keys))
+	for key, rec := range s.keys {
+		snapshot[key] = rec
+	}
+	s.RUnlock()
+
+	for key, rec := range snapshot {
+		entityURN, err := urn.Parse(key)
+		if err != nil {
+			return &keystore.StoreError{Op: op, Err: err}
+		}
+		if err := fn(entityURN, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetFingerprintsBatch looks up every entity under one read lock, so the
+// fingerprints are consistent with each other.
+func (s *Store) GetFingerprintsBatch(ctx context.Context, entityURNs []urn.URN) (map[string]string, error) {
+	s.RLock()
+	defer s.RUnlock()
+	fingerprints := make(map[string]string, len(entityURNs))
+	for _, entityURN := range entityURNs {
+		if rec, ok := s.keys[entityURN.String()]; ok {
+			fingerprints[entityURN.String()] = keystore.Fingerprint(rec.keys)
+		}
+	}
+	return fingerprints, nil
+}
+
+// GetPublicKeysBatch looks up each entity in turn, counting the accesses
+// as GetKeyRecord does.
+func (s *Store) GetPublicKeysBatch(ctx context.Context, entityURNs []urn.URN) (map[string]keystore.KeyRecord, error) {
+	records := make(map[string]keystore.KeyRecord, len(entityURNs))
+	for _, entityURN := range entityURNs {
+		rec, err := s.get(keystore.OpGetPublicKeysBatch, entityURN)
+		if err != nil {
+			continue
+		}
+		records[entityURN.String()] = rec.keyRecord()
+	}
+	return records, nil
+}
+
+// DiffPublicKeys reads both entities under one read lock.
+func (s *Store) DiffPublicKeys(ctx context.Context, a, b urn.URN) (keystore.KeyDiff, error) {
+	s.RLock()
+	defer s.RUnlock()
+	recs := make([]record, 0, 2)
+	for _, entityURN := range []urn.URN{a, b} {
+		rec, ok := s.keys[entityURN.String()]
+		if !ok {
+			return keystore.KeyDiff{}, &keystore.StoreError{
+				Op:  keystore.OpDiffPublicKeys,
+				URN: entityURN,
+				Err: keystore.ErrKeyNotFound,
+			}
+		}
+		recs = append(recs, rec)
+	}
+	return keystore.DiffKeys(recs[0].keys, recs[1].keys), nil
+}
+
+// DeleteOlderThan removes the old records under the write lock.
+func (s *Store) DeleteOlderThan(ctx context.Context, age time.Duration) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+	cutoff := s.now().Add(-age)
+	deleted := 0
+	for key, rec := range s.keys {
+		if rec.updatedAt.Before(cutoff) {
+			delete(s.keys, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// ListEntitiesMissingSigKey scans every record under one read lock.
+func (s *Store) ListEntitiesMissingSigKey(ctx context.Context) ([]urn.URN, error) {
+	s.RLock()
+	var missing []string
+	for key, rec := range s.keys {
+		if len(rec.keys.SigKey) == 0 {
+			missing = append(missing, key)
+		}
+	}
+	s.RUnlock()
+
+	slices.Sort(missing)
+	entityURNs := make([]urn.URN, 0, len(missing))
+	for _, key := range missing {
+		entityURN, err := urn.Parse(key)
+		if err != nil {
+			return nil, &keystore.StoreError{Op: keystore.OpListEntitiesMissingSigKey, Err: err}
+		}
+		entityURNs = append(entityURNs, entityURN)
+	}
+	return entityURNs, nil
+}
+
+// get looks up the record for an entity under a read lock, counting the
+// access if enabled.
+func (s *Store) get(op string, entityURN urn.URN) (record, error) {
 	s.RLock()
 	defer s.RUnlock()
-	keyStruct, ok := s.keys[entityURN.String()]
+	rec, ok := s.keys[entityURN.String()]
 	if !ok {
-		return keys.PublicKeys{}, fmt.Errorf("key for entity %s not found", entityURN.String())
+		return record{}, &keystore.StoreError{
+			Op:  op,
+			URN: entityURN,
+			Err: keystore.ErrKeyNotFound,
+		}
+	}
+	if s.countAccess {
+		rec.accesses.Add(1)
 	}
-	return keyStruct, nil
+	return rec, nil
 }
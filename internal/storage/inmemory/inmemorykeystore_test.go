@@ -3,7 +3,10 @@ package inmemory_test
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -45,5 +48,690 @@ func TestInMemoryStore_Integration(t *testing.T) {
 	nonExistentURN, err := urn.New(urn.SecureMessaging, "user", "not-found")
 	require.NoError(t, err)
 	_, err = store.GetPublicKeys(ctx, nonExistentURN)
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, keystore.ErrKeyNotFound)
+	_, err = store.GetKeyRecord(ctx, nonExistentURN)
+	assert.ErrorIs(t, err, keystore.ErrKeyNotFound)
+}
+
+func TestInMemoryStore_StoreError(t *testing.T) {
+	ctx, store := setupSuite(t)
+
+	// Arrange
+	missingURN, err := urn.New(urn.SecureMessaging, "user", "missing-user")
+	require.NoError(t, err)
+
+	// Act
+	_, err = store.GetPublicKeys(ctx, missingURN)
+
+	// Assert: The error carries the operation and URN
+	require.Error(t, err)
+	var storeErr *keystore.StoreError
+	require.True(t, errors.As(err, &storeErr))
+	assert.Equal(t, keystore.OpGetPublicKeys, storeErr.Op)
+	assert.Equal(t, missingURN, storeErr.URN)
+	assert.Contains(t, err.Error(), missingURN.String())
+}
+
+func TestInMemoryStore_GetPublicKeysIfModifiedSince(t *testing.T) {
+	ctx, store := setupSuite(t)
+
+	// Arrange
+	userURN, err := urn.New(urn.SecureMessaging, "user", "user-123")
+	require.NoError(t, err)
+	testKeys := keys.PublicKeys{
+		EncKey: []byte("enc-key"),
+		SigKey: []byte("sig-key"),
+	}
+
+	beforeStore := time.Now().Add(-time.Second)
+	err = store.StorePublicKeys(ctx, userURN, testKeys)
+	require.NoError(t, err)
+	afterStore := time.Now().Add(time.Second)
+
+	t.Run("Modified - keys stored after since", func(t *testing.T) {
+		retrievedKeys, modified, err := store.GetPublicKeysIfModifiedSince(ctx, userURN, beforeStore)
+		require.NoError(t, err)
+		assert.True(t, modified)
+		assert.Equal(t, testKeys, retrievedKeys)
+	})
+
+	t.Run("Unmodified - keys stored before since", func(t *testing.T) {
+		retrievedKeys, modified, err := store.GetPublicKeysIfModifiedSince(ctx, userURN, afterStore)
+		require.NoError(t, err)
+		assert.False(t, modified)
+		assert.Empty(t, retrievedKeys)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		missingURN, err := urn.New(urn.SecureMessaging, "user", "missing-user")
+		require.NoError(t, err)
+
+		_, _, err = store.GetPublicKeysIfModifiedSince(ctx, missingURN, beforeStore)
+
+		var storeErr *keystore.StoreError
+		require.True(t, errors.As(err, &storeErr))
+		assert.Equal(t, keystore.OpGetPublicKeysIfModifiedSince, storeErr.Op)
+	})
+}
+
+func TestInMemoryStore_IterateAll(t *testing.T) {
+	ctx, store := setupSuite(t)
+
+	// Arrange
+	stored := map[string]keys.PublicKeys{}
+	for _, id := range []string{"user-a", "user-b", "user-c"} {
+		entityURN, err := urn.New(urn.SecureMessaging, "user", id)
+		require.NoError(t, err)
+		k := keys.PublicKeys{EncKey: []byte(id + "-enc"), SigKey: []byte(id + "-sig")}
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, k))
+		stored[entityURN.String()] = k
+	}
+
+	// Act
+	seen := map[string]keys.PublicKeys{}
+	err := store.IterateAll(ctx, func(entityURN urn.URN, k keys.PublicKeys) error {
+		seen[entityURN.String()] = k
+		return nil
+	})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, stored, seen)
+
+	// Act & Assert: An error from the callback stops iteration
+	stop := errors.New("stop")
+	calls := 0
+	err = store.IterateAll(ctx, func(urn.URN, keys.PublicKeys) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestInMemoryStore_AccessCounting(t *testing.T) {
+	ctx := context.Background()
+	entityURN, err := urn.New(urn.SecureMessaging, "user", "popular")
+	require.NoError(t, err)
+	pk := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+
+	t.Run("Enabled - reads are counted and a store resets the count", func(t *testing.T) {
+		// Arrange
+		store := inmemory.New(inmemory.WithAccessCounting())
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, pk))
+
+		// Act
+		for range 3 {
+			_, err := store.GetPublicKeys(ctx, entityURN)
+			require.NoError(t, err)
+		}
+		record, err := store.GetKeyRecord(ctx, entityURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.EqualValues(t, 4, record.AccessCount)
+
+		// Act & Assert: New keys start a new count
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, pk))
+		record, err = store.GetKeyRecord(ctx, entityURN)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, record.AccessCount)
+	})
+
+	t.Run("Disabled - count stays zero", func(t *testing.T) {
+		// Arrange
+		store := inmemory.New()
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, pk))
+
+		// Act
+		_, err := store.GetPublicKeys(ctx, entityURN)
+		require.NoError(t, err)
+		record, err := store.GetKeyRecord(ctx, entityURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Zero(t, record.AccessCount)
+	})
+}
+
+func TestInMemoryStore_RotationHint(t *testing.T) {
+	ctx, store := setupSuite(t)
+
+	// Arrange
+	userURN, err := urn.New(urn.SecureMessaging, "user", "user-123")
+	require.NoError(t, err)
+	testKeys := keys.PublicKeys{
+		EncKey: []byte("enc-key"),
+		SigKey: []byte("sig-key"),
+	}
+	hint := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// Act: Store with a hint and read it back
+	err = store.StorePublicKeysWithMetadata(ctx, userURN, testKeys, keystore.Metadata{RotationHint: hint})
+	require.NoError(t, err)
+	record, err := store.GetKeyRecord(ctx, userURN)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, testKeys, record.Keys)
+	assert.True(t, hint.Equal(record.Metadata.RotationHint))
+	assert.False(t, record.UpdatedAt.IsZero())
+
+	// Act & Assert: A plain store clears the hint
+	require.NoError(t, store.StorePublicKeys(ctx, userURN, testKeys))
+	record, err = store.GetKeyRecord(ctx, userURN)
+	require.NoError(t, err)
+	assert.True(t, record.Metadata.RotationHint.IsZero())
+}
+
+func TestInMemoryStore_GetOrCreatePublicKeys(t *testing.T) {
+	ctx, store := setupSuite(t)
+
+	t.Run("Create - entity is new", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "new-user")
+		require.NoError(t, err)
+		candidate := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+
+		// Act
+		effective, created, err := store.GetOrCreatePublicKeys(ctx, userURN, candidate)
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, created)
+		assert.Equal(t, candidate, effective)
+		stored, err := store.GetPublicKeys(ctx, userURN)
+		require.NoError(t, err)
+		assert.Equal(t, candidate, stored)
+	})
+
+	t.Run("Exists - existing keys are kept", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "existing-user")
+		require.NoError(t, err)
+		existing := keys.PublicKeys{EncKey: []byte("old-enc"), SigKey: []byte("old-sig")}
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, existing))
+
+		// Act
+		effective, created, err := store.GetOrCreatePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte("new-enc"), SigKey: []byte("new-sig")})
+
+		// Assert
+		require.NoError(t, err)
+		assert.False(t, created)
+		assert.Equal(t, existing, effective)
+	})
+
+	t.Run("Concurrent - exactly one caller wins", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "raced-user")
+		require.NoError(t, err)
+		const callers = 8
+		type result struct {
+			effective keys.PublicKeys
+			created   bool
+			err       error
+		}
+		results := make(chan result, callers)
+
+		// Act
+		var wg sync.WaitGroup
+		for i := range callers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				candidate := keys.PublicKeys{EncKey: []byte{byte(i)}, SigKey: []byte{byte(i)}}
+				effective, created, err := store.GetOrCreatePublicKeys(ctx, userURN, candidate)
+				results <- result{effective, created, err}
+			}()
+		}
+		wg.Wait()
+		close(results)
+
+		// Assert: one creator, and every caller sees the same effective keys
+		winners := 0
+		var first *keys.PublicKeys
+		for res := range results {
+			require.NoError(t, res.err)
+			if res.created {
+				winners++
+			}
+			if first == nil {
+				first = &res.effective
+			}
+			assert.Equal(t, *first, res.effective)
+		}
+		assert.Equal(t, 1, winners)
+	})
+}
+
+func TestInMemoryStore_SwapPublicKeys(t *testing.T) {
+	ctx, store := setupSuite(t)
+
+	t.Run("Success - returns the displaced keys", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "swap-user")
+		require.NoError(t, err)
+		oldKeys := keys.PublicKeys{EncKey: []byte("old-enc"), SigKey: []byte("old-sig")}
+		newKeys := keys.PublicKeys{EncKey: []byte("new-enc"), SigKey: []byte("new-sig")}
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, oldKeys))
+		_, err = store.BumpEpoch(ctx, userURN)
+		require.NoError(t, err)
+
+		// Act
+		displaced, err := store.SwapPublicKeys(ctx, userURN, newKeys)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, oldKeys, displaced)
+		record, err := store.GetKeyRecord(ctx, userURN)
+		require.NoError(t, err)
+		assert.Equal(t, newKeys, record.Keys)
+		assert.Equal(t, uint64(1), record.Epoch, "swapping keys must keep the epoch")
+	})
+
+	t.Run("Success - new entity displaces nothing", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "swap-new-user")
+		require.NoError(t, err)
+		newKeys := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+
+		// Act
+		displaced, err := store.SwapPublicKeys(ctx, userURN, newKeys)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, keys.PublicKeys{}, displaced)
+		stored, err := store.GetPublicKeys(ctx, userURN)
+		require.NoError(t, err)
+		assert.Equal(t, newKeys, stored)
+	})
+}
+
+func TestInMemoryStore_BumpEpoch(t *testing.T) {
+	ctx, store := setupSuite(t)
+
+	t.Run("Concurrent - every bump gets a distinct, increasing epoch", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "epoch-user")
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+		const callers = 8
+		epochs := make(chan uint64, callers)
+
+		// Act
+		var wg sync.WaitGroup
+		for range callers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				epoch, err := store.BumpEpoch(ctx, userURN)
+				assert.NoError(t, err)
+				epochs <- epoch
+			}()
+		}
+		wg.Wait()
+		close(epochs)
+
+		// Assert: the bumps were serialized into 1..callers
+		seen := make(map[uint64]bool)
+		for epoch := range epochs {
+			seen[epoch] = true
+		}
+		for want := uint64(1); want <= callers; want++ {
+			assert.True(t, seen[want], "missing epoch %d", want)
+		}
+		record, err := store.GetKeyRecord(ctx, userURN)
+		require.NoError(t, err)
+		assert.EqualValues(t, callers, record.Epoch)
+	})
+
+	t.Run("Success - storing keys keeps the epoch", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "epoch-kept")
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+		_, err = store.BumpEpoch(ctx, userURN)
+		require.NoError(t, err)
+
+		// Act
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte("enc2"), SigKey: []byte("sig2")}))
+		next, err := store.BumpEpoch(ctx, userURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, next)
+	})
+
+	t.Run("Failure - unknown entity", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "epoch-missing")
+		require.NoError(t, err)
+
+		// Act
+		_, err = store.BumpEpoch(ctx, userURN)
+
+		// Assert
+		var storeErr *keystore.StoreError
+		require.ErrorAs(t, err, &storeErr)
+		assert.Equal(t, keystore.OpBumpEpoch, storeErr.Op)
+	})
+}
+
+func TestInMemoryStore_RunInTransaction(t *testing.T) {
+	ctx := context.Background()
+	store := inmemory.New()
+	userURN, err := urn.New(urn.SecureMessaging, "user", "tx-user")
+	require.NoError(t, err)
+	deviceURN, err := urn.New(urn.SecureMessaging, "device", "tx-device")
+	require.NoError(t, err)
+	original := keys.PublicKeys{EncKey: []byte("enc-v1"), SigKey: []byte("sig-v1")}
+	rotated := keys.PublicKeys{EncKey: []byte("enc-v2"), SigKey: []byte("sig-v2")}
+	require.NoError(t, store.StorePublicKeys(ctx, userURN, original))
+	require.NoError(t, store.StorePublicKeys(ctx, deviceURN, original))
+
+	t.Run("Rollback - an error discards every write", func(t *testing.T) {
+		// Arrange
+		errAbort := errors.New("abort")
+
+		// Act
+		err := store.RunInTransaction(ctx, func(tx keystore.StoreTx) error {
+			if _, err := tx.GetKeyRecord(userURN); err != nil {
+				return err
+			}
+			if err := tx.StorePublicKeysWithMetadata(userURN, rotated, keystore.Metadata{}); err != nil {
+				return err
+			}
+			if err := tx.StorePublicKeysWithMetadata(deviceURN, rotated, keystore.Metadata{}); err != nil {
+				return err
+			}
+			return errAbort
+		})
+
+		// Assert
+		assert.ErrorIs(t, err, errAbort)
+		for _, entityURN := range []urn.URN{userURN, deviceURN} {
+			got, err := store.GetPublicKeys(ctx, entityURN)
+			require.NoError(t, err)
+			assert.Equal(t, original, got)
+		}
+	})
+
+	t.Run("Commit - every write lands together", func(t *testing.T) {
+		// Act
+		err := store.RunInTransaction(ctx, func(tx keystore.StoreTx) error {
+			record, err := tx.GetKeyRecord(userURN)
+			if err != nil {
+				return err
+			}
+			if err := tx.StorePublicKeysWithMetadata(userURN, rotated, record.Metadata); err != nil {
+				return err
+			}
+			return tx.StorePublicKeysWithMetadata(deviceURN, rotated, keystore.Metadata{})
+		})
+
+		// Assert
+		require.NoError(t, err)
+		for _, entityURN := range []urn.URN{userURN, deviceURN} {
+			got, err := store.GetPublicKeys(ctx, entityURN)
+			require.NoError(t, err)
+			assert.Equal(t, rotated, got)
+		}
+	})
+
+	t.Run("Failure - reads must come before writes", func(t *testing.T) {
+		// Act
+		err := store.RunInTransaction(ctx, func(tx keystore.StoreTx) error {
+			if err := tx.StorePublicKeysWithMetadata(userURN, original, keystore.Metadata{}); err != nil {
+				return err
+			}
+			_, err := tx.GetKeyRecord(deviceURN)
+			return err
+		})
+
+		// Assert
+		assert.Error(t, err)
+		got, err := store.GetPublicKeys(ctx, userURN)
+		require.NoError(t, err)
+		assert.Equal(t, rotated, got)
+	})
+}
+
+func TestInMemoryStore_Compromised(t *testing.T) {
+	ctx, store := setupSuite(t)
+	testKeys := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+
+	t.Run("Success - flag surfaces on the record and can be cleared", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "compromised-user")
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, testKeys))
+		before, err := store.GetKeyRecord(ctx, userURN)
+		require.NoError(t, err)
+
+		// Act
+		require.NoError(t, store.MarkCompromised(ctx, userURN, "device stolen"))
+		flagged, err := store.GetKeyRecord(ctx, userURN)
+		require.NoError(t, err)
+		require.NoError(t, store.ClearCompromised(ctx, userURN))
+		cleared, err := store.GetKeyRecord(ctx, userURN)
+		require.NoError(t, err)
+
+		// Assert
+		assert.True(t, flagged.Compromised)
+		assert.Equal(t, "device stolen", flagged.CompromisedReason)
+		assert.Equal(t, testKeys, flagged.Keys)
+		assert.True(t, before.UpdatedAt.Equal(flagged.UpdatedAt), "flagging must not touch updatedAt")
+		assert.False(t, cleared.Compromised)
+		assert.Empty(t, cleared.CompromisedReason)
+	})
+
+	t.Run("Success - storing new keys clears the flag", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "compromised-rotated")
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, testKeys))
+		require.NoError(t, store.MarkCompromised(ctx, userURN, "leaked"))
+
+		// Act
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte("enc2"), SigKey: []byte("sig2")}))
+
+		// Assert
+		record, err := store.GetKeyRecord(ctx, userURN)
+		require.NoError(t, err)
+		assert.False(t, record.Compromised)
+	})
+
+	t.Run("Failure - unknown entity", func(t *testing.T) {
+		// Arrange
+		userURN, err := urn.New(urn.SecureMessaging, "user", "compromised-missing")
+		require.NoError(t, err)
+
+		// Act
+		err = store.MarkCompromised(ctx, userURN, "leaked")
+
+		// Assert
+		var storeErr *keystore.StoreError
+		require.ErrorAs(t, err, &storeErr)
+		assert.Equal(t, keystore.OpMarkCompromised, storeErr.Op)
+		_, err = store.GetKeyRecord(ctx, userURN)
+		assert.Error(t, err, "marking must not create the entity")
+	})
+}
+
+func TestInMemoryStore_ListEntitiesMissingSigKey(t *testing.T) {
+	ctx, store := setupSuite(t)
+
+	// Arrange: a mix of complete and encryption-only entities
+	stored := map[string]keys.PublicKeys{
+		"complete-1": {EncKey: []byte("enc"), SigKey: []byte("sig")},
+		"enc-only-1": {EncKey: []byte("enc")},
+		"complete-2": {EncKey: []byte("enc"), SigKey: []byte("sig")},
+		"enc-only-2": {EncKey: []byte("enc"), SigKey: []byte{}},
+	}
+	for id, pk := range stored {
+		entityURN, err := urn.New(urn.SecureMessaging, "user", id)
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, pk))
+	}
+
+	// Act
+	missing, err := store.ListEntitiesMissingSigKey(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	var got []string
+	for _, entityURN := range missing {
+		got = append(got, entityURN.String())
+	}
+	assert.Equal(t, []string{"urn:sm:user:enc-only-1", "urn:sm:user:enc-only-2"}, got)
+}
+
+func TestInMemoryStore_DeleteOlderThan(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	store := inmemory.New(inmemory.WithClock(func() time.Time { return now }))
+
+	// Arrange: entities stored at varying ages before start
+	ages := map[string]time.Duration{"fresh": time.Hour, "stale": 48 * time.Hour, "ancient": 400 * time.Hour}
+	entityURNs := map[string]urn.URN{}
+	for id, age := range ages {
+		now = start.Add(-age)
+		entityURN, err := urn.New(urn.SecureMessaging, "user", id)
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+		entityURNs[id] = entityURN
+	}
+	now = start
+
+	// Act
+	deleted, err := store.DeleteOlderThan(ctx, 24*time.Hour)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	_, err = store.GetPublicKeys(ctx, entityURNs["fresh"])
+	assert.NoError(t, err)
+	for _, id := range []string{"stale", "ancient"} {
+		_, err = store.GetPublicKeys(ctx, entityURNs[id])
+		assert.Error(t, err, id)
+	}
+
+	// Act: nothing left is old enough
+	deleted, err = store.DeleteOlderThan(ctx, 24*time.Hour)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func TestInMemoryStore_DeletePublicKeys(t *testing.T) {
+	ctx, store := setupSuite(t)
+	userURN, err := urn.New(urn.SecureMessaging, "user", "leaving")
+	require.NoError(t, err)
+
+	t.Run("Success - removes the keys and their epoch", func(t *testing.T) {
+		// Arrange
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+		_, err := store.BumpEpoch(ctx, userURN)
+		require.NoError(t, err)
+
+		// Act
+		deleted, err := store.DeletePublicKeys(ctx, userURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, deleted)
+		_, err = store.GetKeyRecord(ctx, userURN)
+		assert.Error(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+		record, err := store.GetKeyRecord(ctx, userURN)
+		require.NoError(t, err)
+		assert.Zero(t, record.Epoch, "a re-registered entity starts a new epoch")
+	})
+
+	t.Run("Success - unknown entity is not deleted", func(t *testing.T) {
+		// Arrange
+		missingURN, err := urn.New(urn.SecureMessaging, "user", "never-stored")
+		require.NoError(t, err)
+
+		// Act
+		deleted, err := store.DeletePublicKeys(ctx, missingURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.False(t, deleted)
+	})
+}
+
+func TestInMemoryStore_GetFingerprintsBatch(t *testing.T) {
+	ctx, store := setupSuite(t)
+
+	// Arrange
+	testKeys := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+	presentURN, err := urn.New(urn.SecureMessaging, "user", "fingerprinted")
+	require.NoError(t, err)
+	absentURN, err := urn.New(urn.SecureMessaging, "user", "fingerprint-missing")
+	require.NoError(t, err)
+	require.NoError(t, store.StorePublicKeys(ctx, presentURN, testKeys))
+
+	// Act
+	fingerprints, err := store.GetFingerprintsBatch(ctx, []urn.URN{presentURN, absentURN})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{presentURN.String(): keystore.Fingerprint(testKeys)}, fingerprints)
+}
+
+func TestInMemoryStore_GetPublicKeysBatch(t *testing.T) {
+	ctx, store := setupSuite(t)
+
+	// Arrange
+	testKeys := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+	presentURN, err := urn.New(urn.SecureMessaging, "user", "batch-present")
+	require.NoError(t, err)
+	absentURN, err := urn.New(urn.SecureMessaging, "user", "batch-missing")
+	require.NoError(t, err)
+	require.NoError(t, store.StorePublicKeys(ctx, presentURN, testKeys))
+
+	// Act
+	records, err := store.GetPublicKeysBatch(ctx, []urn.URN{presentURN, absentURN})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, testKeys, records[presentURN.String()].Keys)
+}
+
+func TestInMemoryStore_DiffPublicKeys(t *testing.T) {
+	ctx, store := setupSuite(t)
+
+	// Arrange
+	shared := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+	aURN, err := urn.New(urn.SecureMessaging, "user", "diff-a")
+	require.NoError(t, err)
+	bURN, err := urn.New(urn.SecureMessaging, "user", "diff-b")
+	require.NoError(t, err)
+	cURN, err := urn.New(urn.SecureMessaging, "user", "diff-c")
+	require.NoError(t, err)
+	absentURN, err := urn.New(urn.SecureMessaging, "user", "diff-missing")
+	require.NoError(t, err)
+	require.NoError(t, store.StorePublicKeys(ctx, aURN, shared))
+	require.NoError(t, store.StorePublicKeys(ctx, bURN, shared))
+	require.NoError(t, store.StorePublicKeys(ctx, cURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("other")}))
+
+	// Act & Assert
+	diff, err := store.DiffPublicKeys(ctx, aURN, bURN)
+	require.NoError(t, err)
+	assert.True(t, diff.Identical())
+
+	diff, err = store.DiffPublicKeys(ctx, aURN, cURN)
+	require.NoError(t, err)
+	assert.Equal(t, keystore.KeyDiff{EncKeyEqual: true, SigKeyEqual: false}, diff)
+
+	_, err = store.DiffPublicKeys(ctx, aURN, absentURN)
+	assert.ErrorIs(t, err, keystore.ErrKeyNotFound)
+	var storeErr *keystore.StoreError
+	require.ErrorAs(t, err, &storeErr)
+	assert.Equal(t, absentURN, storeErr.URN)
 }
@@ -0,0 +1,76 @@
+// --- File: internal/storage/inmemory/stats.go ---
+package inmemory
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+)
+
+// WithStatsLogging logs the store's Stats to logger every interval, for
+// visibility where metrics are not scraped. A non-positive interval
+// disables it. Call Close to stop the logger.
+func WithStatsLogging(logger *slog.Logger, interval time.Duration) Option {
+	return func(s *Store) {
+		if interval <= 0 {
+			return
+		}
+		s.statsLogger = logger.With("component", "inmemory_store")
+		s.statsInterval = interval
+	}
+}
+
+// WithStatsTicks makes the stats logger log on every value received from
+// ticks instead of on its own interval, so tests can drive it. It has no
+// effect unless WithStatsLogging is enabled too.
+func WithStatsTicks(ticks <-chan time.Time) Option {
+	return func(s *Store) {
+		s.statsTicks = ticks
+	}
+}
+
+// Stats returns an exact count of the stored entities. ApproxBytes is the
+// memory held by their keys, IDs and metadata; it ignores map and
+// allocator overhead.
+func (s *Store) Stats(ctx context.Context) (keystore.StoreStats, error) {
+	s.RLock()
+	defer s.RUnlock()
+	stats := keystore.StoreStats{Entities: int64(len(s.keys))}
+	for key, rec := range s.keys {
+		stats.ApproxBytes += int64(len(key) + len(rec.keys.EncKey) + len(rec.keys.SigKey) +
+			len(rec.meta.EncKeySignature) + len(rec.meta.ProvisioningSignature))
+	}
+	return stats, nil
+}
+
+// Close stops any background work started by the store's options. It is
+// safe to call more than once, and the store remains usable afterwards.
+func (s *Store) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	s.wg.Wait()
+	return nil
+}
+
+// logStats logs Stats every statsInterval until the store is closed.
+func (s *Store) logStats() {
+	defer s.wg.Done()
+	ticks := s.statsTicks
+	if ticks == nil {
+		ticker := time.NewTicker(s.statsInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticks:
+			stats, _ := s.Stats(context.Background())
+			s.statsLogger.Info("In-memory store stats",
+				"entries", stats.Entities,
+				"approx_bytes", stats.ApproxBytes)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// --- File: internal/storage/inmemory/stats_test.go ---
+package inmemory_test
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// capturingHandler records every log record it handles.
+type capturingHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *capturingHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *capturingHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *capturingHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *capturingHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *capturingHandler) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.records)
+}
+
+func TestInMemoryStore_StatsLogging(t *testing.T) {
+	// Arrange
+	handler := &capturingHandler{}
+	ticks := make(chan time.Time)
+	store := inmemory.New(inmemory.WithStatsLogging(slog.New(handler), time.Hour), inmemory.WithStatsTicks(ticks))
+	entityURN, err := urn.Parse("urn:sm:user:stats")
+	require.NoError(t, err)
+	require.NoError(t, store.StorePublicKeys(context.Background(), entityURN, keys.PublicKeys{
+		EncKey: []byte("enc"),
+		SigKey: []byte("sig"),
+	}))
+
+	// Act: one tick, then Close waits for the logger to finish with it
+	ticks <- time.Now()
+	require.NoError(t, store.Close())
+
+	// Assert: stats were logged once for the tick
+	require.Equal(t, 1, handler.count())
+	first := handler.records[0]
+	assert.Equal(t, "In-memory store stats", first.Message)
+	attrs := map[string]int64{}
+	first.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value.Int64()
+		return true
+	})
+	assert.EqualValues(t, 1, attrs["entries"])
+	stats, err := store.Stats(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, stats.ApproxBytes, attrs["approx_bytes"])
+
+	// Assert: Close stopped the logger, so nothing takes the next tick
+	select {
+	case ticks <- time.Now():
+		t.Fatal("stats logger still running after Close")
+	default:
+	}
+	assert.NoError(t, store.Close(), "Close is idempotent")
+}
+
+func TestInMemoryStore_Stats(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := inmemory.New()
+	for _, id := range []string{"alice", "bob", "carol"} {
+		entityURN, err := urn.New(urn.SecureMessaging, "user", id)
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}))
+	}
+
+	// Act
+	stats, err := store.Stats(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, stats.Entities)
+	// Each record holds its URN plus six bytes of keys.
+	assert.EqualValues(t, len("urn:sm:user:alice")+len("urn:sm:user:bob")+len("urn:sm:user:carol")+3*6, stats.ApproxBytes)
+}
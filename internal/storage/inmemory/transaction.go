@@ -0,0 +1,63 @@
+// --- File: internal/storage/inmemory/transaction.go ---
+package inmemory
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// errReadAfterWrite mirrors Firestore's rule that a transaction's reads
+// all come before its writes, so code tested here behaves the same there.
+var errReadAfterWrite = errors.New("transaction read after write")
+
+// RunInTransaction holds the store's write lock for the whole of fn, so
+// transactions are serialized with each other and with every other call.
+// Writes are buffered and applied only if fn returns nil.
+func (s *Store) RunInTransaction(ctx context.Context, fn func(tx keystore.StoreTx) error) error {
+	s.Lock()
+	defer s.Unlock()
+
+	tx := &storeTx{store: s, writes: make(map[string]record)}
+	if err := fn(tx); err != nil {
+		return &keystore.StoreError{Op: keystore.OpRunInTransaction, Err: err}
+	}
+	for key, rec := range tx.writes {
+		s.keys[key] = rec
+	}
+	return nil
+}
+
+// storeTx is the in-memory StoreTx. It is only used while RunInTransaction
+// holds the store's lock.
+type storeTx struct {
+	store  *Store
+	writes map[string]record
+}
+
+// GetKeyRecord reads the committed record for the entity.
+func (tx *storeTx) GetKeyRecord(entityURN urn.URN) (keystore.KeyRecord, error) {
+	if len(tx.writes) > 0 {
+		return keystore.KeyRecord{}, &keystore.StoreError{Op: keystore.OpGetKeyRecord, URN: entityURN, Err: errReadAfterWrite}
+	}
+	rec, ok := tx.store.keys[entityURN.String()]
+	if !ok {
+		return keystore.KeyRecord{}, &keystore.StoreError{
+			Op:  keystore.OpGetKeyRecord,
+			URN: entityURN,
+			Err: keystore.ErrKeyNotFound,
+		}
+	}
+	return rec.keyRecord(), nil
+}
+
+// StorePublicKeysWithMetadata stages a write, keeping the entity's epoch.
+func (tx *storeTx) StorePublicKeysWithMetadata(entityURN urn.URN, keys keys.PublicKeys, meta keystore.Metadata) error {
+	rec := tx.store.newRecord(keys, meta)
+	rec.epoch = tx.store.keys[entityURN.String()].epoch
+	tx.writes[entityURN.String()] = rec
+	return nil
+}
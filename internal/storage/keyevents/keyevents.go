@@ -0,0 +1,220 @@
+// --- File: internal/storage/keyevents/keyevents.go ---
+// Package keyevents provides a keystore.Store wrapper that announces key
+// changes as CloudEvents, for event-driven consumers downstream.
+package keyevents
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// EventTypeKeysStored is the CloudEvents type of an entity's keys being
+// created or replaced.
+const EventTypeKeysStored = "com.tinywideclouds.keyservice.keys.stored"
+
+// EventTypeKeysDeleted is the CloudEvents type of an entity's keys being
+// removed, whether deregistered or cleaned up for age.
+const EventTypeKeysDeleted = "com.tinywideclouds.keyservice.keys.deleted"
+
+// EventTypeKeysCompromised is the CloudEvents type of an entity's keys
+// being flagged as compromised. The reason is not included.
+const EventTypeKeysCompromised = "com.tinywideclouds.keyservice.keys.compromised"
+
+// EventTypeKeysCompromiseCleared is the CloudEvents type of an entity's
+// compromised flag being cleared by an admin.
+const EventTypeKeysCompromiseCleared = "com.tinywideclouds.keyservice.keys.compromise_cleared"
+
+// EventTypeKeysEpochBumped is the CloudEvents type of an entity's
+// rotation epoch being incremented.
+const EventTypeKeysEpochBumped = "com.tinywideclouds.keyservice.keys.epoch_bumped"
+
+// DefaultSource is the CloudEvents source used when none is configured.
+const DefaultSource = "//keyservice.tinywideclouds.com"
+
+// publishTimeout bounds how long a write waits for its event to be accepted.
+const publishTimeout = 5 * time.Second
+
+// Publisher sends a single message with the given attributes.
+type Publisher interface {
+	Publish(ctx context.Context, data []byte, attributes map[string]string) error
+}
+
+// EventData is the payload of a key-change event.
+type EventData struct {
+	URN urn.URN `json:"urn"`
+	// KID is the key ID the entity's signing key is published under.
+	KID string `json:"kid"`
+}
+
+// Store wraps a keystore.Store and publishes an event after every
+// successful key write or delete, and after every change to an entity's
+// compromised flag or rotation epoch. Publishing is best effort: a failure is logged
+// and never fails the write, which has already been committed.
+type Store struct {
+	keystore.Store
+
+	publisher Publisher
+	source    string
+	logger    *slog.Logger
+	now       func() time.Time
+}
+
+// New wraps next, publishing key-change events through publisher. An
+// empty source selects DefaultSource.
+func New(next keystore.Store, publisher Publisher, source string, logger *slog.Logger) *Store {
+	if source == "" {
+		source = DefaultSource
+	}
+	return &Store{
+		Store:     next,
+		publisher: publisher,
+		source:    source,
+		logger:    logger.With("component", "key_events"),
+		now:       time.Now,
+	}
+}
+
+// StorePublicKeys stores the keys, then publishes a keys-stored event.
+func (s *Store) StorePublicKeys(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys) error {
+	if err := s.Store.StorePublicKeys(ctx, entityURN, pk); err != nil {
+		return err
+	}
+	s.publish(ctx, EventTypeKeysStored, entityURN)
+	return nil
+}
+
+// StorePublicKeysWithMetadata stores the keys, then publishes a keys-stored event.
+func (s *Store) StorePublicKeysWithMetadata(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys, meta keystore.Metadata) error {
+	if err := s.Store.StorePublicKeysWithMetadata(ctx, entityURN, pk, meta); err != nil {
+		return err
+	}
+	s.publish(ctx, EventTypeKeysStored, entityURN)
+	return nil
+}
+
+// GetOrCreatePublicKeys publishes a keys-stored event only when the
+// candidate keys were actually created.
+func (s *Store) GetOrCreatePublicKeys(ctx context.Context, entityURN urn.URN, candidate keys.PublicKeys) (keys.PublicKeys, bool, error) {
+	effective, created, err := s.Store.GetOrCreatePublicKeys(ctx, entityURN, candidate)
+	if err == nil && created {
+		s.publish(ctx, EventTypeKeysStored, entityURN)
+	}
+	return effective, created, err
+}
+
+// SwapPublicKeys swaps the keys, then publishes a keys-stored event.
+func (s *Store) SwapPublicKeys(ctx context.Context, entityURN urn.URN, newKeys keys.PublicKeys) (keys.PublicKeys, error) {
+	old, err := s.Store.SwapPublicKeys(ctx, entityURN, newKeys)
+	if err == nil {
+		s.publish(ctx, EventTypeKeysStored, entityURN)
+	}
+	return old, err
+}
+
+// BumpEpoch bumps the epoch, then publishes an epoch-bumped event.
+func (s *Store) BumpEpoch(ctx context.Context, entityURN urn.URN) (uint64, error) {
+	epoch, err := s.Store.BumpEpoch(ctx, entityURN)
+	if err == nil {
+		s.publish(ctx, EventTypeKeysEpochBumped, entityURN)
+	}
+	return epoch, err
+}
+
+// MarkCompromised flags the keys, then publishes a keys-compromised event.
+func (s *Store) MarkCompromised(ctx context.Context, entityURN urn.URN, reason string) error {
+	if err := s.Store.MarkCompromised(ctx, entityURN, reason); err != nil {
+		return err
+	}
+	s.publish(ctx, EventTypeKeysCompromised, entityURN)
+	return nil
+}
+
+// ClearCompromised clears the flag, then publishes a compromise-cleared event.
+func (s *Store) ClearCompromised(ctx context.Context, entityURN urn.URN) error {
+	if err := s.Store.ClearCompromised(ctx, entityURN); err != nil {
+		return err
+	}
+	s.publish(ctx, EventTypeKeysCompromiseCleared, entityURN)
+	return nil
+}
+
+// DeletePublicKeys deletes the keys, then publishes a keys-deleted event
+// if there were any to delete.
+func (s *Store) DeletePublicKeys(ctx context.Context, entityURN urn.URN) (bool, error) {
+	deleted, err := s.Store.DeletePublicKeys(ctx, entityURN)
+	if err == nil && deleted {
+		s.publish(ctx, EventTypeKeysDeleted, entityURN)
+	}
+	return deleted, err
+}
+
+// DeleteOlderThan runs the cleanup, then publishes a keys-deleted event
+// for every entity it removed. The backend reports only a count, so the
+// entities are noted beforehand and those no longer found afterwards are
+// the ones announced. Events are still sent for whatever was removed
+// before a partial failure.
+func (s *Store) DeleteOlderThan(ctx context.Context, age time.Duration) (int, error) {
+	var candidates []urn.URN
+	listErr := s.Store.IterateAll(ctx, func(entityURN urn.URN, _ keys.PublicKeys) error {
+		candidates = append(candidates, entityURN)
+		return nil
+	})
+	if listErr != nil {
+		s.logger.Warn("Failed to list entities before cleanup; no delete events will be published", "err", listErr)
+	}
+
+	deleted, err := s.Store.DeleteOlderThan(ctx, age)
+	if deleted == 0 || listErr != nil {
+		return deleted, err
+	}
+
+	remaining, lookupErr := s.Store.GetFingerprintsBatch(context.WithoutCancel(ctx), candidates)
+	if lookupErr != nil {
+		s.logger.Warn("Failed to find entities removed by cleanup; no delete events will be published", "err", lookupErr)
+		return deleted, err
+	}
+	for _, entityURN := range candidates {
+		if _, ok := remaining[entityURN.String()]; !ok {
+			s.publish(ctx, EventTypeKeysDeleted, entityURN)
+		}
+	}
+	return deleted, err
+}
+
+// publish sends an event of the given type using the CloudEvents binary content
+// mode: the context attributes travel as "ce-" message attributes and the
+// message body is the JSON event data.
+func (s *Store) publish(ctx context.Context, eventType string, entityURN urn.URN) {
+	// The write has landed, so the event should go out even if the
+	// client has since disconnected.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), publishTimeout)
+	defer cancel()
+
+	data, err := json.Marshal(EventData{URN: entityURN, KID: entityURN.String()})
+	if err != nil {
+		s.logger.Warn("Failed to encode key event", "entity_urn", entityURN.String(), "err", err)
+		return
+	}
+	attributes := map[string]string{
+		"ce-specversion": "1.0",
+		"ce-id":          uuid.NewString(),
+		"ce-source":      s.source,
+		"ce-type":        eventType,
+		"ce-subject":     entityURN.String(),
+		"ce-time":        s.now().UTC().Format(time.RFC3339Nano),
+		"content-type":   "application/json",
+	}
+	if err := s.publisher.Publish(ctx, data, attributes); err != nil {
+		s.logger.Warn("Failed to publish key event", "entity_urn", entityURN.String(), "err", err)
+		return
+	}
+	s.logger.Debug("Published key event", "entity_urn", entityURN.String(), "event_id", attributes["ce-id"])
+}
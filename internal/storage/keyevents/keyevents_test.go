@@ -0,0 +1,202 @@
+// --- File: internal/storage/keyevents/keyevents_test.go ---
+package keyevents_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/internal/storage/keyevents"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+type message struct {
+	data       []byte
+	attributes map[string]string
+}
+
+// recordingPublisher captures published messages, failing with err if set.
+type recordingPublisher struct {
+	mu       sync.Mutex
+	err      error
+	messages []message
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, data []byte, attributes map[string]string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.messages = append(p.messages, message{data: data, attributes: attributes})
+	return nil
+}
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestStore_PublishesKeyEvents(t *testing.T) {
+	ctx := context.Background()
+	entityURN, err := urn.Parse("urn:sm:user:evented")
+	require.NoError(t, err)
+	pk := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+
+	t.Run("Success - store publishes a CloudEvent", func(t *testing.T) {
+		// Arrange
+		publisher := &recordingPublisher{}
+		store := keyevents.New(inmemory.New(), publisher, "//test", newTestLogger())
+
+		// Act
+		err := store.StorePublicKeysWithMetadata(ctx, entityURN, pk, keystore.Metadata{})
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, publisher.messages, 1)
+		msg := publisher.messages[0]
+		assert.Equal(t, "1.0", msg.attributes["ce-specversion"])
+		assert.Equal(t, keyevents.EventTypeKeysStored, msg.attributes["ce-type"])
+		assert.Equal(t, "//test", msg.attributes["ce-source"])
+		assert.Equal(t, entityURN.String(), msg.attributes["ce-subject"])
+		assert.NotEmpty(t, msg.attributes["ce-id"])
+
+		var data keyevents.EventData
+		require.NoError(t, json.Unmarshal(msg.data, &data))
+		assert.Equal(t, entityURN, data.URN)
+		assert.Equal(t, entityURN.String(), data.KID)
+	})
+
+	t.Run("Success - publish failure does not fail the write", func(t *testing.T) {
+		// Arrange
+		backend := inmemory.New()
+		publisher := &recordingPublisher{err: errors.New("topic unavailable")}
+		store := keyevents.New(backend, publisher, "", newTestLogger())
+
+		// Act
+		err := store.StorePublicKeys(ctx, entityURN, pk)
+
+		// Assert
+		require.NoError(t, err)
+		stored, err := backend.GetPublicKeys(ctx, entityURN)
+		require.NoError(t, err)
+		assert.Equal(t, pk, stored)
+	})
+
+	t.Run("Success - get-or-create publishes only on create", func(t *testing.T) {
+		// Arrange
+		publisher := &recordingPublisher{}
+		store := keyevents.New(inmemory.New(), publisher, "", newTestLogger())
+
+		// Act
+		_, created, err := store.GetOrCreatePublicKeys(ctx, entityURN, pk)
+		require.NoError(t, err)
+		require.True(t, created)
+		_, created, err = store.GetOrCreatePublicKeys(ctx, entityURN, pk)
+		require.NoError(t, err)
+		require.False(t, created)
+
+		// Assert
+		assert.Len(t, publisher.messages, 1)
+	})
+
+	t.Run("Success - delete publishes only when keys were removed", func(t *testing.T) {
+		// Arrange
+		publisher := &recordingPublisher{}
+		store := keyevents.New(inmemory.New(), publisher, "", newTestLogger())
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, pk))
+
+		// Act
+		deleted, err := store.DeletePublicKeys(ctx, entityURN)
+		require.NoError(t, err)
+		require.True(t, deleted)
+		deleted, err = store.DeletePublicKeys(ctx, entityURN)
+		require.NoError(t, err)
+		require.False(t, deleted)
+
+		// Assert
+		require.Len(t, publisher.messages, 2)
+		msg := publisher.messages[1]
+		assert.Equal(t, keyevents.EventTypeKeysDeleted, msg.attributes["ce-type"])
+		assert.Equal(t, entityURN.String(), msg.attributes["ce-subject"])
+	})
+
+	t.Run("Success - compromise and epoch changes publish", func(t *testing.T) {
+		// Arrange
+		publisher := &recordingPublisher{}
+		store := keyevents.New(inmemory.New(), publisher, "", newTestLogger())
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, pk))
+		publisher.messages = nil
+
+		// Act
+		require.NoError(t, store.MarkCompromised(ctx, entityURN, "device stolen"))
+		require.NoError(t, store.ClearCompromised(ctx, entityURN))
+		_, err := store.BumpEpoch(ctx, entityURN)
+		require.NoError(t, err)
+
+		// Assert
+		require.Len(t, publisher.messages, 3)
+		var types []string
+		for _, msg := range publisher.messages {
+			types = append(types, msg.attributes["ce-type"])
+			assert.Equal(t, entityURN.String(), msg.attributes["ce-subject"])
+			assert.NotContains(t, string(msg.data), "device stolen")
+		}
+		assert.Equal(t, []string{
+			keyevents.EventTypeKeysCompromised,
+			keyevents.EventTypeKeysCompromiseCleared,
+			keyevents.EventTypeKeysEpochBumped,
+		}, types)
+	})
+
+	t.Run("Failure - no event when the entity has no keys", func(t *testing.T) {
+		// Arrange
+		publisher := &recordingPublisher{}
+		store := keyevents.New(inmemory.New(), publisher, "", newTestLogger())
+		absentURN, err := urn.Parse("urn:sm:user:absent")
+		require.NoError(t, err)
+
+		// Act
+		err = store.MarkCompromised(ctx, absentURN, "device stolen")
+
+		// Assert
+		require.ErrorIs(t, err, keystore.ErrKeyNotFound)
+		assert.Empty(t, publisher.messages)
+	})
+
+	t.Run("Success - cleanup publishes for each removed entity", func(t *testing.T) {
+		// Arrange
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := now.Add(-time.Hour)
+		backend := inmemory.New(inmemory.WithClock(func() time.Time { return clock }))
+		publisher := &recordingPublisher{}
+		store := keyevents.New(backend, publisher, "", newTestLogger())
+		freshURN, err := urn.Parse("urn:sm:user:fresh")
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, pk))
+		clock = now
+		require.NoError(t, store.StorePublicKeys(ctx, freshURN, pk))
+		publisher.messages = nil
+
+		// Act
+		deleted, err := store.DeleteOlderThan(ctx, 30*time.Minute)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+		require.Len(t, publisher.messages, 1)
+		msg := publisher.messages[0]
+		assert.Equal(t, keyevents.EventTypeKeysDeleted, msg.attributes["ce-type"])
+		assert.Equal(t, entityURN.String(), msg.attributes["ce-subject"])
+	})
+}
@@ -0,0 +1,30 @@
+// --- File: internal/storage/keyevents/pubsub.go ---
+package keyevents
+
+import (
+	"context"
+
+	"cloud.google.com/go/pubsub/v2"
+)
+
+// PubSubPublisher publishes events to a Pub/Sub topic.
+type PubSubPublisher struct {
+	publisher *pubsub.Publisher
+}
+
+// NewPubSubPublisher creates a publisher for the given topic ID or full
+// topic name. Call Stop on shutdown to flush pending messages.
+func NewPubSubPublisher(client *pubsub.Client, topic string) *PubSubPublisher {
+	return &PubSubPublisher{publisher: client.Publisher(topic)}
+}
+
+// Publish sends the message and waits until Pub/Sub has accepted it.
+func (p *PubSubPublisher) Publish(ctx context.Context, data []byte, attributes map[string]string) error {
+	_, err := p.publisher.Publish(ctx, &pubsub.Message{Data: data, Attributes: attributes}).Get(ctx)
+	return err
+}
+
+// Stop flushes pending messages and stops the underlying publisher.
+func (p *PubSubPublisher) Stop() {
+	p.publisher.Stop()
+}
@@ -0,0 +1,78 @@
+// --- File: internal/storage/keyevents/pubsub_test.go ---
+//go:build integration
+
+package keyevents_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"github.com/illmade-knight/go-test/emulators"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/internal/storage/keyevents"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func TestPubSubPublisher_Integration(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+
+	// Arrange
+	const projectID = "test-project-keyevents"
+	topicName := "projects/" + projectID + "/topics/key-events"
+	subName := "projects/" + projectID + "/subscriptions/key-events-test"
+
+	conn := emulators.SetupPubsubEmulator(t, ctx, emulators.GetDefaultPubsubConfig(projectID))
+	client, err := pubsub.NewClient(ctx, projectID, conn.ClientOptions...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	_, err = client.TopicAdminClient.CreateTopic(ctx, &pubsubpb.Topic{Name: topicName})
+	require.NoError(t, err)
+	_, err = client.SubscriptionAdminClient.CreateSubscription(ctx, &pubsubpb.Subscription{Name: subName, Topic: topicName})
+	require.NoError(t, err)
+
+	publisher := keyevents.NewPubSubPublisher(client, topicName)
+	t.Cleanup(publisher.Stop)
+	store := keyevents.New(inmemory.New(), publisher, "", newTestLogger())
+
+	entityURN, err := urn.Parse("urn:sm:user:pubsub-user")
+	require.NoError(t, err)
+
+	// Act
+	err = store.StorePublicKeys(ctx, entityURN, keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")})
+	require.NoError(t, err)
+
+	// Assert
+	received := make(chan *pubsub.Message, 1)
+	recvCtx, stop := context.WithTimeout(ctx, 30*time.Second)
+	defer stop()
+	err = client.Subscriber(subName).Receive(recvCtx, func(_ context.Context, msg *pubsub.Message) {
+		msg.Ack()
+		select {
+		case received <- msg:
+			stop()
+		default:
+		}
+	})
+	require.NoError(t, err)
+
+	var msg *pubsub.Message
+	select {
+	case msg = <-received:
+	default:
+		t.Fatal("no key event was published")
+	}
+	assert.Equal(t, keyevents.EventTypeKeysStored, msg.Attributes["ce-type"])
+	var data keyevents.EventData
+	require.NoError(t, json.Unmarshal(msg.Data, &data))
+	assert.Equal(t, entityURN, data.URN)
+}
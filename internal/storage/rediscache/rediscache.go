@@ -0,0 +1,379 @@
+// --- File: internal/storage/rediscache/rediscache.go ---
+// Package rediscache provides a keystore.Store wrapper that caches key
+// reads in Redis, so every service instance sharing that Redis also shares
+// one cache in front of the backend.
+package rediscache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// DefaultTTL is used when no cache TTL is configured.
+const DefaultTTL = time.Minute
+
+const (
+	// keyPrefix namespaces cached records within the Redis keyspace.
+	keyPrefix = "keyservice:record:"
+	// InvalidationChannel carries the URN of every entity written through
+	// any instance, or flushAllPayload.
+	InvalidationChannel = "keyservice:invalidations"
+	// flushAllPayload on InvalidationChannel drops every local copy. It
+	// can never be a URN.
+	flushAllPayload = "*"
+	// flushScanCount is the SCAN batch size used when flushing Redis.
+	flushScanCount = 500
+)
+
+// cachedRecord is the form a KeyRecord takes in Redis. AccessCount is left
+// out: it changes on every read, so a cached copy would always be wrong.
+type cachedRecord struct {
+	EncKey                []byte                     `json:"encKey,omitempty"`
+	SigKey                []byte                     `json:"sigKey,omitempty"`
+	RotationHint          time.Time                  `json:"rotationHint,omitzero"`
+	EncKeySignature       []byte                     `json:"encKeySignature,omitempty"`
+	ProvisioningSignature []byte                     `json:"provisioningSignature,omitempty"`
+	UsageRestrictions     keystore.UsageRestrictions `json:"usageRestrictions,omitzero"`
+	UpdatedAt             time.Time                  `json:"updatedAt,omitzero"`
+	Epoch                 uint64                     `json:"epoch,omitempty"`
+	Compromised           bool                       `json:"compromised,omitempty"`
+	CompromisedReason     string                     `json:"compromisedReason,omitempty"`
+}
+
+// localEntry is a record held in this instance's own memory.
+type localEntry struct {
+	record   keystore.KeyRecord
+	cachedAt time.Time
+}
+
+// Store caches records in two tiers: a small in-process copy and the shared
+// Redis entry behind it. Every write through any instance deletes the
+// Redis entry and publishes the entity's URN on InvalidationChannel; each
+// instance drops its own copy when it hears the URN. Both tiers also expire
+// after the TTL, which bounds staleness if an invalidation is missed (for
+// example while the subscription is reconnecting).
+//
+// Redis failures are logged and the read falls through to the backend, so
+// an unavailable cache degrades performance, not availability. Lookups
+// that fail, including not-found, are never cached.
+type Store struct {
+	keystore.Store
+
+	client *redis.Client
+	pubsub *redis.PubSub
+	ttl    time.Duration
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu    sync.Mutex
+	local map[string]localEntry
+}
+
+// New wraps next with a cache in the given Redis. It subscribes to
+// InvalidationChannel before returning, so no invalidation published
+// afterwards is missed. A non-positive ttl selects DefaultTTL. The caller
+// keeps ownership of client; Close stops only the subscription.
+func New(ctx context.Context, next keystore.Store, client *redis.Client, ttl time.Duration, logger *slog.Logger) (*Store, error) {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	pubsub := client.Subscribe(ctx, InvalidationChannel)
+	// The first reply confirms the subscription is in place.
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", InvalidationChannel, err)
+	}
+
+	s := &Store{
+		Store:  next,
+		client: client,
+		pubsub: pubsub,
+		ttl:    ttl,
+		logger: logger.With("component", "redis_cache"),
+		now:    time.Now,
+		local:  make(map[string]localEntry),
+	}
+	go s.listen()
+	return s, nil
+}
+
+// Close stops listening for invalidations.
+func (s *Store) Close() error {
+	return s.pubsub.Close()
+}
+
+// listen drops local copies named on InvalidationChannel until Close.
+func (s *Store) listen() {
+	for msg := range s.pubsub.Channel() {
+		s.mu.Lock()
+		if msg.Payload == flushAllPayload {
+			clear(s.local)
+		} else {
+			delete(s.local, msg.Payload)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// StorePublicKeys writes through to the backend and invalidates the entity.
+func (s *Store) StorePublicKeys(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys) error {
+	if err := s.Store.StorePublicKeys(ctx, entityURN, pk); err != nil {
+		return err
+	}
+	s.invalidate(ctx, entityURN)
+	return nil
+}
+
+// StorePublicKeysWithMetadata writes through to the backend and
+// invalidates the entity.
+func (s *Store) StorePublicKeysWithMetadata(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys, meta keystore.Metadata) error {
+	if err := s.Store.StorePublicKeysWithMetadata(ctx, entityURN, pk, meta); err != nil {
+		return err
+	}
+	s.invalidate(ctx, entityURN)
+	return nil
+}
+
+// GetOrCreatePublicKeys delegates to the backend and invalidates the
+// entity when the candidate was stored.
+func (s *Store) GetOrCreatePublicKeys(ctx context.Context, entityURN urn.URN, candidate keys.PublicKeys) (keys.PublicKeys, bool, error) {
+	effective, created, err := s.Store.GetOrCreatePublicKeys(ctx, entityURN, candidate)
+	if err == nil && created {
+		s.invalidate(ctx, entityURN)
+	}
+	return effective, created, err
+}
+
+// SwapPublicKeys delegates to the backend and invalidates the entity.
+func (s *Store) SwapPublicKeys(ctx context.Context, entityURN urn.URN, newKeys keys.PublicKeys) (keys.PublicKeys, error) {
+	old, err := s.Store.SwapPublicKeys(ctx, entityURN, newKeys)
+	if err == nil {
+		s.invalidate(ctx, entityURN)
+	}
+	return old, err
+}
+
+// BumpEpoch delegates to the backend and invalidates the entity.
+func (s *Store) BumpEpoch(ctx context.Context, entityURN urn.URN) (uint64, error) {
+	epoch, err := s.Store.BumpEpoch(ctx, entityURN)
+	if err == nil {
+		s.invalidate(ctx, entityURN)
+	}
+	return epoch, err
+}
+
+// MarkCompromised delegates to the backend and invalidates the entity.
+func (s *Store) MarkCompromised(ctx context.Context, entityURN urn.URN, reason string) error {
+	if err := s.Store.MarkCompromised(ctx, entityURN, reason); err != nil {
+		return err
+	}
+	s.invalidate(ctx, entityURN)
+	return nil
+}
+
+// ClearCompromised delegates to the backend and invalidates the entity.
+func (s *Store) ClearCompromised(ctx context.Context, entityURN urn.URN) error {
+	if err := s.Store.ClearCompromised(ctx, entityURN); err != nil {
+		return err
+	}
+	s.invalidate(ctx, entityURN)
+	return nil
+}
+
+// DeletePublicKeys delegates to the backend and invalidates the entity.
+func (s *Store) DeletePublicKeys(ctx context.Context, entityURN urn.URN) (bool, error) {
+	deleted, err := s.Store.DeletePublicKeys(ctx, entityURN)
+	if err == nil && deleted {
+		s.invalidate(ctx, entityURN)
+	}
+	return deleted, err
+}
+
+// DeleteOlderThan delegates to the backend and, if anything was deleted,
+// flushes the whole cache: the backend does not report which entities it
+// removed. Deletes made before a failure still flush.
+func (s *Store) DeleteOlderThan(ctx context.Context, age time.Duration) (int, error) {
+	deleted, err := s.Store.DeleteOlderThan(ctx, age)
+	if deleted > 0 {
+		s.flush(ctx)
+	}
+	return deleted, err
+}
+
+// GetPublicKeys serves the entity's keys from the cache, filling it from
+// the backend on a miss.
+func (s *Store) GetPublicKeys(ctx context.Context, entityURN urn.URN) (keys.PublicKeys, error) {
+	record, err := s.GetKeyRecord(ctx, entityURN)
+	if err != nil {
+		return keys.PublicKeys{}, err
+	}
+	return record.Keys, nil
+}
+
+// GetKeyRecord serves the entity's record from the cache, filling it from
+// the backend on a miss. Cached records report a zero AccessCount.
+func (s *Store) GetKeyRecord(ctx context.Context, entityURN urn.URN) (keystore.KeyRecord, error) {
+	id := entityURN.String()
+	if record, ok := s.localRecord(id); ok {
+		return record, nil
+	}
+
+	if record, ok := s.sharedRecord(ctx, id); ok {
+		s.keepLocal(id, record)
+		return record, nil
+	}
+
+	record, err := s.Store.GetKeyRecord(ctx, entityURN)
+	if err != nil {
+		return keystore.KeyRecord{}, err
+	}
+	record.AccessCount = 0
+	s.share(ctx, id, record)
+	s.keepLocal(id, record)
+	return record, nil
+}
+
+// GetPublicKeysIfModifiedSince answers from the cached record, filling the
+// cache from the backend on a miss.
+func (s *Store) GetPublicKeysIfModifiedSince(ctx context.Context, entityURN urn.URN, since time.Time) (keys.PublicKeys, bool, error) {
+	record, err := s.GetKeyRecord(ctx, entityURN)
+	if err != nil {
+		return keys.PublicKeys{}, false, err
+	}
+	if !record.UpdatedAt.After(since) {
+		return keys.PublicKeys{}, false, nil
+	}
+	return record.Keys, true, nil
+}
+
+// invalidate removes the entity from every tier on every instance.
+func (s *Store) invalidate(ctx context.Context, entityURN urn.URN) {
+	id := entityURN.String()
+	s.mu.Lock()
+	delete(s.local, id)
+	s.mu.Unlock()
+
+	// The write has already succeeded, so do not let the caller's
+	// cancellation leave a stale entry behind.
+	ctx = context.WithoutCancel(ctx)
+	if err := s.client.Del(ctx, keyPrefix+id).Err(); err != nil {
+		s.logger.Warn("Failed to delete cached record; it may be served until it expires",
+			"entity_urn", id, "err", err)
+	}
+	if err := s.client.Publish(ctx, InvalidationChannel, id).Err(); err != nil {
+		s.logger.Warn("Failed to publish cache invalidation; other instances may serve a stale record until it expires",
+			"entity_urn", id, "err", err)
+	}
+}
+
+// flush removes every cached record from every tier on every instance.
+func (s *Store) flush(ctx context.Context) {
+	s.mu.Lock()
+	clear(s.local)
+	s.mu.Unlock()
+
+	ctx = context.WithoutCancel(ctx)
+	iter := s.client.Scan(ctx, 0, keyPrefix+"*", flushScanCount).Iterator()
+	for iter.Next(ctx) {
+		if err := s.client.Del(ctx, iter.Val()).Err(); err != nil {
+			s.logger.Warn("Failed to delete cached record; it may be served until it expires",
+				"key", iter.Val(), "err", err)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		s.logger.Warn("Failed to scan cached records; some may be served until they expire", "err", err)
+	}
+	if err := s.client.Publish(ctx, InvalidationChannel, flushAllPayload).Err(); err != nil {
+		s.logger.Warn("Failed to publish cache flush; other instances may serve stale records until they expire",
+			"err", err)
+	}
+}
+
+func (s *Store) localRecord(id string) (keystore.KeyRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.local[id]
+	if !ok {
+		return keystore.KeyRecord{}, false
+	}
+	if s.now().Sub(entry.cachedAt) >= s.ttl {
+		delete(s.local, id)
+		return keystore.KeyRecord{}, false
+	}
+	return entry.record, true
+}
+
+func (s *Store) keepLocal(id string, record keystore.KeyRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	// Drop expired copies while we hold the lock so the map stays bounded
+	// by the number of entities read within one TTL.
+	for key, entry := range s.local {
+		if now.Sub(entry.cachedAt) >= s.ttl {
+			delete(s.local, key)
+		}
+	}
+	s.local[id] = localEntry{record: record, cachedAt: now}
+}
+
+func (s *Store) sharedRecord(ctx context.Context, id string) (keystore.KeyRecord, bool) {
+	data, err := s.client.Get(ctx, keyPrefix+id).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			s.logger.Warn("Failed to read cached record, falling back to the backend", "entity_urn", id, "err", err)
+		}
+		return keystore.KeyRecord{}, false
+	}
+	var cached cachedRecord
+	if err := json.Unmarshal(data, &cached); err != nil {
+		s.logger.Warn("Discarding unreadable cached record", "entity_urn", id, "err", err)
+		return keystore.KeyRecord{}, false
+	}
+	return keystore.KeyRecord{
+		Keys: keys.PublicKeys{EncKey: cached.EncKey, SigKey: cached.SigKey},
+		Metadata: keystore.Metadata{
+			RotationHint:          cached.RotationHint,
+			EncKeySignature:       cached.EncKeySignature,
+			ProvisioningSignature: cached.ProvisioningSignature,
+			UsageRestrictions:     cached.UsageRestrictions,
+		},
+		UpdatedAt:         cached.UpdatedAt,
+		Epoch:             cached.Epoch,
+		Compromised:       cached.Compromised,
+		CompromisedReason: cached.CompromisedReason,
+	}, true
+}
+
+func (s *Store) share(ctx context.Context, id string, record keystore.KeyRecord) {
+	data, err := json.Marshal(cachedRecord{
+		EncKey:                record.Keys.EncKey,
+		SigKey:                record.Keys.SigKey,
+		RotationHint:          record.Metadata.RotationHint,
+		EncKeySignature:       record.Metadata.EncKeySignature,
+		ProvisioningSignature: record.Metadata.ProvisioningSignature,
+		UsageRestrictions:     record.Metadata.UsageRestrictions,
+		UpdatedAt:             record.UpdatedAt,
+		Epoch:                 record.Epoch,
+		Compromised:           record.Compromised,
+		CompromisedReason:     record.CompromisedReason,
+	})
+	if err != nil {
+		s.logger.Warn("Failed to encode record for the cache", "entity_urn", id, "err", err)
+		return
+	}
+	if err := s.client.Set(ctx, keyPrefix+id, data, s.ttl).Err(); err != nil {
+		s.logger.Warn("Failed to cache record", "entity_urn", id, "err", err)
+	}
+}
@@ -0,0 +1,146 @@
+// --- File: internal/storage/rediscache/rediscache_test.go ---
+//go:build integration
+
+package rediscache_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/go-test/emulators"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/internal/storage/rediscache"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// newInstance wraps backend with a cache in the shared Redis, as one
+// service instance would.
+func newInstance(t *testing.T, ctx context.Context, addr string, backend keystore.Store) *rediscache.Store {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { _ = client.Close() })
+	store, err := rediscache.New(ctx, backend, client, time.Minute, newTestLogger())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestRedisCacheStore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	t.Cleanup(cancel)
+	conn := emulators.SetupRedisContainer(t, ctx, emulators.GetDefaultRedisImageContainer())
+
+	userURN, err := urn.New(urn.SecureMessaging, "user", "user-123")
+	require.NoError(t, err)
+	oldKeys := keys.PublicKeys{EncKey: []byte("old-enc"), SigKey: []byte("old-sig")}
+	newKeys := keys.PublicKeys{EncKey: []byte("new-enc"), SigKey: []byte("new-sig")}
+	bypassKeys := keys.PublicKeys{EncKey: []byte("bypass-enc"), SigKey: []byte("bypass-sig")}
+
+	// Arrange: two instances over one backend and one Redis.
+	backend := inmemory.New()
+	require.NoError(t, backend.StorePublicKeys(ctx, userURN, oldKeys))
+	instanceA := newInstance(t, ctx, conn.EmulatorAddress, backend)
+	instanceB := newInstance(t, ctx, conn.EmulatorAddress, backend)
+
+	t.Run("Success - instances share cached reads", func(t *testing.T) {
+		// Arrange: A fills the cache, then the backend changes behind it.
+		_, err := instanceA.GetPublicKeys(ctx, userURN)
+		require.NoError(t, err)
+		require.NoError(t, backend.StorePublicKeys(ctx, userURN, bypassKeys))
+
+		// Act
+		got, err := instanceB.GetPublicKeys(ctx, userURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, oldKeys, got, "B should be served A's cached read")
+	})
+
+	t.Run("Success - a write on one instance invalidates the other", func(t *testing.T) {
+		// Act
+		require.NoError(t, instanceA.StorePublicKeys(ctx, userURN, newKeys))
+
+		// Assert
+		assert.Eventually(t, func() bool {
+			got, err := instanceB.GetPublicKeys(ctx, userURN)
+			return err == nil && assert.ObjectsAreEqual(newKeys, got)
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Success - an epoch bump invalidates the other instance", func(t *testing.T) {
+		// Arrange
+		before, err := instanceB.GetKeyRecord(ctx, userURN)
+		require.NoError(t, err)
+
+		// Act
+		epoch, err := instanceA.BumpEpoch(ctx, userURN)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Greater(t, epoch, before.Epoch)
+		assert.Eventually(t, func() bool {
+			record, err := instanceB.GetKeyRecord(ctx, userURN)
+			return err == nil && record.Epoch == epoch
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Failure - not-found is not cached", func(t *testing.T) {
+		// Arrange
+		otherURN, err := urn.New(urn.SecureMessaging, "user", "user-456")
+		require.NoError(t, err)
+		_, err = instanceB.GetPublicKeys(ctx, otherURN)
+		require.Error(t, err)
+		require.NoError(t, backend.StorePublicKeys(ctx, otherURN, newKeys))
+
+		// Act
+		got, err := instanceB.GetPublicKeys(ctx, otherURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, newKeys, got)
+	})
+
+	t.Run("Success - cleanup flushes every instance", func(t *testing.T) {
+		// Arrange: a record stored an hour ago, cached in Redis and in both
+		// instances' memory. Only this test's goroutine touches the clock.
+		cleanupURN, err := urn.New(urn.SecureMessaging, "user", "user-789")
+		require.NoError(t, err)
+		clock := time.Now().Add(-time.Hour)
+		aging := inmemory.New(inmemory.WithClock(func() time.Time { return clock }))
+		require.NoError(t, aging.StorePublicKeys(ctx, cleanupURN, oldKeys))
+		clock = time.Now()
+		cleanerA := newInstance(t, ctx, conn.EmulatorAddress, aging)
+		cleanerB := newInstance(t, ctx, conn.EmulatorAddress, aging)
+		_, err = cleanerA.GetPublicKeys(ctx, cleanupURN)
+		require.NoError(t, err)
+		_, err = cleanerB.GetPublicKeys(ctx, cleanupURN)
+		require.NoError(t, err)
+
+		// Act
+		deleted, err := cleanerA.DeleteOlderThan(ctx, time.Minute)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+		_, err = cleanerA.GetPublicKeys(ctx, cleanupURN)
+		assert.ErrorIs(t, err, keystore.ErrKeyNotFound)
+		assert.Eventually(t, func() bool {
+			_, err := cleanerB.GetPublicKeys(ctx, cleanupURN)
+			return errors.Is(err, keystore.ErrKeyNotFound)
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,281 @@
+// --- File: internal/storage/sessioncache/sessioncache.go ---
+// Package sessioncache provides a keystore.Store wrapper that gives each
+// client session read-your-writes consistency over a possibly stale backend.
+package sessioncache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+type sessionIDKey struct{}
+
+// ContextWithSessionID returns a copy of ctx tagged with a client session ID.
+func ContextWithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionIDKey{}, sessionID)
+}
+
+// SessionIDFromContext returns the session ID stored by ContextWithSessionID.
+func SessionIDFromContext(ctx context.Context) (string, bool) {
+	sessionID, ok := ctx.Value(sessionIDKey{}).(string)
+	return sessionID, ok && sessionID != ""
+}
+
+// writeKey identifies one entity's keys as written within one session.
+type writeKey struct {
+	sessionID string
+	entityURN string
+}
+
+// write is a value recently stored by a session.
+type write struct {
+	keys      keys.PublicKeys
+	meta      keystore.Metadata
+	writtenAt time.Time
+}
+
+// Store wraps a keystore.Store and remembers each session's writes for a
+// short window. Reads within the same session are served from those writes,
+// so a client always sees what it just stored even if the backend lags.
+// Requests without a session ID pass straight through.
+type Store struct {
+	keystore.Store
+
+	window time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	writes map[writeKey]write
+}
+
+// New wraps next, remembering session writes for the given window.
+func New(next keystore.Store, window time.Duration) *Store {
+	return &Store{
+		Store:  next,
+		window: window,
+		now:    time.Now,
+		writes: make(map[writeKey]write),
+	}
+}
+
+// StorePublicKeys writes through to the backend and, on success, records
+// the value against the caller's session.
+func (s *Store) StorePublicKeys(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys) error {
+	if err := s.Store.StorePublicKeys(ctx, entityURN, pk); err != nil {
+		return err
+	}
+	s.remember(ctx, entityURN, pk, keystore.Metadata{})
+	return nil
+}
+
+// StorePublicKeysWithMetadata writes through to the backend and, on
+// success, records the value against the caller's session.
+func (s *Store) StorePublicKeysWithMetadata(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys, meta keystore.Metadata) error {
+	if err := s.Store.StorePublicKeysWithMetadata(ctx, entityURN, pk, meta); err != nil {
+		return err
+	}
+	s.remember(ctx, entityURN, pk, meta)
+	return nil
+}
+
+// GetOrCreatePublicKeys delegates to the backend and, when the candidate
+// was stored, records it against the caller's session.
+func (s *Store) GetOrCreatePublicKeys(ctx context.Context, entityURN urn.URN, candidate keys.PublicKeys) (keys.PublicKeys, bool, error) {
+	effective, created, err := s.Store.GetOrCreatePublicKeys(ctx, entityURN, candidate)
+	if err == nil && created {
+		s.remember(ctx, entityURN, effective, keystore.Metadata{})
+	}
+	return effective, created, err
+}
+
+// SwapPublicKeys writes through to the backend and, on success, records
+// the new value against the caller's session.
+func (s *Store) SwapPublicKeys(ctx context.Context, entityURN urn.URN, newKeys keys.PublicKeys) (keys.PublicKeys, error) {
+	old, err := s.Store.SwapPublicKeys(ctx, entityURN, newKeys)
+	if err == nil {
+		s.remember(ctx, entityURN, newKeys, keystore.Metadata{})
+	}
+	return old, err
+}
+
+// DeletePublicKeys deletes through to the backend and forgets every
+// session's write of the entity, so none is served after the delete.
+// The deleting session then reads the backend like any other, so it may
+// briefly still see the keys if the backend lags.
+func (s *Store) DeletePublicKeys(ctx context.Context, entityURN urn.URN) (bool, error) {
+	deleted, err := s.Store.DeletePublicKeys(ctx, entityURN)
+	if err != nil {
+		return deleted, err
+	}
+	s.forget(entityURN)
+	return deleted, nil
+}
+
+// DeleteOlderThan runs the cleanup on the backend and, if it removed
+// anything, forgets every session's writes. The backend does not say
+// which entities went, so none of the remembered writes can be trusted.
+func (s *Store) DeleteOlderThan(ctx context.Context, age time.Duration) (int, error) {
+	deleted, err := s.Store.DeleteOlderThan(ctx, age)
+	if deleted > 0 {
+		s.mu.Lock()
+		clear(s.writes)
+		s.mu.Unlock()
+	}
+	return deleted, err
+}
+
+// MarkCompromised flags the keys on the backend and forgets every
+// session's write of the entity, so reads go to the backend and see the
+// flag alongside its current keys.
+func (s *Store) MarkCompromised(ctx context.Context, entityURN urn.URN, reason string) error {
+	if err := s.Store.MarkCompromised(ctx, entityURN, reason); err != nil {
+		return err
+	}
+	s.forget(entityURN)
+	return nil
+}
+
+// ClearCompromised clears the flag on the backend and forgets every
+// session's write of the entity.
+func (s *Store) ClearCompromised(ctx context.Context, entityURN urn.URN) error {
+	if err := s.Store.ClearCompromised(ctx, entityURN); err != nil {
+		return err
+	}
+	s.forget(entityURN)
+	return nil
+}
+
+// BumpEpoch bumps the epoch on the backend and forgets every session's
+// write of the entity.
+func (s *Store) BumpEpoch(ctx context.Context, entityURN urn.URN) (uint64, error) {
+	epoch, err := s.Store.BumpEpoch(ctx, entityURN)
+	if err != nil {
+		return epoch, err
+	}
+	s.forget(entityURN)
+	return epoch, nil
+}
+
+// forget drops every session's write of entityURN.
+func (s *Store) forget(entityURN urn.URN) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.writes {
+		if key.entityURN == entityURN.String() {
+			delete(s.writes, key)
+		}
+	}
+}
+
+// remember records a successful write against the caller's session, if any.
+func (s *Store) remember(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys, meta keystore.Metadata) {
+	sessionID, ok := SessionIDFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.pruneLocked(now)
+	s.writes[writeKey{sessionID: sessionID, entityURN: entityURN.String()}] = write{keys: pk, meta: meta, writtenAt: now}
+}
+
+// GetPublicKeys serves the session's own recent write if there is one,
+// and otherwise reads from the backend.
+func (s *Store) GetPublicKeys(ctx context.Context, entityURN urn.URN) (keys.PublicKeys, error) {
+	if w, ok := s.recentWrite(ctx, entityURN); ok {
+		return w.keys, nil
+	}
+	return s.Store.GetPublicKeys(ctx, entityURN)
+}
+
+// GetKeyRecord serves the session's own recent write if there is one,
+// and otherwise reads from the backend. A served write reports the time
+// it passed through this wrapper as its UpdatedAt. A write does not carry
+// the epoch or the compromised flag, so those are still read from the
+// backend, and changing either forgets the write altogether.
+func (s *Store) GetKeyRecord(ctx context.Context, entityURN urn.URN) (keystore.KeyRecord, error) {
+	if w, ok := s.recentWrite(ctx, entityURN); ok {
+		rec := keystore.KeyRecord{Keys: w.keys, Metadata: w.meta, UpdatedAt: w.writtenAt}
+		if backend, err := s.Store.GetKeyRecord(ctx, entityURN); err == nil {
+			rec.Epoch = backend.Epoch
+			rec.Compromised, rec.CompromisedReason = backend.Compromised, backend.CompromisedReason
+		}
+		return rec, nil
+	}
+	return s.Store.GetKeyRecord(ctx, entityURN)
+}
+
+// GetPublicKeysBatch reads the batch from the backend and overlays the
+// session's own recent writes, as GetKeyRecord does for each entity.
+func (s *Store) GetPublicKeysBatch(ctx context.Context, entityURNs []urn.URN) (map[string]keystore.KeyRecord, error) {
+	records, err := s.Store.GetPublicKeysBatch(ctx, entityURNs)
+	if err != nil {
+		return nil, err
+	}
+	for _, entityURN := range entityURNs {
+		w, ok := s.recentWrite(ctx, entityURN)
+		if !ok {
+			continue
+		}
+		backend := records[entityURN.String()]
+		records[entityURN.String()] = keystore.KeyRecord{
+			Keys:              w.keys,
+			Metadata:          w.meta,
+			UpdatedAt:         w.writtenAt,
+			Epoch:             backend.Epoch,
+			Compromised:       backend.Compromised,
+			CompromisedReason: backend.CompromisedReason,
+		}
+	}
+	return records, nil
+}
+
+// GetPublicKeysIfModifiedSince serves the session's own recent write if
+// there is one, and otherwise reads from the backend.
+func (s *Store) GetPublicKeysIfModifiedSince(ctx context.Context, entityURN urn.URN, since time.Time) (keys.PublicKeys, bool, error) {
+	if w, ok := s.recentWrite(ctx, entityURN); ok {
+		if !w.writtenAt.After(since) {
+			return keys.PublicKeys{}, false, nil
+		}
+		return w.keys, true, nil
+	}
+	return s.Store.GetPublicKeysIfModifiedSince(ctx, entityURN, since)
+}
+
+// recentWrite returns the caller's session write for entityURN if it is
+// still inside the window.
+func (s *Store) recentWrite(ctx context.Context, entityURN urn.URN) (write, bool) {
+	sessionID, ok := SessionIDFromContext(ctx)
+	if !ok {
+		return write{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := writeKey{sessionID: sessionID, entityURN: entityURN.String()}
+	w, ok := s.writes[key]
+	if !ok {
+		return write{}, false
+	}
+	if s.now().Sub(w.writtenAt) >= s.window {
+		delete(s.writes, key)
+		return write{}, false
+	}
+	return w, true
+}
+
+// pruneLocked drops writes older than the window. s.mu must be held.
+func (s *Store) pruneLocked(now time.Time) {
+	for key, w := range s.writes {
+		if now.Sub(w.writtenAt) >= s.window {
+			delete(s.writes, key)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// --- File: internal/storage/sessioncache/sessioncache_test.go ---
+package sessioncache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/internal/storage/sessioncache"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// laggingReplica acknowledges writes without applying them, standing in
+// for a backend whose reads have not caught up yet.
+type laggingReplica struct {
+	keystore.Store
+}
+
+func (laggingReplica) StorePublicKeys(ctx context.Context, entityURN urn.URN, keys keys.PublicKeys) error {
+	return nil
+}
+
+func TestSessionCacheStore(t *testing.T) {
+	userURN, err := urn.New(urn.SecureMessaging, "user", "user-123")
+	require.NoError(t, err)
+
+	staleKeys := keys.PublicKeys{EncKey: []byte("old-enc"), SigKey: []byte("old-sig")}
+	freshKeys := keys.PublicKeys{EncKey: []byte("new-enc"), SigKey: []byte("new-sig")}
+
+	// newStore returns a wrapper over a backend that still serves staleKeys.
+	newStore := func(t *testing.T, window time.Duration) *sessioncache.Store {
+		t.Helper()
+		backend := inmemory.New()
+		require.NoError(t, backend.StorePublicKeys(context.Background(), userURN, staleKeys))
+		return sessioncache.New(laggingReplica{Store: backend}, window)
+	}
+
+	t.Run("Success - same session reads its own write", func(t *testing.T) {
+		// Arrange
+		store := newStore(t, time.Minute)
+		ctx := sessioncache.ContextWithSessionID(context.Background(), "session-a")
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, freshKeys))
+
+		// Act
+		got, err := store.GetPublicKeys(ctx, userURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, freshKeys, got)
+	})
+
+	t.Run("Success - own write is not served after a delete", func(t *testing.T) {
+		// Arrange
+		store := newStore(t, time.Minute)
+		ctx := sessioncache.ContextWithSessionID(context.Background(), "session-a")
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, freshKeys))
+
+		// Act
+		deleted, err := store.DeletePublicKeys(ctx, userURN)
+		require.NoError(t, err)
+		_, getErr := store.GetPublicKeys(ctx, userURN)
+
+		// Assert
+		assert.True(t, deleted)
+		assert.Error(t, getErr)
+	})
+
+	t.Run("Success - other session reads the backend", func(t *testing.T) {
+		// Arrange
+		store := newStore(t, time.Minute)
+		writerCtx := sessioncache.ContextWithSessionID(context.Background(), "session-a")
+		readerCtx := sessioncache.ContextWithSessionID(context.Background(), "session-b")
+		require.NoError(t, store.StorePublicKeys(writerCtx, userURN, freshKeys))
+
+		// Act
+		got, err := store.GetPublicKeys(readerCtx, userURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, staleKeys, got)
+	})
+
+	t.Run("Success - no session reads the backend", func(t *testing.T) {
+		// Arrange
+		store := newStore(t, time.Minute)
+		require.NoError(t, store.StorePublicKeys(context.Background(), userURN, freshKeys))
+
+		// Act
+		got, err := store.GetPublicKeys(context.Background(), userURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, staleKeys, got)
+	})
+
+	t.Run("Success - write is forgotten after the window", func(t *testing.T) {
+		// Arrange
+		store := newStore(t, time.Nanosecond)
+		ctx := sessioncache.ContextWithSessionID(context.Background(), "session-a")
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, freshKeys))
+		time.Sleep(time.Millisecond)
+
+		// Act
+		got, err := store.GetPublicKeys(ctx, userURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, staleKeys, got)
+	})
+
+	t.Run("Success - conditional read sees own write", func(t *testing.T) {
+		// Arrange
+		store := newStore(t, time.Minute)
+		ctx := sessioncache.ContextWithSessionID(context.Background(), "session-a")
+		before := time.Now().Add(-time.Second)
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, freshKeys))
+
+		// Act
+		got, modified, err := store.GetPublicKeysIfModifiedSince(ctx, userURN, before)
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, modified)
+		assert.Equal(t, freshKeys, got)
+	})
+
+	t.Run("Success - own write carries the backend's epoch", func(t *testing.T) {
+		// Arrange
+		store := newStore(t, time.Minute)
+		ctx := sessioncache.ContextWithSessionID(context.Background(), "session-a")
+		_, err := store.BumpEpoch(ctx, userURN)
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, freshKeys))
+
+		// Act
+		record, err := store.GetKeyRecord(ctx, userURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, freshKeys, record.Keys)
+		assert.EqualValues(t, 1, record.Epoch)
+	})
+
+	t.Run("Success - own write is forgotten after a compromise", func(t *testing.T) {
+		// Arrange
+		store := newStore(t, time.Minute)
+		ctx := sessioncache.ContextWithSessionID(context.Background(), "session-a")
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, freshKeys))
+		require.NoError(t, store.MarkCompromised(ctx, userURN, "leaked"))
+
+		// Act
+		record, err := store.GetKeyRecord(ctx, userURN)
+		records, batchErr := store.GetPublicKeysBatch(ctx, []urn.URN{userURN})
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, staleKeys, record.Keys)
+		assert.True(t, record.Compromised)
+		require.NoError(t, batchErr)
+		assert.Equal(t, staleKeys, records[userURN.String()].Keys)
+	})
+
+	t.Run("Success - own write is forgotten after a clear or epoch bump", func(t *testing.T) {
+		// Arrange
+		store := newStore(t, time.Minute)
+		ctx := sessioncache.ContextWithSessionID(context.Background(), "session-a")
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, freshKeys))
+		require.NoError(t, store.ClearCompromised(ctx, userURN))
+		clearedKeys, clearedErr := store.GetPublicKeys(ctx, userURN)
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, freshKeys))
+		_, err := store.BumpEpoch(ctx, userURN)
+		require.NoError(t, err)
+
+		// Act
+		bumped, err := store.GetKeyRecord(ctx, userURN)
+
+		// Assert
+		require.NoError(t, clearedErr)
+		assert.Equal(t, staleKeys, clearedKeys)
+		require.NoError(t, err)
+		assert.Equal(t, staleKeys, bumped.Keys)
+		assert.EqualValues(t, 1, bumped.Epoch)
+	})
+
+	t.Run("Success - cleanup forgets every session's writes", func(t *testing.T) {
+		// Arrange
+		clock := time.Now().Add(-time.Hour)
+		backend := inmemory.New(inmemory.WithClock(func() time.Time { return clock }))
+		require.NoError(t, backend.StorePublicKeys(context.Background(), userURN, staleKeys))
+		clock = time.Now()
+		store := sessioncache.New(laggingReplica{Store: backend}, time.Minute)
+		ctx := sessioncache.ContextWithSessionID(context.Background(), "session-a")
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, freshKeys))
+
+		// Act
+		deleted, err := store.DeleteOlderThan(ctx, 30*time.Minute)
+		require.NoError(t, err)
+		_, getErr := store.GetKeyRecord(ctx, userURN)
+
+		// Assert
+		assert.Equal(t, 1, deleted)
+		assert.ErrorIs(t, getErr, keystore.ErrKeyNotFound)
+	})
+
+	t.Run("Success - batch read overlays own writes", func(t *testing.T) {
+		// Arrange
+		store := newStore(t, time.Minute)
+		newURN, err := urn.New(urn.SecureMessaging, "user", "user-456")
+		require.NoError(t, err)
+		ctx := sessioncache.ContextWithSessionID(context.Background(), "session-a")
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, freshKeys))
+		require.NoError(t, store.StorePublicKeys(ctx, newURN, freshKeys))
+
+		// Act
+		records, err := store.GetPublicKeysBatch(ctx, []urn.URN{userURN, newURN})
+		otherRecords, otherErr := store.GetPublicKeysBatch(context.Background(), []urn.URN{userURN, newURN})
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, freshKeys, records[userURN.String()].Keys)
+		assert.Equal(t, freshKeys, records[newURN.String()].Keys)
+		require.NoError(t, otherErr)
+		assert.Equal(t, staleKeys, otherRecords[userURN.String()].Keys)
+		assert.NotContains(t, otherRecords, newURN.String())
+	})
+}
@@ -0,0 +1,174 @@
+// --- File: internal/storage/shadow/shadow.go ---
+// Package shadow provides a keystore.Store wrapper that mirrors reads to a
+// candidate backend and reports where it disagrees with the primary, so a
+// new backend can be validated under real traffic before cutover.
+package shadow
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// DefaultMaxInFlight caps concurrent shadow reads when no limit is given.
+const DefaultMaxInFlight = 16
+
+// shadowReadTimeout bounds a single candidate read.
+const shadowReadTimeout = 5 * time.Second
+
+// Outcomes recorded for each shadow read.
+const (
+	OutcomeMatch    = "match"
+	OutcomeMismatch = "mismatch"
+	// OutcomeSkipped means the shadow-read budget was spent, so the
+	// candidate was not consulted.
+	OutcomeSkipped = "skipped"
+)
+
+var shadowReads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "keyservice_shadow_reads_total",
+	Help: "Reads mirrored to the candidate store, by operation and outcome.",
+}, []string{"operation", "outcome"})
+
+// Outcomes counts shadow-read outcomes since the wrapper was created.
+type Outcomes struct {
+	Matches    int64
+	Mismatches int64
+	Skipped    int64
+}
+
+// Store serves every call from the primary store. GetPublicKeys and
+// GetKeyRecord are also repeated against the candidate in the background
+// and the two results compared; the caller never waits for, or sees, the
+// candidate. Writes are not mirrored, so the candidate must be kept in
+// sync by other means (e.g. a backfill plus dual writes).
+//
+// Records are compared on keys and metadata only. Fields a migration
+// cannot preserve, such as UpdatedAt, are ignored. A lookup that fails on
+// one side only counts as a mismatch.
+type Store struct {
+	keystore.Store
+
+	candidate keystore.Store
+	logger    *slog.Logger
+	slots     chan struct{}
+
+	matches    atomic.Int64
+	mismatches atomic.Int64
+	skipped    atomic.Int64
+}
+
+// New wraps primary, mirroring its reads to candidate. A non-positive
+// maxInFlight selects DefaultMaxInFlight.
+func New(primary, candidate keystore.Store, maxInFlight int, logger *slog.Logger) *Store {
+	if maxInFlight <= 0 {
+		maxInFlight = DefaultMaxInFlight
+	}
+	return &Store{
+		Store:     primary,
+		candidate: candidate,
+		logger:    logger.With("component", "shadow_store"),
+		slots:     make(chan struct{}, maxInFlight),
+	}
+}
+
+// Outcomes returns the counts so far.
+func (s *Store) Outcomes() Outcomes {
+	return Outcomes{
+		Matches:    s.matches.Load(),
+		Mismatches: s.mismatches.Load(),
+		Skipped:    s.skipped.Load(),
+	}
+}
+
+// GetPublicKeys reads from the primary and shadows the read.
+func (s *Store) GetPublicKeys(ctx context.Context, entityURN urn.URN) (keys.PublicKeys, error) {
+	pk, err := s.Store.GetPublicKeys(ctx, entityURN)
+	shadowed(ctx, s, keystore.OpGetPublicKeys, entityURN, pk, err, s.candidate.GetPublicKeys, publicKeysEqual)
+	return pk, err
+}
+
+// GetKeyRecord reads from the primary and shadows the read.
+func (s *Store) GetKeyRecord(ctx context.Context, entityURN urn.URN) (keystore.KeyRecord, error) {
+	record, err := s.Store.GetKeyRecord(ctx, entityURN)
+	shadowed(ctx, s, keystore.OpGetKeyRecord, entityURN, record, err, s.candidate.GetKeyRecord, recordsEqual)
+	return record, err
+}
+
+// shadowed repeats a read against the candidate in the background and
+// records how its result compares with the primary's.
+func shadowed[T any](
+	ctx context.Context,
+	s *Store,
+	op string,
+	entityURN urn.URN,
+	primary T,
+	primaryErr error,
+	read func(context.Context, urn.URN) (T, error),
+	equal func(a, b T) bool,
+) {
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		s.record(op, OutcomeSkipped)
+		return
+	}
+
+	// The caller's response does not wait for this, so detach it from the
+	// request's cancellation and give it its own deadline.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shadowReadTimeout)
+	go func() {
+		defer func() { <-s.slots }()
+		defer cancel()
+
+		candidate, candidateErr := read(ctx, entityURN)
+		switch {
+		case primaryErr != nil && candidateErr != nil:
+			s.record(op, OutcomeMatch)
+		case primaryErr != nil || candidateErr != nil:
+			s.record(op, OutcomeMismatch)
+			s.logger.Warn("Shadow read disagreed with primary",
+				"op", op, "entity_urn", entityURN.String(),
+				"primary_err", primaryErr, "candidate_err", candidateErr)
+		case !equal(primary, candidate):
+			s.record(op, OutcomeMismatch)
+			s.logger.Warn("Shadow read disagreed with primary",
+				"op", op, "entity_urn", entityURN.String())
+		default:
+			s.record(op, OutcomeMatch)
+		}
+	}()
+}
+
+func (s *Store) record(op, outcome string) {
+	switch outcome {
+	case OutcomeMatch:
+		s.matches.Add(1)
+	case OutcomeMismatch:
+		s.mismatches.Add(1)
+	case OutcomeSkipped:
+		s.skipped.Add(1)
+	}
+	shadowReads.WithLabelValues(op, outcome).Inc()
+}
+
+func publicKeysEqual(a, b keys.PublicKeys) bool {
+	return bytes.Equal(a.EncKey, b.EncKey) && bytes.Equal(a.SigKey, b.SigKey)
+}
+
+func recordsEqual(a, b keystore.KeyRecord) bool {
+	return publicKeysEqual(a.Keys, b.Keys) &&
+		a.Metadata.RotationHint.Equal(b.Metadata.RotationHint) &&
+		bytes.Equal(a.Metadata.EncKeySignature, b.Metadata.EncKeySignature) &&
+		bytes.Equal(a.Metadata.ProvisioningSignature, b.Metadata.ProvisioningSignature) &&
+		a.Metadata.UsageRestrictions.Equal(b.Metadata.UsageRestrictions)
+}
@@ -0,0 +1,79 @@
+// --- File: internal/storage/shadow/shadow_test.go ---
+package shadow_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/internal/storage/shadow"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestShadowStore(t *testing.T) {
+	ctx := context.Background()
+	userURN, err := urn.New(urn.SecureMessaging, "user", "user-123")
+	require.NoError(t, err)
+	primaryKeys := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+
+	t.Run("Mismatch - divergent candidate is recorded, primary is returned", func(t *testing.T) {
+		// Arrange
+		primary := inmemory.New()
+		candidate := inmemory.New()
+		require.NoError(t, primary.StorePublicKeys(ctx, userURN, primaryKeys))
+		require.NoError(t, candidate.StorePublicKeys(ctx, userURN, keys.PublicKeys{EncKey: []byte("other"), SigKey: []byte("sig")}))
+		store := shadow.New(primary, candidate, 0, newTestLogger())
+
+		// Act
+		got, err := store.GetPublicKeys(ctx, userURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, primaryKeys, got)
+		assert.Eventually(t, func() bool { return store.Outcomes().Mismatches == 1 }, time.Second, time.Millisecond)
+		assert.Zero(t, store.Outcomes().Matches)
+	})
+
+	t.Run("Mismatch - entity missing from candidate", func(t *testing.T) {
+		// Arrange
+		primary := inmemory.New()
+		require.NoError(t, primary.StorePublicKeys(ctx, userURN, primaryKeys))
+		store := shadow.New(primary, inmemory.New(), 0, newTestLogger())
+
+		// Act
+		record, err := store.GetKeyRecord(ctx, userURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, primaryKeys, record.Keys)
+		assert.Eventually(t, func() bool { return store.Outcomes().Mismatches == 1 }, time.Second, time.Millisecond)
+	})
+
+	t.Run("Match - identical candidate", func(t *testing.T) {
+		// Arrange
+		primary := inmemory.New()
+		candidate := inmemory.New()
+		require.NoError(t, primary.StorePublicKeys(ctx, userURN, primaryKeys))
+		require.NoError(t, candidate.StorePublicKeys(ctx, userURN, primaryKeys))
+		store := shadow.New(primary, candidate, 0, newTestLogger())
+
+		// Act
+		_, err := store.GetKeyRecord(ctx, userURN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Eventually(t, func() bool { return store.Outcomes().Matches == 1 }, time.Second, time.Millisecond)
+		assert.Zero(t, store.Outcomes().Mismatches)
+	})
+}
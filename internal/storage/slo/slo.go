@@ -0,0 +1,286 @@
+// --- File: internal/storage/slo/slo.go ---
+// Package slo provides a keystore.Store wrapper that tracks each operation
+// class against an availability objective and reports its error-budget burn rate.
+package slo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// Defaults applied by New for non-positive arguments.
+const (
+	DefaultObjective  = 0.999
+	DefaultWindowSize = 1000
+)
+
+// Operation names a class of store operation tracked against the objective.
+type Operation string
+
+const (
+	OpGet   Operation = "get"
+	OpStore Operation = "store"
+)
+
+// operations lists the tracked classes in reporting order.
+var operations = []Operation{OpGet, OpStore}
+
+// Results label the operations counter by outcome.
+const (
+	ResultOK       = "ok"
+	ResultError    = "error"
+	ResultCanceled = "canceled"
+)
+
+// results lists every outcome label value.
+var results = []string{ResultOK, ResultError, ResultCanceled}
+
+// Every metric here is labelled only from the closed sets above, never
+// with an entity URN or any other request-supplied value, so the number of
+// series stays fixed however many entities are accessed.
+
+// operationsCounter counts completed store operations per class and outcome.
+var operationsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "keyservice_store_operations_total",
+	Help: "Store operations by operation class and result.",
+}, []string{"operation", "result"})
+
+// burnRateGauge exposes the current burn rate per operation class.
+var burnRateGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "keyservice_slo_burn_rate",
+	Help: "Error-budget burn rate over the rolling window (1 = spending exactly the budget).",
+}, []string{"operation"})
+
+// OperationStatus is a snapshot of one operation class.
+type OperationStatus struct {
+	Operation  Operation `json:"operation"`
+	Objective  float64   `json:"objective"`
+	WindowSize int       `json:"windowSize"`
+	Samples    int       `json:"samples"`
+	Failures   int       `json:"failures"`
+	BurnRate   float64   `json:"burnRate"`
+}
+
+// window is a fixed-size ring of the most recent outcomes.
+type window struct {
+	failed   []bool
+	next     int
+	samples  int
+	failures int
+}
+
+func newWindow(size int) *window {
+	return &window{failed: make([]bool, size)}
+}
+
+// record adds an outcome, evicting the oldest once the ring is full.
+func (w *window) record(failed bool) {
+	if w.samples == len(w.failed) {
+		if w.failed[w.next] {
+			w.failures--
+		}
+	} else {
+		w.samples++
+	}
+	w.failed[w.next] = failed
+	if failed {
+		w.failures++
+	}
+	w.next = (w.next + 1) % len(w.failed)
+}
+
+// Store wraps a keystore.Store and records the outcome of every GET and
+// STORE operation in a rolling window of the most recent calls.
+//
+// The burn rate is the observed error rate divided by the error budget
+// (1 - objective): 1 means the budget is being spent exactly as fast as the
+// objective allows, and anything above 1 means it will run out early.
+// Calls abandoned by the caller (context.Canceled) are not counted. Until
+// stores distinguish a missing entity from a backend failure, every other
+// error counts against the budget.
+type Store struct {
+	keystore.Store
+
+	objective  float64
+	windowSize int
+
+	mu      sync.Mutex
+	windows map[Operation]*window
+}
+
+// New wraps next. A non-positive or >= 1 objective selects DefaultObjective,
+// and a non-positive windowSize selects DefaultWindowSize.
+func New(next keystore.Store, objective float64, windowSize int) *Store {
+	if objective <= 0 || objective >= 1 {
+		objective = DefaultObjective
+	}
+	if windowSize <= 0 {
+		windowSize = DefaultWindowSize
+	}
+	s := &Store{
+		Store:      next,
+		objective:  objective,
+		windowSize: windowSize,
+		windows:    make(map[Operation]*window, len(operations)),
+	}
+	for _, op := range operations {
+		s.windows[op] = newWindow(windowSize)
+		burnRateGauge.WithLabelValues(string(op)).Set(0)
+		for _, result := range results {
+			operationsCounter.WithLabelValues(string(op), result)
+		}
+	}
+	return s
+}
+
+// StorePublicKeys delegates to the wrapped store and records the outcome.
+func (s *Store) StorePublicKeys(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys) error {
+	err := s.Store.StorePublicKeys(ctx, entityURN, pk)
+	s.observe(OpStore, err)
+	return err
+}
+
+// StorePublicKeysWithMetadata delegates to the wrapped store and records the outcome.
+func (s *Store) StorePublicKeysWithMetadata(ctx context.Context, entityURN urn.URN, pk keys.PublicKeys, meta keystore.Metadata) error {
+	err := s.Store.StorePublicKeysWithMetadata(ctx, entityURN, pk, meta)
+	s.observe(OpStore, err)
+	return err
+}
+
+// GetOrCreatePublicKeys delegates to the wrapped store and records the outcome.
+func (s *Store) GetOrCreatePublicKeys(ctx context.Context, entityURN urn.URN, candidate keys.PublicKeys) (keys.PublicKeys, bool, error) {
+	effective, created, err := s.Store.GetOrCreatePublicKeys(ctx, entityURN, candidate)
+	s.observe(OpStore, err)
+	return effective, created, err
+}
+
+// SwapPublicKeys delegates to the wrapped store and records the outcome.
+func (s *Store) SwapPublicKeys(ctx context.Context, entityURN urn.URN, newKeys keys.PublicKeys) (keys.PublicKeys, error) {
+	old, err := s.Store.SwapPublicKeys(ctx, entityURN, newKeys)
+	s.observe(OpStore, err)
+	return old, err
+}
+
+// BumpEpoch delegates to the wrapped store and records the outcome.
+func (s *Store) BumpEpoch(ctx context.Context, entityURN urn.URN) (uint64, error) {
+	epoch, err := s.Store.BumpEpoch(ctx, entityURN)
+	s.observe(OpStore, err)
+	return epoch, err
+}
+
+// MarkCompromised delegates to the wrapped store and records the outcome.
+func (s *Store) MarkCompromised(ctx context.Context, entityURN urn.URN, reason string) error {
+	err := s.Store.MarkCompromised(ctx, entityURN, reason)
+	s.observe(OpStore, err)
+	return err
+}
+
+// ClearCompromised delegates to the wrapped store and records the outcome.
+func (s *Store) ClearCompromised(ctx context.Context, entityURN urn.URN) error {
+	err := s.Store.ClearCompromised(ctx, entityURN)
+	s.observe(OpStore, err)
+	return err
+}
+
+// DeletePublicKeys delegates to the wrapped store and records the outcome.
+func (s *Store) DeletePublicKeys(ctx context.Context, entityURN urn.URN) (bool, error) {
+	deleted, err := s.Store.DeletePublicKeys(ctx, entityURN)
+	s.observe(OpStore, err)
+	return deleted, err
+}
+
+// DeleteOlderThan delegates to the wrapped store and records the outcome.
+func (s *Store) DeleteOlderThan(ctx context.Context, age time.Duration) (int, error) {
+	deleted, err := s.Store.DeleteOlderThan(ctx, age)
+	s.observe(OpStore, err)
+	return deleted, err
+}
+
+// GetPublicKeys delegates to the wrapped store and records the outcome.
+func (s *Store) GetPublicKeys(ctx context.Context, entityURN urn.URN) (keys.PublicKeys, error) {
+	pk, err := s.Store.GetPublicKeys(ctx, entityURN)
+	s.observe(OpGet, err)
+	return pk, err
+}
+
+// GetKeyRecord delegates to the wrapped store and records the outcome.
+func (s *Store) GetKeyRecord(ctx context.Context, entityURN urn.URN) (keystore.KeyRecord, error) {
+	record, err := s.Store.GetKeyRecord(ctx, entityURN)
+	s.observe(OpGet, err)
+	return record, err
+}
+
+// GetPublicKeysIfModifiedSince delegates to the wrapped store and records the outcome.
+func (s *Store) GetPublicKeysIfModifiedSince(ctx context.Context, entityURN urn.URN, since time.Time) (keys.PublicKeys, bool, error) {
+	pk, modified, err := s.Store.GetPublicKeysIfModifiedSince(ctx, entityURN, since)
+	s.observe(OpGet, err)
+	return pk, modified, err
+}
+
+// BurnRate returns the current burn rate for an operation class.
+func (s *Store) BurnRate(op Operation) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.burnRateLocked(s.windows[op])
+}
+
+// Status returns a snapshot of every tracked operation class.
+func (s *Store) Status() []OperationStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]OperationStatus, 0, len(operations))
+	for _, op := range operations {
+		w := s.windows[op]
+		statuses = append(statuses, OperationStatus{
+			Operation:  op,
+			Objective:  s.objective,
+			WindowSize: s.windowSize,
+			Samples:    w.samples,
+			Failures:   w.failures,
+			BurnRate:   s.burnRateLocked(w),
+		})
+	}
+	return statuses
+}
+
+// observe records an outcome and refreshes the exported metrics.
+func (s *Store) observe(op Operation, err error) {
+	operationsCounter.WithLabelValues(string(op), resultOf(err)).Inc()
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w := s.windows[op]
+	w.record(err != nil)
+	burnRateGauge.WithLabelValues(string(op)).Set(s.burnRateLocked(w))
+}
+
+// resultOf maps an operation's error to its result label.
+func resultOf(err error) string {
+	switch {
+	case err == nil:
+		return ResultOK
+	case errors.Is(err, context.Canceled):
+		return ResultCanceled
+	default:
+		return ResultError
+	}
+}
+
+// burnRateLocked computes the burn rate for w. s.mu must be held.
+func (s *Store) burnRateLocked(w *window) float64 {
+	if w == nil || w.samples == 0 {
+		return 0
+	}
+	errorRate := float64(w.failures) / float64(w.samples)
+	return errorRate / (1 - s.objective)
+}
@@ -0,0 +1,181 @@
+// --- File: internal/storage/slo/slo_test.go ---
+package slo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/internal/storage/slo"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	"github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// scriptedStore fails GetPublicKeys with err whenever it is set.
+type scriptedStore struct {
+	keystore.Store
+	err error
+}
+
+func (s *scriptedStore) GetPublicKeys(ctx context.Context, entityURN urn.URN) (keys.PublicKeys, error) {
+	if s.err != nil {
+		return keys.PublicKeys{}, s.err
+	}
+	return s.Store.GetPublicKeys(ctx, entityURN)
+}
+
+// gatheredBurnRate reads the exported burn-rate gauge for op from the default registry.
+func gatheredBurnRate(t *testing.T, op slo.Operation) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "keyservice_slo_burn_rate" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "operation" && label.GetValue() == string(op) {
+					return metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	t.Fatalf("burn rate gauge for %q not found", op)
+	return 0
+}
+
+func TestSLOStore_BurnRate(t *testing.T) {
+	ctx := context.Background()
+	userURN, err := urn.New(urn.SecureMessaging, "user", "user-123")
+	require.NoError(t, err)
+	testKeys := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+
+	// setup returns an SLO store over a backend holding testKeys.
+	// A 90% objective leaves a 10% error budget.
+	setup := func(t *testing.T) (*slo.Store, *scriptedStore) {
+		t.Helper()
+		backend := inmemory.New()
+		require.NoError(t, backend.StorePublicKeys(ctx, userURN, testKeys))
+		scripted := &scriptedStore{Store: backend}
+		return slo.New(scripted, 0.9, 10), scripted
+	}
+
+	// feed issues one GET per outcome, failing where the outcome is true.
+	feed := func(store *slo.Store, scripted *scriptedStore, failures ...bool) {
+		for _, fail := range failures {
+			scripted.err = nil
+			if fail {
+				scripted.err = errors.New("backend unavailable")
+			}
+			_, _ = store.GetPublicKeys(ctx, userURN)
+		}
+	}
+
+	t.Run("Success - no traffic burns nothing", func(t *testing.T) {
+		store, _ := setup(t)
+		assert.Zero(t, store.BurnRate(slo.OpGet))
+	})
+
+	t.Run("Success - error rate at twice the budget", func(t *testing.T) {
+		// Arrange
+		store, scripted := setup(t)
+
+		// Act: 2 failures in 10 calls is a 20% error rate
+		feed(store, scripted, false, true, false, false, false, true, false, false, false, false)
+
+		// Assert
+		assert.InDelta(t, 2.0, store.BurnRate(slo.OpGet), 1e-9)
+		assert.InDelta(t, 2.0, gatheredBurnRate(t, slo.OpGet), 1e-9)
+		assert.Zero(t, store.BurnRate(slo.OpStore))
+	})
+
+	t.Run("Success - old failures roll out of the window", func(t *testing.T) {
+		// Arrange
+		store, scripted := setup(t)
+		feed(store, scripted, true, true, true)
+		require.Greater(t, store.BurnRate(slo.OpGet), 1.0)
+
+		// Act: 10 successes push every failure out
+		feed(store, scripted, false, false, false, false, false, false, false, false, false, false)
+
+		// Assert
+		assert.Zero(t, store.BurnRate(slo.OpGet))
+	})
+
+	t.Run("Success - cancelled calls are not counted", func(t *testing.T) {
+		// Arrange
+		store, scripted := setup(t)
+		scripted.err = context.Canceled
+
+		// Act
+		_, _ = store.GetPublicKeys(ctx, userURN)
+
+		// Assert
+		status := store.Status()
+		require.Len(t, status, 2)
+		assert.Equal(t, slo.OpGet, status[0].Operation)
+		assert.Zero(t, status[0].Samples)
+	})
+
+	t.Run("Success - stores are tracked separately", func(t *testing.T) {
+		// Arrange
+		store, _ := setup(t)
+
+		// Act
+		require.NoError(t, store.StorePublicKeys(ctx, userURN, testKeys))
+
+		// Assert
+		status := store.Status()
+		assert.Equal(t, slo.OpStore, status[1].Operation)
+		assert.Equal(t, 1, status[1].Samples)
+		assert.Zero(t, status[1].Failures)
+	})
+}
+
+func TestSLOStore_LabelCardinality(t *testing.T) {
+	ctx := context.Background()
+	testKeys := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+
+	// Arrange
+	store := slo.New(inmemory.New(), 0, 0)
+
+	// Act: a write, a hit and a miss for each of many distinct entities.
+	for i := range 500 {
+		entityURN, err := urn.New(urn.SecureMessaging, "user", fmt.Sprintf("user-%d", i))
+		require.NoError(t, err)
+		missURN, err := urn.New(urn.SecureMessaging, "user", fmt.Sprintf("missing-%d", i))
+		require.NoError(t, err)
+		require.NoError(t, store.StorePublicKeys(ctx, entityURN, testKeys))
+		_, err = store.GetPublicKeys(ctx, entityURN)
+		require.NoError(t, err)
+		_, err = store.GetPublicKeys(ctx, missURN)
+		require.Error(t, err)
+	}
+
+	// Assert
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	series := map[string]int{}
+	for _, family := range families {
+		name := family.GetName()
+		if name != "keyservice_store_operations_total" && name != "keyservice_slo_burn_rate" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			series[name]++
+			for _, label := range metric.GetLabel() {
+				assert.NotContains(t, label.GetValue(), "urn:", "%s must not be labelled by entity", name)
+			}
+		}
+	}
+	assert.Equal(t, 2*3, series["keyservice_store_operations_total"], "one series per operation and result")
+	assert.Equal(t, 2, series["keyservice_slo_burn_rate"], "one series per operation")
+}
@@ -0,0 +1,62 @@
+// --- File: keyservice/capabilities.go ---
+package keyservice
+
+import (
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/keyservice/config"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+)
+
+// capabilitiesFor derives the GET /capabilities document from the
+// effective config, resolving unset limits to the defaults the API
+// applies.
+func capabilitiesFor(cfg *config.Config) *api.Capabilities {
+	maxJSONDepth := cfg.MaxJSONDepth
+	if maxJSONDepth <= 0 {
+		maxJSONDepth = api.DefaultMaxJSONDepth
+	}
+	consistencyWait := cfg.ConsistencyWait
+	if consistencyWait <= 0 {
+		consistencyWait = api.DefaultConsistencyWait
+	}
+	// The body limit only applies to gzip and multipart bodies.
+	var maxBodyBytes int64
+	if cfg.AcceptGzipRequests || cfg.AcceptMultipartUploads {
+		maxBodyBytes = cfg.MaxDecompressedBodyBytes
+		if maxBodyBytes <= 0 {
+			maxBodyBytes = api.DefaultMaxDecompressedBodyBytes
+		}
+	}
+	validationMode := cfg.KeyValidationMode
+	if validationMode == "" {
+		validationMode = keystore.ValidationLenient
+	}
+
+	return &api.Capabilities{
+		Features: api.CapabilityFeatures{
+			BatchFingerprints:            true,
+			BatchGet:                     true,
+			KeyDiff:                      true,
+			JWKS:                         cfg.JWKSEnabled,
+			Attestation:                  cfg.AttestationKey != nil,
+			ConsistencyTokens:            true,
+			GzipRequests:                 cfg.AcceptGzipRequests,
+			MultipartUploads:             cfg.AcceptMultipartUploads,
+			RequireUTF8Bodies:            cfg.RequireUTF8Bodies,
+			RequireProvisioningSignature: cfg.RequireProvisioningSignature,
+			AsyncWrites:                  cfg.AsyncStoreQueueSize > 0,
+			InviteTokens:                 cfg.InviteTokensEnabled,
+			UsageRestrictions:            cfg.UsageRestrictionsEnabled,
+			CanonicalResponses:           cfg.CanonicalResponses,
+		},
+		Limits: api.CapabilityLimits{
+			KeyValidationMode:       string(validationMode),
+			MaxFingerprintBatch:     api.MaxFingerprintBatch,
+			MaxKeysBatch:            api.MaxKeysBatch,
+			MaxJSONDepth:            maxJSONDepth,
+			MaxBodyBytes:            maxBodyBytes,
+			RotationCooldownSeconds: int64(cfg.RotationCooldown.Seconds()),
+			ConsistencyWaitMillis:   consistencyWait.Milliseconds(),
+		},
+	}
+}
@@ -0,0 +1,85 @@
+// --- File: keyservice/capabilities_test.go ---
+//go:build integration
+
+package keyservice_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/keyservice"
+	"github.com/tinywideclouds/go-key-service/keyservice/config"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+)
+
+func TestKeyService_Capabilities(t *testing.T) {
+	getCapabilities := func(t *testing.T, cfg *config.Config) api.Capabilities {
+		t.Helper()
+		service := keyservice.NewKeyService(cfg, new(MockStore), newMockAuthMiddleware(t, newTestLogger()), newTestLogger())
+		server := httptest.NewServer(service.Mux())
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + "/capabilities")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var capabilities api.Capabilities
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&capabilities))
+		return capabilities
+	}
+
+	t.Run("Success - defaults report optional features off", func(t *testing.T) {
+		// Act
+		capabilities := getCapabilities(t, &config.Config{HTTPListenAddr: ":0"})
+
+		// Assert
+		assert.False(t, capabilities.Features.JWKS)
+		assert.False(t, capabilities.Features.GzipRequests)
+		assert.False(t, capabilities.Features.Versioning)
+		assert.False(t, capabilities.Features.AsyncWrites)
+		assert.False(t, capabilities.Features.InviteTokens)
+		assert.False(t, capabilities.Features.UsageRestrictions)
+		assert.False(t, capabilities.Features.CanonicalResponses)
+		assert.True(t, capabilities.Features.BatchFingerprints)
+		assert.True(t, capabilities.Features.BatchGet)
+		assert.True(t, capabilities.Features.KeyDiff)
+		assert.Equal(t, string(keystore.ValidationLenient), capabilities.Limits.KeyValidationMode)
+		assert.Equal(t, api.DefaultMaxJSONDepth, capabilities.Limits.MaxJSONDepth)
+		assert.Equal(t, api.MaxKeysBatch, capabilities.Limits.MaxKeysBatch)
+		assert.Zero(t, capabilities.Limits.MaxBodyBytes)
+		assert.Zero(t, capabilities.Limits.RotationCooldownSeconds)
+	})
+
+	t.Run("Success - enabled features and limits are reported", func(t *testing.T) {
+		// Arrange
+		cfg := &config.Config{
+			HTTPListenAddr:           ":0",
+			JWKSEnabled:              true,
+			AcceptGzipRequests:       true,
+			MaxDecompressedBodyBytes: 4096,
+			KeyValidationMode:        keystore.ValidationStrict,
+			RotationCooldown:         time.Hour,
+			ConsistencyWait:          250 * time.Millisecond,
+			CanonicalResponses:       true,
+		}
+
+		// Act
+		capabilities := getCapabilities(t, cfg)
+
+		// Assert
+		assert.True(t, capabilities.Features.JWKS)
+		assert.True(t, capabilities.Features.GzipRequests)
+		assert.False(t, capabilities.Features.MultipartUploads)
+		assert.True(t, capabilities.Features.CanonicalResponses)
+		assert.Equal(t, "strict", capabilities.Limits.KeyValidationMode)
+		assert.EqualValues(t, 4096, capabilities.Limits.MaxBodyBytes)
+		assert.EqualValues(t, 3600, capabilities.Limits.RotationCooldownSeconds)
+		assert.EqualValues(t, 250, capabilities.Limits.ConsistencyWaitMillis)
+	})
+}
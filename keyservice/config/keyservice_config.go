@@ -2,13 +2,151 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"crypto/tls"
+	"encoding/base64"
 	"fmt"
 	"log/slog"
+	"net/netip"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
 	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
 )
 
+// RunModeProduction is the run_mode value used by production deployments.
+const RunModeProduction = "production"
+
+// Supported log output formats.
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+// ResolveLogFormat validates a log format value. An empty value selects
+// JSON in production and text in every other run mode.
+func ResolveLogFormat(format, runMode string) (string, error) {
+	switch format {
+	case "":
+		if runMode == RunModeProduction {
+			return LogFormatJSON, nil
+		}
+		return LogFormatText, nil
+	case LogFormatJSON, LogFormatText:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown log format %q (expected json or text)", format)
+	}
+}
+
+// Supported error detail verbosities.
+const (
+	ErrorDetailFull    = "full"
+	ErrorDetailMinimal = "minimal"
+)
+
+// ResolveErrorDetailVerbosity validates an error_detail_verbosity value. An
+// empty value selects minimal in production and full in every other run mode.
+func ResolveErrorDetailVerbosity(verbosity, runMode string) (string, error) {
+	switch verbosity {
+	case "":
+		if runMode == RunModeProduction {
+			return ErrorDetailMinimal, nil
+		}
+		return ErrorDetailFull, nil
+	case ErrorDetailFull, ErrorDetailMinimal:
+		return verbosity, nil
+	default:
+		return "", fmt.Errorf("unknown error detail verbosity %q (expected full or minimal)", verbosity)
+	}
+}
+
+// Supported authentication modes.
+const (
+	AuthModeJWT    = "jwt"
+	AuthModeAPIKey = "apikey"
+)
+
+// ParseAuthMode validates an auth_mode value. An empty value selects jwt.
+func ParseAuthMode(mode string) (string, error) {
+	switch mode {
+	case "", AuthModeJWT:
+		return AuthModeJWT, nil
+	case AuthModeAPIKey:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown auth mode %q (expected jwt or apikey)", mode)
+	}
+}
+
+// ParseTLSMinVersion converts a tls_min_version value into a crypto/tls
+// version. An empty value selects TLS 1.2; anything older is refused.
+func ParseTLSMinVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported tls_min_version %q (expected 1.2 or 1.3)", version)
+	}
+}
+
+// NormalizeBasePath validates a base_path value and returns it with any
+// trailing slash removed, so "/" and "" both mean "no prefix".
+func NormalizeBasePath(basePath string) (string, error) {
+	if basePath == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(basePath, "/") {
+		return "", fmt.Errorf("base_path %q must start with /", basePath)
+	}
+	if strings.ContainsAny(basePath, "{} ") {
+		return "", fmt.Errorf("base_path %q must not contain spaces or braces", basePath)
+	}
+	return strings.TrimRight(basePath, "/"), nil
+}
+
+// LoadPreloadURNs parses the preload_urns list and, if file is set, the
+// URNs listed one per line in that file. Blank lines and lines starting
+// with # are skipped, and duplicates are dropped. Any malformed URN fails
+// the whole load so a typo is caught at startup.
+func LoadPreloadURNs(urns []string, file string) ([]urn.URN, error) {
+	raw := append([]string(nil), urns...)
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read preload_urns_file: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			raw = append(raw, line)
+		}
+	}
+
+	seen := make(map[string]bool, len(raw))
+	parsed := make([]urn.URN, 0, len(raw))
+	for _, s := range raw {
+		u, err := urn.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid preload URN %q: %w", s, err)
+		}
+		if seen[u.String()] {
+			continue
+		}
+		seen[u.String()] = true
+		parsed = append(parsed, u)
+	}
+	return parsed, nil
+}
+
 // Config defines the *single*, authoritative configuration for the Key Service.
 // It is created in two stages:
 // 1. Loaded from YAML (see NewConfigFromYaml).
@@ -20,6 +158,179 @@ type Config struct {
 	HTTPListenAddr      string `yaml:"http_listen_addr"`
 	IdentityServiceURL  string `yaml:"identity_service_url"`
 	FirestoreCollection string `yaml:"firestore_collection"`
+	// FirestoreMaxConcurrency caps in-flight Firestore operations (0 = unlimited).
+	FirestoreMaxConcurrency int `yaml:"firestore_max_concurrency"`
+	// VerifyFirestoreIndexes makes startup probe the indexes the enabled
+	// features' queries need, and exit if one is missing.
+	VerifyFirestoreIndexes bool `yaml:"verify_firestore_indexes"`
+	// ShutdownDrainPeriod is how long /readyz fails before the server stops
+	// accepting connections on shutdown, so a load balancer can notice
+	// (0 stops immediately).
+	ShutdownDrainPeriod time.Duration `yaml:"shutdown_drain_period"`
+
+	// RequireTLS rejects cleartext requests when running in production.
+	RequireTLS bool `yaml:"require_tls"`
+	// TrustedProxies lists the CIDRs whose X-Forwarded-Proto header is honored.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// KeyValidationMode is one of strict, lenient (default) or off.
+	KeyValidationMode keystore.ValidationMode `yaml:"key_validation_mode"`
+	// JWKSEnabled serves all stored signing keys at /.well-known/jwks.json.
+	JWKSEnabled bool `yaml:"jwks_enabled"`
+	// JWKSCacheTTL is how long the aggregated JWKS is cached (default 5m).
+	JWKSCacheTTL time.Duration `yaml:"jwks_cache_ttl"`
+	// LogFormat is json or text; it defaults by run mode (see ResolveLogFormat).
+	LogFormat string `yaml:"log_format"`
+	// ErrorDetailVerbosity is full or minimal; it defaults by run mode (see
+	// ResolveErrorDetailVerbosity). Minimal hides internal error strings
+	// from 500 responses behind a correlation ID.
+	ErrorDetailVerbosity string `yaml:"error_detail_verbosity"`
+	// TokenCorrelation records each authenticated token's sub and jti in
+	// logs and span attributes, for correlation with identity-service audits.
+	TokenCorrelation bool `yaml:"token_correlation"`
+	// LogAuthzDecisions logs every allow and deny on POST /keys in one
+	// structured form, for security audits.
+	LogAuthzDecisions bool `yaml:"log_authz_decisions"`
+	// SelfStoreDeniedEntityTypes lists entity types (e.g. "org") whose keys
+	// can only be provisioned by an admin, not stored by the entity itself.
+	SelfStoreDeniedEntityTypes []string `yaml:"self_store_denied_entity_types"`
+	// AdminUserIDs lists the user IDs allowed to use the admin routes.
+	AdminUserIDs []string `yaml:"admin_user_ids"`
+	// AdminAllowedCIDRs lists the networks admin requests may come from
+	// (default loopback only). AdminDeniedCIDRs are refused even inside
+	// an allowed network.
+	AdminAllowedCIDRs []string `yaml:"admin_allowed_cidrs"`
+	AdminDeniedCIDRs  []string `yaml:"admin_denied_cidrs"`
+	// SessionConsistencyWindow is how long a session's writes are served
+	// back to that session ahead of the backend (0 disables).
+	SessionConsistencyWindow time.Duration `yaml:"session_consistency_window"`
+	// TrustedIssuers, when set, lists the only JWT "iss" values accepted on
+	// authenticated routes.
+	TrustedIssuers []string `yaml:"trusted_issuers"`
+	// AuthMode is jwt (default) or apikey. In apikey mode requests carry
+	// the pre-shared API_KEY instead of a token and name their entity in a
+	// header, for closed deployments without an identity service.
+	AuthMode string `yaml:"auth_mode"`
+	// SLOObjective enables error-budget tracking of store operations against
+	// this success ratio (e.g. 0.999). 0 disables tracking.
+	SLOObjective float64 `yaml:"slo_objective"`
+	// SLOWindowSize is how many recent operations the burn rate covers (default 1000).
+	SLOWindowSize int `yaml:"slo_window_size"`
+	// MaxJSONDepth bounds nesting in request bodies (default 4).
+	MaxJSONDepth int `yaml:"max_json_depth"`
+	// HedgeDelay is how long a key read may run before a second, hedged
+	// attempt is started (0 disables hedging).
+	HedgeDelay time.Duration `yaml:"hedge_delay"`
+	// HedgeMaxInFlight caps concurrent hedged attempts (default 16).
+	HedgeMaxInFlight int `yaml:"hedge_max_in_flight"`
+	// BasePath prefixes every route, including health and metrics, for
+	// deployments behind a proxy that does not strip a path prefix.
+	BasePath string `yaml:"base_path"`
+	// KeyEventsTopic is the Pub/Sub topic key-change CloudEvents are
+	// published to (empty disables events).
+	KeyEventsTopic string `yaml:"key_events_topic"`
+	// CDCFilePath, when set, appends a change-data-capture record for every
+	// write to this file as JSON lines. CDCPubSubTopic publishes them to a
+	// Pub/Sub topic instead. At most one may be set.
+	CDCFilePath    string `yaml:"cdc_file_path"`
+	CDCPubSubTopic string `yaml:"cdc_pubsub_topic"`
+	// FallbackToInMemory starts the service on a non-durable in-memory
+	// store if the Firestore client cannot be created, instead of exiting.
+	FallbackToInMemory bool `yaml:"fallback_to_inmemory"`
+	// InMemoryStatsInterval is how often the in-memory store logs its size
+	// (0 disables). It only applies when that store is in use.
+	InMemoryStatsInterval time.Duration `yaml:"inmemory_stats_interval"`
+	// AccessCounting makes the store count reads of each entity's keys,
+	// shown in the verbose admin listing. It adds a write per read.
+	AccessCounting bool `yaml:"access_counting"`
+	// EntityVerificationURL, when set, is an identity-service endpoint
+	// queried as <url>/<entityID> to confirm an entity exists before its
+	// keys are stored. Empty disables the check.
+	EntityVerificationURL string `yaml:"entity_verification_url"`
+	// EntityVerificationCacheTTL is how long a lookup answer is reused.
+	EntityVerificationCacheTTL time.Duration `yaml:"entity_verification_cache_ttl"`
+	// EntityTypePolicies overrides which keys are required, and how
+	// strictly they are validated, for specific entity types.
+	EntityTypePolicies map[string]keystore.KeyPolicy `yaml:"entity_type_policies"`
+	// ShadowFirestoreCollection, when set, names a candidate collection
+	// that reads are mirrored to and compared against, without affecting
+	// responses.
+	ShadowFirestoreCollection string `yaml:"shadow_firestore_collection"`
+	// TLSCertFile and TLSKeyFile, when set, make the service terminate TLS
+	// itself. Both must be given together.
+	TLSCertFile string `yaml:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file"`
+	// TLSMinVersion is the oldest TLS version the listener accepts
+	// (see ParseTLSMinVersion).
+	TLSMinVersion uint16 `yaml:"tls_min_version"`
+	// AllowIdenticalKeys accepts stores whose encKey and sigKey are the
+	// same bytes, which are otherwise rejected as a likely client bug.
+	AllowIdenticalKeys bool `yaml:"allow_identical_keys"`
+	// RotationCooldown is the minimum interval between key changes for
+	// the same entity (0 disables the limit).
+	RotationCooldown time.Duration `yaml:"rotation_cooldown"`
+	// MaxClockSkew is how far a client-supplied timestamp may lag the
+	// server clock before it is rejected (default 5m).
+	MaxClockSkew time.Duration `yaml:"max_clock_skew"`
+	// ConsistencyWait is how long a GET carrying a consistency token waits
+	// for the store to reach that version (default 1s).
+	ConsistencyWait time.Duration `yaml:"consistency_wait"`
+	// RetryAfter is the back-off sent in Retry-After on throttled responses
+	// whose wait is not otherwise known (default 30s).
+	RetryAfter time.Duration `yaml:"throttle_retry_after"`
+	// CoalesceReads makes concurrent reads of the same entity share a
+	// single backend call.
+	CoalesceReads bool `yaml:"coalesce_reads"`
+	// AuditHashChain records every key write in a tamper-evident hash
+	// chain that admins can verify at GET /admin/audit/verify.
+	AuditHashChain bool `yaml:"audit_hash_chain"`
+	// StorageBudgetBytes caps the approximate bytes of keys the store may
+	// hold; writes that would grow it further get 507 (0 disables the cap).
+	StorageBudgetBytes int64 `yaml:"storage_budget_bytes"`
+	// AsyncStoreQueueSize, when positive, lets POST /keys honor Prefer:
+	// respond-async by queueing up to this many writes (0 disables).
+	AsyncStoreQueueSize int `yaml:"async_store_queue_size"`
+	// InviteTokensEnabled serves POST /keys:redeemInvite, where a newly
+	// provisioned user registers keys with a one-time invite token signed
+	// with JWT_SECRET instead of a session token.
+	InviteTokensEnabled bool `yaml:"invite_tokens_enabled"`
+	// UsageRestrictionsEnabled lets stores declare usageRestrictions
+	// (encrypt-only or sign-only, and a notBefore/notAfter window), which
+	// are served on GET. Without it stores carrying them are rejected.
+	UsageRestrictionsEnabled bool `yaml:"usage_restrictions_enabled"`
+	// CanonicalResponses encodes GET /keys bodies with their fields in a
+	// fixed order, encKey and sigKey first, and tags them with an ETag
+	// computed from those bytes. Off keeps the fields in alphabetical order.
+	CanonicalResponses bool `yaml:"canonical_responses"`
+	// RedisAddr, when set, is the host:port of a Redis shared by every
+	// instance and used to cache key reads. Empty disables the cache.
+	RedisAddr string `yaml:"redis_addr"`
+	// RedisCacheTTL is how long a cached record may be served (default 1m).
+	RedisCacheTTL time.Duration `yaml:"redis_cache_ttl"`
+	// AcceptGzipRequests lets POST bodies arrive with Content-Encoding: gzip.
+	AcceptGzipRequests bool `yaml:"accept_gzip_requests"`
+	// RequireUTF8Bodies rejects request bodies whose Content-Type names a
+	// charset other than UTF-8.
+	RequireUTF8Bodies bool `yaml:"require_utf8_bodies"`
+	// MaxDecompressedBodyBytes caps a gzip body once inflated, and each
+	// part of a multipart upload (default 1 MiB).
+	MaxDecompressedBodyBytes int64 `yaml:"max_decompressed_body_bytes"`
+	// AcceptMultipartUploads lets key stores arrive as multipart/form-data
+	// with encKey and sigKey file parts.
+	AcceptMultipartUploads bool `yaml:"accept_multipart_uploads"`
+	// PreloadURNs are read through the cache once the service is ready, so
+	// the first requests for these hot entities do not miss. They come from
+	// preload_urns and preload_urns_file (see LoadPreloadURNs).
+	PreloadURNs []urn.URN `yaml:"-"`
+	// TrustedProvisionerKeys are the Ed25519 public keys of upstream
+	// services allowed to vouch for stored keys, parsed from
+	// trusted_provisioner_keys (see ParseProvisionerKey).
+	TrustedProvisionerKeys []ed25519.PublicKey `yaml:"-"`
+	// RequireProvisioningSignature makes GET serve only keys whose
+	// provisioning signature verifies against TrustedProvisionerKeys.
+	RequireProvisioningSignature bool `yaml:"require_provisioning_signature"`
+	// EntityIDPattern is compiled from entity_id_pattern (see
+	// CompileEntityIDPattern). Nil accepts any entity ID.
+	EntityIDPattern *regexp.Regexp `yaml:"-"`
 
 	Cors struct {
 		AllowedOrigins []string `yaml:"allowed_origins"`
@@ -28,8 +339,69 @@ type Config struct {
 	// CorsConfig is the processed, ready-to-use middleware config.
 	CorsConfig middleware.CorsConfig `yaml:"-"` // Ignored by YAML
 
+	// TrustedProxyPrefixes is the parsed form of TrustedProxies.
+	TrustedProxyPrefixes []netip.Prefix `yaml:"-"` // Ignored by YAML
+	// AdminAllowedPrefixes and AdminDeniedPrefixes are the parsed forms of
+	// AdminAllowedCIDRs and AdminDeniedCIDRs.
+	AdminAllowedPrefixes []netip.Prefix `yaml:"-"` // Ignored by YAML
+	AdminDeniedPrefixes  []netip.Prefix `yaml:"-"` // Ignored by YAML
+
 	// JWTSecret is populated from the "JWT_SECRET" env var.
 	JWTSecret string `yaml:"-"` // Ignored by YAML
+
+	// RedisPassword is populated from the "REDIS_PASSWORD" env var.
+	RedisPassword string `yaml:"-"` // Ignored by YAML
+
+	// APIKey is populated from the "API_KEY" env var.
+	APIKey string `yaml:"-"` // Ignored by YAML
+
+	// AttestationKey is parsed from the "ATTESTATION_SIGNING_KEY" env var.
+	// When set, GET can return attestations signed with it.
+	AttestationKey ed25519.PrivateKey `yaml:"-"` // Ignored by YAML
+}
+
+// CompileEntityIDPattern compiles an entity_id_pattern value. The pattern
+// is anchored so it must match the whole entity ID. An empty value
+// returns nil, which accepts any ID.
+func CompileEntityIDPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid entity_id_pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// ParseProvisionerKey decodes a standard base64 32-byte Ed25519 public key.
+func ParseProvisionerKey(raw string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("provisioner key is not valid base64: %w", err)
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("provisioner key is %d bytes, expected %d", len(b), ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// ParseAttestationKey decodes a standard base64 Ed25519 private key,
+// given either as its 32-byte seed or in the 64-byte expanded form.
+func ParseAttestationKey(raw string) (ed25519.PrivateKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("attestation key is not valid base64: %w", err)
+	}
+	switch len(b) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(b), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(b), nil
+	default:
+		return nil, fmt.Errorf("attestation key must be a %d-byte Ed25519 seed or %d-byte private key, got %d bytes",
+			ed25519.SeedSize, ed25519.PrivateKeySize, len(b))
+	}
 }
 
 // UpdateConfigWithEnvOverrides takes the base configuration (created from YAML)
@@ -47,17 +419,55 @@ func UpdateConfigWithEnvOverrides(cfg *Config, logger *slog.Logger) (*Config, er
 		logger.Debug("Overriding config value", "key", "IDENTITY_SERVICE_URL", "source", "env")
 		cfg.IdentityServiceURL = idURL
 	}
+	if logFormat := os.Getenv("LOG_FORMAT"); logFormat != "" {
+		logger.Debug("Overriding config value", "key", "LOG_FORMAT", "source", "env")
+		format, err := ResolveLogFormat(logFormat, cfg.RunMode)
+		if err != nil {
+			logger.Error("Invalid LOG_FORMAT override", "err", err)
+			return nil, err
+		}
+		cfg.LogFormat = format
+	}
 	// JWT Secret is exclusively environment-sourced
 	if jwtSecret := os.Getenv("JWT_SECRET"); jwtSecret != "" {
 		logger.Debug("Loaded config value", "key", "JWT_SECRET", "source", "env")
 		cfg.JWTSecret = jwtSecret
 	}
+	// As is the Redis password
+	if redisPassword := os.Getenv("REDIS_PASSWORD"); redisPassword != "" {
+		logger.Debug("Loaded config value", "key", "REDIS_PASSWORD", "source", "env")
+		cfg.RedisPassword = redisPassword
+	}
+	// And the pre-shared API key
+	if apiKey := os.Getenv("API_KEY"); apiKey != "" {
+		logger.Debug("Loaded config value", "key", "API_KEY", "source", "env")
+		cfg.APIKey = apiKey
+	}
+	// And the attestation signing key
+	if rawKey := os.Getenv("ATTESTATION_SIGNING_KEY"); rawKey != "" {
+		logger.Debug("Loaded config value", "key", "ATTESTATION_SIGNING_KEY", "source", "env")
+		key, err := ParseAttestationKey(rawKey)
+		if err != nil {
+			logger.Error("Invalid ATTESTATION_SIGNING_KEY", "err", err)
+			return nil, err
+		}
+		cfg.AttestationKey = key
+	}
 
 	// 2. Final Validation
-	if cfg.JWTSecret == "" {
+	if cfg.AuthMode == AuthModeAPIKey {
+		if cfg.APIKey == "" {
+			logger.Error("Final config validation failed", "error", "API_KEY is not set")
+			return nil, fmt.Errorf("API_KEY environment variable is not set or is empty (required by auth_mode apikey)")
+		}
+	} else if cfg.JWTSecret == "" {
 		logger.Error("Final config validation failed", "error", "JWT_SECRET is not set")
 		return nil, fmt.Errorf("JWT_SECRET environment variable is not set or is empty")
 	}
+	if cfg.InviteTokensEnabled && cfg.JWTSecret == "" {
+		logger.Error("Final config validation failed", "error", "JWT_SECRET is not set")
+		return nil, fmt.Errorf("JWT_SECRET environment variable is not set or is empty (required by invite_tokens_enabled)")
+	}
 
 	logger.Debug("Configuration finalized and validated successfully")
 	return cfg, nil
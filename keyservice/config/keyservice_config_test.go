@@ -2,6 +2,9 @@
 package config_test
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
 	"io"
 	"log/slog"
 	"os"
@@ -41,6 +44,7 @@ func TestUpdateConfigWithEnvOverrides(t *testing.T) {
 		t.Setenv("GCP_PROJECT_ID", "env-project-override")
 		t.Setenv("IDENTITY_SERVICE_URL", "http://env-id-service.com")
 		t.Setenv("JWT_SECRET", "my-secret-key-from-env")
+		t.Setenv("REDIS_PASSWORD", "redis-password-from-env")
 
 		// Act
 		cfg, err := config.UpdateConfigWithEnvOverrides(baseCfg, logger)
@@ -53,6 +57,7 @@ func TestUpdateConfigWithEnvOverrides(t *testing.T) {
 		assert.Equal(t, "env-project-override", cfg.ProjectID)
 		assert.Equal(t, "http://env-id-service.com", cfg.IdentityServiceURL)
 		assert.Equal(t, "my-secret-key-from-env", cfg.JWTSecret)
+		assert.Equal(t, "redis-password-from-env", cfg.RedisPassword)
 
 		// Check that non-overridden fields remain
 		assert.Equal(t, "base-mode", cfg.RunMode)
@@ -103,4 +108,110 @@ func TestUpdateConfigWithEnvOverrides(t *testing.T) {
 		assert.Nil(t, cfg)
 		assert.Contains(t, err.Error(), "JWT_SECRET environment variable is not set or is empty")
 	})
+
+	t.Run("Success - ATTESTATION_SIGNING_KEY seed parsed", func(t *testing.T) {
+		// Arrange
+		baseCfg := newBaseConfig()
+		seed := bytes.Repeat([]byte{7}, ed25519.SeedSize)
+		t.Setenv("JWT_SECRET", "my-secret-key-from-env")
+		t.Setenv("ATTESTATION_SIGNING_KEY", base64.StdEncoding.EncodeToString(seed))
+
+		// Act
+		cfg, err := config.UpdateConfigWithEnvOverrides(baseCfg, logger)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, ed25519.NewKeyFromSeed(seed), cfg.AttestationKey)
+	})
+
+	t.Run("Failure - ATTESTATION_SIGNING_KEY of the wrong length", func(t *testing.T) {
+		// Arrange
+		baseCfg := newBaseConfig()
+		t.Setenv("JWT_SECRET", "my-secret-key-from-env")
+		t.Setenv("ATTESTATION_SIGNING_KEY", base64.StdEncoding.EncodeToString([]byte("too-short")))
+
+		// Act
+		cfg, err := config.UpdateConfigWithEnvOverrides(baseCfg, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("Success - LOG_FORMAT override applied", func(t *testing.T) {
+		// Arrange
+		baseCfg := newBaseConfig()
+		baseCfg.LogFormat = config.LogFormatJSON
+		t.Setenv("LOG_FORMAT", "text")
+		t.Setenv("JWT_SECRET", "my-secret-key-from-env")
+
+		// Act
+		cfg, err := config.UpdateConfigWithEnvOverrides(baseCfg, logger)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, config.LogFormatText, cfg.LogFormat)
+	})
+
+	t.Run("Failure - invalid LOG_FORMAT override", func(t *testing.T) {
+		// Arrange
+		baseCfg := newBaseConfig()
+		t.Setenv("LOG_FORMAT", "xml")
+		t.Setenv("JWT_SECRET", "my-secret-key-from-env")
+
+		// Act
+		cfg, err := config.UpdateConfigWithEnvOverrides(baseCfg, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("Success - API_KEY stands in for JWT_SECRET in apikey mode", func(t *testing.T) {
+		// Arrange
+		baseCfg := newBaseConfig()
+		baseCfg.AuthMode = config.AuthModeAPIKey
+		os.Unsetenv("JWT_SECRET")
+		t.Setenv("API_KEY", "pre-shared-key")
+
+		// Act
+		cfg, err := config.UpdateConfigWithEnvOverrides(baseCfg, logger)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "pre-shared-key", cfg.APIKey)
+	})
+
+	t.Run("Failure - apikey mode without API_KEY", func(t *testing.T) {
+		// Arrange
+		baseCfg := newBaseConfig()
+		baseCfg.AuthMode = config.AuthModeAPIKey
+		os.Unsetenv("API_KEY")
+		t.Setenv("JWT_SECRET", "my-secret-key-from-env")
+
+		// Act
+		cfg, err := config.UpdateConfigWithEnvOverrides(baseCfg, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+		assert.Contains(t, err.Error(), "API_KEY")
+	})
+
+	t.Run("Failure - invite tokens in apikey mode without JWT_SECRET", func(t *testing.T) {
+		// Arrange
+		baseCfg := newBaseConfig()
+		baseCfg.AuthMode = config.AuthModeAPIKey
+		baseCfg.InviteTokensEnabled = true
+		os.Unsetenv("JWT_SECRET")
+		t.Setenv("API_KEY", "pre-shared-key")
+
+		// Act
+		cfg, err := config.UpdateConfigWithEnvOverrides(baseCfg, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+		assert.Contains(t, err.Error(), "invite_tokens_enabled")
+	})
 }
@@ -0,0 +1,95 @@
+// --- File: keyservice/config/summary.go ---
+package config
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+)
+
+// redacted replaces secret values in the effective-config summary.
+const redacted = "[REDACTED]"
+
+// LogEffectiveConfig writes a single Info line summarizing the final
+// configuration, so operators can check a deployment from its first few
+// log lines. Secrets are never logged.
+func LogEffectiveConfig(logger *slog.Logger, cfg *Config) {
+	logger.LogAttrs(context.Background(), slog.LevelInfo, "Effective configuration", EffectiveConfigAttrs(cfg)...)
+}
+
+// EffectiveConfigAttrs returns the attributes logged by LogEffectiveConfig.
+// The JWT secret is reduced to whether one is set, and any credentials in
+// URLs are masked.
+func EffectiveConfigAttrs(cfg *Config) []slog.Attr {
+	jwtSecret := ""
+	if cfg.JWTSecret != "" {
+		jwtSecret = redacted
+	}
+	authMode := "jwks"
+	if cfg.AuthMode == AuthModeAPIKey {
+		authMode = AuthModeAPIKey
+	}
+	return []slog.Attr{
+		slog.String("run_mode", cfg.RunMode),
+		slog.String("backend", "firestore"),
+		slog.String("project_id", cfg.ProjectID),
+		slog.String("firestore_collection", cfg.FirestoreCollection),
+		slog.Bool("fallback_to_inmemory", cfg.FallbackToInMemory),
+		slog.String("http_listen_addr", cfg.HTTPListenAddr),
+		slog.Bool("tls", cfg.TLSCertFile != ""),
+		slog.String("base_path", cfg.BasePath),
+		slog.String("auth_mode", authMode),
+		slog.String("identity_service_url", redactURL(cfg.IdentityServiceURL)),
+		slog.String("jwt_secret", jwtSecret),
+		slog.Any("trusted_issuers", cfg.TrustedIssuers),
+		slog.Int("admin_user_count", len(cfg.AdminUserIDs)),
+		slog.String("key_validation_mode", string(cfg.KeyValidationMode)),
+		slog.String("log_format", cfg.LogFormat),
+		slog.String("error_detail_verbosity", cfg.ErrorDetailVerbosity),
+		slog.Group("features",
+			slog.Bool("require_tls", cfg.RequireTLS),
+			slog.Bool("shutdown_drain", cfg.ShutdownDrainPeriod > 0),
+			slog.Bool("jwks", cfg.JWKSEnabled),
+			slog.Bool("token_correlation", cfg.TokenCorrelation),
+			slog.Bool("log_authz_decisions", cfg.LogAuthzDecisions),
+			slog.Bool("session_consistency", cfg.SessionConsistencyWindow > 0),
+			slog.Bool("slo", cfg.SLOObjective > 0),
+			slog.Bool("hedging", cfg.HedgeDelay > 0),
+			slog.Bool("coalesce_reads", cfg.CoalesceReads),
+			slog.Bool("audit_hash_chain", cfg.AuditHashChain),
+			slog.Bool("storage_budget", cfg.StorageBudgetBytes > 0),
+			slog.Bool("async_store", cfg.AsyncStoreQueueSize > 0),
+			slog.Bool("invite_tokens", cfg.InviteTokensEnabled),
+			slog.Bool("usage_restrictions", cfg.UsageRestrictionsEnabled),
+			slog.Bool("canonical_responses", cfg.CanonicalResponses),
+			slog.Bool("key_events", cfg.KeyEventsTopic != ""),
+			slog.Bool("cdc", cfg.CDCFilePath != "" || cfg.CDCPubSubTopic != ""),
+			slog.Bool("access_counting", cfg.AccessCounting),
+			slog.Bool("entity_verification", cfg.EntityVerificationURL != ""),
+			slog.Bool("rotation_cooldown", cfg.RotationCooldown > 0),
+			slog.Bool("shadow_reads", cfg.ShadowFirestoreCollection != ""),
+			slog.Bool("verify_firestore_indexes", cfg.VerifyFirestoreIndexes),
+			slog.Bool("redis_cache", cfg.RedisAddr != ""),
+			slog.Bool("attestation", cfg.AttestationKey != nil),
+			slog.Bool("gzip_requests", cfg.AcceptGzipRequests),
+			slog.Bool("require_utf8_bodies", cfg.RequireUTF8Bodies),
+			slog.Bool("cache_preload", len(cfg.PreloadURNs) > 0),
+			slog.Bool("require_provisioning_signature", cfg.RequireProvisioningSignature),
+			slog.Bool("entity_id_pattern", cfg.EntityIDPattern != nil),
+			slog.Bool("multipart_uploads", cfg.AcceptMultipartUploads),
+		),
+	}
+}
+
+// redactURL masks any password in raw. Values that do not parse are
+// dropped entirely rather than risk logging a secret.
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redacted
+	}
+	return u.Redacted()
+}
@@ -2,41 +2,290 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"crypto/tls"
+	"fmt"
 	"log/slog"
+	"net/netip"
+	"time"
 
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
 	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
 )
 
 // YamlConfig is the structure that mirrors the raw config.yaml file.
 type YamlConfig struct {
-	RunMode             string `yaml:"run_mode"`
-	ProjectID           string `yaml:"project_id"`
-	HTTPListenAddr      string `yaml:"http_listen_addr"`
-	IdentityServiceURL  string `yaml:"identity_service_url"`
-	FirestoreCollection string `yaml:"firestore_collection"` // ADDED
-	Cors                struct {
+	RunMode                      string                   `yaml:"run_mode"`
+	ProjectID                    string                   `yaml:"project_id"`
+	HTTPListenAddr               string                   `yaml:"http_listen_addr"`
+	IdentityServiceURL           string                   `yaml:"identity_service_url"`
+	FirestoreCollection          string                   `yaml:"firestore_collection"` // ADDED
+	FirestoreMaxConcurrency      int                      `yaml:"firestore_max_concurrency"`
+	VerifyFirestoreIndexes       bool                     `yaml:"verify_firestore_indexes"`
+	ShutdownDrainPeriod          time.Duration            `yaml:"shutdown_drain_period"`
+	RequireTLS                   bool                     `yaml:"require_tls"`
+	TrustedProxies               []string                 `yaml:"trusted_proxies"`
+	KeyValidationMode            string                   `yaml:"key_validation_mode"`
+	JWKSEnabled                  bool                     `yaml:"jwks_enabled"`
+	JWKSCacheTTL                 time.Duration            `yaml:"jwks_cache_ttl"`
+	LogFormat                    string                   `yaml:"log_format"`
+	ErrorDetailVerbosity         string                   `yaml:"error_detail_verbosity"`
+	TokenCorrelation             bool                     `yaml:"token_correlation"`
+	LogAuthzDecisions            bool                     `yaml:"log_authz_decisions"`
+	SelfStoreDeniedEntityTypes   []string                 `yaml:"self_store_denied_entity_types"`
+	AdminUserIDs                 []string                 `yaml:"admin_user_ids"`
+	AdminAllowedCIDRs            []string                 `yaml:"admin_allowed_cidrs"`
+	AdminDeniedCIDRs             []string                 `yaml:"admin_denied_cidrs"`
+	SessionConsistencyWindow     time.Duration            `yaml:"session_consistency_window"`
+	TrustedIssuers               []string                 `yaml:"trusted_issuers"`
+	AuthMode                     string                   `yaml:"auth_mode"`
+	SLOObjective                 float64                  `yaml:"slo_objective"`
+	SLOWindowSize                int                      `yaml:"slo_window_size"`
+	MaxJSONDepth                 int                      `yaml:"max_json_depth"`
+	HedgeDelay                   time.Duration            `yaml:"hedge_delay"`
+	HedgeMaxInFlight             int                      `yaml:"hedge_max_in_flight"`
+	BasePath                     string                   `yaml:"base_path"`
+	KeyEventsTopic               string                   `yaml:"key_events_topic"`
+	CDCFilePath                  string                   `yaml:"cdc_file_path"`
+	CDCPubSubTopic               string                   `yaml:"cdc_pubsub_topic"`
+	FallbackToInMemory           bool                     `yaml:"fallback_to_inmemory"`
+	AllowIdenticalKeys           bool                     `yaml:"allow_identical_keys"`
+	RotationCooldown             time.Duration            `yaml:"rotation_cooldown"`
+	MaxClockSkew                 time.Duration            `yaml:"max_clock_skew"`
+	ConsistencyWait              time.Duration            `yaml:"consistency_wait"`
+	ThrottleRetryAfter           time.Duration            `yaml:"throttle_retry_after"`
+	CoalesceReads                bool                     `yaml:"coalesce_reads"`
+	AuditHashChain               bool                     `yaml:"audit_hash_chain"`
+	StorageBudgetBytes           int64                    `yaml:"storage_budget_bytes"`
+	AsyncStoreQueueSize          int                      `yaml:"async_store_queue_size"`
+	InviteTokensEnabled          bool                     `yaml:"invite_tokens_enabled"`
+	UsageRestrictionsEnabled     bool                     `yaml:"usage_restrictions_enabled"`
+	CanonicalResponses           bool                     `yaml:"canonical_responses"`
+	InMemoryStatsInterval        time.Duration            `yaml:"inmemory_stats_interval"`
+	AccessCounting               bool                     `yaml:"access_counting"`
+	EntityVerificationURL        string                   `yaml:"entity_verification_url"`
+	EntityVerificationCacheTTL   time.Duration            `yaml:"entity_verification_cache_ttl"`
+	EntityTypePolicies           map[string]YamlKeyPolicy `yaml:"entity_type_policies"`
+	ShadowFirestoreCollection    string                   `yaml:"shadow_firestore_collection"`
+	TLSCertFile                  string                   `yaml:"tls_cert_file"`
+	TLSKeyFile                   string                   `yaml:"tls_key_file"`
+	TLSMinVersion                string                   `yaml:"tls_min_version"`
+	RedisAddr                    string                   `yaml:"redis_addr"`
+	RedisCacheTTL                time.Duration            `yaml:"redis_cache_ttl"`
+	AcceptGzipRequests           bool                     `yaml:"accept_gzip_requests"`
+	RequireUTF8Bodies            bool                     `yaml:"require_utf8_bodies"`
+	MaxDecompressedBodyBytes     int64                    `yaml:"max_decompressed_body_bytes"`
+	PreloadURNs                  []string                 `yaml:"preload_urns"`
+	PreloadURNsFile              string                   `yaml:"preload_urns_file"`
+	TrustedProvisionerKeys       []string                 `yaml:"trusted_provisioner_keys"`
+	RequireProvisioningSignature bool                     `yaml:"require_provisioning_signature"`
+	EntityIDPattern              string                   `yaml:"entity_id_pattern"`
+	AcceptMultipartUploads       bool                     `yaml:"accept_multipart_uploads"`
+	Cors                         struct {
 		AllowedOrigins []string `yaml:"allowed_origins"`
 		Role           string   `yaml:"cors_role"`
 	} `yaml:"cors"`
 }
 
+// YamlKeyPolicy mirrors one entry of entity_type_policies.
+type YamlKeyPolicy struct {
+	EncKeyOptional    bool   `yaml:"enc_key_optional"`
+	SigKeyOptional    bool   `yaml:"sig_key_optional"`
+	KeyValidationMode string `yaml:"key_validation_mode"`
+}
+
 // NewConfigFromYaml converts the YamlConfig into a clean, base Config struct.
 // This struct is the "Stage 1" configuration, ready to be augmented by environment overrides.
 func NewConfigFromYaml(baseCfg *YamlConfig, logger *slog.Logger) (*Config, error) {
 	logger.Debug("Mapping YAML config to base config struct")
 
+	// Parse the CIDR lists up front so a typo fails at startup.
+	trustedProxies, err := parsePrefixes("trusted_proxies", baseCfg.TrustedProxies, logger)
+	if err != nil {
+		return nil, err
+	}
+	adminAllowed, err := parsePrefixes("admin_allowed_cidrs", baseCfg.AdminAllowedCIDRs, logger)
+	if err != nil {
+		return nil, err
+	}
+	adminDenied, err := parsePrefixes("admin_denied_cidrs", baseCfg.AdminDeniedCIDRs, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	validationMode, err := keystore.ParseValidationMode(baseCfg.KeyValidationMode)
+	if err != nil {
+		logger.Error("Invalid key validation mode", "key_validation_mode", baseCfg.KeyValidationMode, "err", err)
+		return nil, err
+	}
+
+	logFormat, err := ResolveLogFormat(baseCfg.LogFormat, baseCfg.RunMode)
+	if err != nil {
+		logger.Error("Invalid log format", "log_format", baseCfg.LogFormat, "err", err)
+		return nil, err
+	}
+
+	errorDetail, err := ResolveErrorDetailVerbosity(baseCfg.ErrorDetailVerbosity, baseCfg.RunMode)
+	if err != nil {
+		logger.Error("Invalid error detail verbosity", "error_detail_verbosity", baseCfg.ErrorDetailVerbosity, "err", err)
+		return nil, err
+	}
+
+	authMode, err := ParseAuthMode(baseCfg.AuthMode)
+	if err != nil {
+		logger.Error("Invalid auth mode", "auth_mode", baseCfg.AuthMode, "err", err)
+		return nil, err
+	}
+	if baseCfg.CDCFilePath != "" && baseCfg.CDCPubSubTopic != "" {
+		logger.Error("Invalid CDC configuration", "cdc_file_path", baseCfg.CDCFilePath, "cdc_pubsub_topic", baseCfg.CDCPubSubTopic)
+		return nil, fmt.Errorf("set at most one of cdc_file_path and cdc_pubsub_topic")
+	}
+	if authMode == AuthModeAPIKey && len(baseCfg.TrustedIssuers) > 0 {
+		logger.Error("Invalid auth configuration", "auth_mode", authMode, "trusted_issuers", baseCfg.TrustedIssuers)
+		return nil, fmt.Errorf("trusted_issuers requires auth_mode jwt")
+	}
+
+	var policies map[string]keystore.KeyPolicy
+	if len(baseCfg.EntityTypePolicies) > 0 {
+		policies = make(map[string]keystore.KeyPolicy, len(baseCfg.EntityTypePolicies))
+	}
+	for entityType, p := range baseCfg.EntityTypePolicies {
+		policy := keystore.KeyPolicy{EncKeyOptional: p.EncKeyOptional, SigKeyOptional: p.SigKeyOptional}
+		// An empty mode inherits key_validation_mode rather than defaulting to lenient.
+		if p.KeyValidationMode != "" {
+			mode, err := keystore.ParseValidationMode(p.KeyValidationMode)
+			if err != nil {
+				logger.Error("Invalid entity type key validation mode", "entity_type", entityType, "err", err)
+				return nil, fmt.Errorf("entity_type_policies[%s]: %w", entityType, err)
+			}
+			policy.ValidationMode = mode
+		}
+		policies[entityType] = policy
+	}
+
+	if (baseCfg.TLSCertFile == "") != (baseCfg.TLSKeyFile == "") {
+		logger.Error("Incomplete TLS configuration", "tls_cert_file", baseCfg.TLSCertFile, "tls_key_file", baseCfg.TLSKeyFile)
+		return nil, fmt.Errorf("tls_cert_file and tls_key_file must be set together")
+	}
+	tlsMinVersion, err := ParseTLSMinVersion(baseCfg.TLSMinVersion)
+	if err != nil {
+		logger.Error("Invalid TLS minimum version", "tls_min_version", baseCfg.TLSMinVersion, "err", err)
+		return nil, err
+	}
+
+	preloadURNs, err := LoadPreloadURNs(baseCfg.PreloadURNs, baseCfg.PreloadURNsFile)
+	if err != nil {
+		logger.Error("Invalid cache preload list", "preload_urns_file", baseCfg.PreloadURNsFile, "err", err)
+		return nil, err
+	}
+	// Preloading only pays off when there is a shared cache to fill.
+	if len(preloadURNs) > 0 && baseCfg.RedisAddr == "" {
+		logger.Error("Cache preload configured without a cache", "preload_urn_count", len(preloadURNs))
+		return nil, fmt.Errorf("preload_urns and preload_urns_file require redis_addr")
+	}
+
+	provisionerKeys := make([]ed25519.PublicKey, 0, len(baseCfg.TrustedProvisionerKeys))
+	for i, raw := range baseCfg.TrustedProvisionerKeys {
+		key, err := ParseProvisionerKey(raw)
+		if err != nil {
+			logger.Error("Invalid trusted provisioner key", "index", i, "err", err)
+			return nil, fmt.Errorf("trusted_provisioner_keys[%d]: %w", i, err)
+		}
+		provisionerKeys = append(provisionerKeys, key)
+	}
+	// Without a trusted key, strict mode would withhold every entity.
+	if baseCfg.RequireProvisioningSignature && len(provisionerKeys) == 0 {
+		logger.Error("Provisioning signatures required but no provisioner is trusted")
+		return nil, fmt.Errorf("require_provisioning_signature needs at least one trusted_provisioner_keys entry")
+	}
+
+	entityIDPattern, err := CompileEntityIDPattern(baseCfg.EntityIDPattern)
+	if err != nil {
+		logger.Error("Invalid entity ID pattern", "entity_id_pattern", baseCfg.EntityIDPattern, "err", err)
+		return nil, err
+	}
+
+	basePath, err := NormalizeBasePath(baseCfg.BasePath)
+	if err != nil {
+		logger.Error("Invalid base path", "base_path", baseCfg.BasePath, "err", err)
+		return nil, err
+	}
+
 	// Map and Build initial Config structure
 	cfg := &Config{
-		RunMode:             baseCfg.RunMode,
-		ProjectID:           baseCfg.ProjectID,
-		HTTPListenAddr:      baseCfg.HTTPListenAddr,
-		IdentityServiceURL:  baseCfg.IdentityServiceURL,
-		FirestoreCollection: baseCfg.FirestoreCollection,
+		RunMode:                      baseCfg.RunMode,
+		ProjectID:                    baseCfg.ProjectID,
+		HTTPListenAddr:               baseCfg.HTTPListenAddr,
+		IdentityServiceURL:           baseCfg.IdentityServiceURL,
+		FirestoreCollection:          baseCfg.FirestoreCollection,
+		FirestoreMaxConcurrency:      baseCfg.FirestoreMaxConcurrency,
+		VerifyFirestoreIndexes:       baseCfg.VerifyFirestoreIndexes,
+		ShutdownDrainPeriod:          baseCfg.ShutdownDrainPeriod,
+		RequireTLS:                   baseCfg.RequireTLS,
+		TrustedProxies:               baseCfg.TrustedProxies,
+		KeyValidationMode:            validationMode,
+		JWKSEnabled:                  baseCfg.JWKSEnabled,
+		JWKSCacheTTL:                 baseCfg.JWKSCacheTTL,
+		LogFormat:                    logFormat,
+		ErrorDetailVerbosity:         errorDetail,
+		TokenCorrelation:             baseCfg.TokenCorrelation,
+		LogAuthzDecisions:            baseCfg.LogAuthzDecisions,
+		SelfStoreDeniedEntityTypes:   baseCfg.SelfStoreDeniedEntityTypes,
+		AdminUserIDs:                 baseCfg.AdminUserIDs,
+		AdminAllowedCIDRs:            baseCfg.AdminAllowedCIDRs,
+		AdminDeniedCIDRs:             baseCfg.AdminDeniedCIDRs,
+		SessionConsistencyWindow:     baseCfg.SessionConsistencyWindow,
+		TrustedIssuers:               baseCfg.TrustedIssuers,
+		AuthMode:                     authMode,
+		SLOObjective:                 baseCfg.SLOObjective,
+		SLOWindowSize:                baseCfg.SLOWindowSize,
+		MaxJSONDepth:                 baseCfg.MaxJSONDepth,
+		HedgeDelay:                   baseCfg.HedgeDelay,
+		HedgeMaxInFlight:             baseCfg.HedgeMaxInFlight,
+		BasePath:                     basePath,
+		KeyEventsTopic:               baseCfg.KeyEventsTopic,
+		CDCFilePath:                  baseCfg.CDCFilePath,
+		CDCPubSubTopic:               baseCfg.CDCPubSubTopic,
+		FallbackToInMemory:           baseCfg.FallbackToInMemory,
+		AllowIdenticalKeys:           baseCfg.AllowIdenticalKeys,
+		RotationCooldown:             baseCfg.RotationCooldown,
+		MaxClockSkew:                 baseCfg.MaxClockSkew,
+		ConsistencyWait:              baseCfg.ConsistencyWait,
+		RetryAfter:                   baseCfg.ThrottleRetryAfter,
+		CoalesceReads:                baseCfg.CoalesceReads,
+		AuditHashChain:               baseCfg.AuditHashChain,
+		StorageBudgetBytes:           baseCfg.StorageBudgetBytes,
+		AsyncStoreQueueSize:          baseCfg.AsyncStoreQueueSize,
+		InviteTokensEnabled:          baseCfg.InviteTokensEnabled,
+		UsageRestrictionsEnabled:     baseCfg.UsageRestrictionsEnabled,
+		CanonicalResponses:           baseCfg.CanonicalResponses,
+		InMemoryStatsInterval:        baseCfg.InMemoryStatsInterval,
+		AccessCounting:               baseCfg.AccessCounting,
+		EntityVerificationURL:        baseCfg.EntityVerificationURL,
+		EntityVerificationCacheTTL:   baseCfg.EntityVerificationCacheTTL,
+		EntityTypePolicies:           policies,
+		ShadowFirestoreCollection:    baseCfg.ShadowFirestoreCollection,
+		TLSCertFile:                  baseCfg.TLSCertFile,
+		TLSKeyFile:                   baseCfg.TLSKeyFile,
+		TLSMinVersion:                tlsMinVersion,
+		RedisAddr:                    baseCfg.RedisAddr,
+		RedisCacheTTL:                baseCfg.RedisCacheTTL,
+		AcceptGzipRequests:           baseCfg.AcceptGzipRequests,
+		RequireUTF8Bodies:            baseCfg.RequireUTF8Bodies,
+		MaxDecompressedBodyBytes:     baseCfg.MaxDecompressedBodyBytes,
+		PreloadURNs:                  preloadURNs,
+		TrustedProvisionerKeys:       provisionerKeys,
+		RequireProvisioningSignature: baseCfg.RequireProvisioningSignature,
+		EntityIDPattern:              entityIDPattern,
+		AcceptMultipartUploads:       baseCfg.AcceptMultipartUploads,
 		// Map Cors data here since it's a direct YAML-to-Config mapping
 		CorsConfig: middleware.CorsConfig{
 			AllowedOrigins: baseCfg.Cors.AllowedOrigins,
 			Role:           middleware.CorsRole(baseCfg.Cors.Role),
 		},
+		TrustedProxyPrefixes: trustedProxies,
+		AdminAllowedPrefixes: adminAllowed,
+		AdminDeniedPrefixes:  adminDenied,
 	}
 	// Note: JWTSecret is intentionally left blank here, as it's an override/injection point.
 
@@ -46,9 +295,82 @@ func NewConfigFromYaml(baseCfg *YamlConfig, logger *slog.Logger) (*Config, error
 		"http_listen_addr", cfg.HTTPListenAddr,
 		"identity_service_url", cfg.IdentityServiceURL,
 		"firestore_collection", cfg.FirestoreCollection,
+		"firestore_max_concurrency", cfg.FirestoreMaxConcurrency,
+		"verify_firestore_indexes", cfg.VerifyFirestoreIndexes,
+		"shutdown_drain_period", cfg.ShutdownDrainPeriod,
+		"require_tls", cfg.RequireTLS,
+		"trusted_proxies", cfg.TrustedProxies,
+		"key_validation_mode", cfg.KeyValidationMode,
+		"jwks_enabled", cfg.JWKSEnabled,
+		"jwks_cache_ttl", cfg.JWKSCacheTTL,
+		"log_format", cfg.LogFormat,
+		"error_detail_verbosity", cfg.ErrorDetailVerbosity,
+		"token_correlation", cfg.TokenCorrelation,
+		"log_authz_decisions", cfg.LogAuthzDecisions,
+		"self_store_denied_entity_types", cfg.SelfStoreDeniedEntityTypes,
+		"admin_user_ids", cfg.AdminUserIDs,
+		"admin_allowed_cidrs", cfg.AdminAllowedCIDRs,
+		"admin_denied_cidrs", cfg.AdminDeniedCIDRs,
+		"session_consistency_window", cfg.SessionConsistencyWindow,
+		"trusted_issuers", cfg.TrustedIssuers,
+		"auth_mode", cfg.AuthMode,
+		"slo_objective", cfg.SLOObjective,
+		"slo_window_size", cfg.SLOWindowSize,
+		"max_json_depth", cfg.MaxJSONDepth,
+		"hedge_delay", cfg.HedgeDelay,
+		"hedge_max_in_flight", cfg.HedgeMaxInFlight,
+		"base_path", cfg.BasePath,
+		"key_events_topic", cfg.KeyEventsTopic,
+		"cdc_file_path", cfg.CDCFilePath,
+		"cdc_pubsub_topic", cfg.CDCPubSubTopic,
+		"fallback_to_inmemory", cfg.FallbackToInMemory,
+		"allow_identical_keys", cfg.AllowIdenticalKeys,
+		"rotation_cooldown", cfg.RotationCooldown,
+		"max_clock_skew", cfg.MaxClockSkew,
+		"consistency_wait", cfg.ConsistencyWait,
+		"throttle_retry_after", cfg.RetryAfter,
+		"coalesce_reads", cfg.CoalesceReads,
+		"audit_hash_chain", cfg.AuditHashChain,
+		"storage_budget_bytes", cfg.StorageBudgetBytes,
+		"async_store_queue_size", cfg.AsyncStoreQueueSize,
+		"invite_tokens_enabled", cfg.InviteTokensEnabled,
+		"usage_restrictions_enabled", cfg.UsageRestrictionsEnabled,
+		"canonical_responses", cfg.CanonicalResponses,
+		"inmemory_stats_interval", cfg.InMemoryStatsInterval,
+		"access_counting", cfg.AccessCounting,
+		"entity_verification_url", cfg.EntityVerificationURL,
+		"entity_verification_cache_ttl", cfg.EntityVerificationCacheTTL,
+		"entity_type_policies", cfg.EntityTypePolicies,
+		"shadow_firestore_collection", cfg.ShadowFirestoreCollection,
+		"tls_cert_file", cfg.TLSCertFile,
+		"tls_min_version", tls.VersionName(cfg.TLSMinVersion),
+		"redis_addr", cfg.RedisAddr,
+		"redis_cache_ttl", cfg.RedisCacheTTL,
+		"accept_gzip_requests", cfg.AcceptGzipRequests,
+		"require_utf8_bodies", cfg.RequireUTF8Bodies,
+		"max_decompressed_body_bytes", cfg.MaxDecompressedBodyBytes,
+		"preload_urn_count", len(cfg.PreloadURNs),
+		"trusted_provisioner_count", len(cfg.TrustedProvisionerKeys),
+		"require_provisioning_signature", cfg.RequireProvisioningSignature,
+		"entity_id_pattern", baseCfg.EntityIDPattern,
+		"accept_multipart_uploads", cfg.AcceptMultipartUploads,
 		"cors_origins", cfg.CorsConfig.AllowedOrigins,
 		"cors_role", cfg.CorsConfig.Role,
 	)
 
 	return cfg, nil
 }
+
+// parsePrefixes parses the CIDRs of the named setting.
+func parsePrefixes(setting string, cidrs []string, logger *slog.Logger) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			logger.Error("Invalid CIDR", "setting", setting, "cidr", cidr, "err", err)
+			return nil, fmt.Errorf("invalid %s entry %q: %w", setting, cidr, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
@@ -2,11 +2,20 @@
 package config_test
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/tls"
+	"encoding/base64"
+	"net/netip"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tinywideclouds/go-key-service/keyservice/config"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
 	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
 )
 
@@ -15,6 +24,7 @@ func TestNewConfigFromYaml(t *testing.T) {
 
 	t.Run("Success - maps all fields correctly from YAML struct", func(t *testing.T) {
 		// Arrange
+		provisionerKey := ed25519.PublicKey(bytes.Repeat([]byte{5}, ed25519.PublicKeySize))
 		// This simulates the raw struct after unmarshaling the YAML file
 		yamlCfg := &config.YamlConfig{
 			RunMode:            "test-mode",
@@ -22,7 +32,68 @@ func TestNewConfigFromYaml(t *testing.T) {
 			HTTPListenAddr:     ":9090",
 			IdentityServiceURL: "http://yaml-identity.com",
 			// This is the fix for the hardcoded value
-			FirestoreCollection: "my-keys-collection",
+			FirestoreCollection:        "my-keys-collection",
+			FirestoreMaxConcurrency:    16,
+			VerifyFirestoreIndexes:     true,
+			ShutdownDrainPeriod:        15 * time.Second,
+			RequireTLS:                 true,
+			TrustedProxies:             []string{"10.0.0.0/8"},
+			KeyValidationMode:          "strict",
+			JWKSEnabled:                true,
+			JWKSCacheTTL:               10 * time.Minute,
+			LogFormat:                  "json",
+			ErrorDetailVerbosity:       "minimal",
+			TokenCorrelation:           true,
+			LogAuthzDecisions:          true,
+			SelfStoreDeniedEntityTypes: []string{"org"},
+			AdminUserIDs:               []string{"admin-1"},
+			AdminAllowedCIDRs:          []string{"192.168.0.0/16"},
+			AdminDeniedCIDRs:           []string{"192.168.66.0/24"},
+			SessionConsistencyWindow:   5 * time.Second,
+			TrustedIssuers:             []string{"https://identity.example.com"},
+			SLOObjective:               0.995,
+			SLOWindowSize:              500,
+			MaxJSONDepth:               6,
+			HedgeDelay:                 50 * time.Millisecond,
+			HedgeMaxInFlight:           8,
+			BasePath:                   "/keyservice/",
+			KeyEventsTopic:             "key-events",
+			CDCPubSubTopic:             "key-cdc",
+			FallbackToInMemory:         true,
+			AllowIdenticalKeys:         true,
+			RotationCooldown:           time.Hour,
+			MaxClockSkew:               30 * time.Second,
+			ConsistencyWait:            2 * time.Second,
+			ThrottleRetryAfter:         45 * time.Second,
+			CoalesceReads:              true,
+			AuditHashChain:             true,
+			StorageBudgetBytes:         1 << 30,
+			AsyncStoreQueueSize:        64,
+			InviteTokensEnabled:        true,
+			UsageRestrictionsEnabled:   true,
+			CanonicalResponses:         true,
+			InMemoryStatsInterval:      time.Minute,
+			AccessCounting:             true,
+			EntityVerificationURL:      "http://identity/users",
+			EntityVerificationCacheTTL: 30 * time.Second,
+			EntityTypePolicies: map[string]config.YamlKeyPolicy{
+				"service": {EncKeyOptional: true, KeyValidationMode: "strict"},
+				"device":  {},
+			},
+			ShadowFirestoreCollection:    "public-keys-v2",
+			TLSCertFile:                  "/etc/tls/tls.crt",
+			TLSKeyFile:                   "/etc/tls/tls.key",
+			TLSMinVersion:                "1.3",
+			RedisAddr:                    "redis:6379",
+			RedisCacheTTL:                10 * time.Second,
+			AcceptGzipRequests:           true,
+			RequireUTF8Bodies:            true,
+			MaxDecompressedBodyBytes:     64 << 10,
+			PreloadURNs:                  []string{"urn:sm:user:alice"},
+			TrustedProvisionerKeys:       []string{base64.StdEncoding.EncodeToString(provisionerKey)},
+			RequireProvisioningSignature: true,
+			EntityIDPattern:              "[0-9a-f-]{36}",
+			AcceptMultipartUploads:       true,
 			Cors: struct {
 				AllowedOrigins []string `yaml:"allowed_origins"`
 				Role           string   `yaml:"cors_role"`
@@ -45,6 +116,70 @@ func TestNewConfigFromYaml(t *testing.T) {
 		assert.Equal(t, ":9090", cfg.HTTPListenAddr)
 		assert.Equal(t, "http://yaml-identity.com", cfg.IdentityServiceURL)
 		assert.Equal(t, "my-keys-collection", cfg.FirestoreCollection)
+		assert.Equal(t, 16, cfg.FirestoreMaxConcurrency)
+		assert.True(t, cfg.VerifyFirestoreIndexes)
+		assert.Equal(t, 15*time.Second, cfg.ShutdownDrainPeriod)
+		assert.True(t, cfg.RequireTLS)
+		assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}, cfg.TrustedProxyPrefixes)
+		assert.Equal(t, keystore.ValidationStrict, cfg.KeyValidationMode)
+		assert.True(t, cfg.JWKSEnabled)
+		assert.Equal(t, 10*time.Minute, cfg.JWKSCacheTTL)
+		assert.Equal(t, config.LogFormatJSON, cfg.LogFormat)
+		assert.Equal(t, config.ErrorDetailMinimal, cfg.ErrorDetailVerbosity)
+		assert.True(t, cfg.TokenCorrelation)
+		assert.True(t, cfg.LogAuthzDecisions)
+		assert.Equal(t, []string{"org"}, cfg.SelfStoreDeniedEntityTypes)
+		assert.Equal(t, []string{"admin-1"}, cfg.AdminUserIDs)
+		assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")}, cfg.AdminAllowedPrefixes)
+		assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("192.168.66.0/24")}, cfg.AdminDeniedPrefixes)
+		assert.Equal(t, 5*time.Second, cfg.SessionConsistencyWindow)
+		assert.Equal(t, []string{"https://identity.example.com"}, cfg.TrustedIssuers)
+		assert.Equal(t, 0.995, cfg.SLOObjective)
+		assert.Equal(t, 500, cfg.SLOWindowSize)
+		assert.Equal(t, 6, cfg.MaxJSONDepth)
+		assert.Equal(t, 50*time.Millisecond, cfg.HedgeDelay)
+		assert.Equal(t, 8, cfg.HedgeMaxInFlight)
+		assert.Equal(t, "/keyservice", cfg.BasePath)
+		assert.Equal(t, "key-events", cfg.KeyEventsTopic)
+		assert.Equal(t, "key-cdc", cfg.CDCPubSubTopic)
+		assert.True(t, cfg.FallbackToInMemory)
+		assert.True(t, cfg.AllowIdenticalKeys)
+		assert.Equal(t, time.Hour, cfg.RotationCooldown)
+		assert.Equal(t, 30*time.Second, cfg.MaxClockSkew)
+		assert.Equal(t, 2*time.Second, cfg.ConsistencyWait)
+		assert.Equal(t, 45*time.Second, cfg.RetryAfter)
+		assert.True(t, cfg.CoalesceReads)
+		assert.True(t, cfg.AuditHashChain)
+		assert.Equal(t, int64(1<<30), cfg.StorageBudgetBytes)
+		assert.Equal(t, 64, cfg.AsyncStoreQueueSize)
+		assert.True(t, cfg.InviteTokensEnabled)
+		assert.True(t, cfg.UsageRestrictionsEnabled)
+		assert.True(t, cfg.CanonicalResponses)
+		assert.Equal(t, time.Minute, cfg.InMemoryStatsInterval)
+		assert.True(t, cfg.AccessCounting)
+		assert.Equal(t, "http://identity/users", cfg.EntityVerificationURL)
+		assert.Equal(t, 30*time.Second, cfg.EntityVerificationCacheTTL)
+		assert.Equal(t, map[string]keystore.KeyPolicy{
+			"service": {EncKeyOptional: true, ValidationMode: keystore.ValidationStrict},
+			"device":  {},
+		}, cfg.EntityTypePolicies)
+		assert.Equal(t, "public-keys-v2", cfg.ShadowFirestoreCollection)
+		assert.Equal(t, "/etc/tls/tls.crt", cfg.TLSCertFile)
+		assert.Equal(t, "/etc/tls/tls.key", cfg.TLSKeyFile)
+		assert.Equal(t, uint16(tls.VersionTLS13), cfg.TLSMinVersion)
+		assert.Equal(t, "redis:6379", cfg.RedisAddr)
+		assert.Equal(t, 10*time.Second, cfg.RedisCacheTTL)
+		assert.True(t, cfg.AcceptGzipRequests)
+		assert.True(t, cfg.RequireUTF8Bodies)
+		assert.Equal(t, int64(64<<10), cfg.MaxDecompressedBodyBytes)
+		require.Len(t, cfg.PreloadURNs, 1)
+		assert.Equal(t, "urn:sm:user:alice", cfg.PreloadURNs[0].String())
+		assert.Equal(t, []ed25519.PublicKey{provisionerKey}, cfg.TrustedProvisionerKeys)
+		assert.True(t, cfg.RequireProvisioningSignature)
+		assert.True(t, cfg.AcceptMultipartUploads)
+		require.NotNil(t, cfg.EntityIDPattern)
+		assert.True(t, cfg.EntityIDPattern.MatchString("3f2b8c1e-4d5a-4e6f-9a7b-0c1d2e3f4a5b"))
+		assert.False(t, cfg.EntityIDPattern.MatchString("x3f2b8c1e-4d5a-4e6f-9a7b-0c1d2e3f4a5b"), "the pattern is anchored")
 
 		// Check that the CORS struct was correctly processed and mapped
 		assert.NotNil(t, cfg.CorsConfig)
@@ -54,4 +189,263 @@ func TestNewConfigFromYaml(t *testing.T) {
 		// IMPORTANT: Check that fields set in Stage 2 are empty
 		assert.Empty(t, cfg.JWTSecret, "JWTSecret should not be set at Stage 1")
 	})
+
+	t.Run("Failure - invalid trusted proxy CIDR", func(t *testing.T) {
+		// Arrange
+		yamlCfg := &config.YamlConfig{
+			TrustedProxies: []string{"not-a-cidr"},
+		}
+
+		// Act
+		cfg, err := config.NewConfigFromYaml(yamlCfg, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+		assert.Contains(t, err.Error(), "not-a-cidr")
+	})
+
+	t.Run("Failure - invalid admin allowed CIDR", func(t *testing.T) {
+		// Arrange
+		yamlCfg := &config.YamlConfig{
+			AdminAllowedCIDRs: []string{"10.0.0.0/33"},
+		}
+
+		// Act
+		cfg, err := config.NewConfigFromYaml(yamlCfg, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+		assert.Contains(t, err.Error(), "admin_allowed_cidrs")
+	})
+
+	t.Run("Success - key validation mode defaults to lenient", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{}, logger)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, keystore.ValidationLenient, cfg.KeyValidationMode)
+	})
+
+	t.Run("Failure - unknown key validation mode", func(t *testing.T) {
+		// Arrange
+		yamlCfg := &config.YamlConfig{KeyValidationMode: "paranoid"}
+
+		// Act
+		cfg, err := config.NewConfigFromYaml(yamlCfg, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("Failure - unknown entity type key validation mode", func(t *testing.T) {
+		// Arrange
+		yamlCfg := &config.YamlConfig{EntityTypePolicies: map[string]config.YamlKeyPolicy{
+			"service": {KeyValidationMode: "paranoid"},
+		}}
+
+		// Act
+		cfg, err := config.NewConfigFromYaml(yamlCfg, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+		assert.Contains(t, err.Error(), "service")
+	})
+
+	t.Run("Success - TLS minimum version defaults to 1.2", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{}, logger)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, uint16(tls.VersionTLS12), cfg.TLSMinVersion)
+	})
+
+	t.Run("Failure - TLS minimum version below 1.2", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{TLSMinVersion: "1.0"}, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("Failure - TLS certificate without key", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{TLSCertFile: "/etc/tls/tls.crt"}, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("Success - log format defaults by run mode", func(t *testing.T) {
+		// Act
+		localCfg, err := config.NewConfigFromYaml(&config.YamlConfig{RunMode: "local"}, logger)
+		require.NoError(t, err)
+		prodCfg, err := config.NewConfigFromYaml(&config.YamlConfig{RunMode: config.RunModeProduction}, logger)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, config.LogFormatText, localCfg.LogFormat)
+		assert.Equal(t, config.LogFormatJSON, prodCfg.LogFormat)
+	})
+
+	t.Run("Failure - unknown log format", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{LogFormat: "xml"}, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("Success - auth mode defaults to jwt", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{}, logger)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, config.AuthModeJWT, cfg.AuthMode)
+	})
+
+	t.Run("Success - apikey auth mode", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{AuthMode: "apikey"}, logger)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, config.AuthModeAPIKey, cfg.AuthMode)
+	})
+
+	t.Run("Failure - unknown auth mode", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{AuthMode: "basic"}, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("Failure - trusted issuers need jwt auth", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{AuthMode: "apikey", TrustedIssuers: []string{"https://id.example.com"}}, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("Failure - both CDC sinks", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{CDCFilePath: "/tmp/cdc.jsonl", CDCPubSubTopic: "key-cdc"}, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("Success - error detail verbosity defaults by run mode", func(t *testing.T) {
+		// Act
+		localCfg, err := config.NewConfigFromYaml(&config.YamlConfig{RunMode: "local"}, logger)
+		require.NoError(t, err)
+		prodCfg, err := config.NewConfigFromYaml(&config.YamlConfig{RunMode: config.RunModeProduction}, logger)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, config.ErrorDetailFull, localCfg.ErrorDetailVerbosity)
+		assert.Equal(t, config.ErrorDetailMinimal, prodCfg.ErrorDetailVerbosity)
+	})
+
+	t.Run("Failure - unknown error detail verbosity", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{ErrorDetailVerbosity: "verbose"}, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("Failure - base path without leading slash", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{BasePath: "keyservice"}, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("Success - preload list merges the file", func(t *testing.T) {
+		// Arrange
+		file := filepath.Join(t.TempDir(), "preload.txt")
+		contents := "# hot entities\nurn:sm:user:bob\n\nurn:sm:user:alice\n"
+		require.NoError(t, os.WriteFile(file, []byte(contents), 0o600))
+
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{
+			RedisAddr:       "redis:6379",
+			PreloadURNs:     []string{"urn:sm:user:alice"},
+			PreloadURNsFile: file,
+		}, logger)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, cfg.PreloadURNs, 2, "duplicates are dropped")
+		assert.Equal(t, "urn:sm:user:alice", cfg.PreloadURNs[0].String())
+		assert.Equal(t, "urn:sm:user:bob", cfg.PreloadURNs[1].String())
+	})
+
+	t.Run("Failure - malformed preload URN", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{
+			RedisAddr:   "redis:6379",
+			PreloadURNs: []string{"urn:sm:user"},
+		}, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("Failure - provisioning signature required without a trusted key", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{RequireProvisioningSignature: true}, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("Failure - trusted provisioner key of the wrong size", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{
+			TrustedProvisionerKeys: []string{base64.StdEncoding.EncodeToString([]byte{1, 2, 3})},
+		}, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("Failure - invalid entity ID pattern", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{EntityIDPattern: "[a-z"}, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	})
+
+	t.Run("Failure - preload without a cache", func(t *testing.T) {
+		// Act
+		cfg, err := config.NewConfigFromYaml(&config.YamlConfig{PreloadURNs: []string{"urn:sm:user:alice"}}, logger)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, cfg)
+	})
 }
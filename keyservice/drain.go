@@ -0,0 +1,27 @@
+// --- File: keyservice/drain.go ---
+package keyservice
+
+import (
+	"context"
+	"time"
+)
+
+// drain fails the readiness probe and then waits out the drain period
+// with the listener still open, so a load balancer probing /readyz stops
+// routing new requests here before connections are refused. It returns
+// early if ctx ends first.
+func (w *Wrapper) drain(ctx context.Context) {
+	if w.drainPeriod <= 0 {
+		return
+	}
+	w.SetReady(false)
+	w.logger.Info("Draining: readiness now fails; waiting before closing the listener", "drain_period", w.drainPeriod)
+
+	timer := time.NewTimer(w.drainPeriod)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		w.logger.Warn("Drain cut short by the shutdown deadline", "err", ctx.Err())
+	}
+}
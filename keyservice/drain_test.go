@@ -0,0 +1,69 @@
+// --- File: keyservice/drain_test.go ---
+//go:build integration
+
+package keyservice_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/keyservice"
+	"github.com/tinywideclouds/go-key-service/keyservice/config"
+)
+
+func TestKeyService_ShutdownDrain(t *testing.T) {
+	// Arrange
+	const drainPeriod = 500 * time.Millisecond
+	cfg := &config.Config{HTTPListenAddr: ":0", ShutdownDrainPeriod: drainPeriod}
+	passthrough := func(next http.Handler) http.Handler { return next }
+	service := keyservice.NewKeyService(cfg, inmemory.New(), passthrough, newTestLogger())
+
+	startErr := make(chan error, 1)
+	go func() { startErr <- service.Start() }()
+	require.Eventually(t, func() bool { return service.GetHTTPPort() != ":0" }, 5*time.Second, 10*time.Millisecond)
+	readyzURL := "http://127.0.0.1" + service.GetHTTPPort() + "/readyz"
+
+	readyzStatus := func() (int, error) {
+		resp, err := http.Get(readyzURL)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+	require.Eventually(t, func() bool {
+		status, err := readyzStatus()
+		return err == nil && status == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Act
+	shutdownStarted := time.Now()
+	shutdownErr := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownErr <- service.Shutdown(ctx)
+	}()
+
+	// Assert: readiness fails while the listener still answers
+	require.Eventually(t, func() bool {
+		status, err := readyzStatus()
+		return err == nil && status == http.StatusServiceUnavailable
+	}, drainPeriod/2, 10*time.Millisecond)
+
+	require.NoError(t, <-shutdownErr)
+	assert.GreaterOrEqual(t, time.Since(shutdownStarted), drainPeriod, "server must not stop before the drain period ends")
+	_, err := readyzStatus()
+	assert.Error(t, err, "listener should be closed after shutdown")
+	select {
+	case err := <-startErr:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after shutdown")
+	}
+}
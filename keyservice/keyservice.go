@@ -2,15 +2,27 @@
 package keyservice
 
 import (
+	"context"
+	"encoding/base64"
 	"errors"
 	"log/slog"
+	"net"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/tinywideclouds/go-key-service/internal/api"
+	"github.com/tinywideclouds/go-key-service/internal/storage/auditchain"
+	"github.com/tinywideclouds/go-key-service/internal/storage/budget"
+	"github.com/tinywideclouds/go-key-service/internal/storage/coalesce"
+	"github.com/tinywideclouds/go-key-service/internal/storage/hedge"
+	"github.com/tinywideclouds/go-key-service/internal/storage/sessioncache"
+	"github.com/tinywideclouds/go-key-service/internal/storage/slo"
 	"github.com/tinywideclouds/go-key-service/keyservice/config"
 	"github.com/tinywideclouds/go-key-service/pkg/keystore"
 	"github.com/tinywideclouds/go-microservice-base/pkg/microservice"
 	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
 )
 
 // Wrapper encapsulates the key service, embedding a BaseServer to provide
@@ -18,6 +30,28 @@ import (
 type Wrapper struct {
 	*microservice.BaseServer
 	logger *slog.Logger
+
+	// tlsServer, when set, serves the base server's mux over TLS in place
+	// of its cleartext listener.
+	tlsServer   *http.Server
+	tlsCertFile string
+	tlsKeyFile  string
+	mu          sync.RWMutex
+	tlsAddr     string
+
+	// preloadURNs are read through preloadStore once the service is ready
+	// (see startPreload); stopPreload cancels a preload still running at
+	// shutdown.
+	preloadURNs  []urn.URN
+	preloadStore keystore.Store
+	stopPreload  context.CancelFunc
+
+	// drainPeriod is how long Shutdown fails readiness before it stops
+	// the server (see drain).
+	drainPeriod time.Duration
+
+	// asyncWrites, if set, is flushed by Shutdown once the server stops.
+	asyncWrites *api.AsyncWrites
 }
 
 // NewKeyService creates and wires up the entire key service.
@@ -31,31 +65,307 @@ func NewKeyService(
 ) *Wrapper {
 	// 1. Create the standard base server.
 	baseServer := microservice.NewBaseServer(logger, cfg.HTTPListenAddr)
+	preloadStore := store
+
+	// Optionally cap the bytes the store may hold. This sits beneath the
+	// audit chain, which only records the writes it lets through.
+	if cfg.StorageBudgetBytes > 0 {
+		budgeted, err := budget.New(context.Background(), store, cfg.StorageBudgetBytes, logger)
+		if err != nil {
+			logger.Error("Storage budget disabled", "err", err)
+		} else {
+			logger.Info("Storage budget enabled", "limit_bytes", cfg.StorageBudgetBytes, "used_bytes", budgeted.Used())
+			store = budgeted
+		}
+	}
+
+	// Optionally chain every write into a tamper-evident audit log. This
+	// wraps the backend directly so it sees each write exactly once.
+	var auditStore *auditchain.Store
+	if cfg.AuditHashChain {
+		chained, err := auditchain.New(context.Background(), store, &auditchain.MemoryLog{}, logger)
+		if err != nil {
+			logger.Error("Audit hash chain disabled", "err", err)
+		} else {
+			logger.Info("Audit hash chain enabled")
+			auditStore = chained
+			store = chained
+		}
+	}
+
+	// 1a. Optionally hedge slow reads with a second backend attempt. This sits
+	// beneath the SLO tracker so a hedged read counts as one operation.
+	if cfg.HedgeDelay > 0 {
+		logger.Info("Read hedging enabled", "delay", cfg.HedgeDelay, "max_in_flight", cfg.HedgeMaxInFlight)
+		store = hedge.New(store, cfg.HedgeDelay, cfg.HedgeMaxInFlight)
+	}
+
+	// ...and optionally merge concurrent reads of one entity into one call.
+	if cfg.CoalesceReads {
+		logger.Info("Read coalescing enabled")
+		store = coalesce.New(store)
+	}
+
+	// 1b. Optionally track store operations against an availability objective.
+	// This wraps the backend directly so session-cache hits are not counted.
+	var sloStore *slo.Store
+	if cfg.SLOObjective > 0 {
+		logger.Info("SLO tracking enabled", "objective", cfg.SLOObjective, "window_size", cfg.SLOWindowSize)
+		sloStore = slo.New(store, cfg.SLOObjective, cfg.SLOWindowSize)
+		store = sloStore
+	}
+
+	// 1c. Optionally give each client session read-your-writes consistency.
+	sessionMiddleware := func(next http.Handler) http.Handler { return next }
+	if cfg.SessionConsistencyWindow > 0 {
+		logger.Info("Session read-your-writes enabled", "window", cfg.SessionConsistencyWindow)
+		store = sessioncache.New(store, cfg.SessionConsistencyWindow)
+		sessionMiddleware = api.SessionID
+	}
 
 	// 2. Create the service-specific API handlers.
-	apiHandler := &api.API{Store: store, Logger: logger, JWTSecret: cfg.JWTSecret}
+	apiHandler := &api.API{
+		Store:                        store,
+		Logger:                       logger,
+		JWTSecret:                    cfg.JWTSecret,
+		KeyValidationMode:            cfg.KeyValidationMode,
+		EntityTypePolicies:           cfg.EntityTypePolicies,
+		SelfStoreDeniedTypes:         cfg.SelfStoreDeniedEntityTypes,
+		AdminUserIDs:                 cfg.AdminUserIDs,
+		SLO:                          sloStore,
+		AuditChain:                   auditStore,
+		MaxJSONDepth:                 cfg.MaxJSONDepth,
+		WriteLock:                    &api.WriteLock{},
+		AllowIdenticalKeys:           cfg.AllowIdenticalKeys,
+		RotationCooldown:             cfg.RotationCooldown,
+		MaxClockSkew:                 cfg.MaxClockSkew,
+		ConsistencyWait:              cfg.ConsistencyWait,
+		RetryAfter:                   cfg.RetryAfter,
+		ProvisionerKeys:              cfg.TrustedProvisionerKeys,
+		RequireProvisioningSignature: cfg.RequireProvisioningSignature,
+		EntityIDPattern:              cfg.EntityIDPattern,
+		AcceptMultipart:              cfg.AcceptMultipartUploads,
+		MaxPartBytes:                 cfg.MaxDecompressedBodyBytes,
+		Capabilities:                 capabilitiesFor(cfg),
+		LogAuthzDecisions:            cfg.LogAuthzDecisions,
+		AcceptUsageRestrictions:      cfg.UsageRestrictionsEnabled,
+		CanonicalResponses:           cfg.CanonicalResponses,
+		FullErrorDetail:              cfg.ErrorDetailVerbosity == config.ErrorDetailFull,
+		BasePath:                     cfg.BasePath,
+	}
+	if cfg.AsyncStoreQueueSize > 0 {
+		logger.Info("Async stores enabled", "queue_size", cfg.AsyncStoreQueueSize)
+		apiHandler.AsyncWrites = api.NewAsyncWrites(store, apiHandler.WriteLock, cfg.AsyncStoreQueueSize, logger)
+	}
+	if cfg.InviteTokensEnabled {
+		logger.Info("Invite token redemption enabled")
+		apiHandler.Invites = api.NewInviteVerifier(cfg.JWTSecret)
+	}
+	if cfg.EntityVerificationURL != "" {
+		logger.Info("Entity verification enabled", "url", cfg.EntityVerificationURL, "cache_ttl", cfg.EntityVerificationCacheTTL)
+		apiHandler.Entities = api.NewEntityVerifier(cfg.EntityVerificationURL, cfg.EntityVerificationCacheTTL, logger)
+	}
+	if cfg.JWKSEnabled {
+		apiHandler.JWKS = api.NewJWKSCache(store, cfg.JWKSCacheTTL, logger)
+	}
+	if cfg.AttestationKey != nil {
+		apiHandler.Attester = api.NewAttester(cfg.AttestationKey)
+		logger.Info("Freshness attestation enabled",
+			"public_key", base64.StdEncoding.EncodeToString(apiHandler.Attester.PublicKey()))
+	}
 
 	// 3. Get the mux from the base server and register routes.
 	mux := baseServer.Mux()
 
-	// 4. Create CORS middleware from the config.
-	corsMiddleware := middleware.NewCorsMiddleware(cfg.CorsConfig, logger)
+	// 4. Create CORS middleware from the config. Its responses depend on the
+	// request's Origin, so say so for the benefit of shared caches.
+	baseCorsMiddleware := middleware.NewCorsMiddleware(cfg.CorsConfig, logger)
+	corsMiddleware := func(next http.Handler) http.Handler { return api.VaryCORS(baseCorsMiddleware(next)) }
 	optionsHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 
-	// 5. Register OPTIONS for CORS pre-flight
-	mux.Handle("OPTIONS /keys/{entityURN}", corsMiddleware(optionsHandler))
+	// 5. In production, optionally refuse to serve keys over cleartext.
+	// Health and metrics endpoints are left alone so probes keep working.
+	tlsMiddleware := func(next http.Handler) http.Handler { return next }
+	if cfg.RequireTLS && cfg.RunMode == config.RunModeProduction {
+		logger.Info("TLS is required for key routes", "trusted_proxies", cfg.TrustedProxies)
+		tlsMiddleware = api.RequireTLS(cfg.TrustedProxyPrefixes, logger)
+	}
 
-	// 6. Register API Routes
+	// 6. Optionally tag authenticated requests with the token's sub and jti.
+	// This runs inside the auth middleware, so it only sees validated tokens.
+	claimsMiddleware := func(next http.Handler) http.Handler { return next }
+	if cfg.TokenCorrelation {
+		claimsMiddleware = api.CorrelateTokenClaims(logger)
+	}
+	// ...and optionally only accept tokens from the configured issuers.
+	if len(cfg.TrustedIssuers) > 0 {
+		correlate := claimsMiddleware
+		requireIssuer := api.RequireTrustedIssuer(cfg.TrustedIssuers, logger)
+		claimsMiddleware = func(next http.Handler) http.Handler { return correlate(requireIssuer(next)) }
+	}
+
+	// 6b. Writes can be paused by an admin while reads carry on.
+	writeLockMiddleware := api.RejectWhenWriteLocked(apiHandler.WriteLock, cfg.RetryAfter, logger)
+	// ...and their bodies may optionally arrive gzip-compressed.
+	decompressMiddleware := func(next http.Handler) http.Handler { return next }
+	if cfg.AcceptGzipRequests {
+		logger.Info("Accepting gzip request bodies", "max_decompressed_bytes", cfg.MaxDecompressedBodyBytes)
+		decompressMiddleware = api.DecompressRequest(cfg.MaxDecompressedBodyBytes, logger)
+	}
+	// ...and may be required to be UTF-8, as JSON must be.
+	charsetMiddleware := func(next http.Handler) http.Handler { return next }
+	if cfg.RequireUTF8Bodies {
+		logger.Info("Rejecting request bodies in charsets other than UTF-8")
+		charsetMiddleware = api.RequireUTF8(logger)
+	}
+
+	// 6c. Every route lives under the configured base path, if any.
+	route := func(method, path string) string {
+		return method + " " + cfg.BasePath + path
+	}
+
+	// 7. Register OPTIONS for CORS pre-flight
+	mux.Handle(route(http.MethodOptions, "/keys/{entityURN}"), corsMiddleware(optionsHandler))
+
+	// 8. Register API Routes
 	storeKeyHandler := http.HandlerFunc(apiHandler.StoreKeysHandler)
-	mux.Handle("POST /keys/{entityURN}", corsMiddleware(authMiddleware(storeKeyHandler)))
+	mux.Handle(route(http.MethodPost, "/keys/{entityURN}"), tlsMiddleware(corsMiddleware(authMiddleware(claimsMiddleware(writeLockMiddleware(charsetMiddleware(decompressMiddleware(sessionMiddleware(storeKeyHandler)))))))))
+
+	deleteKeyHandler := http.HandlerFunc(apiHandler.DeleteKeysHandler)
+	mux.Handle(route(http.MethodDelete, "/keys/{entityURN}"), tlsMiddleware(corsMiddleware(authMiddleware(claimsMiddleware(writeLockMiddleware(deleteKeyHandler))))))
 
 	getKeyHandler := http.HandlerFunc(apiHandler.GetKeysHandler)
-	mux.Handle("GET /keys/{entityURN}", corsMiddleware(getKeyHandler))
+	mux.Handle(route(http.MethodGet, "/keys/{entityURN}"), tlsMiddleware(corsMiddleware(sessionMiddleware(getKeyHandler))))
+
+	// ...and report on stores queued with Prefer: respond-async.
+	if apiHandler.AsyncWrites != nil {
+		asyncWriteHandler := http.HandlerFunc(apiHandler.GetAsyncWriteHandler)
+		mux.Handle(route(http.MethodGet, "/writes/{writeID}"), tlsMiddleware(corsMiddleware(authMiddleware(claimsMiddleware(asyncWriteHandler)))))
+	}
 
-	return &Wrapper{
+	// ...and let newly provisioned users redeem an invite token for their
+	// first keys. The token is the only credential.
+	if apiHandler.Invites != nil {
+		redeemInviteHandler := http.HandlerFunc(apiHandler.RedeemInviteHandler)
+		mux.Handle(route(http.MethodOptions, "/keys:redeemInvite"), corsMiddleware(optionsHandler))
+		mux.Handle(route(http.MethodPost, "/keys:redeemInvite"), tlsMiddleware(corsMiddleware(writeLockMiddleware(charsetMiddleware(decompressMiddleware(redeemInviteHandler))))))
+	}
+
+	// 8a. Let clients discover what this deployment supports.
+	capabilitiesHandler := http.HandlerFunc(apiHandler.GetCapabilitiesHandler)
+	mux.Handle(route(http.MethodGet, "/capabilities"), tlsMiddleware(corsMiddleware(capabilitiesHandler)))
+
+	// 8b. Batch existence and fingerprint check, e.g. for a contact list.
+	mux.Handle(route(http.MethodOptions, "/keys:fingerprints"), corsMiddleware(optionsHandler))
+	fingerprintsHandler := http.HandlerFunc(apiHandler.GetFingerprintsHandler)
+	mux.Handle(route(http.MethodPost, "/keys:fingerprints"), tlsMiddleware(corsMiddleware(charsetMiddleware(fingerprintsHandler))))
+
+	// 8c. Batch key retrieval, e.g. for every participant in a group.
+	mux.Handle(route(http.MethodOptions, "/keys:batchGet"), corsMiddleware(optionsHandler))
+	batchGetHandler := http.HandlerFunc(apiHandler.BatchGetKeysHandler)
+	mux.Handle(route(http.MethodPost, "/keys:batchGet"), tlsMiddleware(corsMiddleware(charsetMiddleware(sessionMiddleware(batchGetHandler)))))
+
+	// 8d. Key reuse check between two entities, without the key bytes.
+	diffKeysHandler := http.HandlerFunc(apiHandler.DiffKeysHandler)
+	mux.Handle(route(http.MethodGet, "/keys:diff"), tlsMiddleware(corsMiddleware(diffKeysHandler)))
+
+	// 9. Admin provisioning, for entity types that cannot self-store.
+	// In apikey mode the caller names its own user ID, so an admin ID
+	// proves nothing and the admin routes are left unregistered.
+	adminRoutesEnabled := len(cfg.AdminUserIDs) > 0
+	if adminRoutesEnabled && cfg.AuthMode == config.AuthModeAPIKey {
+		logger.Warn("Admin routes disabled: auth_mode apikey cannot authenticate admins", "admin_user_count", len(cfg.AdminUserIDs))
+		adminRoutesEnabled = false
+	}
+	if adminRoutesEnabled {
+		// Admin routes only answer clients on trusted networks.
+		adminAllowed := cfg.AdminAllowedPrefixes
+		if len(adminAllowed) == 0 {
+			adminAllowed = api.DefaultAdminAllowedPrefixes
+		}
+		logger.Info("Admin routes enabled", "allowed_networks", adminAllowed, "denied_networks", cfg.AdminDeniedPrefixes)
+		adminNetwork := api.RestrictAdminNetwork(adminAllowed, cfg.AdminDeniedPrefixes, cfg.TrustedProxyPrefixes, logger)
+		adminMiddleware := func(next http.Handler) http.Handler { return tlsMiddleware(adminNetwork(next)) }
+
+		adminStoreKeyHandler := http.HandlerFunc(apiHandler.AdminStoreKeysHandler)
+		mux.Handle(route(http.MethodPost, "/admin/keys/{entityURN}"), adminMiddleware(authMiddleware(claimsMiddleware(writeLockMiddleware(charsetMiddleware(decompressMiddleware(adminStoreKeyHandler)))))))
+
+		lockHandler := http.HandlerFunc(apiHandler.LockWritesHandler)
+		mux.Handle(route(http.MethodPost, "/admin/lock"), adminMiddleware(authMiddleware(claimsMiddleware(lockHandler))))
+		unlockHandler := http.HandlerFunc(apiHandler.UnlockWritesHandler)
+		mux.Handle(route(http.MethodPost, "/admin/unlock"), adminMiddleware(authMiddleware(claimsMiddleware(unlockHandler))))
+
+		// Flagging keys as compromised is incident response, so it is not
+		// held up by the write lock.
+		markCompromisedHandler := http.HandlerFunc(apiHandler.MarkCompromisedHandler)
+		mux.Handle(route(http.MethodPut, "/admin/keys/{entityURN}/compromised"), adminMiddleware(authMiddleware(claimsMiddleware(charsetMiddleware(markCompromisedHandler)))))
+		clearCompromisedHandler := http.HandlerFunc(apiHandler.ClearCompromisedHandler)
+		mux.Handle(route(http.MethodDelete, "/admin/keys/{entityURN}/compromised"), adminMiddleware(authMiddleware(claimsMiddleware(clearCompromisedHandler))))
+
+		listHandler := http.HandlerFunc(apiHandler.ListEntitiesHandler)
+		mux.Handle(route(http.MethodGet, "/admin/keys"), adminMiddleware(authMiddleware(claimsMiddleware(listHandler))))
+		missingSigHandler := http.HandlerFunc(apiHandler.ListMissingSigKeyHandler)
+		mux.Handle(route(http.MethodGet, "/admin/keys:missingSig"), adminMiddleware(authMiddleware(claimsMiddleware(missingSigHandler))))
+		deleteOlderThanHandler := http.HandlerFunc(apiHandler.DeleteOlderThanHandler)
+		mux.Handle(route(http.MethodPost, "/admin/keys:deleteOlderThan"), adminMiddleware(authMiddleware(claimsMiddleware(writeLockMiddleware(deleteOlderThanHandler)))))
+		statsHandler := http.HandlerFunc(apiHandler.GetStatsHandler)
+		mux.Handle(route(http.MethodGet, "/admin/stats"), adminMiddleware(authMiddleware(claimsMiddleware(statsHandler))))
+
+		if sloStore != nil {
+			sloHandler := http.HandlerFunc(apiHandler.GetSLOHandler)
+			mux.Handle(route(http.MethodGet, "/admin/slo"), adminMiddleware(authMiddleware(claimsMiddleware(sloHandler))))
+		}
+		if auditStore != nil {
+			auditHandler := http.HandlerFunc(apiHandler.VerifyAuditChainHandler)
+			mux.Handle(route(http.MethodGet, "/admin/audit/verify"), adminMiddleware(authMiddleware(claimsMiddleware(auditHandler))))
+		}
+	}
+
+	// 10. Optionally serve every stored signing key as one JWK Set.
+	if cfg.JWKSEnabled {
+		jwksHandler := http.HandlerFunc(apiHandler.GetJWKSHandler)
+		mux.Handle(route(http.MethodGet, "/.well-known/jwks.json"), tlsMiddleware(corsMiddleware(jwksHandler)))
+	}
+
+	// 10b. Publish the key attestations are signed with.
+	if apiHandler.Attester != nil {
+		attestationKeyHandler := http.HandlerFunc(apiHandler.GetAttestationKeyHandler)
+		mux.Handle(route(http.MethodGet, "/attestation/key"), tlsMiddleware(corsMiddleware(attestationKeyHandler)))
+	}
+
+	// 11. The base server registers health and metrics at the root; mirror
+	// them under the base path so probes can go through the same proxy.
+	if cfg.BasePath != "" {
+		logger.Info("Serving routes under base path", "base_path", cfg.BasePath)
+		for _, path := range []string{"/healthz", "/readyz", "/metrics"} {
+			mux.Handle(cfg.BasePath+path, http.StripPrefix(cfg.BasePath, mux))
+		}
+	}
+
+	w := &Wrapper{
 		BaseServer: baseServer,
 		logger:     logger,
+		// Preload through the store as given, beneath the request-path
+		// decorators, so warmup reads fill the cache without counting
+		// against the SLO.
+		preloadURNs:  cfg.PreloadURNs,
+		preloadStore: preloadStore,
+		drainPeriod:  cfg.ShutdownDrainPeriod,
+		asyncWrites:  apiHandler.AsyncWrites,
 	}
+
+	// 12. Optionally terminate TLS here rather than at a proxy.
+	if cfg.TLSCertFile != "" {
+		w.tlsServer = &http.Server{
+			Addr:      baseServer.HTTPPort,
+			Handler:   mux,
+			TLSConfig: newTLSConfig(cfg.TLSMinVersion),
+			// Refused handshakes are routine on a public listener.
+			ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelDebug),
+		}
+		w.tlsCertFile, w.tlsKeyFile = cfg.TLSCertFile, cfg.TLSKeyFile
+	}
+	return w
 }
 
 // Start runs the HTTP server and handles service readiness logic.
@@ -63,6 +373,10 @@ func NewKeyService(
 // then sets the service's ready state.
 // It returns any error encountered during startup or runtime.
 func (w *Wrapper) Start() error {
+	if w.tlsServer != nil {
+		return w.startTLS()
+	}
+
 	errChan := make(chan error, 1)
 	httpReadyChan := make(chan struct{})
 	w.BaseServer.SetReadyChannel(httpReadyChan)
@@ -83,6 +397,7 @@ func (w *Wrapper) Start() error {
 		// Since key-service has no other startup tasks, it's safe to set ready.
 		w.SetReady(true)
 		w.logger.Info("Service is now ready.")
+		w.startPreload()
 
 	case err := <-errChan:
 		// Server failed before it could listen
@@ -92,3 +407,41 @@ func (w *Wrapper) Start() error {
 	// Wait for the server goroutine to exit (which happens on Shutdown)
 	return <-errChan
 }
+
+// Shutdown gracefully stops whichever server Start is running. With a
+// drain period configured, readiness fails for that long first; ctx
+// bounds the drain and the server shutdown together.
+func (w *Wrapper) Shutdown(ctx context.Context) error {
+	w.mu.RLock()
+	if w.stopPreload != nil {
+		w.stopPreload()
+	}
+	w.mu.RUnlock()
+	w.drain(ctx)
+	var err error
+	if w.tlsServer != nil {
+		w.logger.Info("Shutting down HTTPS server...")
+		err = w.tlsServer.Shutdown(ctx)
+	} else {
+		err = w.BaseServer.Shutdown(ctx)
+	}
+	// Queued writes were accepted with a 202, so persist them before exiting.
+	if w.asyncWrites != nil {
+		err = errors.Join(err, w.asyncWrites.Close(ctx))
+	}
+	return err
+}
+
+// GetHTTPPort returns the port the service is listening on.
+func (w *Wrapper) GetHTTPPort() string {
+	if w.tlsServer == nil {
+		return w.BaseServer.GetHTTPPort()
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	_, port, err := net.SplitHostPort(w.tlsAddr)
+	if err != nil {
+		return w.tlsServer.Addr
+	}
+	return ":" + port
+}
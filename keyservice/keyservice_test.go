@@ -7,7 +7,6 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
-	"errors"
 	"io"
 	"log/slog"
 	"net/http"
@@ -22,8 +21,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/api"
 	"github.com/tinywideclouds/go-key-service/keyservice"
 	"github.com/tinywideclouds/go-key-service/keyservice/config"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
 
 	"github.com/tinywideclouds/go-microservice-base/pkg/middleware"
 	"github.com/tinywideclouds/go-microservice-base/pkg/response"
@@ -58,6 +59,114 @@ func (mS *MockStore) GetPublicKeys(ctx context.Context, entityURN urn.URN) (keys
 	return args.Get(0).(keys.PublicKeys), args.Error(1)
 }
 
+// StorePublicKeysWithMetadata is the mock implementation for storing keys with metadata.
+func (mS *MockStore) StorePublicKeysWithMetadata(ctx context.Context, entityURN urn.URN, keys keys.PublicKeys, meta keystore.Metadata) error {
+	args := mS.Called(ctx, entityURN, keys, meta)
+	return args.Error(0)
+}
+
+// GetOrCreatePublicKeys is the mock implementation for a create-if-absent store.
+func (mS *MockStore) GetOrCreatePublicKeys(ctx context.Context, entityURN urn.URN, candidate keys.PublicKeys) (keys.PublicKeys, bool, error) {
+	args := mS.Called(ctx, entityURN, candidate)
+	return args.Get(0).(keys.PublicKeys), args.Bool(1), args.Error(2)
+}
+
+// SwapPublicKeys is the mock implementation for an atomic key swap.
+func (mS *MockStore) SwapPublicKeys(ctx context.Context, entityURN urn.URN, newKeys keys.PublicKeys) (keys.PublicKeys, error) {
+	args := mS.Called(ctx, entityURN, newKeys)
+	return args.Get(0).(keys.PublicKeys), args.Error(1)
+}
+
+// GetKeyRecord is the mock implementation for retrieving keys with their metadata.
+func (mS *MockStore) GetKeyRecord(ctx context.Context, entityURN urn.URN) (keystore.KeyRecord, error) {
+	args := mS.Called(ctx, entityURN)
+	return args.Get(0).(keystore.KeyRecord), args.Error(1)
+}
+
+// GetPublicKeysIfModifiedSince is the mock implementation for a conditional get.
+func (mS *MockStore) GetPublicKeysIfModifiedSince(ctx context.Context, entityURN urn.URN, since time.Time) (keys.PublicKeys, bool, error) {
+	args := mS.Called(ctx, entityURN, since)
+	return args.Get(0).(keys.PublicKeys), args.Bool(1), args.Error(2)
+}
+
+// DeleteOlderThan is the mock implementation for an age-based cleanup.
+func (mS *MockStore) DeleteOlderThan(ctx context.Context, age time.Duration) (int, error) {
+	args := mS.Called(ctx, age)
+	return args.Int(0), args.Error(1)
+}
+
+// ListEntitiesMissingSigKey is the mock implementation for the missing-sigKey scan.
+func (mS *MockStore) ListEntitiesMissingSigKey(ctx context.Context) ([]urn.URN, error) {
+	args := mS.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]urn.URN), args.Error(1)
+}
+
+// GetFingerprintsBatch is the mock implementation for a batch fingerprint read.
+func (mS *MockStore) GetFingerprintsBatch(ctx context.Context, entityURNs []urn.URN) (map[string]string, error) {
+	args := mS.Called(ctx, entityURNs)
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
+// GetPublicKeysBatch is the mock implementation for a batch key read.
+func (mS *MockStore) GetPublicKeysBatch(ctx context.Context, entityURNs []urn.URN) (map[string]keystore.KeyRecord, error) {
+	args := mS.Called(ctx, entityURNs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]keystore.KeyRecord), args.Error(1)
+}
+
+// DiffPublicKeys is the mock implementation for comparing two entities' keys.
+func (mS *MockStore) DiffPublicKeys(ctx context.Context, a, b urn.URN) (keystore.KeyDiff, error) {
+	args := mS.Called(ctx, a, b)
+	return args.Get(0).(keystore.KeyDiff), args.Error(1)
+}
+
+// BumpEpoch is the mock implementation for advancing an entity's epoch.
+func (mS *MockStore) BumpEpoch(ctx context.Context, entityURN urn.URN) (uint64, error) {
+	args := mS.Called(ctx, entityURN)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+// MarkCompromised is the mock implementation for flagging keys as compromised.
+func (mS *MockStore) MarkCompromised(ctx context.Context, entityURN urn.URN, reason string) error {
+	args := mS.Called(ctx, entityURN, reason)
+	return args.Error(0)
+}
+
+// ClearCompromised is the mock implementation for clearing the compromised flag.
+func (mS *MockStore) ClearCompromised(ctx context.Context, entityURN urn.URN) error {
+	args := mS.Called(ctx, entityURN)
+	return args.Error(0)
+}
+
+// DeletePublicKeys is the mock implementation for deleting an entity's keys.
+func (mS *MockStore) DeletePublicKeys(ctx context.Context, entityURN urn.URN) (bool, error) {
+	args := mS.Called(ctx, entityURN)
+	return args.Bool(0), args.Error(1)
+}
+
+// Stats is the mock implementation for summarising the store.
+func (mS *MockStore) Stats(ctx context.Context) (keystore.StoreStats, error) {
+	args := mS.Called(ctx)
+	return args.Get(0).(keystore.StoreStats), args.Error(1)
+}
+
+// IterateKeyRecords is the mock implementation for iterating every stored record.
+func (mS *MockStore) IterateKeyRecords(ctx context.Context, fn func(entityURN urn.URN, record keystore.KeyRecord) error) error {
+	args := mS.Called(ctx, fn)
+	return args.Error(0)
+}
+
+// IterateAll is the mock implementation for iterating every stored entity.
+func (mS *MockStore) IterateAll(ctx context.Context, fn func(entityURN urn.URN, keys keys.PublicKeys) error) error {
+	args := mS.Called(ctx, fn)
+	return args.Error(0)
+}
+
 // createTestToken generates a valid JWT signed by the given private key.
 func createTestToken(t *testing.T, privateKey *rsa.PrivateKey, userID string) string {
 	t.Helper()
@@ -160,7 +269,8 @@ func TestKeyService_Integration(t *testing.T) {
 		}
 		jsonBody := `{"encKey":"AQID","sigKey":"BAUG"}`
 
-		mockStore.On("StorePublicKeys", mock.Anything, testURN, nativeKeys).Return(nil).Once()
+		mockStore.On("GetKeyRecord", mock.Anything, testURN).Return(keystore.KeyRecord{}, keystore.ErrKeyNotFound).Once()
+		mockStore.On("StorePublicKeysWithMetadata", mock.Anything, testURN, nativeKeys, keystore.Metadata{}).Return(nil).Once()
 
 		req, _ := http.NewRequest(http.MethodPost, keyServiceServer.URL+"/keys/"+testURN.String(), strings.NewReader(jsonBody))
 		req.Header.Set("Authorization", "Bearer "+token)
@@ -193,7 +303,7 @@ func TestKeyService_Integration(t *testing.T) {
 
 		// Assert
 		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
-		mockStore.AssertNotCalled(t, "StorePublicKeys")
+		mockStore.AssertNotCalled(t, "StorePublicKeysWithMetadata")
 	})
 
 	t.Run("GetKeys - Success 200", func(t *testing.T) {
@@ -204,9 +314,9 @@ func TestKeyService_Integration(t *testing.T) {
 			EncKey: []byte{1, 2, 3},
 			SigKey: []byte{4, 5, 6},
 		}
-		expectedJSON := `{"encKey":"AQID","sigKey":"BAUG"}`
+		expectedJSON := `{"urn":"urn:sm:user:user-to-get","encKey":"AQID","sigKey":"BAUG"}`
 
-		mockStore.On("GetPublicKeys", mock.Anything, testURN).Return(nativeKeys, nil).Once()
+		mockStore.On("GetKeyRecord", mock.Anything, testURN).Return(keystore.KeyRecord{Keys: nativeKeys}, nil).Once()
 
 		req, _ := http.NewRequest(http.MethodGet, keyServiceServer.URL+"/keys/"+testURN.String(), nil)
 
@@ -227,7 +337,7 @@ func TestKeyService_Integration(t *testing.T) {
 		// Arrange
 		testURN, _ := urn.New(urn.SecureMessaging, "user", "user-not-found")
 
-		mockStore.On("GetPublicKeys", mock.Anything, testURN).Return(keys.PublicKeys{}, errors.New("not found")).Once()
+		mockStore.On("GetKeyRecord", mock.Anything, testURN).Return(keystore.KeyRecord{}, keystore.ErrKeyNotFound).Once()
 
 		req, _ := http.NewRequest(http.MethodGet, keyServiceServer.URL+"/keys/"+testURN.String(), nil)
 
@@ -240,4 +350,165 @@ func TestKeyService_Integration(t *testing.T) {
 		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 		mockStore.AssertExpectations(t)
 	})
+
+	t.Run("DeleteKeys - Success 204", func(t *testing.T) {
+		// Arrange
+		authedUserID := "deregistering-user"
+		testURN, _ := urn.New(urn.SecureMessaging, "user", authedUserID)
+		token := createTestToken(t, privateKey, authedUserID)
+
+		mockStore.On("DeletePublicKeys", mock.Anything, testURN).Return(true, nil).Once()
+
+		req, _ := http.NewRequest(http.MethodDelete, keyServiceServer.URL+"/keys/"+testURN.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		// Act
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		// Assert
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("BatchGetKeys - Success 200", func(t *testing.T) {
+		// Arrange
+		testURN, _ := urn.New(urn.SecureMessaging, "user", "batch-user")
+		testKeys := keys.PublicKeys{EncKey: []byte("enc"), SigKey: []byte("sig")}
+
+		mockStore.On("GetPublicKeysBatch", mock.Anything, []urn.URN{testURN}).
+			Return(map[string]keystore.KeyRecord{testURN.String(): {Keys: testKeys}}, nil).Once()
+
+		body := `{"urns":["` + testURN.String() + `"]}`
+		req, _ := http.NewRequest(http.MethodPost, keyServiceServer.URL+"/keys:batchGet", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		// Act
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		// Assert
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("DiffKeys - Success 200", func(t *testing.T) {
+		// Arrange
+		aURN, _ := urn.New(urn.SecureMessaging, "user", "diff-a")
+		bURN, _ := urn.New(urn.SecureMessaging, "user", "diff-b")
+
+		mockStore.On("DiffPublicKeys", mock.Anything, aURN, bURN).Return(keystore.KeyDiff{EncKeyEqual: true, SigKeyEqual: true}, nil).Once()
+
+		req, _ := http.NewRequest(http.MethodGet, keyServiceServer.URL+"/keys:diff?a="+aURN.String()+"&b="+bURN.String(), nil)
+
+		// Act
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		// Assert
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockStore.AssertExpectations(t)
+	})
+}
+
+func TestKeyService_BasePath(t *testing.T) {
+	// Arrange
+	logger := newTestLogger()
+	mockStore := new(MockStore)
+	cfg := &config.Config{
+		HTTPListenAddr: ":0",
+		JWTSecret:      "not-used-by-mock-auth",
+		BasePath:       "/keyservice",
+	}
+	service := keyservice.NewKeyService(cfg, mockStore, newMockAuthMiddleware(t, logger), logger)
+	keyServiceServer := httptest.NewServer(service.Mux())
+	defer keyServiceServer.Close()
+
+	testURN, _ := urn.New(urn.SecureMessaging, "user", "base-path-user")
+	mockStore.On("GetKeyRecord", mock.Anything, testURN).Return(keystore.KeyRecord{
+		Keys: keys.PublicKeys{EncKey: []byte{1}, SigKey: []byte{2}},
+	}, nil)
+
+	get := func(t *testing.T, path string) int {
+		resp, err := http.Get(keyServiceServer.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("Success - routes are served under the base path", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get(t, "/keyservice/keys/"+testURN.String()))
+		assert.Equal(t, http.StatusOK, get(t, "/keyservice/healthz"))
+		assert.Equal(t, http.StatusOK, get(t, "/keyservice/metrics"))
+	})
+
+	t.Run("Failure - unprefixed routes are not found", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, get(t, "/keys/"+testURN.String()))
+	})
+}
+
+func TestKeyService_APIKeyModeAdminRoutes(t *testing.T) {
+	// Arrange
+	logger := newTestLogger()
+	mockStore := new(MockStore)
+	cfg := &config.Config{
+		HTTPListenAddr: ":0",
+		AuthMode:       config.AuthModeAPIKey,
+		APIKey:         "pre-shared-key",
+		AdminUserIDs:   []string{"admin-user"},
+	}
+	service := keyservice.NewKeyService(cfg, mockStore, api.APIKeyAuth(cfg.APIKey, logger), logger)
+	keyServiceServer := httptest.NewServer(service.Mux())
+	defer keyServiceServer.Close()
+
+	t.Run("Failure - an admin ID in the entity header is rejected", func(t *testing.T) {
+		// Arrange
+		req, err := http.NewRequest(http.MethodGet, keyServiceServer.URL+"/admin/stats", nil)
+		require.NoError(t, err)
+		req.Header.Set(api.APIKeyHeader, cfg.APIKey)
+		req.Header.Set(api.EntityIDHeader, "admin-user")
+
+		// Act
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		mockStore.AssertNotCalled(t, "Stats")
+	})
+}
+
+func TestKeyService_CORSPreflight(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{
+		HTTPListenAddr: ":0",
+		CorsConfig: middleware.CorsConfig{
+			AllowedOrigins: []string{"https://a.example.com", "https://b.example.com"},
+		},
+	}
+	service := keyservice.NewKeyService(cfg, new(MockStore), newMockAuthMiddleware(t, newTestLogger()), newTestLogger())
+	server := httptest.NewServer(service.Mux())
+	defer server.Close()
+
+	for _, origin := range []string{"https://a.example.com", "https://b.example.com"} {
+		req, err := http.NewRequest(http.MethodOptions, server.URL+"/keys/urn:sm:user:alice", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+
+		// Act
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		// Assert
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, origin, resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			resp.Header.Values("Vary"))
+	}
 }
@@ -0,0 +1,41 @@
+// --- File: keyservice/preload.go ---
+package keyservice
+
+import (
+	"context"
+	"time"
+)
+
+// preloadTimeout bounds the whole warmup so a slow backend cannot keep it
+// running long after startup.
+const preloadTimeout = time.Minute
+
+// startPreload reads each configured hot entity through the store in the
+// background, filling the cache before clients ask for them. It runs
+// after the service is marked ready, so a slow or failing preload never
+// delays startup; entities that cannot be read are logged and skipped.
+func (w *Wrapper) startPreload() {
+	if len(w.preloadURNs) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), preloadTimeout)
+	w.mu.Lock()
+	w.stopPreload = cancel
+	w.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		loaded := 0
+		for _, entityURN := range w.preloadURNs {
+			if ctx.Err() != nil {
+				break
+			}
+			if _, err := w.preloadStore.GetKeyRecord(ctx, entityURN); err != nil {
+				w.logger.Warn("Cache preload: failed to read entity", "entity_urn", entityURN.String(), "err", err)
+				continue
+			}
+			loaded++
+		}
+		w.logger.Info("Cache preload complete", "loaded", loaded, "requested", len(w.preloadURNs))
+	}()
+}
@@ -0,0 +1,80 @@
+// --- File: keyservice/preload_test.go ---
+//go:build integration
+
+package keyservice_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/keyservice"
+	"github.com/tinywideclouds/go-key-service/keyservice/config"
+	"github.com/tinywideclouds/go-key-service/pkg/keystore"
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// cachingStore stands in for the cache decorator, remembering every
+// record read through it.
+type cachingStore struct {
+	keystore.Store
+
+	mu     sync.Mutex
+	cached map[string]bool
+}
+
+func (c *cachingStore) GetKeyRecord(ctx context.Context, entityURN urn.URN) (keystore.KeyRecord, error) {
+	record, err := c.Store.GetKeyRecord(ctx, entityURN)
+	if err == nil {
+		c.mu.Lock()
+		c.cached[entityURN.String()] = true
+		c.mu.Unlock()
+	}
+	return record, err
+}
+
+func (c *cachingStore) isCached(entityURN urn.URN) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cached[entityURN.String()]
+}
+
+func TestKeyService_CachePreload(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	alice, err := urn.New(urn.SecureMessaging, "user", "alice")
+	require.NoError(t, err)
+	bob, err := urn.New(urn.SecureMessaging, "user", "bob")
+	require.NoError(t, err)
+	missing, err := urn.New(urn.SecureMessaging, "user", "missing")
+	require.NoError(t, err)
+
+	backend := inmemory.New()
+	for _, entityURN := range []urn.URN{alice, bob} {
+		require.NoError(t, backend.StorePublicKeys(ctx, entityURN, keys.PublicKeys{EncKey: []byte{1}, SigKey: []byte{2}}))
+	}
+	cache := &cachingStore{Store: backend, cached: make(map[string]bool)}
+
+	cfg := &config.Config{HTTPListenAddr: ":0", PreloadURNs: []urn.URN{alice, missing, bob}}
+	passthrough := func(next http.Handler) http.Handler { return next }
+	service := keyservice.NewKeyService(cfg, cache, passthrough, newTestLogger())
+
+	// Act
+	go func() { _ = service.Start() }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = service.Shutdown(ctx)
+	})
+
+	// Assert: a missing entity is skipped without stopping the warmup.
+	require.Eventually(t, func() bool { return cache.isCached(alice) && cache.isCached(bob) },
+		5*time.Second, 10*time.Millisecond)
+	assert.False(t, cache.isCached(missing))
+}
@@ -0,0 +1,62 @@
+// --- File: keyservice/tls.go ---
+package keyservice
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// tlsCipherSuites are the TLS 1.2 suites the listener accepts: ECDHE key
+// exchange with an AEAD cipher. TLS 1.3 suites are not configurable and
+// are all strong.
+var tlsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// newTLSConfig returns the listener's TLS settings for the given minimum
+// protocol version.
+func newTLSConfig(minVersion uint16) *tls.Config {
+	return &tls.Config{
+		MinVersion:   minVersion,
+		CipherSuites: tlsCipherSuites,
+	}
+}
+
+// startTLS serves the mux over TLS on the base server's address. It marks
+// the service ready once the listener is up and blocks until shutdown.
+func (w *Wrapper) startTLS() error {
+	cert, err := tls.LoadX509KeyPair(w.tlsCertFile, w.tlsKeyFile)
+	if err != nil {
+		w.logger.Error("Failed to load TLS certificate", "cert_file", w.tlsCertFile, "err", err)
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	w.tlsServer.TLSConfig.Certificates = []tls.Certificate{cert}
+
+	listener, err := net.Listen("tcp", w.tlsServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", w.tlsServer.Addr, err)
+	}
+	w.mu.Lock()
+	w.tlsAddr = listener.Addr().String()
+	w.mu.Unlock()
+
+	w.logger.Info("HTTPS listener is active.", "address", listener.Addr().String(),
+		"min_version", tls.VersionName(w.tlsServer.TLSConfig.MinVersion))
+	w.SetReady(true)
+	w.logger.Info("Service is now ready.")
+	w.startPreload()
+
+	if err := w.tlsServer.ServeTLS(listener, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		w.logger.Error("HTTPS server failed", "err", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// --- File: keyservice/tls_test.go ---
+//go:build integration
+
+package keyservice_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinywideclouds/go-key-service/internal/storage/inmemory"
+	"github.com/tinywideclouds/go-key-service/keyservice"
+	"github.com/tinywideclouds/go-key-service/keyservice/config"
+)
+
+// writeSelfSignedCert writes a localhost certificate and key as PEM files
+// and returns their paths along with the parsed certificate.
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "tls.crt")
+	keyFile = filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile, cert
+}
+
+func TestKeyService_TLSListener(t *testing.T) {
+	// Arrange
+	certFile, keyFile, cert := writeSelfSignedCert(t)
+	cfg := &config.Config{
+		HTTPListenAddr: ":0",
+		TLSCertFile:    certFile,
+		TLSKeyFile:     keyFile,
+		TLSMinVersion:  tls.VersionTLS12,
+	}
+	passthrough := func(next http.Handler) http.Handler { return next }
+	service := keyservice.NewKeyService(cfg, inmemory.New(), passthrough, newTestLogger())
+
+	go func() { _ = service.Start() }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = service.Shutdown(ctx)
+	})
+	require.Eventually(t, func() bool { return service.GetHTTPPort() != ":0" }, 5*time.Second, 10*time.Millisecond)
+	addr := "127.0.0.1" + service.GetHTTPPort()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	t.Run("Success - TLS 1.2 client is served", func(t *testing.T) {
+		// Arrange
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:    roots,
+			MaxVersion: tls.VersionTLS12,
+		}}}
+
+		// Act
+		resp, err := client.Get("https://" + addr + "/healthz")
+
+		// Assert
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, uint16(tls.VersionTLS12), resp.TLS.Version)
+	})
+
+	t.Run("Failure - TLS 1.0 client is refused", func(t *testing.T) {
+		// Act
+		conn, err := tls.Dial("tcp", addr, &tls.Config{
+			RootCAs:    roots,
+			MinVersion: tls.VersionTLS10,
+			MaxVersion: tls.VersionTLS10,
+		})
+
+		// Assert
+		if conn != nil {
+			conn.Close()
+		}
+		assert.Error(t, err)
+	})
+
+	t.Run("Failure - weak cipher suite is refused", func(t *testing.T) {
+		// Act
+		conn, err := tls.Dial("tcp", addr, &tls.Config{
+			RootCAs:      roots,
+			MaxVersion:   tls.VersionTLS12,
+			CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA},
+		})
+
+		// Assert
+		if conn != nil {
+			conn.Close()
+		}
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,115 @@
+// --- File: pkg/keystore/algorithms.go ---
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+)
+
+// ValidationMode controls how strictly key algorithms are checked on store.
+type ValidationMode string
+
+const (
+	// ValidationStrict requires clients to declare encAlg and sigAlg and
+	// checks the key bytes against each algorithm's expected length.
+	ValidationStrict ValidationMode = "strict"
+	// ValidationLenient checks any declared algorithm, but accepts keys
+	// without a declaration. This is the default.
+	ValidationLenient ValidationMode = "lenient"
+	// ValidationOff skips algorithm validation entirely.
+	ValidationOff ValidationMode = "off"
+)
+
+// ParseValidationMode converts a config value into a ValidationMode.
+// An empty value selects ValidationLenient.
+func ParseValidationMode(s string) (ValidationMode, error) {
+	switch mode := ValidationMode(s); mode {
+	case "":
+		return ValidationLenient, nil
+	case ValidationStrict, ValidationLenient, ValidationOff:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown key validation mode %q (expected strict, lenient or off)", s)
+	}
+}
+
+// Algorithm names a public key algorithm a client can declare.
+type Algorithm string
+
+const (
+	AlgX25519  Algorithm = "X25519"
+	AlgEd25519 Algorithm = "Ed25519"
+	AlgP256    Algorithm = "P-256"
+	AlgRSA2048 Algorithm = "RSA-2048"
+	AlgRSA4096 Algorithm = "RSA-4096"
+)
+
+// algorithmKeyLengths lists the accepted encoded key lengths for each
+// algorithm: the raw public key where one exists, and its SPKI (DER) form.
+var algorithmKeyLengths = map[Algorithm][]int{
+	AlgX25519:  {32, 44},
+	AlgEd25519: {32, 44},
+	AlgP256:    {65, 91},
+	AlgRSA2048: {294},
+	AlgRSA4096: {550},
+}
+
+var (
+	// ErrAlgorithmRequired is returned in strict mode when a key has no declared algorithm.
+	ErrAlgorithmRequired = errors.New("key algorithm must be declared")
+	// ErrUnknownAlgorithm is returned when a declared algorithm is not supported.
+	ErrUnknownAlgorithm = errors.New("unknown key algorithm")
+	// ErrAlgorithmMismatch is returned when key bytes do not fit the declared algorithm.
+	ErrAlgorithmMismatch = errors.New("key length does not match declared algorithm")
+)
+
+// InferAlgorithms returns the algorithms whose encoded keys have the given length.
+func InferAlgorithms(keyLen int) []Algorithm {
+	var algs []Algorithm
+	for alg, lengths := range algorithmKeyLengths {
+		if slices.Contains(lengths, keyLen) {
+			algs = append(algs, alg)
+		}
+	}
+	slices.Sort(algs)
+	return algs
+}
+
+// ValidateKeyAlgorithms checks the key set against the declared encryption
+// and signing algorithms according to the given mode. An absent key with
+// no declared algorithm is skipped; whether it may be absent is a
+// KeyPolicy decision.
+func ValidateKeyAlgorithms(mode ValidationMode, encAlg, sigAlg Algorithm, pk keys.PublicKeys) error {
+	if mode == ValidationOff {
+		return nil
+	}
+	if err := validateKeyAlgorithm(mode, "encKey", encAlg, pk.EncKey); err != nil {
+		return err
+	}
+	return validateKeyAlgorithm(mode, "sigKey", sigAlg, pk.SigKey)
+}
+
+func validateKeyAlgorithm(mode ValidationMode, field string, alg Algorithm, key []byte) error {
+	if alg == "" && len(key) == 0 {
+		return nil
+	}
+	if alg == "" {
+		if mode == ValidationStrict {
+			return fmt.Errorf("%s: %w", field, ErrAlgorithmRequired)
+		}
+		// Lenient: nothing was declared, so there is nothing to hold the key to.
+		return nil
+	}
+
+	lengths, ok := algorithmKeyLengths[alg]
+	if !ok {
+		return fmt.Errorf("%s: %w %q", field, ErrUnknownAlgorithm, alg)
+	}
+	if !slices.Contains(lengths, len(key)) {
+		return fmt.Errorf("%s: %w (%s, got %d bytes)", field, ErrAlgorithmMismatch, alg, len(key))
+	}
+	return nil
+}
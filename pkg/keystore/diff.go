@@ -0,0 +1,28 @@
+// --- File: pkg/keystore/diff.go ---
+package keystore
+
+import (
+	"crypto/sha256"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+)
+
+// KeyDiff reports which keys two entities share. It carries no key
+// material, so it can be shown to callers who may not see the keys.
+type KeyDiff struct {
+	EncKeyEqual bool
+	SigKeyEqual bool
+}
+
+// Identical reports whether both keys are shared.
+func (d KeyDiff) Identical() bool {
+	return d.EncKeyEqual && d.SigKeyEqual
+}
+
+// DiffKeys compares a and b key by key through their SHA-256 digests.
+func DiffKeys(a, b keys.PublicKeys) KeyDiff {
+	return KeyDiff{
+		EncKeyEqual: sha256.Sum256(a.EncKey) == sha256.Sum256(b.EncKey),
+		SigKeyEqual: sha256.Sum256(a.SigKey) == sha256.Sum256(b.SigKey),
+	}
+}
@@ -0,0 +1,65 @@
+// --- File: pkg/keystore/errors.go ---
+package keystore
+
+import (
+	"errors"
+	"fmt"
+
+	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
+)
+
+// Operation names recorded in a StoreError.
+const (
+	OpStorePublicKeys              = "StorePublicKeys"
+	OpStorePublicKeysWithMetadata  = "StorePublicKeysWithMetadata"
+	OpGetOrCreatePublicKeys        = "GetOrCreatePublicKeys"
+	OpSwapPublicKeys               = "SwapPublicKeys"
+	OpGetPublicKeys                = "GetPublicKeys"
+	OpGetKeyRecord                 = "GetKeyRecord"
+	OpGetPublicKeysIfModifiedSince = "GetPublicKeysIfModifiedSince"
+	OpGetFingerprintsBatch         = "GetFingerprintsBatch"
+	OpGetPublicKeysBatch           = "GetPublicKeysBatch"
+	OpDiffPublicKeys               = "DiffPublicKeys"
+	OpListEntitiesMissingSigKey    = "ListEntitiesMissingSigKey"
+	OpIterateAll                   = "IterateAll"
+	OpIterateKeyRecords            = "IterateKeyRecords"
+	OpBumpEpoch                    = "BumpEpoch"
+	OpRunInTransaction             = "RunInTransaction"
+	OpMarkCompromised              = "MarkCompromised"
+	OpClearCompromised             = "ClearCompromised"
+	OpDeletePublicKeys             = "DeletePublicKeys"
+	OpDeleteOlderThan              = "DeleteOlderThan"
+	OpStats                        = "Stats"
+)
+
+// ErrKeyNotFound is the cause of a StoreError for an entity that has no
+// keys. Callers should test for it with errors.Is, so that a backend that
+// failed is not mistaken for an entity without keys.
+var ErrKeyNotFound = errors.New("key not found")
+
+// StoreError is returned by Store implementations when an operation fails.
+// It records the operation and the entity it targeted, so callers can
+// inspect a failure with errors.As rather than parsing the message.
+type StoreError struct {
+	// Op is the name of the store operation that failed (e.g. OpGetPublicKeys).
+	Op string
+	// URN is the entity the operation was acting on. It is the zero URN
+	// for operations that span the whole store, such as OpIterateAll.
+	URN urn.URN
+	// Err is the underlying cause.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *StoreError) Error() string {
+	if e.URN.IsZero() {
+		return fmt.Sprintf("%s: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("%s %s: %v", e.Op, e.URN.String(), e.Err)
+}
+
+// Unwrap returns the underlying cause, allowing errors.Is and errors.As
+// to see through the StoreError.
+func (e *StoreError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,22 @@
+// --- File: pkg/keystore/fingerprint.go ---
+package keystore
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+)
+
+// Fingerprint returns the hex SHA-256 digest identifying a key pair. Each
+// key is prefixed with its length as a 4-byte big-endian integer, encKey
+// first, so no two pairs share an input.
+func Fingerprint(pk keys.PublicKeys) string {
+	h := sha256.New()
+	for _, key := range [][]byte{pk.EncKey, pk.SigKey} {
+		_ = binary.Write(h, binary.BigEndian, uint32(len(key)))
+		h.Write(key)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,49 @@
+// --- File: pkg/keystore/policy.go ---
+package keystore
+
+import (
+	"errors"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+)
+
+// KeyPolicy describes which keys an entity type must supply and how
+// strictly their algorithms are checked. The zero value requires both
+// keys and inherits the service-wide validation mode.
+type KeyPolicy struct {
+	// EncKeyOptional allows a key set without an encryption key.
+	EncKeyOptional bool
+	// SigKeyOptional allows a key set without a signing key.
+	SigKeyOptional bool
+	// ValidationMode overrides the service-wide mode when non-empty.
+	ValidationMode ValidationMode
+}
+
+var (
+	// ErrKeysRequired is returned when the policy requires both keys.
+	ErrKeysRequired = errors.New("encKey and sigKey must not be empty")
+	// ErrEncKeyRequired is returned when the policy requires an encryption key.
+	ErrEncKeyRequired = errors.New("encKey must not be empty")
+	// ErrSigKeyRequired is returned when the policy requires a signing key.
+	ErrSigKeyRequired = errors.New("sigKey must not be empty")
+	// ErrNoKeys is returned when a key set has neither key.
+	ErrNoKeys = errors.New("at least one of encKey and sigKey must be set")
+)
+
+// CheckRequiredKeys reports whether pk holds the keys the policy requires.
+// Even when both keys are optional, an empty key set is rejected.
+func (p KeyPolicy) CheckRequiredKeys(pk keys.PublicKeys) error {
+	missingEnc := len(pk.EncKey) == 0 && !p.EncKeyOptional
+	missingSig := len(pk.SigKey) == 0 && !p.SigKeyOptional
+	switch {
+	case missingEnc && missingSig, (missingEnc || missingSig) && !p.EncKeyOptional && !p.SigKeyOptional:
+		return ErrKeysRequired
+	case missingEnc:
+		return ErrEncKeyRequired
+	case missingSig:
+		return ErrSigKeyRequired
+	case len(pk.EncKey) == 0 && len(pk.SigKey) == 0:
+		return ErrNoKeys
+	}
+	return nil
+}
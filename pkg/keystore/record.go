@@ -0,0 +1,55 @@
+// --- File: pkg/keystore/record.go ---
+package keystore
+
+import (
+	"time"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+)
+
+// Metadata is optional, client-supplied information stored alongside keys.
+type Metadata struct {
+	// RotationHint is when the owner intends to rotate its keys next.
+	// It is advisory only. The zero value means no hint was given.
+	RotationHint time.Time
+	// EncKeySignature is the signing key's signature over the encryption
+	// key, binding the two. The API verifies it before storing. Nil means
+	// no binding was supplied.
+	EncKeySignature []byte
+	// ProvisioningSignature is an upstream provisioner's signature vouching
+	// for the entity and its keys. It is stored as given and checked on
+	// read against the configured trusted provisioners. Nil means none.
+	ProvisioningSignature []byte
+	// UsageRestrictions are the owner's declared constraints on how the
+	// keys are used. The API validates them before storing.
+	UsageRestrictions UsageRestrictions
+}
+
+// KeyRecord is an entity's stored keys together with their metadata.
+type KeyRecord struct {
+	Keys     keys.PublicKeys
+	Metadata Metadata
+	// UpdatedAt is when the record was last written, as set by the store.
+	UpdatedAt time.Time
+	// AccessCount is how many times the keys have been read since they
+	// were last stored. It is zero unless the store counts accesses, and
+	// stores may apply increments asynchronously.
+	AccessCount int64
+	// Epoch is the entity's rotation epoch, advanced only by BumpEpoch.
+	// Storing keys leaves it unchanged. Zero means it was never bumped.
+	Epoch uint64
+	// Compromised is set by MarkCompromised and cleared by ClearCompromised
+	// or by storing keys again. CompromisedReason is the note given with it.
+	Compromised       bool
+	CompromisedReason string
+}
+
+// StoreStats is an approximate summary of a store's contents, for
+// capacity planning.
+type StoreStats struct {
+	// Entities is the number of entities with stored keys.
+	Entities int64
+	// ApproxBytes estimates the storage the records take up. Each store
+	// documents what it counts.
+	ApproxBytes int64
+}
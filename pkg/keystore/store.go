@@ -5,6 +5,7 @@ package keystore
 
 import (
 	"context"
+	"time"
 
 	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
 	urn "github.com/tinywideclouds/go-platform/pkg/net/v1"
@@ -18,7 +19,120 @@ type Store interface {
 	// It should overwrite any existing keys for that entity.
 	StorePublicKeys(ctx context.Context, entityURN urn.URN, keys keys.PublicKeys) error
 
+	// StorePublicKeysWithMetadata is StorePublicKeys with accompanying metadata.
+	// It overwrites both the keys and any existing metadata; StorePublicKeys
+	// behaves as if it were called with empty Metadata.
+	StorePublicKeysWithMetadata(ctx context.Context, entityURN urn.URN, keys keys.PublicKeys, meta Metadata) error
+
+	// GetOrCreatePublicKeys atomically stores candidate only if the entity has
+	// no keys yet. It returns the keys now in effect: the existing keys with
+	// created=false, or candidate with created=true.
+	GetOrCreatePublicKeys(ctx context.Context, entityURN urn.URN, candidate keys.PublicKeys) (effective keys.PublicKeys, created bool, err error)
+
+	// SwapPublicKeys atomically replaces the entity's keys as StorePublicKeys
+	// does and returns the keys it displaced, or the zero value if the
+	// entity had none.
+	SwapPublicKeys(ctx context.Context, entityURN urn.URN, newKeys keys.PublicKeys) (old keys.PublicKeys, err error)
+
 	// GetPublicKeys retrieves the PublicKeys struct for a specific entity.
-	// If no keys are found, it should return an error.
+	// If no keys are found, it should return an error wrapping ErrKeyNotFound.
 	GetPublicKeys(ctx context.Context, entityURN urn.URN) (keys.PublicKeys, error)
+
+	// GetKeyRecord retrieves an entity's keys together with their metadata
+	// and last update time. If no keys are found, it should return an error
+	// wrapping ErrKeyNotFound.
+	GetKeyRecord(ctx context.Context, entityURN urn.URN) (KeyRecord, error)
+
+	// GetPublicKeysIfModifiedSince retrieves the PublicKeys struct only if the
+	// entity's keys were updated after `since`. The bool reports whether they
+	// were; when it is false the returned keys are empty.
+	// If no keys are found, it should return an error wrapping ErrKeyNotFound.
+	GetPublicKeysIfModifiedSince(ctx context.Context, entityURN urn.URN, since time.Time) (keys.PublicKeys, bool, error)
+
+	// GetFingerprintsBatch returns the Fingerprint of each listed entity's
+	// keys, keyed by the URN's string form. Entities without keys are left
+	// out rather than reported as an error. Reads made this way are not
+	// counted as key accesses.
+	GetFingerprintsBatch(ctx context.Context, entityURNs []urn.URN) (map[string]string, error)
+
+	// GetPublicKeysBatch returns the KeyRecord of each listed entity, keyed
+	// by the URN's string form, as GetKeyRecord would for each in turn.
+	// Entities without keys are left out rather than reported as an error.
+	GetPublicKeysBatch(ctx context.Context, entityURNs []urn.URN) (map[string]KeyRecord, error)
+
+	// DiffPublicKeys reports which keys entities a and b share, as DiffKeys
+	// does, reading both as of the same moment where the backend allows.
+	// If either has no keys, it should return an error wrapping
+	// ErrKeyNotFound for that entity. Reads made this way are not counted
+	// as key accesses.
+	DiffPublicKeys(ctx context.Context, a, b urn.URN) (KeyDiff, error)
+
+	// ListEntitiesMissingSigKey returns every entity whose stored keys have
+	// no signing key, ordered by the URN's string form.
+	ListEntitiesMissingSigKey(ctx context.Context) ([]urn.URN, error)
+
+	// BumpEpoch atomically increments the entity's rotation epoch and
+	// returns the new value. The epoch is independent of the keys: storing
+	// keys neither bumps nor resets it. If no keys are found, it should
+	// return an error wrapping ErrKeyNotFound.
+	BumpEpoch(ctx context.Context, entityURN urn.URN) (uint64, error)
+
+	// MarkCompromised atomically flags the entity's keys as compromised,
+	// recording reason, without changing the keys or their update time.
+	// Storing keys again clears the flag. If no keys are found, it should
+	// return an error wrapping ErrKeyNotFound.
+	MarkCompromised(ctx context.Context, entityURN urn.URN, reason string) error
+
+	// ClearCompromised removes the compromised flag and its reason. It is
+	// not an error if the flag was not set, but if no keys are found, it
+	// should return an error wrapping ErrKeyNotFound.
+	ClearCompromised(ctx context.Context, entityURN urn.URN) error
+
+	// DeletePublicKeys removes the entity's keys, along with their metadata,
+	// epoch and compromise flag. The bool reports whether there were keys
+	// to remove; deleting an entity that has none is not an error.
+	DeletePublicKeys(ctx context.Context, entityURN urn.URN) (deleted bool, err error)
+
+	// DeleteOlderThan removes every entity whose keys were last stored more
+	// than age ago, as judged by the store's clock, and returns how many
+	// were removed. Epochs and compromise flags go with the keys. A store
+	// that fails part way may have removed some entities, and reports how
+	// many alongside the error.
+	DeleteOlderThan(ctx context.Context, age time.Duration) (deleted int, err error)
+
+	// IterateAll calls fn once for every stored entity, in no particular order.
+	// Iteration stops at the first error returned by fn, which IterateAll returns.
+	IterateAll(ctx context.Context, fn func(entityURN urn.URN, keys keys.PublicKeys) error) error
+
+	// IterateKeyRecords is IterateAll with each entity's metadata and last
+	// update time, for callers that report on the records themselves.
+	IterateKeyRecords(ctx context.Context, fn func(entityURN urn.URN, record KeyRecord) error) error
+
+	// Stats reports how many entities are stored and roughly how many
+	// bytes they take up. Stores may estimate rather than count exactly.
+	Stats(ctx context.Context) (StoreStats, error)
+}
+
+// Transactor is implemented by stores that can read and write several
+// entities atomically. It is optional: decorators do not forward it, so
+// callers should type-assert the backend store.
+type Transactor interface {
+	// RunInTransaction calls fn with a transactional view of the store.
+	// If fn returns nil, every write it made is committed together;
+	// otherwise none are. fn may be called more than once if the
+	// transaction is retried, so it must not have other side effects,
+	// and it must not call back into the store outside tx.
+	RunInTransaction(ctx context.Context, fn func(tx StoreTx) error) error
+}
+
+// StoreTx is the view of the store inside RunInTransaction. As in
+// Firestore, every read must happen before the first write.
+type StoreTx interface {
+	// GetKeyRecord reads an entity's record as of the transaction.
+	// If no keys are found, it should return an error wrapping ErrKeyNotFound.
+	GetKeyRecord(entityURN urn.URN) (KeyRecord, error)
+
+	// StorePublicKeysWithMetadata stages a write of the entity's keys and
+	// metadata, with the same semantics as Store.StorePublicKeysWithMetadata.
+	StorePublicKeysWithMetadata(entityURN urn.URN, keys keys.PublicKeys, meta Metadata) error
 }
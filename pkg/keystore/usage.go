@@ -0,0 +1,75 @@
+// --- File: pkg/keystore/usage.go ---
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tinywideclouds/go-platform/pkg/keys/v1"
+)
+
+// KeyUsage restricts what an entity's keys may be used for.
+type KeyUsage string
+
+const (
+	// KeyUsageAny places no restriction on usage. It is the zero value.
+	KeyUsageAny KeyUsage = ""
+	// KeyUsageEncryptOnly means peers may encrypt to the entity but should
+	// not accept its signatures.
+	KeyUsageEncryptOnly KeyUsage = "encrypt-only"
+	// KeyUsageSignOnly means peers may verify the entity's signatures but
+	// should not encrypt to it.
+	KeyUsageSignOnly KeyUsage = "sign-only"
+)
+
+// UsageRestrictions are the owner's declared constraints on how its keys
+// are used. The service stores and serves them; honoring them is up to
+// the recipients. The zero value places no restrictions.
+type UsageRestrictions struct {
+	// Usage limits the keys to encryption or signing.
+	Usage KeyUsage `json:"usage,omitempty"`
+	// NotBefore and NotAfter bound when the keys may be used. A zero
+	// time leaves that end of the window open.
+	NotBefore time.Time `json:"notBefore,omitzero"`
+	NotAfter  time.Time `json:"notAfter,omitzero"`
+}
+
+// ErrInvalidUsageRestrictions is returned by UsageRestrictions.Validate.
+var ErrInvalidUsageRestrictions = errors.New("invalid usage restrictions")
+
+// IsZero reports whether u places no restrictions.
+func (u UsageRestrictions) IsZero() bool {
+	return u.Usage == KeyUsageAny && u.NotBefore.IsZero() && u.NotAfter.IsZero()
+}
+
+// Equal reports whether u and other declare the same restrictions.
+func (u UsageRestrictions) Equal(other UsageRestrictions) bool {
+	return u.Usage == other.Usage && u.NotBefore.Equal(other.NotBefore) && u.NotAfter.Equal(other.NotAfter)
+}
+
+// Validate checks that u is a usable declaration for pk at now: the usage
+// is known and names a key pk holds, and the window has not already closed
+// and is not empty. Errors wrap ErrInvalidUsageRestrictions.
+func (u UsageRestrictions) Validate(pk keys.PublicKeys, now time.Time) error {
+	switch u.Usage {
+	case KeyUsageAny:
+	case KeyUsageEncryptOnly:
+		if len(pk.EncKey) == 0 {
+			return fmt.Errorf("%w: usage %q needs an encKey", ErrInvalidUsageRestrictions, u.Usage)
+		}
+	case KeyUsageSignOnly:
+		if len(pk.SigKey) == 0 {
+			return fmt.Errorf("%w: usage %q needs a sigKey", ErrInvalidUsageRestrictions, u.Usage)
+		}
+	default:
+		return fmt.Errorf("%w: unknown usage %q", ErrInvalidUsageRestrictions, u.Usage)
+	}
+	if !u.NotAfter.IsZero() && !u.NotAfter.After(now) {
+		return fmt.Errorf("%w: notAfter must be in the future", ErrInvalidUsageRestrictions)
+	}
+	if !u.NotBefore.IsZero() && !u.NotAfter.IsZero() && !u.NotBefore.Before(u.NotAfter) {
+		return fmt.Errorf("%w: notBefore must be before notAfter", ErrInvalidUsageRestrictions)
+	}
+	return nil
+}